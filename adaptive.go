@@ -0,0 +1,156 @@
+package binq
+
+import "time"
+
+const (
+	// adaptiveReorderInterval is the number of records between reordering the children of an AdaptiveRecordMatcher.
+	adaptiveReorderInterval = 1024
+	// adaptiveSampleInterval is the number of records between timing the children of an AdaptiveRecordMatcher.
+	adaptiveSampleInterval = 16
+	// adaptiveMinSamples is the number of times a child is timed before its time replaces its estimated cost.
+	adaptiveMinSamples = 4
+)
+
+var _ RecordMatcher = (*AdaptiveRecordMatcher)(nil)
+
+// AdaptiveRecordMatcher is a RecordMatcher like AllRecords or AnyRecords, which evaluates its children
+// in order of their cost and selectivity rather than in the order they are given.
+//
+// Children are first ordered by their estimated cost, such as from ExpressionCost. While records are
+// matched, it counts how often each child decides the result and periodically times the children,
+// and then reorders them, such that cheap children that often decide the result are evaluated first.
+// Older records count for less at every reordering, so the order follows changes in the data of a scan.
+//
+// The result of records for which no child fails does not depend on the order. A record for which
+// a child fails may instead be decided by another child evaluated before it, and vice versa.
+// An AdaptiveRecordMatcher is not safe for concurrent use.
+type AdaptiveRecordMatcher struct {
+	// decides is the result of a child that decides the result: false for All and true for Any.
+	decides  bool
+	children []*adaptiveChild
+	records  uint64
+}
+
+// adaptiveChild is a child of an AdaptiveRecordMatcher and its statistics.
+type adaptiveChild struct {
+	matcher RecordMatcher
+	index   int
+	// cost is the estimated cost of the child.
+	cost float64
+	// evaluations counts the records the child matched, of which decisions decided the result.
+	evaluations, decisions uint64
+	// samples counts the times the child was timed, taking elapsed in total.
+	samples uint64
+	elapsed time.Duration
+}
+
+// AdaptiveAllRecords creates an AdaptiveRecordMatcher that matches if all RecordMatcher predicates
+// are satisfied. costs are the estimated costs of the matchers, or nil if they are unknown.
+func AdaptiveAllRecords(costs []float64, funcs ...RecordMatcher) *AdaptiveRecordMatcher {
+	return newAdaptiveRecordMatcher(false, costs, funcs)
+}
+
+// AdaptiveAnyRecords creates an AdaptiveRecordMatcher that matches if at least one RecordMatcher
+// predicate is satisfied. costs are the estimated costs of the matchers, or nil if they are unknown.
+func AdaptiveAnyRecords(costs []float64, funcs ...RecordMatcher) *AdaptiveRecordMatcher {
+	return newAdaptiveRecordMatcher(true, costs, funcs)
+}
+
+func newAdaptiveRecordMatcher(decides bool, costs []float64, funcs []RecordMatcher) *AdaptiveRecordMatcher {
+	m := &AdaptiveRecordMatcher{decides: decides, children: make([]*adaptiveChild, len(funcs))}
+	for index, f := range funcs {
+		cost := 1.0
+		if index < len(costs) {
+			cost = costs[index]
+		}
+		m.children[index] = &adaptiveChild{matcher: f, index: index, cost: cost}
+	}
+	m.reorder()
+	return m
+}
+
+// MatchRecord satisfies the RecordMatcher interface.
+func (m *AdaptiveRecordMatcher) MatchRecord(key, value []byte) (bool, error) {
+	m.records++
+	if m.records%adaptiveReorderInterval == 0 {
+		m.reorder()
+	}
+	sample := m.records%adaptiveSampleInterval == 0
+	for _, child := range m.children {
+		var start time.Time
+		if sample {
+			start = time.Now()
+		}
+		result, err := child.matcher.MatchRecord(key, value)
+		if sample {
+			child.elapsed += time.Since(start)
+			child.samples++
+		}
+		if err != nil {
+			return false, wrap(err, "unable to run matcher")
+		}
+		child.evaluations++
+		if result == m.decides {
+			child.decisions++
+			return m.decides, nil
+		}
+	}
+	return !m.decides, nil
+}
+
+// Order returns the indexes of the children, in the order they are evaluated.
+func (m *AdaptiveRecordMatcher) Order() []int {
+	order := make([]int, len(m.children))
+	for position, child := range m.children {
+		order[position] = child.index
+	}
+	return order
+}
+
+// reorder sorts the children by their rank and halves their statistics, so that they follow recent records.
+func (m *AdaptiveRecordMatcher) reorder() {
+	scale := m.timeScale()
+	// Insertion sort is stable and does not allocate, and there are few children.
+	for i := 1; i < len(m.children); i++ {
+		for j := i; j > 0 && m.children[j].rank(scale) < m.children[j-1].rank(scale); j-- {
+			m.children[j], m.children[j-1] = m.children[j-1], m.children[j]
+		}
+	}
+	for _, child := range m.children {
+		child.evaluations /= 2
+		child.decisions /= 2
+		child.samples /= 2
+		child.elapsed /= 2
+	}
+}
+
+// timeScale estimates the time per unit of estimated cost from the children that were timed,
+// so that children that were not timed can be compared with those that were. It is 0 if no
+// child was timed enough.
+func (m *AdaptiveRecordMatcher) timeScale() float64 {
+	var elapsed, cost float64
+	for _, child := range m.children {
+		if child.samples >= adaptiveMinSamples && child.cost > 0 {
+			elapsed += float64(child.elapsed)
+			cost += child.cost * float64(child.samples)
+		}
+	}
+	if cost == 0 {
+		return 0
+	}
+	return elapsed / cost
+}
+
+// rank is the expected cost of the child for each record its result decides, lowest first.
+// The chance of deciding the result starts at one half and follows the records the child matched.
+func (c *adaptiveChild) rank(scale float64) float64 {
+	cost := c.cost
+	switch {
+	case c.samples >= adaptiveMinSamples && scale > 0:
+		cost = float64(c.elapsed) / float64(c.samples)
+	case scale > 0:
+		cost *= scale
+	}
+	chance := float64(c.decisions+1) / float64(c.evaluations+2)
+	return cost / chance
+}
@@ -0,0 +1,206 @@
+package binq
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestAdaptiveAllRecords(t *testing.T) {
+	t.Parallel()
+	var testKey, testValue []byte
+
+	cases := []struct {
+		name          string
+		matchers      []RecordMatcher
+		expectedMatch bool
+		expectedErr   bool
+	}{
+		{"empty", []RecordMatcher{}, true, false},
+		{"single-match", []RecordMatcher{MatchValue(matchesAny())}, true, false},
+		{"single-no-match", []RecordMatcher{MatchKey(matchesNone())}, false, false},
+		{"single-err", []RecordMatcher{MatchValue(matchesErr())}, false, true},
+		{"multi-match", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesAny())}, true, false},
+		{"multi-no-match", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesNone())}, false, false},
+		{"multi-err", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesErr())}, false, true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := AdaptiveAllRecords(nil, tc.matchers...).MatchRecord(testKey, testValue)
+			assert.Equal(t, tc.expectedErr, err != nil, "(un)expected error")
+			assert.Equal(t, tc.expectedMatch, matches, "(un)expected match")
+		})
+	}
+}
+
+func TestAdaptiveAnyRecords(t *testing.T) {
+	t.Parallel()
+	var testKey, testValue []byte
+
+	cases := []struct {
+		name          string
+		matchers      []RecordMatcher
+		expectedMatch bool
+		expectedErr   bool
+	}{
+		{"empty", []RecordMatcher{}, false, false},
+		{"single-match", []RecordMatcher{MatchValue(matchesAny())}, true, false},
+		{"single-no-match", []RecordMatcher{MatchKey(matchesNone())}, false, false},
+		{"single-err", []RecordMatcher{MatchValue(matchesErr())}, false, true},
+		{"multi-one-match", []RecordMatcher{MatchKey(matchesNone()), MatchValue(matchesAny())}, true, false},
+		{"multi-no-match", []RecordMatcher{MatchKey(matchesNone()), MatchValue(matchesNone())}, false, false},
+		{"multi-match-err", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesErr())}, true, false},
+		{"multi-no-match-err", []RecordMatcher{MatchKey(matchesNone()), MatchValue(matchesErr())}, false, true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := AdaptiveAnyRecords(nil, tc.matchers...).MatchRecord(testKey, testValue)
+			assert.Equal(t, tc.expectedErr, err != nil, "(un)expected error")
+			assert.Equal(t, tc.expectedMatch, matches, "(un)expected match")
+		})
+	}
+}
+
+func TestAdaptiveRecordMatcher_Order(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		matcher  *AdaptiveRecordMatcher
+		initial  []int
+		expected []int
+	}{
+		{
+			"all-by-cost",
+			AdaptiveAllRecords([]float64{3, 1, 2}, MatchValue(matchesNone()), MatchValue(matchesNone()), MatchValue(matchesNone())),
+			[]int{1, 2, 0},
+			[]int{1, 2, 0},
+		},
+		{
+			"all-by-selectivity",
+			AdaptiveAllRecords(nil, MatchValue(matchesAny()), MatchValue(matchesNone())),
+			[]int{0, 1},
+			[]int{1, 0},
+		},
+		{
+			"any-by-selectivity",
+			AdaptiveAnyRecords([]float64{1, 2}, MatchValue(matchesNone()), MatchValue(matchesAny())),
+			[]int{0, 1},
+			[]int{1, 0},
+		},
+		{
+			"selective-over-cheap",
+			AdaptiveAllRecords([]float64{1, 4}, MatchValue(matchesAny()), MatchValue(matchesNone())),
+			[]int{0, 1},
+			[]int{1, 0},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.initial, tc.matcher.Order())
+			for i := 0; i < 2*adaptiveReorderInterval; i++ {
+				_, err := tc.matcher.MatchRecord(nil, nil)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, tc.matcher.Order())
+		})
+	}
+}
+
+// TestPredicateToRecordMatcher_WithAdaptiveOrder asserts that adaptive matchers of random predicates
+// match the same records as the predicates, while they are reordered.
+func TestPredicateToRecordMatcher_WithAdaptiveOrder(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(6))
+	for i := 0; i < 100; i++ {
+		pred := randomPredicate(r)
+		matcher, err := PredicateToRecordMatcher(pred)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		adaptive, err := PredicateToRecordMatcher(pred, WithAdaptiveOrder())
+		if !assert.NoError(t, err) {
+			continue
+		}
+		for j := 0; j < 3*adaptiveReorderInterval; j++ {
+			key, value := randomRecordBytes(r), randomRecordBytes(r)
+			expected, expectedErr := matcher.MatchRecord(key, value)
+			got, err := adaptive.MatchRecord(key, value)
+			if expectedErr == nil && err == nil {
+				assert.Equal(t, expected, got, "%v", pred)
+			}
+		}
+	}
+}
+
+// TestPredicateToRecordMatcher_WithAdaptiveOrder_nested asserts that the operands of a chain
+// within an All or Any are ordered separately.
+func TestPredicateToRecordMatcher_WithAdaptiveOrder_nested(t *testing.T) {
+	t.Parallel()
+	comparison := func(offset uint64) *Expression {
+		return makeBinaryOperationExpression(makeValueExpression(ValueType_VALUE_TYPE_U8, offset), BinaryOpCode_BINARY_OP_CODE_EQ, makeScalarExpression(t, uint32(1)))
+	}
+	nested := func(opCode BinaryOpCode) []*Expression {
+		return []*Expression{comparison(0), makeBinaryOperationExpression(comparison(1), opCode, comparison(2))}
+	}
+	cases := []struct {
+		name  string
+		pred  *Predicate
+		value []byte
+	}{
+		{"all", &Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: nested(BinaryOpCode_BINARY_OP_CODE_AND)}}}, []byte{1, 1, 0}},
+		{"any", &Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: nested(BinaryOpCode_BINARY_OP_CODE_OR)}}}, []byte{0, 0, 1}},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matcher, err := PredicateToRecordMatcher(tc.pred, WithAdaptiveOrder())
+			if !assert.NoError(t, err) {
+				return
+			}
+			adaptive, ok := matcher.(*AdaptiveRecordMatcher)
+			if !assert.True(t, ok, "%T", matcher) {
+				return
+			}
+			assert.Len(t, adaptive.Order(), 3)
+			for i := 0; i < 2*adaptiveReorderInterval; i++ {
+				_, err := adaptive.MatchRecord(nil, tc.value)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, 2, adaptive.Order()[0])
+		})
+	}
+}
+
+// BenchmarkAdaptiveRecordMatcher matches a predicate whose first expression is expensive and rarely
+// decides the result, in order and with adaptive order.
+func BenchmarkAdaptiveRecordMatcher(b *testing.B) {
+	pred, err := NewParser("VALUE(JUMP(0, U8), BYTES(U16LE)) != \"world\" AND VALUE(1, U8) = U32(1)").ReadPredicate()
+	if err != nil {
+		b.Fatal(err)
+	}
+	value := makeBytes(b, u8(3), u8(0), u8(0), u16le(5), "hello")
+	options := map[string][]MatcherOption{"in-order": nil, "adaptive": {WithAdaptiveOrder()}}
+	for name, opts := range options {
+		b.Run(name, func(b *testing.B) {
+			matcher, err := PredicateToRecordMatcher(pred, opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := matcher.MatchRecord(nil, value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
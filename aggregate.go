@@ -0,0 +1,464 @@
+package binq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// MaxGroups is the most groups an Aggregator holds, which bounds the memory of an aggregation.
+const MaxGroups = 1 << 20
+
+// ErrTooManyGroups indicates that the records of an aggregation fall into more than MaxGroups groups.
+var ErrTooManyGroups = errors.New("too many groups")
+
+// aggregateFunctionPrefix is the prefix of the names of AggregateFunction values,
+// which are named without it in text.
+const aggregateFunctionPrefix = "AGGREGATE_FUNCTION_"
+
+// aggregateFunctions are the AggregateFunction values by their names in text.
+var aggregateFunctions = make(map[string]AggregateFunction)
+
+func init() {
+	for value, name := range AggregateFunction_name {
+		if function := AggregateFunction(value); function != AggregateFunction_AGGREGATE_FUNCTION_UNKNOWN {
+			aggregateFunctions[strings.TrimPrefix(name, aggregateFunctionPrefix)] = function
+		}
+	}
+}
+
+// aggregateFunctionText is the name of an AggregateFunction in text.
+func aggregateFunctionText(function AggregateFunction) string {
+	return strings.TrimPrefix(function.String(), aggregateFunctionPrefix)
+}
+
+// CheckAggregation reports every type error of an aggregation like Check, each with the path
+// to the offending node, and aliases that are invalid or used more than once.
+// Returns nil if the aggregation is well typed.
+func CheckAggregation(aggregation *Aggregation) CheckErrors {
+	c := &checker{}
+	aliases := make(map[string]bool)
+	c.checkProjection("aggregation.group_by", aggregation.GetGroupBy(), aliases)
+	for index, aggregate := range aggregation.GetAggregates() {
+		path := fmt.Sprintf("aggregation.aggregates[%d]", index)
+		c.checkAlias(joinPath(path, "alias"), aggregate.GetAlias(), aliases)
+		c.checkAggregate(path, aggregate)
+	}
+	return c.errs
+}
+
+func (c *checker) checkAggregate(path string, aggregate *Aggregate) {
+	function := aggregate.GetFunction()
+	if _, known := AggregateFunction_name[int32(function)]; !known || function == AggregateFunction_AGGREGATE_FUNCTION_UNKNOWN {
+		c.errorf(joinPath(path, "function"), "unknown aggregate function %s", function)
+		return
+	}
+	expressionPath := joinPath(path, "expression")
+	if function == AggregateFunction_AGGREGATE_FUNCTION_COUNT {
+		if aggregate.GetExpression() != nil {
+			c.errorf(expressionPath, "COUNT takes no expression")
+		}
+		return
+	}
+	if aggregate.GetExpression() == nil {
+		c.errorf(path, "missing expression")
+		return
+	}
+	returnType, ok := c.checkExpression(expressionPath, aggregate.GetExpression())
+	if !ok {
+		return
+	}
+	numeric := isIntegerType(returnType) || isFloatType(returnType)
+	switch function {
+	case AggregateFunction_AGGREGATE_FUNCTION_MIN, AggregateFunction_AGGREGATE_FUNCTION_MAX:
+		if !numeric && returnType != ReturnType_RETURN_TYPE_BYTES {
+			c.errorf(expressionPath, "%s expects a number or bytes, got %s", aggregateFunctionText(function), returnType)
+		}
+	default:
+		if !numeric {
+			c.errorf(expressionPath, "%s expects a number, got %s", aggregateFunctionText(function), returnType)
+		}
+	}
+}
+
+// Aggregator computes an Aggregation over records.
+type Aggregator struct {
+	// groupBy extracts the fields that group records.
+	groupBy *Projector
+	// aggregates are the aggregates computed for each group.
+	aggregates []*aggregateSpec
+	// names are the names of the fields of the results.
+	names []string
+	// groups are the groups by their encoded fields, in the order of their first record.
+	groups map[string]*aggregateGroup
+	order  []*aggregateGroup
+	// maxGroups is the most groups held, which is MaxGroups outside of tests.
+	maxGroups int
+	// groupKey is a buffer for encoding the fields of a group.
+	groupKey []byte
+}
+
+// aggregateSpec is an aggregate of an Aggregator.
+type aggregateSpec struct {
+	function AggregateFunction
+	// expression is the compiled expression of the aggregate, which is unset for COUNT.
+	expression compiled
+}
+
+// aggregateGroup is a group of records and the accumulators of its aggregates.
+type aggregateGroup struct {
+	fields       []*Scalar
+	accumulators []accumulator
+}
+
+// NewAggregator creates an Aggregator for an aggregation, after checking it with CheckAggregation.
+func NewAggregator(aggregation *Aggregation) (*Aggregator, error) {
+	if errs := CheckAggregation(aggregation); len(errs) > 0 {
+		return nil, errs
+	}
+	groupBy, err := newProjector(aggregation.GetGroupBy())
+	if err != nil {
+		return nil, wrap(err, "unable to create group by projector")
+	}
+	a := &Aggregator{
+		groupBy:   groupBy,
+		names:     append([]string(nil), groupBy.Names()...),
+		groups:    make(map[string]*aggregateGroup),
+		maxGroups: MaxGroups,
+	}
+	for _, aggregate := range aggregation.GetAggregates() {
+		spec := &aggregateSpec{function: aggregate.GetFunction()}
+		if aggregate.GetExpression() != nil {
+			if spec.expression, err = compileExpression(aggregate.GetExpression()); err != nil {
+				return nil, wrap(err, "unable to compile aggregate expression")
+			}
+		}
+		name := aggregate.GetAlias()
+		if name == "" {
+			if name, err = formatAggregate(&Aggregate{Function: aggregate.GetFunction(), Expression: aggregate.GetExpression()}); err != nil {
+				return nil, wrap(err, "unable to format aggregate")
+			}
+		}
+		a.aggregates = append(a.aggregates, spec)
+		a.names = append(a.names, name)
+	}
+	if len(aggregation.GetGroupBy()) == 0 {
+		// A single group is returned even if there are no records.
+		if _, err := a.group(nil); err != nil {
+			return nil, wrap(err, "unable to create group")
+		}
+	}
+	return a, nil
+}
+
+// Names returns the names of the fields of the results: the fields that group records,
+// then the aggregates. They are their aliases, or their text if they have none.
+func (a *Aggregator) Names() []string {
+	return a.names
+}
+
+// Add adds a record to its group. A record of a new group fails with ErrTooManyGroups
+// once there are MaxGroups groups.
+func (a *Aggregator) Add(key, value []byte) error {
+	fields, err := a.groupBy.Project(key, value)
+	if err != nil {
+		return wrap(err, "unable to group record")
+	}
+	group, err := a.group(fields)
+	if err != nil {
+		return wrap(err, "unable to group record")
+	}
+	for index, spec := range a.aggregates {
+		if err := group.accumulators[index].add(&spec.expression, key, value); err != nil {
+			return wrap(err, fmt.Sprintf("unable to aggregate %s", a.names[len(a.groupBy.names)+index]))
+		}
+	}
+	return nil
+}
+
+// group gets the group with the fields, creating it if there is none.
+func (a *Aggregator) group(fields []*Scalar) (*aggregateGroup, error) {
+	a.groupKey = a.groupKey[:0]
+	for _, field := range fields {
+		a.groupKey = appendGroupKey(a.groupKey, field)
+	}
+	if group, ok := a.groups[string(a.groupKey)]; ok {
+		return group, nil
+	}
+	if len(a.order) >= a.maxGroups {
+		return nil, ErrTooManyGroups
+	}
+	group := &aggregateGroup{fields: fields, accumulators: make([]accumulator, len(a.aggregates))}
+	for index, spec := range a.aggregates {
+		group.accumulators[index] = newAccumulator(spec.function, spec.expression.returnType)
+	}
+	a.groups[string(a.groupKey)] = group
+	a.order = append(a.order, group)
+	return group, nil
+}
+
+// Results returns the fields of each group, in the order of the first record of each group.
+// The aggregates of a group without records, or without values for MIN, MAX and AVG, are
+// empty Scalars.
+func (a *Aggregator) Results() ([][]*Scalar, error) {
+	results := make([][]*Scalar, len(a.order))
+	for index, group := range a.order {
+		fields := append([]*Scalar(nil), group.fields...)
+		for aggregateIndex, acc := range group.accumulators {
+			result, err := acc.result()
+			if err != nil {
+				return nil, wrap(err, fmt.Sprintf("unable to aggregate %s", a.names[len(group.fields)+aggregateIndex]))
+			}
+			fields = append(fields, result)
+		}
+		results[index] = fields
+	}
+	return results, nil
+}
+
+// appendGroupKey appends an encoding of a scalar that differs from that of any other scalar.
+func appendGroupKey(b []byte, s *Scalar) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	switch t := s.GetValue().(type) {
+	case *Scalar_Bool:
+		b = append(b, 'b', byte(boolInt(t.Bool)))
+	case *Scalar_U32:
+		b = append(b, 'u')
+		b = append(b, buf[:binary.PutUvarint(buf[:], uint64(t.U32))]...)
+	case *Scalar_U64:
+		b = append(b, 'U')
+		b = append(b, buf[:binary.PutUvarint(buf[:], t.U64)]...)
+	case *Scalar_I32:
+		b = append(b, 'i')
+		b = append(b, buf[:binary.PutVarint(buf[:], int64(t.I32))]...)
+	case *Scalar_I64:
+		b = append(b, 'I')
+		b = append(b, buf[:binary.PutVarint(buf[:], t.I64)]...)
+	case *Scalar_F32:
+		b = append(b, 'f')
+		b = append(b, buf[:binary.PutUvarint(buf[:], uint64(math.Float32bits(t.F32)))]...)
+	case *Scalar_F64:
+		b = append(b, 'F')
+		b = append(b, buf[:binary.PutUvarint(buf[:], math.Float64bits(t.F64))]...)
+	case *Scalar_Bytes:
+		b = append(b, 'B')
+		b = append(b, buf[:binary.PutUvarint(buf[:], uint64(len(t.Bytes)))]...)
+		b = append(b, t.Bytes...)
+	default:
+		b = append(b, '?')
+	}
+	return b
+}
+
+// accumulator accumulates the values of an aggregate over the records of a group.
+type accumulator interface {
+	// add adds the value of the compiled expression of the aggregate for a record.
+	// The expression is unset for COUNT.
+	add(ex *compiled, key, value []byte) error
+	// result returns the aggregate of the values.
+	result() (*Scalar, error)
+}
+
+// newAccumulator creates an accumulator for an aggregate function over values of a type.
+func newAccumulator(function AggregateFunction, returnType ReturnType) accumulator {
+	switch function {
+	case AggregateFunction_AGGREGATE_FUNCTION_COUNT:
+		return &countAccumulator{}
+	case AggregateFunction_AGGREGATE_FUNCTION_SUM:
+		return &sumAccumulator{returnType: returnType}
+	case AggregateFunction_AGGREGATE_FUNCTION_AVG:
+		return &sumAccumulator{returnType: returnType, average: true}
+	case AggregateFunction_AGGREGATE_FUNCTION_MIN:
+		return &extremeAccumulator{returnType: returnType, want: -1}
+	default:
+		return &extremeAccumulator{returnType: returnType, want: 1}
+	}
+}
+
+// countAccumulator counts records.
+type countAccumulator struct {
+	count uint64
+}
+
+func (a *countAccumulator) add(*compiled, []byte, []byte) error {
+	a.count++
+	return nil
+}
+
+func (a *countAccumulator) result() (*Scalar, error) {
+	return &Scalar{Value: &Scalar_U64{U64: a.count}}, nil
+}
+
+// sumAccumulator adds values for SUM and AVG. Integers are added as 128 bit integers,
+// which cannot overflow, and floats as F64.
+type sumAccumulator struct {
+	returnType ReturnType
+	// average indicates AVG rather than SUM.
+	average bool
+	count   uint64
+	// hi and lo are the high and low 64 bits of the sum of integers, in two's complement if signed.
+	hi, lo uint64
+	// sum is the sum of floats.
+	sum float64
+}
+
+func (a *sumAccumulator) add(ex *compiled, key, value []byte) error {
+	var carry uint64
+	switch {
+	case isUnsignedType(a.returnType):
+		v, err := ex.uintFn(key, value)
+		if err != nil {
+			return err
+		}
+		a.lo, carry = bits.Add64(a.lo, v, 0)
+		a.hi += carry
+	case isSignedType(a.returnType):
+		v, err := ex.intFn(key, value)
+		if err != nil {
+			return err
+		}
+		a.lo, carry = bits.Add64(a.lo, uint64(v), 0)
+		// Sign extend the value into the high bits.
+		a.hi += uint64(v>>63) + carry
+	default:
+		v, err := ex.floatFn(key, value)
+		if err != nil {
+			return err
+		}
+		a.sum += v
+	}
+	a.count++
+	return nil
+}
+
+func (a *sumAccumulator) result() (*Scalar, error) {
+	if a.average {
+		if a.count == 0 {
+			return &Scalar{}, nil
+		}
+		return &Scalar{Value: &Scalar_F64{F64: a.float() / float64(a.count)}}, nil
+	}
+	switch {
+	case isUnsignedType(a.returnType):
+		if a.hi != 0 {
+			return nil, errors.Errorf("sum %v overflows U64", a.float())
+		}
+		return &Scalar{Value: &Scalar_U64{U64: a.lo}}, nil
+	case isSignedType(a.returnType):
+		if a.hi != uint64(int64(a.lo)>>63) {
+			return nil, errors.Errorf("sum %v overflows I64", a.float())
+		}
+		return &Scalar{Value: &Scalar_I64{I64: int64(a.lo)}}, nil
+	default:
+		return &Scalar{Value: &Scalar_F64{F64: a.sum}}, nil
+	}
+}
+
+// float returns the sum as a F64.
+func (a *sumAccumulator) float() float64 {
+	const twoTo64 = 1 << 64
+	switch {
+	case isUnsignedType(a.returnType):
+		return float64(a.hi)*twoTo64 + float64(a.lo)
+	case isSignedType(a.returnType):
+		return float64(int64(a.hi))*twoTo64 + float64(a.lo)
+	default:
+		return a.sum
+	}
+}
+
+// extremeAccumulator keeps the least or greatest value for MIN and MAX. NaN values are ignored.
+type extremeAccumulator struct {
+	returnType ReturnType
+	// want is the sign of the comparison of a value with the kept value for it to replace it.
+	want int
+	// kept is set if there is a kept value, which is in the field for the kind of returnType.
+	kept      bool
+	keptUint  uint64
+	keptInt   int64
+	keptFloat float64
+	keptBytes []byte
+}
+
+func (a *extremeAccumulator) add(ex *compiled, key, value []byte) error {
+	switch {
+	case isUnsignedType(a.returnType):
+		v, err := ex.uintFn(key, value)
+		if err != nil {
+			return err
+		}
+		if !a.kept || compareOrdered(v < a.keptUint, v > a.keptUint) == a.want {
+			a.keptUint = v
+		}
+	case isSignedType(a.returnType):
+		v, err := ex.intFn(key, value)
+		if err != nil {
+			return err
+		}
+		if !a.kept || compareOrdered(v < a.keptInt, v > a.keptInt) == a.want {
+			a.keptInt = v
+		}
+	case isFloatType(a.returnType):
+		v, err := ex.floatFn(key, value)
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(v) {
+			return nil
+		}
+		if !a.kept || compareOrdered(v < a.keptFloat, v > a.keptFloat) == a.want {
+			a.keptFloat = v
+		}
+	default:
+		v, err := ex.bytesFn(key, value)
+		if err != nil {
+			return err
+		}
+		if !a.kept || bytes.Compare(v, a.keptBytes) == a.want {
+			// The bytes share memory with the record.
+			a.keptBytes = append([]byte(nil), v...)
+		}
+	}
+	a.kept = true
+	return nil
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (a *extremeAccumulator) result() (*Scalar, error) {
+	if !a.kept {
+		return &Scalar{}, nil
+	}
+	switch {
+	case isUnsignedType(a.returnType):
+		if typeBits(a.returnType) <= 32 {
+			return &Scalar{Value: &Scalar_U32{U32: uint32(a.keptUint)}}, nil
+		}
+		return &Scalar{Value: &Scalar_U64{U64: a.keptUint}}, nil
+	case isSignedType(a.returnType):
+		if typeBits(a.returnType) <= 32 {
+			return &Scalar{Value: &Scalar_I32{I32: int32(a.keptInt)}}, nil
+		}
+		return &Scalar{Value: &Scalar_I64{I64: a.keptInt}}, nil
+	case isFloatType(a.returnType):
+		if a.returnType == ReturnType_RETURN_TYPE_F32 {
+			return &Scalar{Value: &Scalar_F32{F32: float32(a.keptFloat)}}, nil
+		}
+		return &Scalar{Value: &Scalar_F64{F64: a.keptFloat}}, nil
+	default:
+		return &Scalar{Value: &Scalar_Bytes{Bytes: a.keptBytes}}, nil
+	}
+}
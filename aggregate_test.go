@@ -0,0 +1,355 @@
+package binq
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestParser_ReadAggregates(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"count", "COUNT()", "COUNT()"},
+		{"lowercase", "count() as n", "COUNT() AS n"},
+		{"expression", "SUM(VALUE(0, U8) * U32(2)) AS total", "SUM(VALUE(0, U8) * U32(2)) AS total"},
+		{
+			"many",
+			"MIN(VALUE(0, I32LE)),max(VALUE(JUMP(4, U8), BYTES(U8))) AS name, AVG(VALUE(8, F64LE))",
+			"MIN(VALUE(0, I32LE)), MAX(VALUE(JUMP(4, U8), BYTES(U8))) AS name, AVG(VALUE(8, F64LE))",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			aggregates, err := NewParser(tc.input).ReadAggregates()
+			if !assert.NoError(t, err) {
+				return
+			}
+			text, err := FormatAggregates(aggregates)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, text)
+		})
+	}
+}
+
+func TestParser_ReadAggregates_errors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"empty-field", "COUNT(),, COUNT()"},
+		{"unknown", "MEDIAN(VALUE(0, U8))"},
+		{"not-a-call", "VALUE(0, U8)"},
+		{"missing-paren", "SUM(VALUE(0, U8)"},
+		{"many-arguments", "SUM(VALUE(0, U8) VALUE(1, U8))"},
+		{"missing-alias", "COUNT() AS"},
+		{"invalid-alias", "COUNT() AS 1x"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewParser(tc.input).ReadAggregates()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheckAggregation(t *testing.T) {
+	t.Parallel()
+	value := &Value{Jump: &Jump{Jump: &Jump_Offset{}}, Type: ValueType_VALUE_TYPE_U8}
+	boolean := &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_Bool{Bool: true}}}}
+	bytes := &Expression{Expression: &Expression_Value{Value: &Value{Jump: &Jump{Jump: &Jump_Offset{}}, Type: ValueType_VALUE_TYPE_BYTES}}}
+	aggregation := &Aggregation{
+		GroupBy: []*Projection{{Value: value, Alias: "a"}},
+		Aggregates: []*Aggregate{
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_COUNT, Alias: "n"},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_MAX, Expression: bytes},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_COUNT, Expression: bytes},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_SUM},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_AVG, Expression: bytes},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_MIN, Expression: boolean},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_UNKNOWN},
+			{Function: AggregateFunction_AGGREGATE_FUNCTION_COUNT, Alias: "a"},
+		},
+	}
+	var paths []string
+	for _, err := range CheckAggregation(aggregation) {
+		paths = append(paths, err.Path)
+	}
+	assert.Equal(t, []string{
+		"aggregation.aggregates[2].expression",
+		"aggregation.aggregates[3]",
+		"aggregation.aggregates[4].expression",
+		"aggregation.aggregates[5].expression",
+		"aggregation.aggregates[6].function",
+		"aggregation.aggregates[7].alias",
+	}, paths)
+	assert.Nil(t, CheckAggregation(&Aggregation{GroupBy: aggregation.GroupBy, Aggregates: aggregation.Aggregates[:2]}))
+
+	_, err := NewAggregator(aggregation)
+	assert.Error(t, err)
+}
+
+func TestAggregator(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		groupBy    string
+		aggregates string
+		records    [][]byte
+		names      []string
+		expected   [][]*Scalar
+	}{
+		{
+			"empty",
+			"",
+			"COUNT(), SUM(VALUE(0, U8)), SUM(VALUE(0, I8)), SUM(VALUE(0, F32LE)), MIN(VALUE(0, U8)), MAX(VALUE(0, BYTES(1))), AVG(VALUE(0, U8))",
+			nil,
+			[]string{"COUNT()", "SUM(VALUE(0, U8))", "SUM(VALUE(0, I8))", "SUM(VALUE(0, F32LE))", "MIN(VALUE(0, U8))", "MAX(VALUE(0, BYTES(1)))", "AVG(VALUE(0, U8))"},
+			[][]*Scalar{{
+				{Value: &Scalar_U64{U64: 0}},
+				{Value: &Scalar_U64{U64: 0}},
+				{Value: &Scalar_I64{I64: 0}},
+				{Value: &Scalar_F64{F64: 0}},
+				{},
+				{},
+				{},
+			}},
+		},
+		{
+			"unsigned",
+			"",
+			"COUNT() AS n, SUM(VALUE(0, U8)), MIN(VALUE(0, U8)), MAX(VALUE(0, U8)), AVG(VALUE(0, U8))",
+			[][]byte{makeBytes(t, u8(3)), makeBytes(t, u8(250)), makeBytes(t, u8(1))},
+			[]string{"n", "SUM(VALUE(0, U8))", "MIN(VALUE(0, U8))", "MAX(VALUE(0, U8))", "AVG(VALUE(0, U8))"},
+			[][]*Scalar{{
+				{Value: &Scalar_U64{U64: 3}},
+				{Value: &Scalar_U64{U64: 254}},
+				{Value: &Scalar_U32{U32: 1}},
+				{Value: &Scalar_U32{U32: 250}},
+				{Value: &Scalar_F64{F64: 254.0 / 3}},
+			}},
+		},
+		{
+			"signed",
+			"",
+			"SUM(VALUE(0, I64LE)), MIN(VALUE(0, I64LE)), MAX(VALUE(0, I64LE)), AVG(VALUE(0, I64LE))",
+			[][]byte{makeBytes(t, i64le(math.MaxInt64)), makeBytes(t, i64le(math.MaxInt64)), makeBytes(t, i64le(math.MinInt64)), makeBytes(t, i64le(-10))},
+			[]string{"SUM(VALUE(0, I64LE))", "MIN(VALUE(0, I64LE))", "MAX(VALUE(0, I64LE))", "AVG(VALUE(0, I64LE))"},
+			[][]*Scalar{{
+				{Value: &Scalar_I64{I64: math.MaxInt64 - 11}},
+				{Value: &Scalar_I64{I64: math.MinInt64}},
+				{Value: &Scalar_I64{I64: math.MaxInt64}},
+				{Value: &Scalar_F64{F64: float64(math.MaxInt64-11) / 4}},
+			}},
+		},
+		{
+			"float",
+			"",
+			"SUM(VALUE(0, F32LE)), MIN(VALUE(0, F32LE)), MAX(VALUE(0, F32LE))",
+			[][]byte{makeBytes(t, f32le(1.5)), makeBytes(t, f32le(-2))},
+			[]string{"SUM(VALUE(0, F32LE))", "MIN(VALUE(0, F32LE))", "MAX(VALUE(0, F32LE))"},
+			[][]*Scalar{{
+				{Value: &Scalar_F64{F64: -0.5}},
+				{Value: &Scalar_F32{F32: -2}},
+				{Value: &Scalar_F32{F32: 1.5}},
+			}},
+		},
+		{
+			"nan",
+			"VALUE(0, U8)",
+			"MIN(VALUE(1, F64LE)), MAX(VALUE(1, F64LE))",
+			[][]byte{makeBytes(t, u8(1), f64le(math.NaN())), makeBytes(t, u8(2), f64le(math.NaN())), makeBytes(t, u8(1), f64le(3))},
+			[]string{"VALUE(0, U8)", "MIN(VALUE(1, F64LE))", "MAX(VALUE(1, F64LE))"},
+			[][]*Scalar{
+				{{Value: &Scalar_U32{U32: 1}}, {Value: &Scalar_F64{F64: 3}}, {Value: &Scalar_F64{F64: 3}}},
+				{{Value: &Scalar_U32{U32: 2}}, {}, {}},
+			},
+		},
+		{
+			"group-by",
+			"VALUE(0, U8) AS kind",
+			"COUNT(), MIN(VALUE(1, BYTES(2))) AS least",
+			[][]byte{makeBytes(t, u8(2), "bb"), makeBytes(t, u8(1), "zz"), makeBytes(t, u8(2), "ab"), makeBytes(t, u8(2), "ba")},
+			[]string{"kind", "COUNT()", "least"},
+			[][]*Scalar{
+				{{Value: &Scalar_U32{U32: 2}}, {Value: &Scalar_U64{U64: 3}}, {Value: &Scalar_Bytes{Bytes: []byte("ab")}}},
+				{{Value: &Scalar_U32{U32: 1}}, {Value: &Scalar_U64{U64: 1}}, {Value: &Scalar_Bytes{Bytes: []byte("zz")}}},
+			},
+		},
+		{
+			"group-by-empty",
+			"VALUE(0, U8)",
+			"COUNT()",
+			nil,
+			[]string{"VALUE(0, U8)", "COUNT()"},
+			[][]*Scalar{},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			aggregation := &Aggregation{}
+			var err error
+			if tc.groupBy != "" {
+				aggregation.GroupBy, err = NewParser(tc.groupBy).ReadProjection()
+				if !assert.NoError(t, err) {
+					return
+				}
+			}
+			aggregation.Aggregates, err = NewParser(tc.aggregates).ReadAggregates()
+			if !assert.NoError(t, err) {
+				return
+			}
+			aggregator, err := NewAggregator(aggregation)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.names, aggregator.Names())
+			for _, record := range tc.records {
+				if !assert.NoError(t, aggregator.Add(nil, record)) {
+					return
+				}
+				// Values must not be retained.
+				for index := range record {
+					record[index] = 0
+				}
+			}
+			results, err := aggregator.Results()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expected, results)
+		})
+	}
+}
+
+func TestAggregator_overflow(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		aggregates string
+		records    [][]byte
+	}{
+		{"unsigned", "SUM(VALUE(0, U64LE))", [][]byte{makeBytes(t, u64le(math.MaxUint64)), makeBytes(t, u64le(1))}},
+		{"signed", "SUM(VALUE(0, I64LE))", [][]byte{makeBytes(t, i64le(math.MaxInt64)), makeBytes(t, i64le(1))}},
+		{"signed-negative", "SUM(VALUE(0, I64LE))", [][]byte{makeBytes(t, i64le(math.MinInt64)), makeBytes(t, i64le(-1))}},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			aggregates, err := NewParser(tc.aggregates).ReadAggregates()
+			if !assert.NoError(t, err) {
+				return
+			}
+			aggregator, err := NewAggregator(&Aggregation{Aggregates: aggregates})
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, record := range tc.records {
+				assert.NoError(t, aggregator.Add(nil, record))
+			}
+			_, err = aggregator.Results()
+			assert.Error(t, err)
+		})
+	}
+
+	// Intermediate sums may overflow as long as the total does not.
+	aggregates, err := NewParser("SUM(VALUE(0, I64LE)), AVG(VALUE(0, I64LE))").ReadAggregates()
+	if !assert.NoError(t, err) {
+		return
+	}
+	aggregator, err := NewAggregator(&Aggregation{Aggregates: aggregates})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, v := range []int64{math.MaxInt64, math.MaxInt64, math.MinInt64, -3} {
+		assert.NoError(t, aggregator.Add(nil, makeBytes(t, i64le(v))))
+	}
+	results, err := aggregator.Results()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []*Scalar{{Value: &Scalar_I64{I64: math.MaxInt64 - 4}}, {Value: &Scalar_F64{F64: float64(math.MaxInt64-4) / 4}}}, results[0])
+}
+
+func TestAggregator_maxGroups(t *testing.T) {
+	t.Parallel()
+	aggregates, err := NewParser("COUNT()").ReadAggregates()
+	if !assert.NoError(t, err) {
+		return
+	}
+	groupBy, err := NewParser("VALUE(0, U8)").ReadProjection()
+	if !assert.NoError(t, err) {
+		return
+	}
+	aggregator, err := NewAggregator(&Aggregation{GroupBy: groupBy, Aggregates: aggregates})
+	if !assert.NoError(t, err) {
+		return
+	}
+	aggregator.maxGroups = 2
+
+	assert.NoError(t, aggregator.Add(nil, []byte{1}))
+	assert.NoError(t, aggregator.Add(nil, []byte{2}))
+	assert.Equal(t, ErrTooManyGroups, errors.Cause(aggregator.Add(nil, []byte{3})))
+	assert.NoError(t, aggregator.Add(nil, []byte{1}), "existing groups still take records")
+	results, err := aggregator.Results()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, [][]*Scalar{
+		{{Value: &Scalar_U32{U32: 1}}, {Value: &Scalar_U64{U64: 2}}},
+		{{Value: &Scalar_U32{U32: 2}}, {Value: &Scalar_U64{U64: 1}}},
+	}, results)
+}
+
+// newBenchmarkAggregator creates an Aggregator of aggregates of numbers read from records.
+func newBenchmarkAggregator(t TestType) *Aggregator {
+	aggregates, err := NewParser("SUM(VALUE(0, U32LE) * U32(3)), MAX(VALUE(4, U32LE)), MIN(VALUE(8, F64LE)), AVG(VALUE(16, I16LE))").ReadAggregates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregator, err := NewAggregator(&Aggregation{Aggregates: aggregates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aggregator
+}
+
+// TestAggregator_Add_ZeroAllocs is not parallel, since testing.AllocsPerRun cannot be used by parallel tests.
+func TestAggregator_Add_ZeroAllocs(t *testing.T) {
+	aggregator := newBenchmarkAggregator(t)
+	record := makeBytes(t, u32le(1000), u32le(2000), f64le(1.5), i16le(-3))
+	assert.NoError(t, aggregator.Add(nil, record))
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = aggregator.Add(nil, record)
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkAggregator_Add(b *testing.B) {
+	aggregator := newBenchmarkAggregator(b)
+	record := makeBytes(b, u32le(1000), u32le(2000), f64le(1.5), i16le(-3))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := aggregator.Add(nil, record); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,560 @@
+package binq
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"strings"
+)
+
+// opcode is the operation of an instruction of a Program.
+//
+// A Program runs on a stack of slots. Integers are held in the slots as 64 bit two's complement,
+// floats as the bits of a float64, booleans as 0 or 1 and byte strings as slices of the record
+// or of a constant. Values are read from a position of the record that is selected by seek or
+// deref, which also selects the key or the value of the record.
+type opcode uint8
+
+const (
+	opInvalid opcode = iota
+	// opConst pushes arg, a constant of ReturnType a.
+	opConst
+	// opConstBytes pushes the constant byte string at index arg.
+	opConstBytes
+	// opSeek selects the position at offset arg of the Target b.
+	opSeek
+	// opDeref reads the address of ValueType a at offset arg of the Target b,
+	// then selects the position at the address of the same Target.
+	opDeref
+	// opLoad pushes the value of ValueType a at the selected position. BYTES values have length arg.
+	opLoad
+	// opUintToFloat and opIntToFloat convert the top of the stack to a float.
+	// Booleans are converted with opUintToFloat.
+	opUintToFloat
+	opIntToFloat
+	// opArithU, opArithI and opArithF pop two values and push the result of the BinaryOpCode a
+	// on unsigned or signed integers of b bits, or floats of b bits.
+	opArithU
+	opArithI
+	opArithF
+	// opCmpU, opCmpI, opCmpF and opCmpBytes pop two values and push the result of the comparison a.
+	// Booleans are compared with opCmpU.
+	opCmpU
+	opCmpI
+	opCmpF
+	opCmpBytes
+	// opCmpConstU, opCmpConstI and opCmpConstF replace the top of the stack with the result of
+	// the comparison a between it and the constant arg.
+	opCmpConstU
+	opCmpConstI
+	opCmpConstF
+	// opNot negates the boolean at the top of the stack.
+	opNot
+	// opBranchFalse jumps to instruction arg if the top of the stack is false, which is kept
+	// as the result, otherwise it pops the top of the stack. opBranchTrue branches on true.
+	opBranchFalse
+	opBranchTrue
+
+	opCount
+)
+
+var opcodeNames = [opCount]string{
+	opInvalid:     "invalid",
+	opConst:       "const",
+	opConstBytes:  "const.bytes",
+	opSeek:        "seek",
+	opDeref:       "deref",
+	opLoad:        "load",
+	opUintToFloat: "utof",
+	opIntToFloat:  "itof",
+	opArithU:      "arith.u",
+	opArithI:      "arith.i",
+	opArithF:      "arith.f",
+	opCmpU:        "cmp.u",
+	opCmpI:        "cmp.i",
+	opCmpF:        "cmp.f",
+	opCmpBytes:    "cmp.bytes",
+	opCmpConstU:   "cmpi.u",
+	opCmpConstI:   "cmpi.i",
+	opCmpConstF:   "cmpi.f",
+	opNot:         "not",
+	opBranchFalse: "brfalse",
+	opBranchTrue:  "brtrue",
+}
+
+func (op opcode) String() string {
+	if op < opCount {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("opcode(%d)", uint8(op))
+}
+
+// instruction is a single operation of a Program, with operands a, b and arg depending on the opcode.
+type instruction struct {
+	op  opcode
+	a   uint8
+	b   uint8
+	arg uint64
+}
+
+// slot is a value on the stack of a running Program.
+type slot struct {
+	n uint64
+	b []byte
+}
+
+// Program is a Predicate compiled to bytecode for a stack machine, created by CompileProgram.
+// Programs can be serialised with MarshalBinary to be cached by their Hash or run by another process.
+// A Program may be used by multiple goroutines at once.
+type Program struct {
+	code      []instruction
+	constants [][]byte
+	// maxStack is the greatest number of slots used by the program.
+	maxStack int
+}
+
+var (
+	_ Matcher       = (*Program)(nil)
+	_ RecordMatcher = (*Program)(nil)
+)
+
+// Match runs the program on the value of a record without a key.
+func (p *Program) Match(value []byte) (bool, error) {
+	return p.MatchRecord(nil, value)
+}
+
+// MatchRecord runs the program on a record.
+func (p *Program) MatchRecord(key, value []byte) (bool, error) {
+	// Most programs fit in a stack that does not escape to the heap.
+	var fixed [16]slot
+	stack := fixed[:]
+	if p.maxStack > len(fixed) {
+		stack = make([]slot, p.maxStack)
+	}
+	sp := 0
+	var position []byte
+	var err error
+	code := p.code
+	for pc := 0; pc < len(code); pc++ {
+		in := &code[pc]
+		switch in.op {
+		case opConst:
+			stack[sp] = slot{n: in.arg}
+			sp++
+		case opConstBytes:
+			stack[sp] = slot{b: p.constants[in.arg]}
+			sp++
+		case opSeek:
+			position, err = jumpOffset64(in.arg, selectTarget(in.b, key, value))
+		case opDeref:
+			position, err = deref(ValueType(in.a), in.arg, selectTarget(in.b, key, value))
+		case opLoad:
+			stack[sp], err = load(ValueType(in.a), in.arg, position)
+			sp++
+		case opUintToFloat:
+			stack[sp-1].n = math.Float64bits(float64(stack[sp-1].n))
+		case opIntToFloat:
+			stack[sp-1].n = math.Float64bits(float64(int64(stack[sp-1].n)))
+		case opArithU:
+			sp--
+			stack[sp-1].n, err = arithU(BinaryOpCode(in.a), uint(in.b), stack[sp-1].n, stack[sp].n)
+		case opArithI:
+			sp--
+			stack[sp-1].n, err = arithI(BinaryOpCode(in.a), uint(in.b), stack[sp-1].n, stack[sp].n)
+		case opArithF:
+			sp--
+			stack[sp-1].n = arithF(BinaryOpCode(in.a), in.b == 32, stack[sp-1].n, stack[sp].n)
+		case opCmpU:
+			sp--
+			stack[sp-1].n = boolBits(compareU(BinaryOpCode(in.a), stack[sp-1].n, stack[sp].n))
+		case opCmpI:
+			sp--
+			stack[sp-1].n = boolBits(compareI(BinaryOpCode(in.a), int64(stack[sp-1].n), int64(stack[sp].n)))
+		case opCmpF:
+			sp--
+			stack[sp-1].n = boolBits(compareF(BinaryOpCode(in.a), math.Float64frombits(stack[sp-1].n), math.Float64frombits(stack[sp].n)))
+		case opCmpBytes:
+			sp--
+			stack[sp-1] = slot{n: boolBits(compareBytes(BinaryOpCode(in.a), stack[sp-1].b, stack[sp].b))}
+		case opCmpConstU:
+			stack[sp-1].n = boolBits(compareU(BinaryOpCode(in.a), stack[sp-1].n, in.arg))
+		case opCmpConstI:
+			stack[sp-1].n = boolBits(compareI(BinaryOpCode(in.a), int64(stack[sp-1].n), int64(in.arg)))
+		case opCmpConstF:
+			stack[sp-1].n = boolBits(compareF(BinaryOpCode(in.a), math.Float64frombits(stack[sp-1].n), math.Float64frombits(in.arg)))
+		case opNot:
+			stack[sp-1].n ^= 1
+		case opBranchFalse:
+			if stack[sp-1].n == 0 {
+				pc = int(in.arg) - 1
+				continue
+			}
+			sp--
+		case opBranchTrue:
+			if stack[sp-1].n != 0 {
+				pc = int(in.arg) - 1
+				continue
+			}
+			sp--
+		default:
+			err = unhandledEnum("opcode", in.op)
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to run instruction %d %s", pc, in.op)
+		}
+	}
+	return stack[0].n != 0, nil
+}
+
+func selectTarget(target uint8, key, value []byte) []byte {
+	if Target(target) == Target_TARGET_KEY {
+		return key
+	}
+	return value
+}
+
+func boolBits(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// deref reads an address of a type at an offset and jumps to the address, like the Jumper of a Jump.
+func deref(addressType ValueType, offset uint64, target []byte) ([]byte, error) {
+	at, err := jumpOffset64(offset, target)
+	if err != nil {
+		return nil, wrap(err, "unable to jump to address")
+	}
+	address, err := load(addressType, 0, at)
+	if err != nil {
+		return nil, wrap(err, "unable to decode jump address")
+	}
+	return jumpOffset64(address.n, target)
+}
+
+// load reads a value of a type into a slot.
+func load(valueType ValueType, length uint64, b []byte) (slot, error) {
+	switch valueType {
+	case ValueType_VALUE_TYPE_U64LE:
+		val, err := readU64le(b)
+		return slot{n: val}, err
+	case ValueType_VALUE_TYPE_U64BE:
+		val, err := readU64be(b)
+		return slot{n: val}, err
+	case ValueType_VALUE_TYPE_U32LE:
+		val, err := readU32le(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_U32BE:
+		val, err := readU32be(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_U16LE:
+		val, err := readU16le(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_U16BE:
+		val, err := readU16be(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_U8:
+		val, err := readU8(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_I64LE:
+		val, err := readI64le(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_I64BE:
+		val, err := readI64be(b)
+		return slot{n: uint64(val)}, err
+	case ValueType_VALUE_TYPE_I32LE:
+		val, err := readI32le(b)
+		return slot{n: uint64(int64(val))}, err
+	case ValueType_VALUE_TYPE_I32BE:
+		val, err := readI32be(b)
+		return slot{n: uint64(int64(val))}, err
+	case ValueType_VALUE_TYPE_I16LE:
+		val, err := readI16le(b)
+		return slot{n: uint64(int64(val))}, err
+	case ValueType_VALUE_TYPE_I16BE:
+		val, err := readI16be(b)
+		return slot{n: uint64(int64(val))}, err
+	case ValueType_VALUE_TYPE_I8:
+		val, err := readI8(b)
+		return slot{n: uint64(int64(val))}, err
+	case ValueType_VALUE_TYPE_F64LE:
+		val, err := readF64le(b)
+		return slot{n: math.Float64bits(val)}, err
+	case ValueType_VALUE_TYPE_F64BE:
+		val, err := readF64be(b)
+		return slot{n: math.Float64bits(val)}, err
+	case ValueType_VALUE_TYPE_F32LE:
+		val, err := readF32le(b)
+		return slot{n: math.Float64bits(float64(val))}, err
+	case ValueType_VALUE_TYPE_F32BE:
+		val, err := readF32be(b)
+		return slot{n: math.Float64bits(float64(val))}, err
+	case ValueType_VALUE_TYPE_BYTES:
+		val, err := readBytes(b, length)
+		return slot{b: val}, err
+	case ValueType_VALUE_TYPE_BYTES_U8:
+		val, err := readBytesU8(b)
+		return slot{b: val}, err
+	case ValueType_VALUE_TYPE_BYTES_U16LE:
+		val, err := readBytesU16le(b)
+		return slot{b: val}, err
+	case ValueType_VALUE_TYPE_BYTES_U16BE:
+		val, err := readBytesU16be(b)
+		return slot{b: val}, err
+	case ValueType_VALUE_TYPE_BYTES_U32LE:
+		val, err := readBytesU32le(b)
+		return slot{b: val}, err
+	case ValueType_VALUE_TYPE_BYTES_U32BE:
+		val, err := readBytesU32be(b)
+		return slot{b: val}, err
+	default:
+		return slot{}, unhandledEnum("value type", valueType)
+	}
+}
+
+// arithU performs an operation on unsigned integers of a width, with the semantics of performOpU64.
+func arithU(op BinaryOpCode, bits uint, a, b uint64) (uint64, error) {
+	mask := uint64(math.MaxUint64) >> (64 - bits)
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		return (a + b) & mask, nil
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		return (a - b) & mask, nil
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		return (a * b) & mask, nil
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		if b == 0 {
+			return 0, ErrDivideByZero
+		}
+		return a / b, nil
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		if b == 0 {
+			return 0, ErrDivideByZero
+		}
+		return a % b, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_AND:
+		return a & b, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_OR:
+		return a | b, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_XOR:
+		return a ^ b, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHL:
+		return (a << b) & mask, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHR:
+		return a >> b, nil
+	default:
+		return 0, unhandledEnum("uint op code", op)
+	}
+}
+
+// arithI performs an operation on signed integers of a width, with the semantics of performOpI64.
+func arithI(op BinaryOpCode, bits uint, ua, ub uint64) (uint64, error) {
+	a, b := int64(ua), int64(ub)
+	shift := 64 - bits
+	var result int64
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		result = a + b
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		result = a - b
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		result = a * b
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		if b == 0 {
+			return 0, ErrDivideByZero
+		}
+		result = a / b
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		if b == 0 {
+			return 0, ErrDivideByZero
+		}
+		result = a % b
+	case BinaryOpCode_BINARY_OP_CODE_BIT_AND:
+		result = a & b
+	case BinaryOpCode_BINARY_OP_CODE_BIT_OR:
+		result = a | b
+	case BinaryOpCode_BINARY_OP_CODE_BIT_XOR:
+		result = a ^ b
+	case BinaryOpCode_BINARY_OP_CODE_SHL:
+		if b < 0 {
+			return 0, ErrNegativeShift
+		}
+		result = a << uint64(b)
+	case BinaryOpCode_BINARY_OP_CODE_SHR:
+		if b < 0 {
+			return 0, ErrNegativeShift
+		}
+		result = a >> uint64(b)
+	default:
+		return 0, unhandledEnum("int op code", op)
+	}
+	return uint64(result << shift >> shift), nil
+}
+
+// arithF performs an operation on the bits of floats, rounding the result of F32 operations
+// like compileFloatOperation.
+func arithF(op BinaryOpCode, isF32 bool, ua, ub uint64) uint64 {
+	a, b := math.Float64frombits(ua), math.Float64frombits(ub)
+	var result float64
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		result = a + b
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		result = a - b
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		result = a * b
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		result = a / b
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		result = math.Mod(a, b)
+	default:
+		result = math.NaN()
+	}
+	if isF32 {
+		result = float64(float32(result))
+	}
+	return math.Float64bits(result)
+}
+
+func compareU(op BinaryOpCode, a, b uint64) bool {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return a == b
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return a != b
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return a < b
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return a <= b
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func compareI(op BinaryOpCode, a, b int64) bool {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return a == b
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return a != b
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return a < b
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return a <= b
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// compareF compares floats. Go comparisons of NaN agree with performOpUnordered.
+func compareF(op BinaryOpCode, a, b float64) bool {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return a == b
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return a != b
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return a < b
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return a <= b
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func compareBytes(op BinaryOpCode, a, b []byte) bool {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return bytes.Equal(a, b)
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return !bytes.Equal(a, b)
+	default:
+		return compareI(op, int64(bytes.Compare(a, b)), 0)
+	}
+}
+
+// Disassemble renders the program as text for debugging, one instruction per line, for example:
+//
+//	0000  seek        value+8
+//	0001  load        u64le
+//	0002  cmpi.u      greater_eq 1000
+func (p *Program) Disassemble() string {
+	var sb strings.Builder
+	for pc, in := range p.code {
+		fmt.Fprintf(&sb, "%04d  %-11s %s\n", pc, in.op, p.operands(in))
+	}
+	return sb.String()
+}
+
+func (p *Program) operands(in instruction) string {
+	op := BinaryOpCode(in.a)
+	switch in.op {
+	case opConst:
+		return fmt.Sprintf("%s %s", returnTypeName(ReturnType(in.a)), formatConst(ReturnType(in.a), in.arg))
+	case opConstBytes:
+		if in.arg < uint64(len(p.constants)) {
+			return fmt.Sprintf("#%d %q", in.arg, p.constants[in.arg])
+		}
+		return fmt.Sprintf("#%d", in.arg)
+	case opSeek:
+		return fmt.Sprintf("%s+%d", targetName(in.b), in.arg)
+	case opDeref:
+		return fmt.Sprintf("%s at %s+%d", valueTypeName(ValueType(in.a)), targetName(in.b), in.arg)
+	case opLoad:
+		if ValueType(in.a) == ValueType_VALUE_TYPE_BYTES {
+			return fmt.Sprintf("bytes(%d)", in.arg)
+		}
+		return valueTypeName(ValueType(in.a))
+	case opArithU, opArithI, opArithF:
+		return fmt.Sprintf("%s %d", binaryOpCodeName(op), in.b)
+	case opCmpU, opCmpI, opCmpF, opCmpBytes:
+		return binaryOpCodeName(op)
+	case opCmpConstU:
+		return fmt.Sprintf("%s %d", binaryOpCodeName(op), in.arg)
+	case opCmpConstI:
+		return fmt.Sprintf("%s %d", binaryOpCodeName(op), int64(in.arg))
+	case opCmpConstF:
+		return fmt.Sprintf("%s %g", binaryOpCodeName(op), math.Float64frombits(in.arg))
+	case opBranchFalse, opBranchTrue:
+		return fmt.Sprintf("%04d", in.arg)
+	default:
+		return ""
+	}
+}
+
+func formatConst(returnType ReturnType, bits uint64) string {
+	switch {
+	case returnType == ReturnType_RETURN_TYPE_BOOL:
+		return fmt.Sprint(bits != 0)
+	case isSignedType(returnType):
+		return fmt.Sprint(int64(bits))
+	case isFloatType(returnType):
+		return fmt.Sprint(math.Float64frombits(bits))
+	default:
+		return fmt.Sprint(bits)
+	}
+}
+
+func targetName(target uint8) string {
+	return strings.ToLower(strings.TrimPrefix(Target(target).String(), "TARGET_"))
+}
+
+func returnTypeName(returnType ReturnType) string {
+	return strings.ToLower(strings.TrimPrefix(returnType.String(), "RETURN_TYPE_"))
+}
+
+func valueTypeName(valueType ValueType) string {
+	return strings.ToLower(strings.TrimPrefix(valueType.String(), "VALUE_TYPE_"))
+}
+
+func binaryOpCodeName(op BinaryOpCode) string {
+	return strings.ToLower(strings.TrimPrefix(op.String(), "BINARY_OP_CODE_"))
+}
@@ -0,0 +1,317 @@
+package binq
+
+import (
+	"github.com/pkg/errors"
+	"math"
+)
+
+// derefTypes are the types of the addresses that a Jump reads, by the type of Jump.
+var derefTypes = map[ValueType]struct{}{
+	ValueType_VALUE_TYPE_U64LE: {},
+	ValueType_VALUE_TYPE_U64BE: {},
+	ValueType_VALUE_TYPE_U32LE: {},
+	ValueType_VALUE_TYPE_U32BE: {},
+	ValueType_VALUE_TYPE_U16LE: {},
+	ValueType_VALUE_TYPE_U16BE: {},
+	ValueType_VALUE_TYPE_U8:    {},
+}
+
+// CompileProgram compiles a Predicate into a Program, which matches the same records as the
+// RecordMatcher created by PredicateToRecordMatcher. Type errors are reported as CheckErrors.
+func CompileProgram(pred *Predicate) (*Program, error) {
+	c := &checker{types: make(map[*Expression]ReturnType)}
+	c.checkPredicate(pred)
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+	b := &programBuilder{types: c.types}
+	var err error
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		_, err = b.expression(t.Expression)
+	case *Predicate_Any:
+		err = b.junction(t.Any.GetExpressions(), opBranchTrue)
+	case *Predicate_All:
+		err = b.junction(t.All.GetExpressions(), opBranchFalse)
+	default:
+		err = unhandledType("predicate type", t)
+	}
+	if err != nil {
+		return nil, wrap(err, "unable to compile predicate")
+	}
+	p := &Program{code: b.code, constants: b.constants}
+	if err := p.verify(); err != nil {
+		return nil, wrap(err, "compiled an invalid program")
+	}
+	return p, nil
+}
+
+// programBuilder emits the instructions of a Program.
+type programBuilder struct {
+	code      []instruction
+	constants [][]byte
+	// types are the types of the expressions of the predicate, inferred by Check.
+	types map[*Expression]ReturnType
+}
+
+// emit appends an instruction and returns its index.
+func (b *programBuilder) emit(op opcode, a, bb uint8, arg uint64) int {
+	b.code = append(b.code, instruction{op: op, a: a, b: bb, arg: arg})
+	return len(b.code) - 1
+}
+
+// patch sets the target of a branch to the next instruction to be emitted.
+func (b *programBuilder) patch(branch int) {
+	b.code[branch].arg = uint64(len(b.code))
+}
+
+// junction emits the expressions of an Any or All predicate, which branch to the end
+// as soon as one of them decides the result.
+func (b *programBuilder) junction(exs []*Expression, branch opcode) error {
+	if len(exs) == 0 {
+		// All of nothing is true and any of nothing is false.
+		b.emit(opConst, uint8(ReturnType_RETURN_TYPE_BOOL), 0, boolBits(branch == opBranchFalse))
+		return nil
+	}
+	var branches []int
+	for index, ex := range exs {
+		if index > 0 {
+			branches = append(branches, b.emit(branch, 0, 0, 0))
+		}
+		if _, err := b.expression(ex); err != nil {
+			return errors.Wrapf(err, "unable to compile expression %d", index)
+		}
+	}
+	for _, index := range branches {
+		b.patch(index)
+	}
+	return nil
+}
+
+func (b *programBuilder) expression(ex *Expression) (ReturnType, error) {
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Scalar:
+		returnType, bits, err := scalarBits(t.Scalar)
+		if err != nil {
+			return ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to compile scalar")
+		}
+		if returnType == ReturnType_RETURN_TYPE_BYTES {
+			b.constants = append(b.constants, t.Scalar.GetBytes())
+			b.emit(opConstBytes, 0, 0, uint64(len(b.constants)-1))
+		} else {
+			b.emit(opConst, uint8(returnType), 0, bits)
+		}
+		return returnType, nil
+	case *Expression_Value:
+		returnType, err := b.value(t.Value)
+		if err != nil {
+			return ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to compile value")
+		}
+		return returnType, nil
+	case *Expression_BinaryOperation:
+		// nowrap: recursive call
+		return b.binaryOperation(t.BinaryOperation)
+	case *Expression_UnaryOperation:
+		if t.UnaryOperation.UnaryOpCode != UnaryOpCode_UNARY_OP_CODE_NOT {
+			return ReturnType_RETURN_TYPE_UNKNOWN, unhandledEnum("unary op code", t.UnaryOperation.UnaryOpCode)
+		}
+		if _, err := b.expression(t.UnaryOperation.Operand); err != nil {
+			// nowrap: recursive call
+			return ReturnType_RETURN_TYPE_UNKNOWN, err
+		}
+		b.emit(opNot, 0, 0, 0)
+		return ReturnType_RETURN_TYPE_BOOL, nil
+	default:
+		return ReturnType_RETURN_TYPE_UNKNOWN, unhandledType("expression type", t)
+	}
+}
+
+// scalarBits returns the type of a scalar and its value in a slot. Byte strings have no bits.
+func scalarBits(s *Scalar) (ReturnType, uint64, error) {
+	switch t := s.GetValue().(type) {
+	case *Scalar_Bool:
+		return ReturnType_RETURN_TYPE_BOOL, boolBits(t.Bool), nil
+	case *Scalar_U32:
+		return ReturnType_RETURN_TYPE_U32, uint64(t.U32), nil
+	case *Scalar_U64:
+		return ReturnType_RETURN_TYPE_U64, t.U64, nil
+	case *Scalar_I32:
+		return ReturnType_RETURN_TYPE_I32, uint64(int64(t.I32)), nil
+	case *Scalar_I64:
+		return ReturnType_RETURN_TYPE_I64, uint64(t.I64), nil
+	case *Scalar_F32:
+		return ReturnType_RETURN_TYPE_F32, math.Float64bits(float64(t.F32)), nil
+	case *Scalar_F64:
+		return ReturnType_RETURN_TYPE_F64, math.Float64bits(t.F64), nil
+	case *Scalar_Bytes:
+		return ReturnType_RETURN_TYPE_BYTES, 0, nil
+	default:
+		return ReturnType_RETURN_TYPE_UNKNOWN, 0, unhandledType("scalar type", t)
+	}
+}
+
+func (b *programBuilder) value(v *Value) (ReturnType, error) {
+	returnType, ok := valueTypeReturnTypes[v.Type]
+	if !ok || returnType == ReturnType_RETURN_TYPE_UNKNOWN {
+		return ReturnType_RETURN_TYPE_UNKNOWN, unhandledEnum("value type", v.Type)
+	}
+	if _, ok := Target_name[int32(v.Target)]; !ok {
+		return ReturnType_RETURN_TYPE_UNKNOWN, unhandledEnum("value target", v.Target)
+	}
+	target := uint8(v.Target)
+	switch t := v.GetJump().GetJump().(type) {
+	case *Jump_Offset:
+		b.emit(opSeek, 0, target, t.Offset)
+	case *Jump_U64Le:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U64LE), target, t.U64Le)
+	case *Jump_U64Be:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U64BE), target, t.U64Be)
+	case *Jump_U32Le:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U32LE), target, t.U32Le)
+	case *Jump_U32Be:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U32BE), target, t.U32Be)
+	case *Jump_U16Le:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U16LE), target, t.U16Le)
+	case *Jump_U16Be:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U16BE), target, t.U16Be)
+	case *Jump_U8:
+		b.emit(opDeref, uint8(ValueType_VALUE_TYPE_U8), target, t.U8)
+	default:
+		return ReturnType_RETURN_TYPE_UNKNOWN, unhandledType("jump type", t)
+	}
+	b.emit(opLoad, uint8(v.Type), 0, v.Length)
+	return returnType, nil
+}
+
+func (b *programBuilder) binaryOperation(op *BinaryOperation) (ReturnType, error) {
+	opCode := op.BinaryOpCode
+	if _, isLogical := logicalOps[opCode]; isLogical {
+		branch := opBranchFalse
+		if opCode == BinaryOpCode_BINARY_OP_CODE_OR {
+			branch = opBranchTrue
+		}
+		if _, err := b.expression(op.Left); err != nil {
+			// nowrap: recursive call
+			return ReturnType_RETURN_TYPE_UNKNOWN, err
+		}
+		index := b.emit(branch, 0, 0, 0)
+		if _, err := b.expression(op.Right); err != nil {
+			// nowrap: recursive call
+			return ReturnType_RETURN_TYPE_UNKNOWN, err
+		}
+		b.patch(index)
+		return ReturnType_RETURN_TYPE_BOOL, nil
+	}
+
+	// Operands are converted as they are pushed, so the type of both is needed first.
+	leftType, leftOK := b.types[op.Left]
+	rightType, rightOK := b.types[op.Right]
+	if !leftOK || !rightOK {
+		return ReturnType_RETURN_TYPE_UNKNOWN, errors.New("invalid operand")
+	}
+	c := &checker{}
+	returnType, upscaledType, _ := c.binaryOperationType("", opCode, leftType, true, rightType, true)
+	if err := c.err(); err != nil {
+		return ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "invalid expression")
+	}
+
+	if _, isComparison := booleanOps[opCode]; isComparison {
+		// Compare against constant numbers without pushing them.
+		cmpConst, ok := cmpConstOpcode(upscaledType)
+		switch {
+		case ok && op.Right.GetScalar() != nil:
+			if err := b.operand(op.Left, leftType, upscaledType); err != nil {
+				return ReturnType_RETURN_TYPE_UNKNOWN, err
+			}
+			_, bits, _ := scalarBits(op.Right.GetScalar())
+			b.emit(cmpConst, uint8(opCode), 0, convertBits(bits, rightType, upscaledType))
+			return returnType, nil
+		case ok && op.Left.GetScalar() != nil:
+			if err := b.operand(op.Right, rightType, upscaledType); err != nil {
+				return ReturnType_RETURN_TYPE_UNKNOWN, err
+			}
+			_, bits, _ := scalarBits(op.Left.GetScalar())
+			b.emit(cmpConst, uint8(flipComparison(opCode)), 0, convertBits(bits, leftType, upscaledType))
+			return returnType, nil
+		}
+	}
+
+	if err := b.operand(op.Left, leftType, upscaledType); err != nil {
+		return ReturnType_RETURN_TYPE_UNKNOWN, err
+	}
+	if err := b.operand(op.Right, rightType, upscaledType); err != nil {
+		return ReturnType_RETURN_TYPE_UNKNOWN, err
+	}
+	if _, isComparison := booleanOps[opCode]; isComparison {
+		b.emit(cmpOpcode(upscaledType), uint8(opCode), 0, 0)
+		return returnType, nil
+	}
+	switch {
+	case isUnsignedType(upscaledType):
+		b.emit(opArithU, uint8(opCode), uint8(typeBits(upscaledType)), 0)
+	case isSignedType(upscaledType):
+		b.emit(opArithI, uint8(opCode), uint8(typeBits(upscaledType)), 0)
+	case upscaledType == ReturnType_RETURN_TYPE_F32:
+		b.emit(opArithF, uint8(opCode), 32, 0)
+	case upscaledType == ReturnType_RETURN_TYPE_F64:
+		b.emit(opArithF, uint8(opCode), 64, 0)
+	default:
+		return ReturnType_RETURN_TYPE_UNKNOWN, unhandledEnum("binary op value type", upscaledType)
+	}
+	return returnType, nil
+}
+
+// operand emits an operand of a binary operation and converts it from its type to the upscaled type.
+func (b *programBuilder) operand(ex *Expression, returnType, upscaledType ReturnType) error {
+	if _, err := b.expression(ex); err != nil {
+		// nowrap: recursive call
+		return err
+	}
+	if isFloatType(upscaledType) && !isFloatType(returnType) {
+		if isSignedType(returnType) {
+			b.emit(opIntToFloat, 0, 0, 0)
+		} else {
+			b.emit(opUintToFloat, 0, 0, 0)
+		}
+	}
+	return nil
+}
+
+// convertBits converts a constant like operand converts the values it emits.
+func convertBits(bits uint64, returnType, upscaledType ReturnType) uint64 {
+	if !isFloatType(upscaledType) || isFloatType(returnType) {
+		return bits
+	}
+	if isSignedType(returnType) {
+		return math.Float64bits(float64(int64(bits)))
+	}
+	return math.Float64bits(float64(bits))
+}
+
+// cmpOpcode is the comparison of values of a type.
+func cmpOpcode(returnType ReturnType) opcode {
+	switch {
+	case isSignedType(returnType):
+		return opCmpI
+	case isFloatType(returnType):
+		return opCmpF
+	case returnType == ReturnType_RETURN_TYPE_BYTES:
+		return opCmpBytes
+	default:
+		return opCmpU
+	}
+}
+
+// cmpConstOpcode is the comparison of values of a type against a constant, if there is one.
+func cmpConstOpcode(returnType ReturnType) (opcode, bool) {
+	switch {
+	case isUnsignedType(returnType) || returnType == ReturnType_RETURN_TYPE_BOOL:
+		return opCmpConstU, true
+	case isSignedType(returnType):
+		return opCmpConstI, true
+	case isFloatType(returnType):
+		return opCmpConstF, true
+	default:
+		return opInvalid, false
+	}
+}
@@ -0,0 +1,257 @@
+package binq
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/pkg/errors"
+)
+
+// programMagic begins every serialised Program, followed by programVersion.
+const (
+	programMagic   = "BINQ"
+	programVersion = 1
+)
+
+var (
+	// ErrInvalidProgram indicates that serialised bytecode is malformed or unsafe to run.
+	ErrInvalidProgram = errors.New("invalid program")
+)
+
+// MarshalBinary serialises the program as:
+//
+//	"BINQ" version
+//	uvarint(number of constants) { uvarint(length) bytes }
+//	uvarint(number of instructions) { op a b uvarint(arg) }
+func (p *Program) MarshalBinary() ([]byte, error) {
+	out := append([]byte(programMagic), programVersion)
+	out = appendUvarint(out, uint64(len(p.constants)))
+	for _, constant := range p.constants {
+		out = appendUvarint(out, uint64(len(constant)))
+		out = append(out, constant...)
+	}
+	out = appendUvarint(out, uint64(len(p.code)))
+	for _, in := range p.code {
+		out = append(out, byte(in.op), in.a, in.b)
+		out = appendUvarint(out, in.arg)
+	}
+	return out, nil
+}
+
+func appendUvarint(out []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], x)
+	return append(out, buf[:n]...)
+}
+
+// UnmarshalBinary reads a program serialised by MarshalBinary.
+// The program is verified, such that running it cannot fail other than by reading the record.
+func (p *Program) UnmarshalBinary(data []byte) error {
+	if len(data) < len(programMagic)+1 || string(data[:len(programMagic)]) != programMagic {
+		return errors.Wrap(ErrInvalidProgram, "missing header")
+	}
+	if version := data[len(programMagic)]; version != programVersion {
+		return errors.Wrapf(ErrInvalidProgram, "unsupported version %d", version)
+	}
+	r := &programReader{data: data[len(programMagic)+1:]}
+
+	// Each constant and instruction takes at least one byte, so counts are limited by the data.
+	numConstants := r.count()
+	constants := make([][]byte, numConstants)
+	for index := range constants {
+		length := r.count()
+		constants[index] = r.bytes(length)
+	}
+	numInstructions := r.count()
+	code := make([]instruction, numInstructions)
+	for index := range code {
+		header := r.bytes(3)
+		if r.err != nil {
+			break
+		}
+		code[index] = instruction{op: opcode(header[0]), a: header[1], b: header[2], arg: r.uvarint()}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.data) > 0 {
+		return errors.Wrapf(ErrInvalidProgram, "%d trailing bytes", len(r.data))
+	}
+	program := Program{code: code, constants: constants}
+	if err := program.verify(); err != nil {
+		return err
+	}
+	*p = program
+	return nil
+}
+
+// programReader reads serialised programs, keeping the first error.
+type programReader struct {
+	data []byte
+	err  error
+}
+
+func (r *programReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.Wrap(ErrInvalidProgram, "malformed varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return x
+}
+
+// count reads a number of items or bytes that follow, which cannot exceed the remaining data.
+func (r *programReader) count() int {
+	x := r.uvarint()
+	if r.err == nil && x > uint64(len(r.data)) {
+		r.err = errors.Wrapf(ErrInvalidProgram, "count %d exceeds data", x)
+		return 0
+	}
+	return int(x)
+}
+
+func (r *programReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = errors.Wrap(ErrInvalidProgram, "truncated data")
+		return nil
+	}
+	b := r.data[:n:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// Hash is the SHA-256 hash of the serialised program, to cache programs by.
+func (p *Program) Hash() [sha256.Size]byte {
+	b, _ := p.MarshalBinary()
+	return sha256.Sum256(b)
+}
+
+// verify checks that every instruction is valid, that branches only jump forward to the same
+// depth of the stack and that the stack never underflows and ends with the result.
+// Sets the maximum depth of the stack.
+func (p *Program) verify() error {
+	// depths are the depths of the stack at the targets of branches, or -1.
+	depths := make([]int, len(p.code)+1)
+	for index := range depths {
+		depths[index] = -1
+	}
+	depth, maxDepth := 0, 0
+	for pc := 0; pc <= len(p.code); pc++ {
+		if depths[pc] >= 0 && depths[pc] != depth {
+			return errors.Wrapf(ErrInvalidProgram, "instruction %d: stack depth %d differs from branch depth %d", pc, depth, depths[pc])
+		}
+		if pc == len(p.code) {
+			break
+		}
+		in := p.code[pc]
+		pops, pushes, err := p.verifyInstruction(in)
+		if err != nil {
+			return errors.Wrapf(err, "instruction %d %s", pc, in.op)
+		}
+		if depth < pops {
+			return errors.Wrapf(ErrInvalidProgram, "instruction %d %s: stack underflow", pc, in.op)
+		}
+		if in.op == opBranchFalse || in.op == opBranchTrue {
+			if in.arg <= uint64(pc) || in.arg > uint64(len(p.code)) {
+				return errors.Wrapf(ErrInvalidProgram, "instruction %d %s: invalid branch target %d", pc, in.op, in.arg)
+			}
+			if depths[in.arg] >= 0 && depths[in.arg] != depth {
+				return errors.Wrapf(ErrInvalidProgram, "instruction %d %s: branch depth %d differs from %d", pc, in.op, depth, depths[in.arg])
+			}
+			depths[in.arg] = depth
+		}
+		depth += pushes - pops
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	if depth != 1 {
+		return errors.Wrapf(ErrInvalidProgram, "program ends with %d values on the stack", depth)
+	}
+	p.maxStack = maxDepth
+	return nil
+}
+
+// verifyInstruction checks the operands of an instruction and returns its effect on the stack.
+func (p *Program) verifyInstruction(in instruction) (pops, pushes int, err error) {
+	op := BinaryOpCode(in.a)
+	switch in.op {
+	case opConst:
+		if ReturnType(in.a) == ReturnType_RETURN_TYPE_UNKNOWN || ReturnType(in.a) == ReturnType_RETURN_TYPE_BYTES {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid constant type %d", in.a)
+		}
+		if _, ok := ReturnType_name[int32(in.a)]; !ok {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid constant type %d", in.a)
+		}
+		return 0, 1, nil
+	case opConstBytes:
+		if in.arg >= uint64(len(p.constants)) {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "unknown constant %d", in.arg)
+		}
+		return 0, 1, nil
+	case opSeek:
+		return 0, 0, verifyTarget(in.b)
+	case opDeref:
+		if _, ok := derefTypes[ValueType(in.a)]; !ok {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid address type %d", in.a)
+		}
+		return 0, 0, verifyTarget(in.b)
+	case opLoad:
+		if ValueType(in.a) == ValueType_VALUE_TYPE_UNKNOWN {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid value type %d", in.a)
+		}
+		if _, ok := ValueType_name[int32(in.a)]; !ok {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid value type %d", in.a)
+		}
+		return 0, 1, nil
+	case opUintToFloat, opIntToFloat, opNot:
+		return 1, 1, nil
+	case opArithU, opArithI:
+		if in.b != 8 && in.b != 16 && in.b != 32 && in.b != 64 {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid width %d", in.b)
+		}
+		if err := checkBinaryOperation(ReturnType_RETURN_TYPE_U64, op); err != nil {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid operation %d", in.a)
+		}
+		if _, isComparison := booleanOps[op]; isComparison {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid operation %d", in.a)
+		}
+		return 2, 1, nil
+	case opArithF:
+		if in.b != 32 && in.b != 64 {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid width %d", in.b)
+		}
+		if _, ok := arithmeticOps[op]; !ok {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid operation %d", in.a)
+		}
+		return 2, 1, nil
+	case opCmpU, opCmpI, opCmpF, opCmpBytes:
+		if _, ok := booleanOps[op]; !ok {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid comparison %d", in.a)
+		}
+		return 2, 1, nil
+	case opCmpConstU, opCmpConstI, opCmpConstF:
+		if _, ok := booleanOps[op]; !ok {
+			return 0, 0, errors.Wrapf(ErrInvalidProgram, "invalid comparison %d", in.a)
+		}
+		return 1, 1, nil
+	case opBranchFalse, opBranchTrue:
+		// A branch that is not taken pops the value it tested.
+		return 1, 0, nil
+	default:
+		return 0, 0, errors.Wrapf(ErrInvalidProgram, "unknown opcode %d", uint8(in.op))
+	}
+}
+
+func verifyTarget(target uint8) error {
+	if _, ok := Target_name[int32(target)]; !ok {
+		return errors.Wrapf(ErrInvalidProgram, "invalid target %d", target)
+	}
+	return nil
+}
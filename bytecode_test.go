@@ -0,0 +1,236 @@
+package binq
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func compileProgram(t TestType, input string) *Program {
+	pred, err := NewParser(input).ReadPredicate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := CompileProgram(pred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestProgram_Disassemble(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"constant-comparison",
+			"VALUE(8, U64LE) >= U64(1000)",
+			"0000  seek        value+8\n" +
+				"0001  load        u64le\n" +
+				"0002  cmpi.u      greater_eq 1000\n",
+		},
+		{
+			"flipped-constant-comparison",
+			"I32(-5) < KEY(JUMP(2, U16BE), I8)",
+			"0000  deref       u16be at key+2\n" +
+				"0001  load        i8\n" +
+				"0002  cmpi.i      greater -5\n",
+		},
+		{
+			"all",
+			`VALUE(0, U32LE) + U32(1) > VALUE(4, U8) AND VALUE(5, BYTES(2)) = "ab" AND NOT VALUE(0, F32LE) = U32(3)`,
+			"0000  seek        value+0\n" +
+				"0001  load        u32le\n" +
+				"0002  const       u32 1\n" +
+				"0003  arith.u     add 32\n" +
+				"0004  seek        value+4\n" +
+				"0005  load        u8\n" +
+				"0006  cmp.u       greater\n" +
+				"0007  brfalse     0017\n" +
+				"0008  seek        value+5\n" +
+				"0009  load        bytes(2)\n" +
+				"0010  const.bytes #0 \"ab\"\n" +
+				"0011  cmp.bytes   eq\n" +
+				"0012  brfalse     0017\n" +
+				"0013  seek        value+0\n" +
+				"0014  load        f32le\n" +
+				"0015  cmpi.f      eq 3\n" +
+				"0016  not         \n",
+		},
+		{
+			"nested",
+			"(VALUE(0, I16LE) * I32(2) < VALUE(2, F64BE)) OR (true AND false)",
+			"0000  seek        value+0\n" +
+				"0001  load        i16le\n" +
+				"0002  const       i32 2\n" +
+				"0003  arith.i     mul 32\n" +
+				"0004  itof        \n" +
+				"0005  seek        value+2\n" +
+				"0006  load        f64be\n" +
+				"0007  cmp.f       less\n" +
+				"0008  brtrue      0012\n" +
+				"0009  const       bool true\n" +
+				"0010  brfalse     0012\n" +
+				"0011  const       bool false\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, compileProgram(t, tc.input).Disassemble())
+		})
+	}
+}
+
+func TestCompileProgram_CheckErrors(t *testing.T) {
+	t.Parallel()
+	pred := &Predicate{Predicate: &Predicate_Expression{Expression: makeValueExpression(ValueType_VALUE_TYPE_U64LE, 0)}}
+	_, err := CompileProgram(pred)
+	assert.Equal(t, Check(pred), err)
+}
+
+// TestProgram_RecordMatcher asserts that programs, and programs read from their serialised form,
+// agree with the RecordMatcher of random predicates, including their errors.
+func TestProgram_RecordMatcher(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(4))
+	valid := 0
+	for i := 0; i < 20000 && valid < 2000; i++ {
+		var pred *Predicate
+		if r.Intn(4) == 0 {
+			pred = randomPredicate(r)
+		} else {
+			pred = &Predicate{Predicate: &Predicate_Expression{Expression: randomTypedExpression(r, 4)}}
+			if len(Check(pred)) > 0 {
+				continue
+			}
+		}
+		valid++
+		matcher, err := PredicateToRecordMatcher(pred)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		program, err := CompileProgram(pred)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		b, err := program.MarshalBinary()
+		assert.NoError(t, err)
+		unmarshalled := &Program{}
+		if assert.NoError(t, unmarshalled.UnmarshalBinary(b), "%v", pred) {
+			remarshalled, _ := unmarshalled.MarshalBinary()
+			assert.Equal(t, b, remarshalled)
+			assert.Equal(t, program.maxStack, unmarshalled.maxStack)
+			program = unmarshalled
+		}
+
+		for j := 0; j < 8; j++ {
+			key, value := randomRecordBytes(r), randomRecordBytes(r)
+			expected, expectedErr := matcher.MatchRecord(key, value)
+			got, err := program.MatchRecord(key, value)
+			if expectedErr != nil || err != nil {
+				assert.Equal(t, errors.Cause(expectedErr), errors.Cause(err), "%v: %v, %v\n%s", pred, expectedErr, err, program.Disassemble())
+				continue
+			}
+			assert.Equal(t, expected, got, "%v\n%s", pred, program.Disassemble())
+		}
+	}
+	assert.Equal(t, 2000, valid)
+}
+
+func TestProgram_Hash(t *testing.T) {
+	t.Parallel()
+	a := compileProgram(t, "VALUE(8, U64LE) >= U64(1000)")
+	b := compileProgram(t, "VALUE(8, U64LE) >= U64(1000)")
+	c := compileProgram(t, "VALUE(8, U64LE) >= U64(1001)")
+	assert.Equal(t, a.Hash(), b.Hash())
+	assert.NotEqual(t, a.Hash(), c.Hash())
+}
+
+func TestProgram_UnmarshalBinary_Invalid(t *testing.T) {
+	t.Parallel()
+
+	header := []byte(programMagic + "\x01")
+	program := func(objs ...interface{}) []byte {
+		return append(append([]byte{}, header...), makeBytes(t, objs...)...)
+	}
+	cases := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"magic", []byte("BINX\x01\x00\x01\x01\x01\x00\x01")},
+		{"version", []byte("BINQ\x02\x00\x01\x01\x01\x00\x01")},
+		{"no-code", program(u8(0), u8(0))},
+		{"truncated", program(u8(0), u8(1), u8(opConst))},
+		{"constant-count", program(u8(200))},
+		{"instruction-count", program(u8(0), u8(100), u8(opConst), u8(1), u8(0), u8(1))},
+		{"trailing", program(u8(0), u8(1), u8(opConst), u8(1), u8(0), u8(1), u8(0))},
+		{"varint", program(u8(0), u8(1), u8(opConst), u8(1), u8(0), u8(0xff))},
+		{"unknown-opcode", program(u8(0), u8(1), u8(opCount), u8(0), u8(0), u8(0))},
+		{"constant-type", program(u8(0), u8(1), u8(opConst), u8(ReturnType_RETURN_TYPE_BYTES), u8(0), u8(0))},
+		{"constant-index", program(u8(0), u8(1), u8(opConstBytes), u8(0), u8(0), u8(0))},
+		{"underflow", program(u8(0), u8(1), u8(opNot), u8(0), u8(0), u8(0))},
+		{"leftover", program(u8(0), u8(2), u8(opConst), u8(1), u8(0), u8(1), u8(opConst), u8(1), u8(0), u8(1))},
+		{"target", program(u8(0), u8(3), u8(opSeek), u8(0), u8(2), u8(0), u8(opLoad), u8(ValueType_VALUE_TYPE_U8), u8(0), u8(0), u8(opNot), u8(0), u8(0), u8(0))},
+		{"value-type", program(u8(0), u8(2), u8(opSeek), u8(0), u8(0), u8(0), u8(opLoad), u8(99), u8(0), u8(0))},
+		{"address-type", program(u8(0), u8(2), u8(opDeref), u8(ValueType_VALUE_TYPE_F64LE), u8(0), u8(0), u8(opLoad), u8(ValueType_VALUE_TYPE_U8), u8(0), u8(0))},
+		{"width", program(u8(0), u8(3), u8(opConst), u8(2), u8(0), u8(1), u8(opConst), u8(2), u8(0), u8(1), u8(opArithU), u8(BinaryOpCode_BINARY_OP_CODE_ADD), u8(7), u8(0))},
+		{"arithmetic", program(u8(0), u8(3), u8(opConst), u8(2), u8(0), u8(1), u8(opConst), u8(2), u8(0), u8(1), u8(opArithF), u8(BinaryOpCode_BINARY_OP_CODE_SHL), u8(64), u8(0))},
+		{"comparison", program(u8(0), u8(3), u8(opConst), u8(2), u8(0), u8(1), u8(opConst), u8(2), u8(0), u8(1), u8(opCmpU), u8(BinaryOpCode_BINARY_OP_CODE_ADD), u8(0), u8(0))},
+		{"backward-branch", program(u8(0), u8(2), u8(opConst), u8(1), u8(0), u8(1), u8(opBranchTrue), u8(0), u8(0), u8(0))},
+		{"branch-out-of-range", program(u8(0), u8(2), u8(opConst), u8(1), u8(0), u8(1), u8(opBranchTrue), u8(0), u8(0), u8(3))},
+		{"branch-depth", program(u8(0), u8(4), u8(opConst), u8(1), u8(0), u8(1), u8(opBranchTrue), u8(0), u8(0), u8(4), u8(opConst), u8(1), u8(0), u8(1), u8(opConst), u8(1), u8(0), u8(1))},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p := &Program{}
+			err := p.UnmarshalBinary(tc.input)
+			assert.Equal(t, ErrInvalidProgram, errors.Cause(err), "%v", err)
+		})
+	}
+}
+
+// TestProgram_ZeroAllocs is not parallel, since testing.AllocsPerRun cannot be used by parallel tests.
+func TestProgram_ZeroAllocs(t *testing.T) {
+	for _, shape := range compiledShapes {
+		t.Run(shape.name, func(t *testing.T) {
+			program := compileProgram(t, shape.input)
+			key, value := makeBytes(t, shape.key...), makeBytes(t, shape.value...)
+			matched, err := program.MatchRecord(key, value)
+			assert.NoError(t, err)
+			assert.True(t, matched)
+			allocs := testing.AllocsPerRun(100, func() {
+				_, _ = program.MatchRecord(key, value)
+			})
+			assert.Zero(t, allocs)
+		})
+	}
+}
+
+// BenchmarkProgram runs the shapes of BenchmarkPredicateToRecordMatcher on programs.
+func BenchmarkProgram(b *testing.B) {
+	for _, shape := range compiledShapes {
+		b.Run(shape.name, func(b *testing.B) {
+			program := compileProgram(b, shape.input)
+			key, value := makeBytes(b, shape.key...), makeBytes(b, shape.value...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := program.MatchRecord(key, value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
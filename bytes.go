@@ -0,0 +1,84 @@
+package binq
+
+import "encoding/binary"
+
+// GetBytes creates a getter of the first length bytes in the byte slice.
+// The returned value shares memory with the byte slice.
+func GetBytes(length uint64) func(bytes []byte) (interface{}, error) {
+	return func(bytes []byte) (interface{}, error) {
+		return readBytes(bytes, length)
+	}
+}
+
+// readBytes is a getter created by GetBytes without boxing the value.
+func readBytes(bytes []byte, length uint64) ([]byte, error) {
+	if uint64(len(bytes)) < length {
+		return nil, ErrBytesTooSmall
+	}
+	return bytes[:length], nil
+}
+
+// GetBytesU8 gets the bytes following a uint8 length prefix in the byte slice.
+func GetBytesU8(bytes []byte) (interface{}, error) {
+	return readBytesU8(bytes)
+}
+
+// readBytesU8 is GetBytesU8 without boxing the value.
+func readBytesU8(bytes []byte) ([]byte, error) {
+	if len(bytes) < 1 {
+		return nil, ErrBytesTooSmall
+	}
+	return readBytes(bytes[1:], uint64(bytes[0]))
+}
+
+// GetBytesU16le gets the bytes following a little-endian uint16 length prefix in the byte slice.
+func GetBytesU16le(bytes []byte) (interface{}, error) {
+	return readBytesU16le(bytes)
+}
+
+// readBytesU16le is GetBytesU16le without boxing the value.
+func readBytesU16le(bytes []byte) ([]byte, error) {
+	if len(bytes) < 2 {
+		return nil, ErrBytesTooSmall
+	}
+	return readBytes(bytes[2:], uint64(binary.LittleEndian.Uint16(bytes)))
+}
+
+// GetBytesU16be gets the bytes following a big-endian uint16 length prefix in the byte slice.
+func GetBytesU16be(bytes []byte) (interface{}, error) {
+	return readBytesU16be(bytes)
+}
+
+// readBytesU16be is GetBytesU16be without boxing the value.
+func readBytesU16be(bytes []byte) ([]byte, error) {
+	if len(bytes) < 2 {
+		return nil, ErrBytesTooSmall
+	}
+	return readBytes(bytes[2:], uint64(binary.BigEndian.Uint16(bytes)))
+}
+
+// GetBytesU32le gets the bytes following a little-endian uint32 length prefix in the byte slice.
+func GetBytesU32le(bytes []byte) (interface{}, error) {
+	return readBytesU32le(bytes)
+}
+
+// readBytesU32le is GetBytesU32le without boxing the value.
+func readBytesU32le(bytes []byte) ([]byte, error) {
+	if len(bytes) < 4 {
+		return nil, ErrBytesTooSmall
+	}
+	return readBytes(bytes[4:], uint64(binary.LittleEndian.Uint32(bytes)))
+}
+
+// GetBytesU32be gets the bytes following a big-endian uint32 length prefix in the byte slice.
+func GetBytesU32be(bytes []byte) (interface{}, error) {
+	return readBytesU32be(bytes)
+}
+
+// readBytesU32be is GetBytesU32be without boxing the value.
+func readBytesU32be(bytes []byte) ([]byte, error) {
+	if len(bytes) < 4 {
+		return nil, ErrBytesTooSmall
+	}
+	return readBytes(bytes[4:], uint64(binary.BigEndian.Uint32(bytes)))
+}
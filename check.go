@@ -0,0 +1,328 @@
+package binq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// Return types declared by the return_type and enum_return_type options in query.proto.
+var (
+	predicateReturnTypes map[string]ReturnType
+	scalarReturnTypes    map[string]ReturnType
+	binaryOpReturnTypes  map[BinaryOpCode]ReturnType
+	unaryOpReturnTypes   map[UnaryOpCode]ReturnType
+	valueTypeReturnTypes map[ValueType]ReturnType
+)
+
+func init() {
+	gz, _ := BinaryOpCode(0).EnumDescriptor()
+	fd, err := extractFileDescriptor(gz)
+	if err != nil {
+		panic(err)
+	}
+	predicateReturnTypes = fieldReturnTypes(fd, "Predicate")
+	scalarReturnTypes = fieldReturnTypes(fd, "Scalar")
+	binaryOpReturnTypes = make(map[BinaryOpCode]ReturnType)
+	for number, returnType := range enumReturnTypes(fd, "BinaryOpCode") {
+		binaryOpReturnTypes[BinaryOpCode(number)] = returnType
+	}
+	unaryOpReturnTypes = make(map[UnaryOpCode]ReturnType)
+	for number, returnType := range enumReturnTypes(fd, "UnaryOpCode") {
+		unaryOpReturnTypes[UnaryOpCode(number)] = returnType
+	}
+	valueTypeReturnTypes = make(map[ValueType]ReturnType)
+	for number, returnType := range enumReturnTypes(fd, "ValueType") {
+		valueTypeReturnTypes[ValueType(number)] = returnType
+	}
+}
+
+func extractFileDescriptor(gz []byte) (*descpb.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, wrap(err, "unable to open file descriptor")
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, wrap(err, "unable to read file descriptor")
+	}
+	fd := &descpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, fd); err != nil {
+		return nil, wrap(err, "unable to unmarshal file descriptor")
+	}
+	return fd, nil
+}
+
+// fieldReturnTypes reads the return_type option of each field of a message, by field name.
+func fieldReturnTypes(fd *descpb.FileDescriptorProto, messageName string) map[string]ReturnType {
+	returnTypes := make(map[string]ReturnType)
+	for _, message := range fd.GetMessageType() {
+		if message.GetName() != messageName {
+			continue
+		}
+		for _, field := range message.GetField() {
+			if returnType, ok := returnTypeOption(field.GetOptions(), E_ReturnType); ok {
+				returnTypes[field.GetName()] = returnType
+			}
+		}
+	}
+	return returnTypes
+}
+
+// enumReturnTypes reads the enum_return_type option of each value of an enum, by number.
+func enumReturnTypes(fd *descpb.FileDescriptorProto, enumName string) map[int32]ReturnType {
+	returnTypes := make(map[int32]ReturnType)
+	for _, enum := range fd.GetEnumType() {
+		if enum.GetName() != enumName {
+			continue
+		}
+		for _, value := range enum.GetValue() {
+			if returnType, ok := returnTypeOption(value.GetOptions(), E_EnumReturnType); ok {
+				returnTypes[value.GetNumber()] = returnType
+			}
+		}
+	}
+	return returnTypes
+}
+
+func returnTypeOption(options proto.Message, extension *proto.ExtensionDesc) (ReturnType, bool) {
+	if reflect.ValueOf(options).IsNil() || !proto.HasExtension(options, extension) {
+		return ReturnType_RETURN_TYPE_UNKNOWN, false
+	}
+	value, err := proto.GetExtension(options, extension)
+	if err != nil {
+		return ReturnType_RETURN_TYPE_UNKNOWN, false
+	}
+	return *value.(*ReturnType), true
+}
+
+// oneofName returns the field name of the oneof wrapper set in a message, such as "binary_operation".
+func oneofName(msg proto.Message, wrapper interface{}) string {
+	if wrapper == nil {
+		return ""
+	}
+	props := proto.GetProperties(reflect.TypeOf(msg).Elem())
+	wrapperType := reflect.TypeOf(wrapper)
+	for name, oneof := range props.OneofTypes {
+		if oneof.Type == wrapperType {
+			return name
+		}
+	}
+	return ""
+}
+
+// CheckError is a type error found by Check.
+type CheckError struct {
+	// Path locates the offending node from the root of the Predicate,
+	// such as all.expressions[2].binary_operation.right.
+	Path string
+	// Message describes the problem.
+	Message string
+}
+
+func (e *CheckError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// CheckErrors are all of the type errors found by Check.
+type CheckErrors []*CheckError
+
+var _ error = CheckErrors(nil)
+
+func (e CheckErrors) Error() string {
+	parts := make([]string, len(e))
+	for index, err := range e {
+		parts[index] = err.Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Check infers the ReturnType of every node of a Predicate and reports every type error,
+// each with the path to the offending node. Returns nil if the Predicate is well typed.
+func Check(pred *Predicate) CheckErrors {
+	c := &checker{}
+	c.checkPredicate(pred)
+	return c.errs
+}
+
+// checker collects type errors while walking a Predicate.
+// It is the only place the type of an operation is inferred: the parser and compilers
+// infer the type of each operation from the types of its operands with a checker.
+type checker struct {
+	errs CheckErrors
+	// types records the type inferred for each checked expression, if not nil.
+	types map[*Expression]ReturnType
+}
+
+func (c *checker) errorf(path string, format string, args ...interface{}) {
+	c.errs = append(c.errs, &CheckError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the errors found so far, or nil if there are none.
+func (c *checker) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func (c *checker) checkPredicate(pred *Predicate) {
+	field := oneofName(pred, pred.GetPredicate())
+	want := predicateReturnTypes[field]
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		c.expect(field, t.Expression, want)
+	case *Predicate_Any:
+		c.checkExpressions(field, t.Any.GetExpressions(), want)
+	case *Predicate_All:
+		c.checkExpressions(field, t.All.GetExpressions(), want)
+	default:
+		c.errorf("", "missing predicate")
+	}
+}
+
+func (c *checker) checkExpressions(path string, exs []*Expression, want ReturnType) {
+	for index, ex := range exs {
+		c.expect(fmt.Sprintf("%s.expressions[%d]", path, index), ex, want)
+	}
+}
+
+// expect checks an expression and reports an error if it does not return the wanted type.
+func (c *checker) expect(path string, ex *Expression, want ReturnType) {
+	got, ok := c.checkExpression(path, ex)
+	if ok && got != want {
+		c.errorf(path, "expected %s, got %s", want, got)
+	}
+}
+
+// checkExpression infers the type of an expression.
+// ok is false if the type cannot be inferred, in which case the error has been reported.
+func (c *checker) checkExpression(path string, ex *Expression) (returnType ReturnType, ok bool) {
+	returnType, ok = c.inferExpression(path, ex)
+	if ok && c.types != nil {
+		c.types[ex] = returnType
+	}
+	return returnType, ok
+}
+
+func (c *checker) inferExpression(path string, ex *Expression) (ReturnType, bool) {
+	fieldPath := joinPath(path, oneofName(ex, ex.GetExpression()))
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Scalar:
+		return c.checkScalar(fieldPath, t.Scalar)
+	case *Expression_Value:
+		return c.checkValue(fieldPath, t.Value)
+	case *Expression_BinaryOperation:
+		return c.checkBinaryOperation(fieldPath, t.BinaryOperation)
+	case *Expression_UnaryOperation:
+		return c.checkUnaryOperation(fieldPath, t.UnaryOperation)
+	default:
+		c.errorf(path, "missing expression")
+		return ReturnType_RETURN_TYPE_UNKNOWN, false
+	}
+}
+
+func (c *checker) checkScalar(path string, s *Scalar) (ReturnType, bool) {
+	returnType, ok := scalarReturnTypes[oneofName(s, s.GetValue())]
+	if !ok {
+		c.errorf(path, "missing scalar value")
+	}
+	return returnType, ok
+}
+
+func (c *checker) checkValue(path string, v *Value) (ReturnType, bool) {
+	ok := true
+	if v.GetJump().GetJump() == nil {
+		c.errorf(joinPath(path, "jump"), "missing jump")
+		ok = false
+	}
+	if _, known := Target_name[int32(v.GetTarget())]; !known {
+		c.errorf(joinPath(path, "target"), "unknown target %s", v.GetTarget())
+		ok = false
+	}
+	returnType, known := valueTypeReturnTypes[v.GetType()]
+	if !known || returnType == ReturnType_RETURN_TYPE_UNKNOWN {
+		c.errorf(joinPath(path, "type"), "unknown value type %s", v.GetType())
+		ok = false
+	}
+	return returnType, ok
+}
+
+func (c *checker) checkBinaryOperation(path string, op *BinaryOperation) (ReturnType, bool) {
+	leftType, leftOK := c.checkExpression(joinPath(path, "left"), op.GetLeft())
+	rightType, rightOK := c.checkExpression(joinPath(path, "right"), op.GetRight())
+	returnType, _, ok := c.binaryOperationType(path, op.GetBinaryOpCode(), leftType, leftOK, rightType, rightOK)
+	return returnType, ok
+}
+
+// binaryOperationType infers the type of a binary operation from the types of its operands,
+// along with the type that both operands are converted to before the operation is applied.
+// leftOK and rightOK are false for operands whose type could not be inferred.
+func (c *checker) binaryOperationType(path string, opCode BinaryOpCode, leftType ReturnType, leftOK bool, rightType ReturnType, rightOK bool) (returnType, operandType ReturnType, ok bool) {
+	if _, known := BinaryOpCode_name[int32(opCode)]; !known || opCode == BinaryOpCode_BINARY_OP_CODE_UNKNOWN {
+		c.errorf(joinPath(path, "binary_op_code"), "unknown binary op code %s", opCode)
+		return ReturnType_RETURN_TYPE_UNKNOWN, ReturnType_RETURN_TYPE_UNKNOWN, false
+	}
+	// Comparisons and logical operations declare their return type, which is known even if an operand is not.
+	returnType, declared := binaryOpReturnTypes[opCode]
+
+	if _, isLogical := logicalOps[opCode]; isLogical {
+		if leftOK && leftType != ReturnType_RETURN_TYPE_BOOL {
+			c.errorf(joinPath(path, "left"), "%s requires %s, got %s", opCode, ReturnType_RETURN_TYPE_BOOL, leftType)
+		}
+		if rightOK && rightType != ReturnType_RETURN_TYPE_BOOL {
+			c.errorf(joinPath(path, "right"), "%s requires %s, got %s", opCode, ReturnType_RETURN_TYPE_BOOL, rightType)
+		}
+		return returnType, ReturnType_RETURN_TYPE_BOOL, true
+	}
+	if !leftOK || !rightOK {
+		return returnType, ReturnType_RETURN_TYPE_UNKNOWN, declared
+	}
+	upscaledType, err := getUpscaledType(leftType, rightType)
+	if err != nil {
+		c.errorf(path, "%s cannot be applied to %s and %s", opCode, leftType, rightType)
+		return returnType, ReturnType_RETURN_TYPE_UNKNOWN, declared
+	}
+	if err := checkBinaryOperation(upscaledType, opCode); err != nil {
+		c.errorf(path, "%v", err)
+		return returnType, ReturnType_RETURN_TYPE_UNKNOWN, declared
+	}
+	if declared {
+		return returnType, upscaledType, true
+	}
+	return upscaledType, upscaledType, true
+}
+
+func (c *checker) checkUnaryOperation(path string, op *UnaryOperation) (ReturnType, bool) {
+	operandType, operandOK := c.checkExpression(joinPath(path, "operand"), op.GetOperand())
+	return c.unaryOperationType(path, op.GetUnaryOpCode(), operandType, operandOK)
+}
+
+// unaryOperationType infers the type of a unary operation from the type of its operand.
+// operandOK is false for an operand whose type could not be inferred.
+func (c *checker) unaryOperationType(path string, opCode UnaryOpCode, operandType ReturnType, operandOK bool) (ReturnType, bool) {
+	returnType, declared := unaryOpReturnTypes[opCode]
+	if opCode != UnaryOpCode_UNARY_OP_CODE_NOT || !declared {
+		c.errorf(joinPath(path, "unary_op_code"), "unknown unary op code %s", opCode)
+		return ReturnType_RETURN_TYPE_UNKNOWN, false
+	}
+	if operandOK && operandType != ReturnType_RETURN_TYPE_BOOL {
+		c.errorf(joinPath(path, "operand"), "%s requires %s, got %s", opCode, ReturnType_RETURN_TYPE_BOOL, operandType)
+	}
+	return returnType, true
+}
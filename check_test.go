@@ -0,0 +1,160 @@
+package binq
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	u64le0 := makeValueExpression(ValueType_VALUE_TYPE_U64LE, 0)
+	i64le0 := makeValueExpression(ValueType_VALUE_TYPE_I64LE, 0)
+	u64le0EqU64100 := makeBinaryOperationExpression(u64le0, BinaryOpCode_BINARY_OP_CODE_EQ, makeScalarExpression(t, uint64(100)))
+
+	cases := []struct {
+		name     string
+		pred     *Predicate
+		expected []string
+	}{
+		{
+			"valid",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				u64le0EqU64100,
+				makeUnaryOperationExpression(makeScalarExpression(t, false)),
+				makeBinaryOperationExpression(
+					makeBinaryOperationExpression(u64le0, BinaryOpCode_BINARY_OP_CODE_ADD, makeScalarExpression(t, uint32(1))),
+					BinaryOpCode_BINARY_OP_CODE_GREATER,
+					makeScalarExpression(t, uint64(2))),
+			}}}},
+			nil,
+		},
+		{
+			"missing-predicate",
+			&Predicate{},
+			[]string{"missing predicate"},
+		},
+		{
+			"non-boolean-expression",
+			&Predicate{Predicate: &Predicate_Expression{Expression: u64le0}},
+			[]string{"expression: expected RETURN_TYPE_BOOL, got RETURN_TYPE_U64"},
+		},
+		{
+			"non-boolean-any",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: []*Expression{
+				u64le0EqU64100, makeScalarExpression(t, []byte("abc")),
+			}}}},
+			[]string{"any.expressions[1]: expected RETURN_TYPE_BOOL, got RETURN_TYPE_BYTES"},
+		},
+		{
+			"mismatched-operands",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				makeScalarExpression(t, true),
+				makeScalarExpression(t, true),
+				makeBinaryOperationExpression(
+					makeScalarExpression(t, true),
+					BinaryOpCode_BINARY_OP_CODE_AND,
+					makeBinaryOperationExpression(u64le0, BinaryOpCode_BINARY_OP_CODE_LESS, i64le0)),
+			}}}},
+			[]string{"all.expressions[2].binary_operation.right.binary_operation: BINARY_OP_CODE_LESS cannot be applied to RETURN_TYPE_U64 and RETURN_TYPE_I64"},
+		},
+		{
+			"unsupported-operation",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeBinaryOperationExpression(makeScalarExpression(t, 1.5), BinaryOpCode_BINARY_OP_CODE_SHL, makeScalarExpression(t, 2.5)),
+				BinaryOpCode_BINARY_OP_CODE_EQ,
+				makeScalarExpression(t, 0.0))}},
+			[]string{"expression.binary_operation.left.binary_operation: BINARY_OP_CODE_SHL cannot be applied to RETURN_TYPE_F64"},
+		},
+		{
+			"logical-operands",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				u64le0,
+				BinaryOpCode_BINARY_OP_CODE_OR,
+				makeUnaryOperationExpression(makeScalarExpression(t, int32(1))))}},
+			[]string{
+				"expression.binary_operation.right.unary_operation.operand: UNARY_OP_CODE_NOT requires RETURN_TYPE_BOOL, got RETURN_TYPE_I32",
+				"expression.binary_operation.left: BINARY_OP_CODE_OR requires RETURN_TYPE_BOOL, got RETURN_TYPE_U64",
+			},
+		},
+		{
+			"malformed",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: []*Expression{
+				{},
+				{Expression: &Expression_Scalar{Scalar: &Scalar{}}},
+				{Expression: &Expression_Value{Value: &Value{Type: ValueType_VALUE_TYPE_UNKNOWN, Target: Target(7)}}},
+				{Expression: &Expression_BinaryOperation{BinaryOperation: &BinaryOperation{
+					Left:  makeScalarExpression(t, true),
+					Right: makeScalarExpression(t, true),
+				}}},
+				{Expression: &Expression_UnaryOperation{UnaryOperation: &UnaryOperation{
+					Operand: makeScalarExpression(t, true),
+				}}},
+			}}}},
+			[]string{
+				"any.expressions[0]: missing expression",
+				"any.expressions[1].scalar: missing scalar value",
+				"any.expressions[2].value.jump: missing jump",
+				"any.expressions[2].value.target: unknown target 7",
+				"any.expressions[2].value.type: unknown value type VALUE_TYPE_UNKNOWN",
+				"any.expressions[3].binary_operation.binary_op_code: unknown binary op code BINARY_OP_CODE_UNKNOWN",
+				"any.expressions[4].unary_operation.unary_op_code: unknown unary op code UNARY_OP_CODE_UNKNOWN",
+			},
+		},
+		{
+			"comparison-of-invalid",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				makeBinaryOperationExpression(
+					makeBinaryOperationExpression(makeScalarExpression(t, true), BinaryOpCode_BINARY_OP_CODE_ADD, makeScalarExpression(t, true)),
+					BinaryOpCode_BINARY_OP_CODE_EQ,
+					u64le0),
+			}}}},
+			[]string{"all.expressions[0].binary_operation.left.binary_operation: BINARY_OP_CODE_ADD cannot be applied to RETURN_TYPE_BOOL"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			errs := Check(tc.pred)
+			var got []string
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tc.expected, got)
+
+			_, err := PredicateToRecordMatcher(tc.pred)
+			if tc.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, errs, err)
+			}
+		})
+	}
+}
+
+// TestCheck_ValueTypes asserts that the return types declared in query.proto agree with compiled values.
+func TestCheck_ValueTypes(t *testing.T) {
+	t.Parallel()
+	for number, name := range ValueType_name {
+		valueType := ValueType(number)
+		if valueType == ValueType_VALUE_TYPE_UNKNOWN {
+			continue
+		}
+		c, err := compileValue(&Value{Jump: &Jump{Jump: &Jump_Offset{}}, Type: valueType})
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, c.returnType, valueTypeReturnTypes[valueType], name)
+		}
+	}
+}
+
+func TestCheck_RandomPredicates(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		pred := randomPredicate(r)
+		assert.Nil(t, Check(pred))
+	}
+}
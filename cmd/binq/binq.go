@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a binq sub-command.
+type command struct {
+	// name is the name used to invoke the command.
+	name string
+	// usage is a short description of the command.
+	usage string
+	// run runs the command with its arguments.
+	run func(args []string) error
+}
+
+var commands = []command{
+	{"fmt", "reformat filter files in place", fmtCommand},
+	{"check", "report every problem in filter files", checkCommand},
+	{"disasm", "print the bytecode a filter compiles to", disasmCommand},
+	{"serve", "serve a database file over gRPC", serveCommand},
+	{"query", "print the records of a served database", queryCommand},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(args); err != nil {
+			fmt.Fprintf(os.Stderr, "binq %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: binq <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
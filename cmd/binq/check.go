@@ -0,0 +1,50 @@
+package main
+
+import (
+	"explodes/github.com/binq"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// checkCommand reports every problem in filter files, or in standard input.
+func checkCommand(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: binq check [files]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	problems := 0
+	if flags.NArg() == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		problems += checkText("<stdin>", string(src))
+	}
+	for _, name := range flags.Args() {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		problems += checkText(name, string(src))
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problems", problems)
+	}
+	return nil
+}
+
+// checkText prints the diagnostics for a filter to standard error and returns how many there were.
+func checkText(name, src string) int {
+	_, diagnostics := binq.NewParser(src).Diagnose()
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s:%s\n%s", name, diagnostic, diagnostic.Snippet)
+	}
+	return len(diagnostics)
+}
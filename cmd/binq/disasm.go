@@ -0,0 +1,47 @@
+package main
+
+import (
+	"explodes/github.com/binq"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// disasmCommand prints the bytecode that a filter file, or standard input, compiles to.
+func disasmCommand(args []string) error {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: binq disasm [file]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var src []byte
+	var err error
+	switch flags.NArg() {
+	case 0:
+		src, err = ioutil.ReadAll(os.Stdin)
+	case 1:
+		src, err = ioutil.ReadFile(flags.Arg(0))
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		return err
+	}
+	pred, err := binq.NewParser(string(src)).ReadPredicate()
+	if err != nil {
+		return err
+	}
+	program, err := binq.CompileProgram(pred)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("; sha256 %x\n", program.Hash())
+	fmt.Print(program.Disassemble())
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"explodes/github.com/binq"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// fmtCommand reformats filter files in place, or standard input to standard output.
+func fmtCommand(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	list := flags.Bool("l", false, "list files whose formatting differs instead of rewriting them")
+	indent := flags.String("indent", "\t", "indentation for operands of AND and OR, empty for a single line")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: binq fmt [flags] [files]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	opts := binq.FormatOptions{Indent: *indent}
+
+	if flags.NArg() == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text, err := binq.FormatText(string(src), opts)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.WriteString(text)
+		return err
+	}
+
+	for _, name := range flags.Args() {
+		if err := fmtFile(name, opts, *list); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// fmtFile reformats a single file, writing it only when its contents change.
+func fmtFile(name string, opts binq.FormatOptions, list bool) error {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	text, err := binq.FormatText(string(src), opts)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(src, []byte(text)) {
+		return nil
+	}
+	if list {
+		fmt.Println(name)
+		return nil
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, []byte(text), info.Mode())
+}
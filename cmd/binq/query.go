@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"explodes/github.com/binq"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// queryCommand queries a database served by binq serve and prints the records as a table.
+func queryCommand(args []string) error {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:7070", "address of the server")
+	where := flags.String("where", "", "filter of the records")
+	fields := flags.String("select", "", "fields to print, such as: VALUE(0, U32LE) AS id, VALUE(4, U8)")
+	aggregates := flags.String("aggregate", "", "aggregates to print instead of records, such as: COUNT() AS n, MAX(VALUE(4, U8))")
+	groupBy := flags.String("group-by", "", "fields that group the records for -aggregate, such as: VALUE(4, U8) AS kind")
+	limit := flags.Uint64("limit", 0, "maximum number of records or groups, or 0 for no limit")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: binq query [-addr address] [-where filter] [-select fields | -aggregate aggregates [-group-by fields]] [-limit n]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || (*fields != "" && *aggregates != "") || (*groupBy != "" && *aggregates == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	query := &binq.Query{QueryOptions: &binq.Options{Limit: *limit}}
+	if *where != "" {
+		pred, err := binq.NewParser(*where).ReadPredicate()
+		if err != nil {
+			return err
+		}
+		query.Predicate = pred
+	}
+	names := []string{"KEY", "VALUE"}
+	if *fields != "" {
+		projection, err := binq.NewParser(*fields).ReadProjection()
+		if err != nil {
+			return err
+		}
+		projector, err := binq.NewProjector(projection)
+		if err != nil {
+			return err
+		}
+		query.Projection = projection
+		names = projector.Names()
+	}
+	if *aggregates != "" {
+		aggregation := &binq.Aggregation{}
+		var err error
+		if aggregation.Aggregates, err = binq.NewParser(*aggregates).ReadAggregates(); err != nil {
+			return err
+		}
+		if *groupBy != "" {
+			if aggregation.GroupBy, err = binq.NewParser(*groupBy).ReadProjection(); err != nil {
+				return err
+			}
+		}
+		aggregator, err := binq.NewAggregator(aggregation)
+		if err != nil {
+			return err
+		}
+		query.Aggregation = aggregation
+		names = aggregator.Names()
+	}
+
+	conn, err := grpc.Dial(*addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stream, err := binq.NewBinqClient(conn).Query(context.Background(), query)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(names, "\t"))
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		row := []string{hex.EncodeToString(record.GetKey()), hex.EncodeToString(record.GetValue())}
+		if len(query.Projection) > 0 || query.Aggregation != nil {
+			row = make([]string, len(record.GetFields()))
+			for index, field := range record.GetFields() {
+				row[index] = fieldText(field)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// fieldText renders the value of a field for reading.
+func fieldText(s *binq.Scalar) string {
+	switch t := s.GetValue().(type) {
+	case *binq.Scalar_Bool:
+		return strconv.FormatBool(t.Bool)
+	case *binq.Scalar_U64:
+		return strconv.FormatUint(t.U64, 10)
+	case *binq.Scalar_U32:
+		return strconv.FormatUint(uint64(t.U32), 10)
+	case *binq.Scalar_I64:
+		return strconv.FormatInt(t.I64, 10)
+	case *binq.Scalar_I32:
+		return strconv.FormatInt(int64(t.I32), 10)
+	case *binq.Scalar_F64:
+		return strconv.FormatFloat(t.F64, 'g', -1, 64)
+	case *binq.Scalar_F32:
+		return strconv.FormatFloat(float64(t.F32), 'g', -1, 32)
+	case *binq.Scalar_Bytes:
+		return strconv.Quote(string(t.Bytes))
+	case nil:
+		return "NULL"
+	default:
+		return "?"
+	}
+}
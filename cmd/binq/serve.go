@@ -0,0 +1,60 @@
+package main
+
+import (
+	"explodes/github.com/binq"
+	"explodes/github.com/binq/db3"
+	"explodes/github.com/binq/server"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// serveCommand opens a database file and serves it with the Binq gRPC service until interrupted.
+func serveCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:7070", "address to listen on")
+	keySize := flags.Uint("key-size", 16, "maximum length of the key of each record in bytes")
+	dataSize := flags.Uint("data-size", uint(db3.VariableDataSize), "size of the value of each record in bytes, or 0 for values of any size")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: binq serve [-key-size n] [-data-size n] [-addr address] file")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *keySize > db3.KeySizeLimit || *dataSize > 1<<16-1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	pager, err := db3.OpenPager(flags.Arg(0), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer pager.Close()
+	table, err := db3.Open(pager, uint16(*keySize), uint16(*dataSize))
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	binq.RegisterBinqServer(grpcServer, server.New(table))
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		grpcServer.GracefulStop()
+	}()
+
+	fmt.Fprintf(os.Stderr, "serving %s on %s\n", flags.Arg(0), listener.Addr())
+	return grpcServer.Serve(listener)
+}
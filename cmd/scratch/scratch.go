@@ -3,6 +3,7 @@ package main
 import (
 	"explodes/github.com/binq"
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"strings"
 )
 
@@ -58,7 +59,19 @@ func main() {
 	for _, value := range values {
 		verbosePrint(value, lines)
 	}
+	fmt.Println()
 
+	predicate, diagnostics := binq.NewParser(sampleFilter).Diagnose()
+	if len(diagnostics) > 0 {
+		fmt.Println("DIAGNOSTICS", len(diagnostics))
+		for _, diagnostic := range diagnostics {
+			fmt.Println(diagnostic)
+			fmt.Print(diagnostic.Snippet)
+		}
+		return
+	}
+	fmt.Println("PREDICATE")
+	fmt.Println(proto.MarshalTextString(predicate))
 }
 
 func verbosePrint(value *binq.ParserValue, lines []string) {
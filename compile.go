@@ -0,0 +1,1113 @@
+package binq
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"math"
+)
+
+// Compiled expressions are closures specialised for the type they return, so that matching
+// a record does not box any value. Each kind of ReturnType is held in one Go type:
+//
+//	BOOL          bool
+//	U8 ... U64    uint64, wrapped to the width of the type after each operation
+//	I8 ... I64    int64, wrapped to the width of the type after each operation
+//	F32, F64      float64, F32 rounded to a float32 after each operation
+//	BYTES         []byte
+type (
+	boolFunc  func(key, value []byte) (bool, error)
+	uintFunc  func(key, value []byte) (uint64, error)
+	intFunc   func(key, value []byte) (int64, error)
+	floatFunc func(key, value []byte) (float64, error)
+	bytesFunc func(key, value []byte) ([]byte, error)
+)
+
+// compiled is a compiled expression. The closure for the kind of returnType is set.
+type compiled struct {
+	returnType ReturnType
+
+	boolFn  boolFunc
+	uintFn  uintFunc
+	intFn   intFunc
+	floatFn floatFunc
+	bytesFn bytesFunc
+
+	// constant is set if the expression does not read the record, its value is in the field for its kind.
+	constant   bool
+	constBool  bool
+	constUint  uint64
+	constInt   int64
+	constFloat float64
+	constBytes []byte
+}
+
+func isUnsignedType(returnType ReturnType) bool {
+	switch returnType {
+	case ReturnType_RETURN_TYPE_U64, ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_U8:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSignedType(returnType ReturnType) bool {
+	return isIntegerType(returnType) && !isUnsignedType(returnType)
+}
+
+// typeBits is the width of an integer type.
+func typeBits(returnType ReturnType) uint {
+	switch returnType {
+	case ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_I8:
+		return 8
+	case ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_I16:
+		return 16
+	case ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_I32:
+		return 32
+	default:
+		return 64
+	}
+}
+
+func constBool(val bool) compiled {
+	return compiled{
+		returnType: ReturnType_RETURN_TYPE_BOOL,
+		boolFn:     func(_, _ []byte) (bool, error) { return val, nil },
+		constant:   true,
+		constBool:  val,
+	}
+}
+
+func constUint(returnType ReturnType, val uint64) compiled {
+	return compiled{
+		returnType: returnType,
+		uintFn:     func(_, _ []byte) (uint64, error) { return val, nil },
+		constant:   true,
+		constUint:  val,
+	}
+}
+
+func constInt(returnType ReturnType, val int64) compiled {
+	return compiled{
+		returnType: returnType,
+		intFn:      func(_, _ []byte) (int64, error) { return val, nil },
+		constant:   true,
+		constInt:   val,
+	}
+}
+
+func constFloat(returnType ReturnType, val float64) compiled {
+	return compiled{
+		returnType: returnType,
+		floatFn:    func(_, _ []byte) (float64, error) { return val, nil },
+		constant:   true,
+		constFloat: val,
+	}
+}
+
+func constBytes(val []byte) compiled {
+	return compiled{
+		returnType: ReturnType_RETURN_TYPE_BYTES,
+		bytesFn:    func(_, _ []byte) ([]byte, error) { return val, nil },
+		constant:   true,
+		constBytes: val,
+	}
+}
+
+// compileMatcher compiles a boolean expression into a RecordMatcher.
+func compileMatcher(ex *Expression) (RecordMatcherFunc, error) {
+	c, err := compileExpression(ex)
+	if err != nil {
+		return nil, wrap(err, "invalid expression")
+	}
+	if c.returnType != ReturnType_RETURN_TYPE_BOOL {
+		return nil, errors.New("expression is not a boolean expression")
+	}
+	return RecordMatcherFunc(c.boolFn), nil
+}
+
+func compileExpression(ex *Expression) (compiled, error) {
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Scalar:
+		c, err := compileScalar(t.Scalar)
+		if err != nil {
+			return compiled{}, wrap(err, "unable to compile scalar")
+		}
+		return c, nil
+	case *Expression_Value:
+		c, err := compileValue(t.Value)
+		if err != nil {
+			return compiled{}, wrap(err, "unable to compile value")
+		}
+		return c, nil
+	case *Expression_BinaryOperation:
+		// nowrap: recursive call
+		return compileBinaryOperation(t.BinaryOperation)
+	case *Expression_UnaryOperation:
+		// nowrap: recursive call
+		return compileUnaryOperation(t.UnaryOperation)
+	default:
+		return compiled{}, unhandledType("expression type", t)
+	}
+}
+
+func compileScalar(s *Scalar) (compiled, error) {
+	switch t := s.Value.(type) {
+	case *Scalar_Bool:
+		return constBool(t.Bool), nil
+	case *Scalar_U32:
+		return constUint(ReturnType_RETURN_TYPE_U32, uint64(t.U32)), nil
+	case *Scalar_U64:
+		return constUint(ReturnType_RETURN_TYPE_U64, t.U64), nil
+	case *Scalar_I32:
+		return constInt(ReturnType_RETURN_TYPE_I32, int64(t.I32)), nil
+	case *Scalar_I64:
+		return constInt(ReturnType_RETURN_TYPE_I64, t.I64), nil
+	case *Scalar_F32:
+		return constFloat(ReturnType_RETURN_TYPE_F32, float64(t.F32)), nil
+	case *Scalar_F64:
+		return constFloat(ReturnType_RETURN_TYPE_F64, t.F64), nil
+	case *Scalar_Bytes:
+		return constBytes(t.Bytes), nil
+	default:
+		return compiled{}, unhandledType("scalar type", t)
+	}
+}
+
+func compileValue(v *Value) (compiled, error) {
+	jumper, err := jumpToJumper(v.Jump)
+	if err != nil {
+		return compiled{}, wrap(err, "invalid value jump")
+	}
+	var target targetSelector
+	switch v.Target {
+	case Target_TARGET_VALUE:
+		target = selectValue
+	case Target_TARGET_KEY:
+		target = selectKey
+	default:
+		return compiled{}, unhandledEnum("value target", v.Target)
+	}
+	switch v.Type {
+	case ValueType_VALUE_TYPE_U64LE:
+		return compileUintValue(ReturnType_RETURN_TYPE_U64, jumper, target, readU64le), nil
+	case ValueType_VALUE_TYPE_U64BE:
+		return compileUintValue(ReturnType_RETURN_TYPE_U64, jumper, target, readU64be), nil
+	case ValueType_VALUE_TYPE_U32LE:
+		return compileUintValue(ReturnType_RETURN_TYPE_U32, jumper, target, func(b []byte) (uint64, error) {
+			val, err := readU32le(b)
+			return uint64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_U32BE:
+		return compileUintValue(ReturnType_RETURN_TYPE_U32, jumper, target, func(b []byte) (uint64, error) {
+			val, err := readU32be(b)
+			return uint64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_U16LE:
+		return compileUintValue(ReturnType_RETURN_TYPE_U16, jumper, target, func(b []byte) (uint64, error) {
+			val, err := readU16le(b)
+			return uint64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_U16BE:
+		return compileUintValue(ReturnType_RETURN_TYPE_U16, jumper, target, func(b []byte) (uint64, error) {
+			val, err := readU16be(b)
+			return uint64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_U8:
+		return compileUintValue(ReturnType_RETURN_TYPE_U8, jumper, target, func(b []byte) (uint64, error) {
+			val, err := readU8(b)
+			return uint64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_I64LE:
+		return compileIntValue(ReturnType_RETURN_TYPE_I64, jumper, target, readI64le), nil
+	case ValueType_VALUE_TYPE_I64BE:
+		return compileIntValue(ReturnType_RETURN_TYPE_I64, jumper, target, readI64be), nil
+	case ValueType_VALUE_TYPE_I32LE:
+		return compileIntValue(ReturnType_RETURN_TYPE_I32, jumper, target, func(b []byte) (int64, error) {
+			val, err := readI32le(b)
+			return int64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_I32BE:
+		return compileIntValue(ReturnType_RETURN_TYPE_I32, jumper, target, func(b []byte) (int64, error) {
+			val, err := readI32be(b)
+			return int64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_I16LE:
+		return compileIntValue(ReturnType_RETURN_TYPE_I16, jumper, target, func(b []byte) (int64, error) {
+			val, err := readI16le(b)
+			return int64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_I16BE:
+		return compileIntValue(ReturnType_RETURN_TYPE_I16, jumper, target, func(b []byte) (int64, error) {
+			val, err := readI16be(b)
+			return int64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_I8:
+		return compileIntValue(ReturnType_RETURN_TYPE_I8, jumper, target, func(b []byte) (int64, error) {
+			val, err := readI8(b)
+			return int64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_F64LE:
+		return compileFloatValue(ReturnType_RETURN_TYPE_F64, jumper, target, readF64le), nil
+	case ValueType_VALUE_TYPE_F64BE:
+		return compileFloatValue(ReturnType_RETURN_TYPE_F64, jumper, target, readF64be), nil
+	case ValueType_VALUE_TYPE_F32LE:
+		return compileFloatValue(ReturnType_RETURN_TYPE_F32, jumper, target, func(b []byte) (float64, error) {
+			val, err := readF32le(b)
+			return float64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_F32BE:
+		return compileFloatValue(ReturnType_RETURN_TYPE_F32, jumper, target, func(b []byte) (float64, error) {
+			val, err := readF32be(b)
+			return float64(val), err
+		}), nil
+	case ValueType_VALUE_TYPE_BYTES:
+		length := v.Length
+		return compileBytesValue(jumper, target, func(b []byte) ([]byte, error) {
+			return readBytes(b, length)
+		}), nil
+	case ValueType_VALUE_TYPE_BYTES_U8:
+		return compileBytesValue(jumper, target, readBytesU8), nil
+	case ValueType_VALUE_TYPE_BYTES_U16LE:
+		return compileBytesValue(jumper, target, readBytesU16le), nil
+	case ValueType_VALUE_TYPE_BYTES_U16BE:
+		return compileBytesValue(jumper, target, readBytesU16be), nil
+	case ValueType_VALUE_TYPE_BYTES_U32LE:
+		return compileBytesValue(jumper, target, readBytesU32le), nil
+	case ValueType_VALUE_TYPE_BYTES_U32BE:
+		return compileBytesValue(jumper, target, readBytesU32be), nil
+	default:
+		return compiled{}, unhandledEnum("value type", v.Type)
+	}
+}
+
+func compileUintValue(returnType ReturnType, jumper Jumper, target targetSelector, read func([]byte) (uint64, error)) compiled {
+	return compiled{returnType: returnType, uintFn: func(key, value []byte) (uint64, error) {
+		jumped, err := jumper.Jump(target(key, value))
+		if err != nil {
+			return 0, wrap(err, "unable to jump")
+		}
+		result, err := read(jumped)
+		if err != nil {
+			return 0, wrap(err, "unable to read value")
+		}
+		return result, nil
+	}}
+}
+
+func compileIntValue(returnType ReturnType, jumper Jumper, target targetSelector, read func([]byte) (int64, error)) compiled {
+	return compiled{returnType: returnType, intFn: func(key, value []byte) (int64, error) {
+		jumped, err := jumper.Jump(target(key, value))
+		if err != nil {
+			return 0, wrap(err, "unable to jump")
+		}
+		result, err := read(jumped)
+		if err != nil {
+			return 0, wrap(err, "unable to read value")
+		}
+		return result, nil
+	}}
+}
+
+func compileFloatValue(returnType ReturnType, jumper Jumper, target targetSelector, read func([]byte) (float64, error)) compiled {
+	return compiled{returnType: returnType, floatFn: func(key, value []byte) (float64, error) {
+		jumped, err := jumper.Jump(target(key, value))
+		if err != nil {
+			return 0, wrap(err, "unable to jump")
+		}
+		result, err := read(jumped)
+		if err != nil {
+			return 0, wrap(err, "unable to read value")
+		}
+		return result, nil
+	}}
+}
+
+func compileBytesValue(jumper Jumper, target targetSelector, read func([]byte) ([]byte, error)) compiled {
+	return compiled{returnType: ReturnType_RETURN_TYPE_BYTES, bytesFn: func(key, value []byte) ([]byte, error) {
+		jumped, err := jumper.Jump(target(key, value))
+		if err != nil {
+			return nil, wrap(err, "unable to jump")
+		}
+		result, err := read(jumped)
+		if err != nil {
+			return nil, wrap(err, "unable to read value")
+		}
+		return result, nil
+	}}
+}
+
+// convert converts a compiled expression to a type chosen by getUpscaledType,
+// which holds each of its values.
+func convert(c compiled, returnType ReturnType) (compiled, error) {
+	if c.returnType == returnType {
+		return c, nil
+	}
+	var converted compiled
+	switch {
+	case c.returnType == ReturnType_RETURN_TYPE_BOOL && isUnsignedType(returnType):
+		fn := c.boolFn
+		converted.uintFn = func(key, value []byte) (uint64, error) {
+			result, err := fn(key, value)
+			if result {
+				return 1, err
+			}
+			return 0, err
+		}
+	case c.returnType == ReturnType_RETURN_TYPE_BOOL && isSignedType(returnType):
+		fn := c.boolFn
+		converted.intFn = func(key, value []byte) (int64, error) {
+			result, err := fn(key, value)
+			if result {
+				return 1, err
+			}
+			return 0, err
+		}
+	case c.returnType == ReturnType_RETURN_TYPE_BOOL && isFloatType(returnType):
+		fn := c.boolFn
+		converted.floatFn = func(key, value []byte) (float64, error) {
+			result, err := fn(key, value)
+			if result {
+				return 1, err
+			}
+			return 0, err
+		}
+	case isUnsignedType(c.returnType) && isUnsignedType(returnType):
+		converted.uintFn = c.uintFn
+	case isUnsignedType(c.returnType) && isSignedType(returnType):
+		fn := c.uintFn
+		converted.intFn = func(key, value []byte) (int64, error) {
+			result, err := fn(key, value)
+			return int64(result), err
+		}
+	case isUnsignedType(c.returnType) && isFloatType(returnType):
+		fn := c.uintFn
+		converted.floatFn = func(key, value []byte) (float64, error) {
+			result, err := fn(key, value)
+			return float64(result), err
+		}
+	case isSignedType(c.returnType) && isSignedType(returnType):
+		converted.intFn = c.intFn
+	case isSignedType(c.returnType) && isFloatType(returnType):
+		fn := c.intFn
+		converted.floatFn = func(key, value []byte) (float64, error) {
+			result, err := fn(key, value)
+			return float64(result), err
+		}
+	case isFloatType(c.returnType) && isFloatType(returnType):
+		converted.floatFn = c.floatFn
+	default:
+		return compiled{}, errors.Errorf("cannot convert %s to %s", c.returnType, returnType)
+	}
+	converted.returnType = returnType
+	if c.constant {
+		return constantOf(converted)
+	}
+	return converted, nil
+}
+
+// constantOf evaluates a compiled expression that does not read the record into a constant.
+func constantOf(c compiled) (compiled, error) {
+	switch {
+	case c.returnType == ReturnType_RETURN_TYPE_BOOL:
+		val, err := c.boolFn(nil, nil)
+		return constBool(val), err
+	case isUnsignedType(c.returnType):
+		val, err := c.uintFn(nil, nil)
+		return constUint(c.returnType, val), err
+	case isSignedType(c.returnType):
+		val, err := c.intFn(nil, nil)
+		return constInt(c.returnType, val), err
+	case isFloatType(c.returnType):
+		val, err := c.floatFn(nil, nil)
+		return constFloat(c.returnType, val), err
+	case c.returnType == ReturnType_RETURN_TYPE_BYTES:
+		val, err := c.bytesFn(nil, nil)
+		return constBytes(val), err
+	default:
+		return compiled{}, unhandledEnum("constant type", c.returnType)
+	}
+}
+
+func compileBinaryOperation(op *BinaryOperation) (compiled, error) {
+	left, err := compileExpression(op.Left)
+	if err != nil {
+		// nowrap: recursive call
+		return compiled{}, err
+	}
+	right, err := compileExpression(op.Right)
+	if err != nil {
+		// nowrap: recursive call
+		return compiled{}, err
+	}
+	return compileOperation(op.BinaryOpCode, left, right)
+}
+
+// compileOperation compiles a binary operation between two compiled expressions.
+func compileOperation(opCode BinaryOpCode, left, right compiled) (compiled, error) {
+	var err error
+	c := &checker{}
+	returnType, upscaledType, _ := c.binaryOperationType("", opCode, left.returnType, true, right.returnType, true)
+	if err := c.err(); err != nil {
+		return compiled{}, wrap(err, "invalid expression")
+	}
+	if _, isLogical := logicalOps[opCode]; isLogical {
+		return compileLogicalOperation(opCode, left, right)
+	}
+	if left, err = convert(left, upscaledType); err != nil {
+		return compiled{}, wrap(err, "invalid expression")
+	}
+	if right, err = convert(right, upscaledType); err != nil {
+		return compiled{}, wrap(err, "invalid expression")
+	}
+	if _, isComparison := booleanOps[opCode]; isComparison {
+		fn, err := compileComparison(opCode, left, right)
+		if err != nil {
+			return compiled{}, wrap(err, "invalid expression")
+		}
+		return compiled{returnType: returnType, boolFn: fn}, nil
+	}
+	result := compiled{returnType: returnType}
+	switch {
+	case isUnsignedType(upscaledType):
+		result.uintFn, err = compileUintOperation(opCode, typeBits(upscaledType), left.uintFn, right.uintFn)
+	case isSignedType(upscaledType):
+		result.intFn, err = compileIntOperation(opCode, typeBits(upscaledType), left.intFn, right.intFn)
+	case isFloatType(upscaledType):
+		result.floatFn, err = compileFloatOperation(opCode, upscaledType == ReturnType_RETURN_TYPE_F32, left.floatFn, right.floatFn)
+	default:
+		err = unhandledEnum("binary op value type", upscaledType)
+	}
+	if err != nil {
+		return compiled{}, wrap(err, "invalid expression")
+	}
+	return result, nil
+}
+
+// compileLogicalOperation compiles AND or OR between two boolean expressions.
+// The right-hand side is only evaluated when the left-hand side does not decide the result.
+func compileLogicalOperation(opCode BinaryOpCode, left, right compiled) (compiled, error) {
+	leftFn, rightFn := left.boolFn, right.boolFn
+	var fn boolFunc
+	switch opCode {
+	case BinaryOpCode_BINARY_OP_CODE_AND:
+		fn = func(key, value []byte) (bool, error) {
+			result, err := leftFn(key, value)
+			if err != nil {
+				return false, wrap(err, "unable to evaluate left hand expression")
+			}
+			if !result {
+				return false, nil
+			}
+			result, err = rightFn(key, value)
+			if err != nil {
+				return false, wrap(err, "unable to evaluate right hand expression")
+			}
+			return result, nil
+		}
+	case BinaryOpCode_BINARY_OP_CODE_OR:
+		fn = func(key, value []byte) (bool, error) {
+			result, err := leftFn(key, value)
+			if err != nil {
+				return false, wrap(err, "unable to evaluate left hand expression")
+			}
+			if result {
+				return true, nil
+			}
+			result, err = rightFn(key, value)
+			if err != nil {
+				return false, wrap(err, "unable to evaluate right hand expression")
+			}
+			return result, nil
+		}
+	default:
+		return compiled{}, unhandledEnum("logical op code", opCode)
+	}
+	return compiled{returnType: ReturnType_RETURN_TYPE_BOOL, boolFn: fn}, nil
+}
+
+func compileUnaryOperation(op *UnaryOperation) (compiled, error) {
+	operand, err := compileExpression(op.Operand)
+	if err != nil {
+		// nowrap: recursive call
+		return compiled{}, err
+	}
+	c := &checker{}
+	returnType, _ := c.unaryOperationType("", op.UnaryOpCode, operand.returnType, true)
+	if err := c.err(); err != nil {
+		return compiled{}, wrap(err, "invalid expression")
+	}
+	fn := operand.boolFn
+	return compiled{returnType: returnType, boolFn: func(key, value []byte) (bool, error) {
+		result, err := fn(key, value)
+		if err != nil {
+			return false, wrap(err, "unable to evaluate operand")
+		}
+		return !result, nil
+	}}, nil
+}
+
+// flipComparison returns the comparison with its operands swapped, such that a op b == b flipped a.
+func flipComparison(op BinaryOpCode) BinaryOpCode {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return BinaryOpCode_BINARY_OP_CODE_GREATER
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return BinaryOpCode_BINARY_OP_CODE_GREATER_EQ
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return BinaryOpCode_BINARY_OP_CODE_LESS
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return BinaryOpCode_BINARY_OP_CODE_LESS_EQ
+	default:
+		return op
+	}
+}
+
+// compileComparison compiles a comparison between two expressions of the same type.
+// Comparisons of a number against a constant, the most common shape of a filter,
+// compare against the constant without calling a closure to produce it.
+func compileComparison(op BinaryOpCode, left, right compiled) (boolFunc, error) {
+	if left.constant && !right.constant {
+		op = flipComparison(op)
+		left, right = right, left
+	}
+	switch {
+	case left.returnType == ReturnType_RETURN_TYPE_BOOL:
+		return compileBoolComparison(op, left.boolFn, right.boolFn)
+	case isUnsignedType(left.returnType) && right.constant:
+		return compileUintConstComparison(op, left.uintFn, right.constUint)
+	case isUnsignedType(left.returnType):
+		return compileUintComparison(op, left.uintFn, right.uintFn)
+	case isSignedType(left.returnType) && right.constant:
+		return compileIntConstComparison(op, left.intFn, right.constInt)
+	case isSignedType(left.returnType):
+		return compileIntComparison(op, left.intFn, right.intFn)
+	case isFloatType(left.returnType) && right.constant:
+		return compileFloatConstComparison(op, left.floatFn, right.constFloat)
+	case isFloatType(left.returnType):
+		return compileFloatComparison(op, left.floatFn, right.floatFn)
+	case left.returnType == ReturnType_RETURN_TYPE_BYTES:
+		return compileBytesComparison(op, left.bytesFn, right.bytesFn)
+	default:
+		return nil, unhandledEnum("comparison value type", left.returnType)
+	}
+}
+
+func compileBoolComparison(op BinaryOpCode, left, right boolFunc) (boolFunc, error) {
+	var compare func(a, b int) bool
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		compare = func(a, b int) bool { return a == b }
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		compare = func(a, b int) bool { return a != b }
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		compare = func(a, b int) bool { return a < b }
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		compare = func(a, b int) bool { return a <= b }
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		compare = func(a, b int) bool { return a > b }
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		compare = func(a, b int) bool { return a >= b }
+	default:
+		return nil, unhandledEnum("bool op code", op)
+	}
+	return func(key, value []byte) (bool, error) {
+		a, err := left(key, value)
+		if err != nil {
+			return false, wrap(err, "unable to evaluate left hand expression")
+		}
+		b, err := right(key, value)
+		if err != nil {
+			return false, wrap(err, "unable to evaluate right hand expression")
+		}
+		return compare(boolInt(a), boolInt(b)), nil
+	}, nil
+}
+
+func evaluateUints(left, right uintFunc, key, value []byte) (uint64, uint64, error) {
+	a, err := left(key, value)
+	if err != nil {
+		return 0, 0, wrap(err, "unable to evaluate left hand expression")
+	}
+	b, err := right(key, value)
+	if err != nil {
+		return 0, 0, wrap(err, "unable to evaluate right hand expression")
+	}
+	return a, b, nil
+}
+
+func compileUintComparison(op BinaryOpCode, left, right uintFunc) (boolFunc, error) {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return err == nil && a == b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return err == nil && a != b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return err == nil && a < b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return err == nil && a <= b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return err == nil && a > b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return err == nil && a >= b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("uint op code", op)
+	}
+}
+
+func compileUintConstComparison(op BinaryOpCode, left uintFunc, b uint64) (boolFunc, error) {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a == b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a != b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a < b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a <= b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a > b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a >= b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("uint op code", op)
+	}
+}
+
+// compileUintOperation compiles arithmetic or bitwise operations on unsigned integers of a width.
+func compileUintOperation(op BinaryOpCode, bits uint, left, right uintFunc) (uintFunc, error) {
+	mask := uint64(math.MaxUint64) >> (64 - bits)
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return (a + b) & mask, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return (a - b) & mask, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return (a * b) & mask, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			if err != nil {
+				return 0, err
+			}
+			if b == 0 {
+				return 0, ErrDivideByZero
+			}
+			return a / b, nil
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			if err != nil {
+				return 0, err
+			}
+			if b == 0 {
+				return 0, ErrDivideByZero
+			}
+			return a % b, nil
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_AND:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return a & b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_OR:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return a | b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_XOR:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return a ^ b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHL:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return (a << b) & mask, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHR:
+		return func(key, value []byte) (uint64, error) {
+			a, b, err := evaluateUints(left, right, key, value)
+			return a >> b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("uint op code", op)
+	}
+}
+
+func evaluateInts(left, right intFunc, key, value []byte) (int64, int64, error) {
+	a, err := left(key, value)
+	if err != nil {
+		return 0, 0, wrap(err, "unable to evaluate left hand expression")
+	}
+	b, err := right(key, value)
+	if err != nil {
+		return 0, 0, wrap(err, "unable to evaluate right hand expression")
+	}
+	return a, b, nil
+}
+
+func compileIntComparison(op BinaryOpCode, left, right intFunc) (boolFunc, error) {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return err == nil && a == b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return err == nil && a != b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return err == nil && a < b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return err == nil && a <= b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return err == nil && a > b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return err == nil && a >= b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("int op code", op)
+	}
+}
+
+func compileIntConstComparison(op BinaryOpCode, left intFunc, b int64) (boolFunc, error) {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a == b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a != b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a < b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a <= b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a > b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a >= b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("int op code", op)
+	}
+}
+
+// compileIntOperation compiles arithmetic or bitwise operations on signed integers of a width.
+// Results are wrapped to the width by sign extending its lowest bits.
+func compileIntOperation(op BinaryOpCode, bits uint, left, right intFunc) (intFunc, error) {
+	shift := 64 - bits
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return (a + b) << shift >> shift, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return (a - b) << shift >> shift, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return (a * b) << shift >> shift, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			if err != nil {
+				return 0, err
+			}
+			if b == 0 {
+				return 0, ErrDivideByZero
+			}
+			return (a / b) << shift >> shift, nil
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			if err != nil {
+				return 0, err
+			}
+			if b == 0 {
+				return 0, ErrDivideByZero
+			}
+			return a % b, nil
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_AND:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return a & b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_OR:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return a | b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_XOR:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			return a ^ b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHL:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			if err != nil {
+				return 0, err
+			}
+			if b < 0 {
+				return 0, ErrNegativeShift
+			}
+			return (a << uint64(b)) << shift >> shift, nil
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHR:
+		return func(key, value []byte) (int64, error) {
+			a, b, err := evaluateInts(left, right, key, value)
+			if err != nil {
+				return 0, err
+			}
+			if b < 0 {
+				return 0, ErrNegativeShift
+			}
+			return a >> uint64(b), nil
+		}, nil
+	default:
+		return nil, unhandledEnum("int op code", op)
+	}
+}
+
+func evaluateFloats(left, right floatFunc, key, value []byte) (float64, float64, error) {
+	a, err := left(key, value)
+	if err != nil {
+		return 0, 0, wrap(err, "unable to evaluate left hand expression")
+	}
+	b, err := right(key, value)
+	if err != nil {
+		return 0, 0, wrap(err, "unable to evaluate right hand expression")
+	}
+	return a, b, nil
+}
+
+// compileFloatComparison compiles comparisons of floats.
+// Go comparisons of NaN agree with performOpUnordered: only NEQ is true.
+func compileFloatComparison(op BinaryOpCode, left, right floatFunc) (boolFunc, error) {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return err == nil && a == b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return err == nil && a != b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return err == nil && a < b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return err == nil && a <= b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return err == nil && a > b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return err == nil && a >= b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("float op code", op)
+	}
+}
+
+func compileFloatConstComparison(op BinaryOpCode, left floatFunc, b float64) (boolFunc, error) {
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a == b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a != b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a < b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a <= b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a > b, err
+		}, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return func(key, value []byte) (bool, error) {
+			a, err := left(key, value)
+			return err == nil && a >= b, err
+		}, nil
+	default:
+		return nil, unhandledEnum("float op code", op)
+	}
+}
+
+// compileFloatOperation compiles arithmetic on floats. F32 results are rounded to a float32, which
+// gives the same result as float32 arithmetic since a float64 holds the exact result of adding,
+// subtracting, multiplying or dividing two float32 values to more than twice float32 precision.
+func compileFloatOperation(op BinaryOpCode, isF32 bool, left, right floatFunc) (floatFunc, error) {
+	var fn floatFunc
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		fn = func(key, value []byte) (float64, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return a + b, err
+		}
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		fn = func(key, value []byte) (float64, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return a - b, err
+		}
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		fn = func(key, value []byte) (float64, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return a * b, err
+		}
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		fn = func(key, value []byte) (float64, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return a / b, err
+		}
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		fn = func(key, value []byte) (float64, error) {
+			a, b, err := evaluateFloats(left, right, key, value)
+			return math.Mod(a, b), err
+		}
+	default:
+		return nil, unhandledEnum("float op code", op)
+	}
+	if !isF32 {
+		return fn, nil
+	}
+	return func(key, value []byte) (float64, error) {
+		result, err := fn(key, value)
+		return float64(float32(result)), err
+	}, nil
+}
+
+func compileBytesComparison(op BinaryOpCode, left, right bytesFunc) (boolFunc, error) {
+	var compare func(a, b []byte) bool
+	switch op {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		compare = bytes.Equal
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		compare = func(a, b []byte) bool { return !bytes.Equal(a, b) }
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		compare = func(a, b []byte) bool { return bytes.Compare(a, b) < 0 }
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		compare = func(a, b []byte) bool { return bytes.Compare(a, b) <= 0 }
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		compare = func(a, b []byte) bool { return bytes.Compare(a, b) > 0 }
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		compare = func(a, b []byte) bool { return bytes.Compare(a, b) >= 0 }
+	default:
+		return nil, unhandledEnum("bytes op code", op)
+	}
+	return func(key, value []byte) (bool, error) {
+		a, err := left(key, value)
+		if err != nil {
+			return false, wrap(err, "unable to evaluate left hand expression")
+		}
+		b, err := right(key, value)
+		if err != nil {
+			return false, wrap(err, "unable to evaluate right hand expression")
+		}
+		return compare(a, b), nil
+	}, nil
+}
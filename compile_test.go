@@ -0,0 +1,240 @@
+package binq
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// compiledShapes are common shapes of predicates, which match their record.
+var compiledShapes = []struct {
+	name  string
+	input string
+	key   []interface{}
+	value []interface{}
+}{
+	{
+		"u64le-at-offset-8-ge-constant",
+		"VALUE(8, U64LE) >= U64(1000)",
+		nil,
+		[]interface{}{u64le(1), u64le(1000)},
+	},
+	{
+		"all-comparisons",
+		"VALUE(0, U32LE) < U32(10) AND VALUE(4, I64LE) != I64(-1) AND VALUE(12, F64LE) > F64(0.5)",
+		nil,
+		[]interface{}{u32le(5), i64le(-2), f64le(0.75)},
+	},
+	{
+		"any-comparisons",
+		"VALUE(0, U8) = U32(1) OR VALUE(0, U8) = U32(2) OR VALUE(0, U8) = U32(3)",
+		nil,
+		[]interface{}{u8(3)},
+	},
+	{
+		"bytes-equality",
+		`VALUE(JUMP(0, U8), BYTES(U16LE)) = "hello"`,
+		nil,
+		[]interface{}{u8(1), u16le(5), "hello"},
+	},
+	{
+		"arithmetic",
+		"(VALUE(0, U64LE) + VALUE(8, U32LE)) % U64(7) = U64(3) AND VALUE(12, I16BE) * I32(2) < I32(0)",
+		nil,
+		[]interface{}{u64le(8), u32le(2), i16be(-3)},
+	},
+	{
+		"key-and-value",
+		"KEY(0, U32BE) = U32(7) AND NOT VALUE(0, BYTES(3)) < KEY(4, BYTES(3))",
+		[]interface{}{u32be(7), "abc"},
+		[]interface{}{"abd"},
+	},
+}
+
+func compileShape(t TestType, input string) RecordMatcher {
+	pred, err := NewParser(input).ReadPredicate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matcher, err := PredicateToRecordMatcher(pred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matcher
+}
+
+// TestPredicateToRecordMatcher_ZeroAllocs is not parallel, since testing.AllocsPerRun cannot be used by parallel tests.
+func TestPredicateToRecordMatcher_ZeroAllocs(t *testing.T) {
+	for _, shape := range compiledShapes {
+		t.Run(shape.name, func(t *testing.T) {
+			matcher := compileShape(t, shape.input)
+			key, value := makeBytes(t, shape.key...), makeBytes(t, shape.value...)
+			matched, err := matcher.MatchRecord(key, value)
+			assert.NoError(t, err)
+			assert.True(t, matched)
+			allocs := testing.AllocsPerRun(100, func() {
+				_, _ = matcher.MatchRecord(key, value)
+			})
+			assert.Zero(t, allocs)
+		})
+	}
+}
+
+func BenchmarkPredicateToRecordMatcher(b *testing.B) {
+	for _, shape := range compiledShapes {
+		b.Run(shape.name, func(b *testing.B) {
+			matcher := compileShape(b, shape.input)
+			key, value := makeBytes(b, shape.key...), makeBytes(b, shape.value...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := matcher.MatchRecord(key, value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestCompileExpression_Check asserts that random expressions of every type compile exactly
+// when they are well typed, to the type of the checker. Their results are compared with those
+// of programs by TestProgram_RecordMatcher.
+func TestCompileExpression_Check(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(3))
+	valid := 0
+	for i := 0; i < 20000; i++ {
+		ex := randomTypedExpression(r, 3)
+		checker := &checker{}
+		checkedType, _ := checker.checkExpression("", ex)
+		c, err := compileExpression(ex)
+		if !assert.Equal(t, checker.err() == nil, err == nil, "%v: %v", ex, err) || err != nil {
+			continue
+		}
+		valid++
+		assert.Equal(t, checkedType, c.returnType)
+		for j := 0; j < 8; j++ {
+			key, value := randomRecordBytes(r), randomRecordBytes(r)
+			if _, err := evaluateCompiled(c, key, value); err != nil {
+				assert.Contains(t, []error{ErrBytesTooSmall, ErrJumpOffsetOutOfRange, ErrDivideByZero, ErrNegativeShift}, errors.Cause(err), "%v", ex)
+			}
+		}
+	}
+	assert.True(t, valid > 1000, "only %d valid expressions", valid)
+}
+
+// evaluateCompiled evaluates a compiled expression and converts its result to the Go type of its ReturnType.
+func evaluateCompiled(c compiled, key, value []byte) (interface{}, error) {
+	switch {
+	case c.returnType == ReturnType_RETURN_TYPE_BOOL:
+		return c.boolFn(key, value)
+	case c.returnType == ReturnType_RETURN_TYPE_BYTES:
+		return c.bytesFn(key, value)
+	case isUnsignedType(c.returnType):
+		result, err := c.uintFn(key, value)
+		switch c.returnType {
+		case ReturnType_RETURN_TYPE_U8:
+			return uint8(result), err
+		case ReturnType_RETURN_TYPE_U16:
+			return uint16(result), err
+		case ReturnType_RETURN_TYPE_U32:
+			return uint32(result), err
+		}
+		return result, err
+	case isSignedType(c.returnType):
+		result, err := c.intFn(key, value)
+		switch c.returnType {
+		case ReturnType_RETURN_TYPE_I8:
+			return int8(result), err
+		case ReturnType_RETURN_TYPE_I16:
+			return int16(result), err
+		case ReturnType_RETURN_TYPE_I32:
+			return int32(result), err
+		}
+		return result, err
+	case c.returnType == ReturnType_RETURN_TYPE_F32:
+		result, err := c.floatFn(key, value)
+		return float32(result), err
+	default:
+		return c.floatFn(key, value)
+	}
+}
+
+// sameValue compares values like assert.Equal, but considers NaN to be the same as NaN.
+func sameValue(a, b interface{}) bool {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok && math.IsNaN(a) && math.IsNaN(b) {
+			return true
+		}
+	case float32:
+		if b, ok := b.(float32); ok && math.IsNaN(float64(a)) && math.IsNaN(float64(b)) {
+			return true
+		}
+	}
+	return assert.ObjectsAreEqual(a, b)
+}
+
+// randomRecordBytes creates short data with many zero bytes, so that values are often small or zero.
+func randomRecordBytes(r *rand.Rand) []byte {
+	b := make([]byte, r.Intn(24))
+	for index := range b {
+		if r.Intn(2) == 0 {
+			b[index] = byte(r.Intn(256))
+		}
+	}
+	return b
+}
+
+// randomTypedExpression creates an expression of any type, which may not be well typed.
+func randomTypedExpression(r *rand.Rand, depth int) *Expression {
+	if depth > 0 && r.Intn(4) > 0 {
+		left := randomTypedExpression(r, depth-1)
+		if r.Intn(10) == 0 {
+			return makeUnaryOperationExpression(left)
+		}
+		opCode := BinaryOpCode(r.Intn(len(BinaryOpCode_name)-1) + 1)
+		return makeBinaryOperationExpression(left, opCode, randomTypedExpression(r, depth-1))
+	}
+	if r.Intn(2) == 0 {
+		return &Expression{Expression: &Expression_Scalar{Scalar: randomScalar(r)}}
+	}
+	v := &Value{
+		Jump:   &Jump{Jump: &Jump_Offset{Offset: uint64(r.Intn(8))}},
+		Type:   ValueType(r.Intn(len(ValueType_name)-1) + 1),
+		Target: Target(r.Intn(2)),
+	}
+	if v.Type == ValueType_VALUE_TYPE_BYTES {
+		v.Length = uint64(r.Intn(4))
+	}
+	return &Expression{Expression: &Expression_Value{Value: v}}
+}
+
+func randomScalar(r *rand.Rand) *Scalar {
+	integers := []int64{0, 1, 2, 3, 7, 63, 64, -1, -2, -64, math.MaxInt32, math.MinInt32, math.MaxInt64, math.MinInt64}
+	integer := integers[r.Intn(len(integers))]
+	floats := []float64{0, 0.5, -1.25, 3, 1e10, math.Inf(1), math.NaN()}
+	float := floats[r.Intn(len(floats))]
+	switch r.Intn(8) {
+	case 0:
+		return &Scalar{Value: &Scalar_Bool{Bool: r.Intn(2) == 0}}
+	case 1:
+		return &Scalar{Value: &Scalar_U32{U32: uint32(integer)}}
+	case 2:
+		return &Scalar{Value: &Scalar_U64{U64: uint64(integer)}}
+	case 3:
+		return &Scalar{Value: &Scalar_I32{I32: int32(integer)}}
+	case 4:
+		return &Scalar{Value: &Scalar_I64{I64: integer}}
+	case 5:
+		return &Scalar{Value: &Scalar_F32{F32: float32(float)}}
+	case 6:
+		return &Scalar{Value: &Scalar_F64{F64: float}}
+	default:
+		b := make([]byte, r.Intn(3))
+		r.Read(b)
+		return &Scalar{Value: &Scalar_Bytes{Bytes: b}}
+	}
+}
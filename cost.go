@@ -0,0 +1,48 @@
+package binq
+
+// Relative costs of evaluating parts of an expression, in the units of ExpressionCost.
+const (
+	costOperation = 1
+	costRead      = 2
+	// costJump is the cost of reading the address of a jump and following it.
+	costJump = 3
+	// costLengthPrefix is the cost of reading the length of bytes with a length prefix.
+	costLengthPrefix = 2
+	// costDivide is the additional cost of division and modulo.
+	costDivide = 4
+)
+
+// ExpressionCost estimates the relative cost of evaluating an expression from its shape.
+// Reading a value at an offset is cheap, reading one that is jumped to through an address in
+// the record costs more for every jump, and operations cost more with the cost of their operands.
+func ExpressionCost(ex *Expression) float64 {
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Value:
+		return valueCost(t.Value)
+	case *Expression_BinaryOperation:
+		cost := ExpressionCost(t.BinaryOperation.Left) + ExpressionCost(t.BinaryOperation.Right) + costOperation
+		switch t.BinaryOperation.BinaryOpCode {
+		case BinaryOpCode_BINARY_OP_CODE_DIV, BinaryOpCode_BINARY_OP_CODE_MOD:
+			cost += costDivide
+		}
+		return cost
+	case *Expression_UnaryOperation:
+		return ExpressionCost(t.UnaryOperation.Operand) + costOperation
+	default:
+		return 0
+	}
+}
+
+func valueCost(v *Value) float64 {
+	cost := float64(costRead)
+	if _, isOffset := v.GetJump().GetJump().(*Jump_Offset); !isOffset {
+		cost += costJump
+	}
+	switch v.Type {
+	case ValueType_VALUE_TYPE_BYTES_U8,
+		ValueType_VALUE_TYPE_BYTES_U16LE, ValueType_VALUE_TYPE_BYTES_U16BE,
+		ValueType_VALUE_TYPE_BYTES_U32LE, ValueType_VALUE_TYPE_BYTES_U32BE:
+		cost += costLengthPrefix
+	}
+	return cost
+}
@@ -0,0 +1,32 @@
+package binq
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExpressionCost(t *testing.T) {
+	t.Parallel()
+
+	// Each case is cheaper than the next.
+	cases := []string{
+		"true",
+		"VALUE(0, U8) = U32(1)",
+		"VALUE(0, U8) + U32(1) = U32(2)",
+		`VALUE(0, BYTES(U8)) = "a"`,
+		"VALUE(JUMP(0, U64LE), U8) = U32(1)",
+		"VALUE(JUMP(0, U64LE), U8) / U32(3) = U32(1)",
+		"VALUE(JUMP(0, U64LE), U8) / VALUE(JUMP(8, U32BE), U32BE) = U32(1)",
+	}
+	costs := make([]float64, len(cases))
+	for index, input := range cases {
+		pred, err := NewParser(input).ReadPredicate()
+		if !assert.NoError(t, err, input) {
+			return
+		}
+		costs[index] = ExpressionCost(pred.GetExpression())
+	}
+	for index := 1; index < len(costs); index++ {
+		assert.True(t, costs[index-1] < costs[index], "%s costs %v, %s costs %v", cases[index-1], costs[index-1], cases[index], costs[index])
+	}
+}
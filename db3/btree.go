@@ -1,14 +1,18 @@
 package db3
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"unsafe"
 )
 
 const (
-	// keySize is the size of a single key in memory.
-	keySize = unsafe.Sizeof(KeyType(0))
+	// keyLengthSize is the size of the length of a key in a cell.
+	keyLengthSize = 2
+	// KeySizeLimit is the largest maximum key size of a table, so that
+	// branch nodes always hold several keys.
+	KeySizeLimit = 512
 )
 
 // cellptr is the type used for indexing individual cells.
@@ -17,28 +21,49 @@ const (
 type cellptr = uint16
 
 // DataSizer is the interface that supplies the
-// size of data stored in leaf node cells.
+// size of keys and data stored in leaf node cells.
 type DataSizer interface {
+	// MaxKeySize returns the maximum length of keys stored in leaf node cells.
+	MaxKeySize() uint16
 	// DataSize returns the size of data stored in leaf node cells.
 	DataSize() uint16
 }
 
-// KeyType is the primary key type of records in the tree.
-type KeyType = uint32
+// KeyType is the primary key type of records in the tree: a byte string
+// of at most the maximum key size of a table, ordered like bytes.Compare.
+type KeyType = []byte
 
-const (
-	// zeroKey is the zero-value of keys.
-	zeroKey KeyType = 0
-)
+// keyLength returns the length of a key that starts a cell with the key length.
+func keyLength(b []byte) int {
+	return int(binary.LittleEndian.Uint16(b))
+}
 
-// keyFromBytes parses bytes as a KeyType.
-func keyFromBytes(b []byte) KeyType {
-	return KeyType(binary.LittleEndian.Uint32(b))
+// putKeyLength writes the length of a key to the start of a cell.
+func putKeyLength(b []byte, key KeyType) {
+	binary.LittleEndian.PutUint16(b, uint16(len(key)))
 }
 
-// encodeToBytes writes this KeyType into a byte slice.
-func encodeKeyToBytes(key KeyType, b []byte) {
-	binary.LittleEndian.PutUint32(b, uint32(key))
+// separatorKey returns a key s with left <= s < right, for left < right, that is shorter
+// than left when possible, so that branch nodes hold short keys: the common prefix of left
+// and right followed by the next byte of left incremented.
+func separatorKey(left, right KeyType) KeyType {
+	if makeAssertions {
+		_assert(bytes.Compare(left, right) < 0, "separator keys must be ordered")
+	}
+
+	prefix := 0
+	for prefix < len(left) && left[prefix] == right[prefix] {
+		prefix++
+	}
+	// Unless left is a prefix of right, right[prefix] > left[prefix], and the separator
+	// is less than right unless it is right itself.
+	if prefix+1 < len(left) && (left[prefix]+1 < right[prefix] || prefix+1 < len(right)) {
+		separator := make(KeyType, prefix+1)
+		copy(separator, left)
+		separator[prefix]++
+		return separator
+	}
+	return append(KeyType(nil), left...)
 }
 
 // nodeHeader is the header common to leaf and branch nodes.
@@ -2,15 +2,26 @@ package db3
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
-	"sort"
 	"unsafe"
 )
 
 const (
+	// branchNodeMaxCellData is the amount of data in a branchNode
+	// reserved for key-child pairs.
+	branchNodeMaxCellData = PageSize - unsafe.Sizeof(branchNodeHeader{})
+	// branchCellHeaderSize is the size of the child and key length of a branchNodeCell.
+	branchCellHeaderSize = pagePointerSize + keyLengthSize
+	// branchNodeCapacity is the amount of cell data of a branchNode reserved for slots and cells.
+	branchNodeCapacity = branchNodeMaxCellData - slottedHeaderSize
 	// branchNodeMaxCells is the maximum amount of branchNodeCells that
-	// can fit in a branchNode while also fitting in a single page.
-	branchNodeMaxCells = (PageSize - unsafe.Sizeof(branchNodeHeader{})) / unsafe.Sizeof(branchNodeCell{})
+	// can fit in a branchNode, which is when their keys are empty.
+	branchNodeMaxCells = branchNodeCapacity / (slotSize + branchCellHeaderSize)
+	// branchNodeMinUsedSpace is the amount of cell data below which a branchNode underflows
+	// unless it is the root. It is low enough that a branch that cannot borrow from a sibling
+	// holding keys of up to KeySizeLimit can always merge with it.
+	branchNodeMinUsedSpace = branchNodeCapacity / 6
 )
 
 // branchNodeHeader is the header for all branch nodes.
@@ -20,7 +31,7 @@ type branchNodeHeader struct {
 	// cells for branch nodes are key-child pairs.
 	numCells cellptr
 	// rightChild points to the next child of this node.
-	// branch nodes contain N=branchNodeMaxCells [child,key]
+	// branch nodes contain numCells [child,key]
 	// pairs and an additional child.
 	rightChild PagePointer
 }
@@ -33,18 +44,26 @@ func (n *branchNodeHeader) String() string {
 type branchNodeCell struct {
 	// child is the page pointed to by this cell.
 	child PagePointer
-	// key is the the key for this B+Tree cell.
+	// key is the the key for this B+Tree cell. It is at least the
+	// largest key in child, and less than every key in the children after it.
 	key KeyType
 }
 
 func (n *branchNodeCell) String() string {
-	return fmt.Sprintf("{key:%d,child:%d}", n.key, n.child)
+	return fmt.Sprintf("{key:%x,child:%d}", n.key, n.child)
+}
+
+// size returns the amount of cell data used by this cell in a branchNode, including its slot.
+func (n *branchNodeCell) size() uintptr {
+	return slotSize + branchCellHeaderSize + uintptr(len(n.key))
 }
 
 // branchNode is a Page that acts like an branch node in the B+Tree.
 type branchNode struct {
 	branchNodeHeader
-	cells [branchNodeMaxCells]branchNodeCell
+	// cellData holds the cells in a slotted layout, since their keys vary in length.
+	// The format of a cell is {PagePointer(child), uint16(key length), key}.
+	cellData [branchNodeMaxCellData]byte
 }
 
 var branchConvertWhitelist map[string]struct{}
@@ -78,8 +97,8 @@ func (n *branchNode) String() string {
 
 	buf := &bytes.Buffer{}
 	_, _ = fmt.Fprintf(buf, "{branchNodeHeader:%s,cells:[", n.branchNodeHeader.String())
-	for index := cellptr(0); index < n.numCells; index++ {
-		_, _ = fmt.Fprint(buf, n.cells[index].String())
+	for _, cell := range n.getCells() {
+		_, _ = fmt.Fprint(buf, cell.String())
 	}
 	buf.WriteString("]}")
 	return buf.String()
@@ -89,6 +108,67 @@ func (n *branchNode) init() {
 	n.isLeaf = false
 	n.isRoot = false
 	n.numCells = 0
+	n.getSlotted().setContentSize(0)
+}
+
+// getSlotted returns the cell data of this node.
+func (n *branchNode) getSlotted() slottedCells {
+	return n.cellData[:]
+}
+
+// getCellBin returns the bytes of the cell at the given index.
+func (n *branchNode) getCellBin(index cellptr) []byte {
+	if makeAssertions {
+		_assert(!n.isLeaf, "not a branch")
+		_assert(index < n.numCells, "tried to access cell %d >= numCells %d", index, n.numCells)
+	}
+
+	cell := n.cellData[n.getSlotted().getSlot(index):]
+	return cell[:branchCellHeaderSize+uintptr(keyLength(cell[pagePointerSize:]))]
+}
+
+// getKey returns the key of the cell at the given index.
+// The key shares memory with the cell.
+func (n *branchNode) getKey(index cellptr) KeyType {
+	bin := n.getCellBin(index)
+	return bin[branchCellHeaderSize:]
+}
+
+// getCells returns copies of the cells of this node.
+func (n *branchNode) getCells() []branchNodeCell {
+	if makeAssertions {
+		_assert(!n.isLeaf, "not a branch")
+	}
+
+	cells := make([]branchNodeCell, n.numCells)
+	for index := range cells {
+		bin := n.getCellBin(cellptr(index))
+		cells[index].child = binary.LittleEndian.Uint32(bin)
+		cells[index].key = append(KeyType{}, bin[branchCellHeaderSize:]...)
+	}
+	return cells
+}
+
+// setCells replaces the cells and right child of this node. The cells must fit.
+// Does not sync.
+func (n *branchNode) setCells(cells []branchNodeCell, rightChild PagePointer) {
+	if makeAssertions {
+		_assert(!n.isLeaf, "not a branch")
+		_assert(branchFits(cells), "branch node too small for %d cells", len(cells))
+	}
+
+	slotted := n.getSlotted()
+	slotted.setContentSize(0)
+	n.numCells = 0
+	for _, cell := range cells {
+		bin := make([]byte, branchCellHeaderSize+uintptr(len(cell.key)))
+		binary.LittleEndian.PutUint32(bin, cell.child)
+		putKeyLength(bin[pagePointerSize:], cell.key)
+		copy(bin[branchCellHeaderSize:], cell.key)
+		slotted.insert(n.numCells, n.numCells, bin)
+		n.numCells++
+	}
+	n.rightChild = rightChild
 }
 
 // getChildPage returns the page pointed to for a given child.
@@ -103,7 +183,7 @@ func (n *branchNode) getChildPage(childNum cellptr) PagePointer {
 	if n.numCells == childNum {
 		return n.rightChild
 	}
-	return n.cells[childNum].child
+	return binary.LittleEndian.Uint32(n.getCellBin(childNum))
 }
 
 // getMaxKey returns the highest key contained in this branch node's cells.
@@ -112,33 +192,88 @@ func (n *branchNode) getMaxKey() KeyType {
 		_assert(!n.isLeaf, "not a branch")
 	}
 
-	return n.cells[n.numCells-1].key
+	return n.getKey(n.numCells - 1)
 }
 
 // getMaxNumCells is the maximum number of cells that can be held in this node.
 func (n *branchNode) getMaxNumCells() cellptr {
-	const (
-		defaultBranchNodeMaxCells = cellptr(branchNodeMaxCells)
-	)
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
 	}
+
+	return branchMaxNumCells()
+}
+
+// branchMaxNumCells is the maximum number of cells that can be held in a branch node,
+// which may be limited in debug mode.
+func branchMaxNumCells() cellptr {
+	const (
+		defaultBranchNodeMaxCells = cellptr(branchNodeMaxCells)
+	)
 	if debug {
 		return _maxKeysPerBranchOverride()
 	}
 	return defaultBranchNodeMaxCells
 }
 
-// getSplitCounts gets the amount of cells to put in the old and new nodes after a split.
-func (n *branchNode) getSplitCounts() (oldSplitCount, newSplitCount cellptr) {
+// branchNumCellsLimited indicates if the number of cells of branch nodes is limited in debug mode,
+// in which case they are balanced by their number of cells rather than by the size of their cells.
+func branchNumCellsLimited() bool {
+	return branchMaxNumCells() < cellptr(branchNodeMaxCells)
+}
+
+// branchCellsSize returns the amount of cell data used by cells in a branch node.
+func branchCellsSize(cells []branchNodeCell) uintptr {
+	size := uintptr(0)
+	for index := range cells {
+		size += cells[index].size()
+	}
+	return size
+}
+
+// branchFits indicates if cells fit in a branch node.
+func branchFits(cells []branchNodeCell) bool {
+	return len(cells) <= int(branchMaxNumCells()) && branchCellsSize(cells) <= branchNodeCapacity
+}
+
+// branchUnderflows indicates if a branch node with the given cells uses less than its minimum
+// space unless it is the root. When the number of cells is limited, the branch node instead
+// underflows with less than half of the maximum number of cells.
+func branchUnderflows(cells []branchNodeCell) bool {
+	if branchNumCellsLimited() {
+		return len(cells) < int(branchMaxNumCells()/2)
+	}
+	return branchCellsSize(cells) < branchNodeMinUsedSpace
+}
+
+// isUnderflowing indicates if this node uses less than its minimum space unless it is the root.
+func (n *branchNode) isUnderflowing() bool {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
 	}
 
-	maxCells := n.getMaxNumCells()
-	oldSplitCount = (maxCells + 1) / 2
-	newSplitCount = maxCells - oldSplitCount
-	return oldSplitCount, newSplitCount
+	if branchNumCellsLimited() {
+		return n.numCells < n.getMaxNumCells()/2
+	}
+	return n.getSlotted().getUsedSpace(n.numCells) < branchNodeMinUsedSpace
+}
+
+// getBranchSplitCount gets the amount of cells to keep in the old node when splitting cells that
+// do not fit in a branch node: the cells before the one straddling the middle of the cell data,
+// which moves up to the parent.
+func getBranchSplitCount(cells []branchNodeCell) int {
+	if branchNumCellsLimited() {
+		return int(branchMaxNumCells()+1) / 2
+	}
+	total := branchCellsSize(cells)
+	used := uintptr(0)
+	for index := range cells {
+		used += cells[index].size()
+		if 2*used > total {
+			return index
+		}
+	}
+	return len(cells) - 1
 }
 
 // findBranchNodeChild returns the index of the child which should contain the given key.
@@ -152,8 +287,8 @@ func (n *branchNode) findKeyIndex(key KeyType) cellptr {
 	maxIndex := n.numCells // there is one more child than key
 	for minIndex != maxIndex {
 		index := (minIndex + maxIndex) / 2
-		keyToRight := n.cells[index].key
-		if keyToRight >= key {
+		keyToRight := n.getKey(index)
+		if bytes.Compare(keyToRight, key) >= 0 {
 			maxIndex = index
 		} else {
 			minIndex = index + 1
@@ -162,215 +297,111 @@ func (n *branchNode) findKeyIndex(key KeyType) cellptr {
 	return minIndex
 }
 
-// makeRoomForInsert slides cells to the right to make room for an insertion.
+// setChildPage sets the page pointed to for a given child.
+// For branchNode children, childIndex can be 1 greater than the max index
+// to indicate that the rightChild is the value at the index.
 // Does not sync.
-func (n *branchNode) makeRoomForInsert(pos cellptr) {
+func (n *branchNode) setChildPage(childNum cellptr, childPageNum PagePointer) {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
-		_assert(n.numCells < n.getMaxNumCells(), "branch node too big to add room")
+		_assert(childNum <= n.numCells, "tried to access childNum %d > numCells %d", childNum, n.numCells)
 	}
 
-	maxCells := n.getMaxNumCells()
-	tailCells := n.numCells - pos
-
-	srcStart := pos
-	srcEnd := srcStart + tailCells
-
-	dstStart := srcStart + 1
-	dstEnd := dstStart + tailCells
-	if dstEnd > maxCells {
-		dstEnd = maxCells
+	if n.numCells == childNum {
+		n.rightChild = childPageNum
+		return
 	}
-
-	copy(n.cells[dstStart:dstEnd], n.cells[srcStart:srcEnd])
+	binary.LittleEndian.PutUint32(n.getCellBin(childNum), childPageNum)
 }
 
-// insertDirect inserts a key-value into the cursor position
-// for a node that has space to insert the value directly.
-// Does not sync.
-func (n *branchNode) insertDirect(sizer DataSizer, pager *Pager, pos cellptr, key KeyType, child PagePointer) error {
+// findChildIndex returns the index of the given child page,
+// which is numCells for the rightChild.
+func (n *branchNode) findChildIndex(childPageNum PagePointer) cellptr {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
-		_assert(n.numCells < n.getMaxNumCells(), "branch node too big for direct insert")
 	}
 
-	//if pos == n.numCells {
-	rightChildMaxKey, err := n.getRightChildMaxKey(sizer, pager)
-	if err != nil {
-		return wrap(err, "unable to get max key in node")
-	}
-	if key > rightChildMaxKey {
-		// Replace the right child.
-		rightChildPageNum := n.rightChild
-		originalNumCells := n.numCells
-		n.cells[originalNumCells].child = rightChildPageNum
-		n.cells[originalNumCells].key = rightChildMaxKey
-		n.rightChild = child
-		n.numCells++
-		return nil
+	for index := cellptr(0); index < n.numCells; index++ {
+		if n.getChildPage(index) == childPageNum {
+			return index
+		}
 	}
-	//}
-	n.makeRoomForInsert(pos)
-	n.cells[pos].key = key
-	n.cells[pos].child = child
-	n.numCells++
-	return nil
+	if makeAssertions {
+		_assert(n.rightChild == childPageNum, "page %d is not a child", childPageNum)
+	}
+	return n.numCells
 }
 
-func (n *branchNode) getRightChildMaxKey(sizer DataSizer, pager *Pager) (KeyType, error) {
+// removeCell removes the key between the children at index and index+1
+// after they were merged into mergedPageNum, which takes the place of both.
+// Does not sync.
+func (n *branchNode) removeCell(index cellptr, mergedPageNum PagePointer) {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
+		_assert(index < n.numCells, "tried to remove cell %d >= numCells %d", index, n.numCells)
 	}
 
-	rightPage, err := pager.GetPage(n.rightChild)
-	if err != nil {
-		return 0, wrap(err, "unable to get page")
-	}
-	rightNode := pageToNodeHeader(rightPage)
-	max := rightNode.getMaxKey(sizer)
-	return max, nil
+	cells := n.getCells()
+	n.setCells(append(cells[:index], cells[index+1:]...), n.rightChild)
+	n.setChildPage(index, mergedPageNum)
 }
 
-func (n *branchNode) updateMaximum(table *Table, pager *Pager, pageNum PagePointer, oldMax, newMax KeyType) error {
+// setKey replaces the key between the children at index and index+1.
+// Splits this branch, and parents recursively, if the key does not fit.
+func (n *branchNode) setKey(table *Table, pageNum PagePointer, index cellptr, key KeyType) error {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
-		//_assert(oldMax > newMax, "key not decreased after a split")
-	}
-
-	fmt.Println("before Tree")
-	table.printTree()
-	children := collectChildPages(n)
-	fmt.Println("before", children)
-	keys := collectKeys(table, pager, n)
-	fmt.Println("before", keys)
-
-	// After a split, the key stored for the left leaf needs to be updated.
-	pageIndex := n.findKeyIndex(oldMax)
-	if pageIndex < n.numCells {
-		// If the old key does not belong to the right child, we update
-		// that key to point to the new, lower value key.
-		n.cells[pageIndex].key = newMax
-		if err := pager.sync1(pageNum); err != nil {
-			return wrap(err, "unable to sync page")
-		}
+		_assert(index < n.numCells, "tried to access cell %d >= numCells %d", index, n.numCells)
 	}
 
-	fmt.Println("after Tree")
-	table.printTree()
-	children = collectChildPages(n)
-	fmt.Println("after", children)
-	keys = collectKeys(table, pager, n)
-	fmt.Println("after", keys)
-
-	if pageIndex == n.numCells {
-		// We may need to update the parent.
-		if n.isRoot {
-			// We only update the parent if there is one.
-			return nil
-		}
-		// Propagate the update to the parent.
-		parentPageNum := n.parentPointer
-		parentPage, err := pager.GetPage(parentPageNum)
-		if err != nil {
-			return wrap(err, "unable to get page")
-		}
-		parentBranch := pageToBranchNode(parentPage)
-		fmt.Println("update parent")
-		if err := parentBranch.updateMaximum(table, pager, parentPageNum, oldMax, newMax); err != nil {
-			// nowrap: recursive call
-			return err
-		}
-	}
-	return nil
+	cells := n.getCells()
+	cells[index].key = key
+	// Recursive call, do not wrap error.
+	return n.store(table, pageNum, cells, n.rightChild)
 }
 
-// insertAfterSplit inserts a new child into a branch node after a split, updating previous max keys.
-func (n *branchNode) insertAfterSplit(table *Table, sizer DataSizer, pager *Pager, pageNum PagePointer, oldMax, newMax, childPageNum PagePointer) error {
+// insertChild inserts a new child into a branch node after the child at leftPageNum was split,
+// keeping the keys up to separator and moving the keys after it to the child at rightPageNum.
+// Splits parents recursively if necessary.
+func (n *branchNode) insertChild(table *Table, pageNum, leftPageNum PagePointer, separator KeyType, rightPageNum PagePointer) error {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
-		_assert(oldMax > newMax, "key not decreased after a split")
 	}
 
-	if err := n.updateMaximum(table, pager, pageNum, oldMax, newMax); err != nil {
-		return wrap(err, "unable to update maximum")
-	}
-	if err := n.insert(table, sizer, pager, pageNum, childPageNum); err != nil {
-		// nowrap: indirectly recursive call
-		return err
+	index := n.findChildIndex(leftPageNum)
+	cells := n.getCells()
+	cells = append(cells, branchNodeCell{})
+	copy(cells[index+1:], cells[index:])
+	cells[index] = branchNodeCell{key: separator, child: leftPageNum}
+	rightChild := n.rightChild
+	if int(index)+1 == len(cells) {
+		rightChild = rightPageNum
+	} else {
+		cells[index+1].child = rightPageNum
 	}
-	return nil
+	// Recursive call, do not wrap error.
+	return n.store(table, pageNum, cells, rightChild)
 }
 
-// insert inserts a new child into a branch node and splits parents recursively if necessary.
-func (n *branchNode) insert(table *Table, sizer DataSizer, pager *Pager, pageNum PagePointer, childPageNum PagePointer) error {
+// store replaces the cells and right child of a branch node.
+// If they do not fit, the branch is split, and parents are split recursively if necessary.
+func (n *branchNode) store(table *Table, pageNum PagePointer, cells []branchNodeCell, rightChild PagePointer) error {
 	if makeAssertions {
 		_assert(!n.isLeaf, "not a branch")
 	}
 
-	childPage, err := pager.GetPage(childPageNum)
-	if err != nil {
-		return wrap(err, "unable to get page")
-	}
-	childNode := pageToNodeHeader(childPage)
-	childMaxKey := childNode.getMaxKey(sizer)
-
-	leftBranchPageNum := pageNum
-	leftBranch := n
-	branchMaxCells := leftBranch.getMaxNumCells()
-
-	// If this branch has room for a new key, simply add the new key.
-	if leftBranch.numCells < branchMaxCells {
-		originalNumCells := n.numCells
-		rightChildPageNum := n.rightChild
-		rightChildMaxKey, err := n.getRightChildMaxKey(sizer, pager)
-		if err != nil {
-			return wrap(err, "unable to get max key in node")
-		}
-		if childMaxKey > rightChildMaxKey {
-			// Replace the right child.
-			leftBranch.cells[originalNumCells].child = rightChildPageNum
-			leftBranch.cells[originalNumCells].key = rightChildMaxKey
-			leftBranch.rightChild = childPageNum
-			leftBranch.numCells++
-
-			parentPageNum := leftBranch.parentPointer
-			parentPage, err := pager.GetPage(parentPageNum)
-			if err != nil {
-				return wrap(err, "unable to get page")
-			}
-			parentBranch := pageToBranchNode(parentPage)
-			//leftBranchNewMaxKey, err := leftBranch.getRightChildMaxKey(sizer, pager)
-			//if err != nil {
-			//	return wrap(err, "unable to get child maximum")
-			//}
-			if childMaxKey == 36 {
-				sink()
-			}
-			if err := parentBranch.updateMaximum(table, pager, parentPageNum, rightChildMaxKey, childMaxKey); err != nil {
-				// nowrap: indirectly recursive call
-				return err
-			}
-			fmt.Println("After right child update max")
-			table.printTree()
+	pager := table.pager
 
-			if err := pager.sync1(pageNum); err != nil {
-				return wrap(err, "unable to sync page")
-			}
-			return nil
-		} else {
-			// Insert the key directly into our cells.
-			index := leftBranch.findKeyIndex(childMaxKey)
-			if err := leftBranch.insertDirect(sizer, pager, index, childMaxKey, childPageNum); err != nil {
-				return wrap(err, "unable to insert key")
-			}
-			if err := pager.sync1(pageNum); err != nil {
-				return wrap(err, "unable to sync page")
-			}
-			return nil
+	// If the cells fit, simply replace them.
+	if branchFits(cells) {
+		n.setCells(cells, rightChild)
+		if err := pager.sync1(pageNum); err != nil {
+			return wrap(err, "unable to sync page")
 		}
+		return nil
 	}
 
-	/*We have to split the branch. */
+	/* We have to split the branch. */
 
 	// Create a new branch to split into.
 	rightBranchPageNum, err := pager.GetUnusedPageNum()
@@ -383,52 +414,17 @@ func (n *branchNode) insert(table *Table, sizer DataSizer, pager *Pager, pageNum
 	}
 	rightBranch := pageToBranchNode(rightBranchPage)
 	rightBranch.init()
-	rightBranch.parentPointer = leftBranch.parentPointer
+	rightBranch.parentPointer = n.parentPointer
 
-	leftBranchOldMaxKey, err := leftBranch.getRightChildMaxKey(sizer, pager)
-	if err != nil {
-		return wrap(err, "unable to get child maximum")
-	}
-
-	// TODO: remove debug code
-	startKeys := collectKeys(sizer, pager, leftBranch)
-	startChildren := collectChildPages(leftBranch)
-	sink(startKeys, startChildren)
-
-	// Create our complete list of cells.
-	newCells := make([]branchNodeCell, leftBranch.numCells+2)
-	copy(newCells, leftBranch.cells[:leftBranch.numCells])
-	// Insert the right child.
-	newCells[len(newCells)-2].key = leftBranchOldMaxKey
-	newCells[len(newCells)-2].child = leftBranch.rightChild
-	// Insert the new cell.
-	idx := sort.Search(len(newCells)-1, func(i int) bool {
-		return leftBranch.cells[i].key >= childMaxKey
-	})
-	copy(newCells[idx+1:], newCells[idx:])
-	newCells[idx].key = childMaxKey
-	newCells[idx].child = childPageNum
-
-	leftBranchSplitSize, rightBranchSplitSize := leftBranch.getSplitCounts()
-
-	copy(leftBranch.cells[:], newCells[:leftBranchSplitSize])
-	leftBranch.rightChild = newCells[leftBranchSplitSize].child
-	leftBranch.numCells = leftBranchSplitSize
-
-	copy(rightBranch.cells[:], newCells[leftBranchSplitSize+1:])
-	rightBranch.rightChild = newCells[len(newCells)-1].child
-	rightBranch.numCells = rightBranchSplitSize
-
-	// TODO: remove debug code
-	leftPages := collectChildPages(leftBranch)
-	rightPages := collectChildPages(rightBranch)
-	sink(leftPages, rightPages)
-	endKeys := collectKeys(sizer, pager, leftBranch, rightBranch)
-	endChildren := collectChildPages(leftBranch, rightBranch)
-	sink(endKeys, endChildren)
-	if !samePages(childPageNum, endChildren, startChildren) || !sameKeys(childMaxKey, endKeys, startKeys) {
-		fmt.Println("unequal stuff after split.")
-	}
+	// The cell between the halves becomes the right child of the left branch,
+	// and its key moves up to the parent.
+	leftBranchSplitSize := getBranchSplitCount(cells)
+	middle := cells[leftBranchSplitSize]
+
+	leftBranchPageNum := pageNum
+	leftBranch := n
+	leftBranch.setCells(cells[:leftBranchSplitSize], middle.child)
+	rightBranch.setCells(cells[leftBranchSplitSize+1:], rightChild)
 
 	// Sync our changes.
 	if err := pager.sync2(leftBranchPageNum, rightBranchPageNum); err != nil {
@@ -446,179 +442,336 @@ func (n *branchNode) insert(table *Table, sizer DataSizer, pager *Pager, pageNum
 	// In the simple case, we're already at the root. We just need to parent
 	// the left and right node to a new root.
 	if leftBranch.isRoot {
-		// If the old branch is a root node, we need a new root.
-		// We want to preserve the table's root node position.
-		// Lets keep the root at the same page as the branch, so
-		// we copy our left branch onto a new page.
-
-		// We'll need our leftBranch as a page for conversion.
-		leftBranchPage := (*Page)(unsafe.Pointer(leftBranch))
-
-		// Create the new left branch to copy into.
-		newLeftBranchPageNum, err := pager.GetUnusedPageNum()
+		// Create the new root, which moves the root of the table.
+		rootPageNum, err := pager.GetUnusedPageNum()
 		if err != nil {
 			return wrap(err, "unable to get free page")
 		}
-		newLeftBranchPage, err := pager.GetPage(newLeftBranchPageNum)
+		rootPage, err := pager.GetPage(rootPageNum)
 		if err != nil {
 			return wrap(err, "unable to get page")
 		}
-		newLeftBranch := pageToBranchNode(newLeftBranchPage)
-
-		// Copy the branch to the new branch.
-		copy(newLeftBranchPage[:], leftBranchPage[:])
-		newLeftBranch.isRoot = false
-		newLeftBranch.parentPointer = leftBranchPageNum
-
-		newLeftBranchMaxKey, err := newLeftBranch.getRightChildMaxKey(sizer, pager)
-		if err != nil {
-			return wrap(err, "unable to get max key")
-		}
-
-		// Convert the leftBranch to a root.
-		root := pageToBranchNode(leftBranchPage)
+		root := pageToBranchNode(rootPage)
 		root.init()
 		root.isRoot = true
-		root.numCells = 1
-		root.cells[0].key = newLeftBranchMaxKey
-		root.cells[0].child = newLeftBranchPageNum
-		root.rightChild = rightBranchPageNum
+		root.setCells([]branchNodeCell{{key: middle.key, child: leftBranchPageNum}}, rightBranchPageNum)
+		leftBranch.isRoot = false
+		leftBranch.parentPointer = rootPageNum
+		rightBranch.parentPointer = rootPageNum
 		// At this point we have the following configuration:
-		//          branch 0: [child 1, key max(1), child 2]
-		//                        /                   \
-		// branch 1: [0-50% key-children]      branch 2: [51-100% key-children]
+		//              branch: [left, middle key, right]
+		//                       /                  \
+		// left branch: [0-50% key-children]  right branch: [51-100% key-children]
 
 		// Sync the changes.
-		rootPageNum := leftBranchPageNum
-		if err := pager.sync2(rootPageNum, newLeftBranchPageNum); err != nil {
+		if err := pager.sync3(rootPageNum, leftBranchPageNum, rightBranchPageNum); err != nil {
 			return wrap(err, "unable to sync pages")
 		}
-		// Reparent the children.
-		// Our rightBranch children already point to the correct parent page,
-		// but the leftBranch children do not.
-		if err := newLeftBranch.reparentChildren(pager, newLeftBranchPageNum); err != nil {
-			return wrap(err, "unable to reparent children")
-		}
-
+		table.rootPageNum = rootPageNum
 		return nil
+	}
+
+	// Otherwise, we need to recursively insert the new branch into the parent.
+	parentPageNum := leftBranch.parentPointer
+	parentPage, err := pager.GetPage(parentPageNum)
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	parentBranch := pageToBranchNode(parentPage)
+	if err := parentBranch.insertChild(table, parentPageNum, leftBranchPageNum, middle.key, rightBranchPageNum); err != nil {
+		// nowrap: recursive call
+		return err
+	}
+	return nil
+}
+
+// rebalanceChild rebalances the child at index after it underflowed by borrowing
+// from a sibling with cells to spare, or else merging with a sibling. Since this changes
+// the cells of this branch, parents are rebalanced recursively, and a root left with a
+// single child is replaced by that child.
+func (n *branchNode) rebalanceChild(table *Table, pageNum PagePointer, index cellptr) error {
+	if makeAssertions {
+		_assert(!n.isLeaf, "not a branch")
+	}
+
+	pager := table.pager
+	childPage, err := pager.GetPage(n.getChildPage(index))
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	if pageToNodeHeader(childPage).isLeaf {
+		err = n.rebalanceLeafChild(table, pageNum, index)
 	} else {
-		// Otherwise, we need to recursively insert the key into the parent.
-		parentPageNum := leftBranch.parentPointer
-		parentPage, err := pager.GetPage(parentPageNum)
+		err = n.rebalanceBranchChild(table, pageNum, index)
+	}
+	if err != nil {
+		return err
+	}
+
+	/* Borrowing changed a key of this branch, or a merge removed one. */
+
+	if n.isRoot {
+		if n.numCells == 0 {
+			if err := n.shrinkRoot(table, pageNum); err != nil {
+				return wrap(err, "unable to shrink root")
+			}
+		}
+		return nil
+	}
+	if !n.isUnderflowing() {
+		return nil
+	}
+	parentPageNum := n.parentPointer
+	parentPage, err := pager.GetPage(parentPageNum)
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	parentBranch := pageToBranchNode(parentPage)
+	if err := parentBranch.rebalanceChild(table, parentPageNum, parentBranch.findChildIndex(pageNum)); err != nil {
+		// nowrap: recursive call
+		return err
+	}
+	return nil
+}
+
+// rebalanceLeafChild rebalances the leaf child at index.
+func (n *branchNode) rebalanceLeafChild(table *Table, pageNum PagePointer, index cellptr) error {
+	pager := table.pager
+	getLeaf := func(childNum cellptr) (PagePointer, *leafNode, error) {
+		leafPageNum := n.getChildPage(childNum)
+		page, err := pager.GetPage(leafPageNum)
 		if err != nil {
-			return wrap(err, "unable to get page")
+			return 0, nil, wrap(err, "unable to get page")
 		}
-		parentBranch := pageToBranchNode(parentPage)
-		leftBranchNewMaxKey, err := leftBranch.getRightChildMaxKey(sizer, pager)
+		return leafPageNum, pageToLeafNode(page), nil
+	}
+
+	childPageNum, child, err := getLeaf(index)
+	if err != nil {
+		return err
+	}
+
+	// Borrow the last cells of the left sibling.
+	if index > 0 {
+		leftPageNum, left, err := getLeaf(index - 1)
 		if err != nil {
-			return wrap(err, "unable to get child maximum")
+			return err
 		}
-		if childMaxKey == 19 {
-			sink()
+		if count := left.getLendCount(table, child, true); count > 0 {
+			for ; count > 0; count-- {
+				last := left.numCells - 1
+				child.insertCellBin(table, 0, left.getCellBin(table, last))
+				left.removeDirect(table, last)
+			}
+			if err := pager.sync2(leftPageNum, childPageNum); err != nil {
+				return wrap(err, "unable to sync pages")
+			}
+			separator := separatorKey(left.getMaxKey(table), child.getMinKey(table))
+			return wrap(n.setKey(table, pageNum, index-1, separator), "unable to update separator key")
 		}
-		if err := parentBranch.insertAfterSplit(table, sizer, pager, parentPageNum, leftBranchOldMaxKey, leftBranchNewMaxKey, rightBranchPageNum); err != nil {
-			// nowrap: indirectly recursive call
+	}
+
+	// Borrow the first cells of the right sibling.
+	if index < n.numCells {
+		rightPageNum, right, err := getLeaf(index + 1)
+		if err != nil {
 			return err
 		}
-		return nil
+		if count := right.getLendCount(table, child, false); count > 0 {
+			for ; count > 0; count-- {
+				child.insertCellBin(table, child.numCells, right.getCellBin(table, 0))
+				right.removeDirect(table, 0)
+			}
+			if err := pager.sync2(childPageNum, rightPageNum); err != nil {
+				return wrap(err, "unable to sync pages")
+			}
+			separator := separatorKey(child.getMaxKey(table), right.getMinKey(table))
+			return wrap(n.setKey(table, pageNum, index, separator), "unable to update separator key")
+		}
 	}
-}
 
-// reparentChildren updates all child nodes to point to the pageNum of this node.
-func (n *branchNode) reparentChildren(pager *Pager, pageNum PagePointer) error {
-	maxCells := n.getMaxNumCells()
-	for i := cellptr(0); i < maxCells && i < n.numCells; i++ {
-		childPageNum := n.cells[i].child
-		if err := n.reparentChild(pager, pageNum, childPageNum); err != nil {
-			return wrap(err, "unable to reparent child")
-		}
+	// Merge with the left sibling if there is one, otherwise with the right sibling.
+	leftIndex := index
+	if index > 0 {
+		leftIndex = index - 1
 	}
-	if err := n.reparentChild(pager, pageNum, n.rightChild); err != nil {
-		return wrap(err, "unable to reparent child")
+	leftPageNum, left, err := getLeaf(leftIndex)
+	if err != nil {
+		return err
 	}
-	return nil
-}
-
-// reparentChildren updates a child node to point to the pageNum of this node.
-func (n *branchNode) reparentChild(pager *Pager, pageNum, childPageNum PagePointer) error {
-	childPage, err := pager.GetPage(childPageNum)
+	rightPageNum, right, err := getLeaf(leftIndex + 1)
 	if err != nil {
-		return wrap(err, "unable to get page")
+		return err
 	}
-	childNode := pageToNodeHeader(childPage)
-	childNode.parentPointer = pageNum
-	if err := pager.sync1(childPageNum); err != nil {
-		return wrap(err, "unable to sync child")
+	for cellNum := cellptr(0); cellNum < right.numCells; cellNum++ {
+		left.insertCellBin(table, left.numCells, right.getCellBin(table, cellNum))
+	}
+	left.nextLeaf = right.nextLeaf
+	n.removeCell(leftIndex, leftPageNum)
+	if err := pager.sync2(leftPageNum, pageNum); err != nil {
+		return wrap(err, "unable to sync pages")
+	}
+	if err := pager.FreePage(rightPageNum); err != nil {
+		return wrap(err, "unable to free page")
 	}
 	return nil
 }
 
-func collectChildPages(branches ...*branchNode) []PagePointer {
-	var out []PagePointer
-	for _, branch := range branches {
-		for i := cellptr(0); i < branch.numCells; i++ {
-			out = append(out, branch.cells[i].child)
+// rebalanceBranchChild rebalances the branch child at index.
+func (n *branchNode) rebalanceBranchChild(table *Table, pageNum PagePointer, index cellptr) error {
+	pager := table.pager
+	getBranch := func(childNum cellptr) (PagePointer, *branchNode, error) {
+		branchPageNum := n.getChildPage(childNum)
+		page, err := pager.GetPage(branchPageNum)
+		if err != nil {
+			return 0, nil, wrap(err, "unable to get page")
 		}
-		out = append(out, branch.rightChild)
+		return branchPageNum, pageToBranchNode(page), nil
 	}
-	return out
-}
 
-func collectKeys(sizer DataSizer, pager *Pager, branches ...*branchNode) []KeyType {
-	var out []KeyType
-	for _, branch := range branches {
-		for i := cellptr(0); i < branch.numCells; i++ {
-			out = append(out, branch.cells[i].key)
-		}
-		x, err := branch.getRightChildMaxKey(sizer, pager)
+	childPageNum, child, err := getBranch(index)
+	if err != nil {
+		return err
+	}
+
+	// Rotate the last children of the left sibling through the parent key
+	// until the child no longer underflows, unless the sibling would underflow instead.
+	if index > 0 {
+		leftPageNum, left, err := getBranch(index - 1)
 		if err != nil {
-			panic(err)
+			return err
+		}
+		leftCells, leftRightChild := left.getCells(), left.rightChild
+		childCells := child.getCells()
+		parentKey := n.getCells()[index-1].key
+		for branchUnderflows(childCells) && len(leftCells) > 0 && !branchUnderflows(leftCells[:len(leftCells)-1]) {
+			last := leftCells[len(leftCells)-1]
+			childCells = append([]branchNodeCell{{key: parentKey, child: leftRightChild}}, childCells...)
+			leftCells, leftRightChild, parentKey = leftCells[:len(leftCells)-1], last.child, last.key
+		}
+		if !branchUnderflows(childCells) {
+			left.setCells(leftCells, leftRightChild)
+			child.setCells(childCells, child.rightChild)
+			if err := pager.sync2(leftPageNum, childPageNum); err != nil {
+				return wrap(err, "unable to sync pages")
+			}
+			if err := child.reparentChildren(pager, childPageNum); err != nil {
+				return wrap(err, "unable to reparent children")
+			}
+			return wrap(n.setKey(table, pageNum, index-1, parentKey), "unable to update parent key")
 		}
-		out = append(out, x)
 	}
-	return out
-}
 
-func samePages(skip PagePointer, end, start []PagePointer) bool {
-	// TODO: remove debug code
-	if len(end)-1 != len(start) {
-		return false
-	}
-	endi, starti := 0, 0
-	for range end {
-		if end[endi] == skip {
-			endi++
-			continue
+	// Rotate the first children of the right sibling through the parent key
+	// until the child no longer underflows, unless the sibling would underflow instead.
+	if index < n.numCells {
+		rightPageNum, right, err := getBranch(index + 1)
+		if err != nil {
+			return err
+		}
+		rightCells := right.getCells()
+		childCells, childRightChild := child.getCells(), child.rightChild
+		parentKey := n.getCells()[index].key
+		for branchUnderflows(childCells) && len(rightCells) > 0 && !branchUnderflows(rightCells[1:]) {
+			first := rightCells[0]
+			childCells = append(childCells, branchNodeCell{key: parentKey, child: childRightChild})
+			rightCells, childRightChild, parentKey = rightCells[1:], first.child, first.key
 		}
-		if end[endi] != start[starti] {
-			return false
+		if !branchUnderflows(childCells) {
+			child.setCells(childCells, childRightChild)
+			right.setCells(rightCells, right.rightChild)
+			if err := pager.sync2(childPageNum, rightPageNum); err != nil {
+				return wrap(err, "unable to sync pages")
+			}
+			if err := child.reparentChildren(pager, childPageNum); err != nil {
+				return wrap(err, "unable to reparent children")
+			}
+			return wrap(n.setKey(table, pageNum, index, parentKey), "unable to update parent key")
 		}
-		endi++
-		starti++
 	}
-	return true
+
+	// Merge with the left sibling if there is one, otherwise with the right sibling.
+	// The parent key between them separates the right child of the left branch
+	// from the children of the right branch.
+	leftIndex := index
+	if index > 0 {
+		leftIndex = index - 1
+	}
+	leftPageNum, left, err := getBranch(leftIndex)
+	if err != nil {
+		return err
+	}
+	rightPageNum, right, err := getBranch(leftIndex + 1)
+	if err != nil {
+		return err
+	}
+	mergedCells := append(left.getCells(), branchNodeCell{key: n.getCells()[leftIndex].key, child: left.rightChild})
+	mergedCells = append(mergedCells, right.getCells()...)
+	left.setCells(mergedCells, right.rightChild)
+	n.removeCell(leftIndex, leftPageNum)
+	if err := pager.sync2(leftPageNum, pageNum); err != nil {
+		return wrap(err, "unable to sync pages")
+	}
+	if err := left.reparentChildren(pager, leftPageNum); err != nil {
+		return wrap(err, "unable to reparent children")
+	}
+	if err := pager.FreePage(rightPageNum); err != nil {
+		return wrap(err, "unable to free page")
+	}
+	return nil
 }
 
-func sameKeys(skip KeyType, end, start []KeyType) bool {
-	// TODO: remove debug code
-	if len(end)-1 != len(start) {
-		return false
+// shrinkRoot replaces a root without keys by its only child,
+// which becomes the root of the table where it is.
+func (n *branchNode) shrinkRoot(table *Table, pageNum PagePointer) error {
+	if makeAssertions {
+		_assert(n.isRoot, "not the root")
+		_assert(n.numCells == 0, "root has more than one child")
 	}
-	endi, starti := 0, 0
-	for range end {
-		if end[endi] == skip {
-			endi++
-			continue
-		}
-		if end[endi] != start[starti] {
-			return false
+
+	pager := table.pager
+	childPageNum := n.rightChild
+	childPage, err := pager.GetPage(childPageNum)
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	child := pageToNodeHeader(childPage)
+	child.isRoot = true
+	child.parentPointer = 0
+	if err := pager.sync1(childPageNum); err != nil {
+		return wrap(err, "unable to sync page")
+	}
+	// Record the new root before the old root becomes a free page.
+	table.rootPageNum = childPageNum
+	if err := table.syncHeader(); err != nil {
+		return wrap(err, "unable to sync header")
+	}
+	if err := pager.FreePage(pageNum); err != nil {
+		return wrap(err, "unable to free page")
+	}
+	return nil
+}
+
+// reparentChildren updates all child nodes to point to the pageNum of this node.
+func (n *branchNode) reparentChildren(pager *Pager, pageNum PagePointer) error {
+	for i := cellptr(0); i <= n.numCells; i++ {
+		if err := n.reparentChild(pager, pageNum, n.getChildPage(i)); err != nil {
+			return wrap(err, "unable to reparent child")
 		}
-		endi++
-		starti++
 	}
-	return true
+	return nil
 }
 
-func sink(...interface{}) {
-	// TODO: remove debug code
+// reparentChildren updates a child node to point to the pageNum of this node.
+func (n *branchNode) reparentChild(pager *Pager, pageNum, childPageNum PagePointer) error {
+	childPage, err := pager.GetPage(childPageNum)
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	childNode := pageToNodeHeader(childPage)
+	childNode.parentPointer = pageNum
+	if err := pager.sync1(childPageNum); err != nil {
+		return wrap(err, "unable to sync child")
+	}
+	return nil
 }
@@ -31,7 +31,8 @@ func (n *leafNodeHeader) String() string {
 type leafNode struct {
 	leafNodeHeader
 	// cellData holds arbitrary data in cells.
-	// The format of a cell is {KeyType(key), [dataSize]byte}.
+	// The format of a cell is {uint16(key length), [maxKeySize]byte(key), [dataSize]byte},
+	// unless the leaf is slotted.
 	cellData [leafNodeMaxCellData]byte
 }
 
@@ -40,10 +41,11 @@ var leafConvertWhitelist map[string]struct{}
 func init() {
 	if makeAssertions {
 		leafConvertWhitelist = map[string]struct{}{
-			"splitAndInsert": {},
-			"Open":           {},
-			"createNewRoot":  {},
-			"insert":         {},
+			"splitAndInsert":  {},
+			"Open":            {},
+			"createNewRoot":   {},
+			"insert":          {},
+			"insertCellBinAt": {},
 		}
 	}
 }
@@ -69,77 +71,247 @@ func (n *leafNode) init() {
 	n.nextLeaf = 0
 }
 
-// getCellBin returns the {keyType(key), [dataSize]byte} bytes for a given index.
+// isSlotted indicates if leaves hold cells of varying sizes in a slotted layout.
+func isSlotted(sizer DataSizer) bool {
+	return sizer.DataSize() == VariableDataSize
+}
+
+// getCellBin returns the bytes of the cell at a given index: {uint16(key length),
+// [maxKeySize]byte(key), [dataSize]byte} for fixed-size data, or a cell of a slotted leaf.
 func (n *leafNode) getCellBin(sizer DataSizer, index cellptr) (cell []byte) {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
 	}
 
+	if isSlotted(sizer) {
+		return n.getSlottedCellBin(index)
+	}
 	cellSize := n.getCellSize(sizer)
 	offset := uintptr(index) * cellSize
 	return n.cellData[offset : offset+cellSize]
 }
 
 // getCellSize returns the size of cells in this node, including key.
+// Cells of slotted leaves vary in size.
 func (n *leafNode) getCellSize(sizer DataSizer) uintptr {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
+		_assert(!isSlotted(sizer), "cells of slotted leaves vary in size")
 	}
 
-	return keySize + uintptr(sizer.DataSize())
+	return fixedCellSize(sizer)
+}
+
+// fixedCellSize returns the size of cells of leaves with fixed-size data.
+func fixedCellSize(sizer DataSizer) uintptr {
+	return keyLengthSize + uintptr(sizer.MaxKeySize()) + uintptr(sizer.DataSize())
 }
 
 // getMaxNumCells is the maximum number of cells that can be held in this node.
+// For slotted leaves, this is the number of cells with empty values that fit.
 func (n *leafNode) getMaxNumCells(sizer DataSizer) cellptr {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
 	}
 
+	if isSlotted(sizer) {
+		return cellptr(slottedMaxCellData / (slotSize + slottedCellHeaderSize))
+	}
 	cellSize := n.getCellSize(sizer)
 	return cellptr(leafNodeMaxCellData / cellSize)
 }
 
+// getMinNumCells is the minimum number of cells held in this node unless it is the root.
+func (n *leafNode) getMinNumCells(sizer DataSizer) cellptr {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	return n.getMaxNumCells(sizer) / 2
+}
+
+// getUsedSpace returns the amount of cell data used by the cells in this node.
+func (n *leafNode) getUsedSpace(sizer DataSizer) uintptr {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	if isSlotted(sizer) {
+		return n.getSlotted().getUsedSpace(n.numCells)
+	}
+	return uintptr(n.numCells) * n.getCellSize(sizer)
+}
+
+// getCapacity returns the amount of cell data that can be used by the cells in this node.
+func (n *leafNode) getCapacity(sizer DataSizer) uintptr {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	if isSlotted(sizer) {
+		return slottedMaxCellData
+	}
+	return uintptr(n.getMaxNumCells(sizer)) * n.getCellSize(sizer)
+}
+
+// hasRoomFor indicates if a cell of a given size can be inserted into this node without a split.
+func (n *leafNode) hasRoomFor(sizer DataSizer, cellSize int) bool {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	if isSlotted(sizer) {
+		return n.getSlotted().getUsedSpace(n.numCells)+slotSize+uintptr(cellSize) <= slottedMaxCellData
+	}
+	return n.numCells < n.getMaxNumCells(sizer)
+}
+
+// hasRoomToReplace indicates if the cell at the given index can be replaced by a cell
+// of a given size without a split.
+func (n *leafNode) hasRoomToReplace(sizer DataSizer, index cellptr, cellSize int) bool {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	if isSlotted(sizer) {
+		return n.getSlotted().getUsedSpace(n.numCells)-uintptr(len(n.getSlottedCellBin(index)))+uintptr(cellSize) <= slottedMaxCellData
+	}
+	return uintptr(cellSize) == n.getCellSize(sizer)
+}
+
+// getMinUsedSpace returns the amount of cell data used by a node with the minimum number of
+// cells, or for slotted leaves, a quarter of their capacity, which is the most that a single
+// cell and its slot can use.
+func (n *leafNode) getMinUsedSpace(sizer DataSizer) uintptr {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	if isSlotted(sizer) {
+		return slottedMaxCellData / 4
+	}
+	return uintptr(n.getMinNumCells(sizer)) * n.getCellSize(sizer)
+}
+
+// getCellSpace returns the amount of cell data used by the cell at the given index.
+func (n *leafNode) getCellSpace(sizer DataSizer, index cellptr) uintptr {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	if isSlotted(sizer) {
+		return slotSize + uintptr(len(n.getSlottedCellBin(index)))
+	}
+	return n.getCellSize(sizer)
+}
+
+// isUnderflowing indicates if this node uses less than its minimum space unless it is the root.
+func (n *leafNode) isUnderflowing(sizer DataSizer) bool {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	return n.getUsedSpace(sizer) < n.getMinUsedSpace(sizer)
+}
+
+// getLendCount returns the number of cells from the end or start of this node to lend to an
+// underflowing sibling so that it no longer underflows, or 0 if this node would underflow instead.
+func (n *leafNode) getLendCount(sizer DataSizer, sibling *leafNode, fromEnd bool) cellptr {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+		_assert(sibling.isUnderflowing(sizer), "sibling is not underflowing")
+	}
+
+	minUsedSpace := n.getMinUsedSpace(sizer)
+	needed := minUsedSpace - sibling.getUsedSpace(sizer)
+	remaining := n.getUsedSpace(sizer)
+	lent := uintptr(0)
+	for count := cellptr(1); count <= n.numCells; count++ {
+		index := count - 1
+		if fromEnd {
+			index = n.numCells - count
+		}
+		cellSpace := n.getCellSpace(sizer, index)
+		if remaining-cellSpace < minUsedSpace {
+			return 0
+		}
+		remaining -= cellSpace
+		lent += cellSpace
+		if lent >= needed {
+			return count
+		}
+	}
+	return 0
+}
+
+// clear removes every cell from this node.
+// Does not sync.
+func (n *leafNode) clear(sizer DataSizer) {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	n.numCells = 0
+	if isSlotted(sizer) {
+		n.getSlotted().setContentSize(0)
+	}
+}
+
 // getCell returns the key-value pair stored in a cell at the given index.
+// Values in slotted leaves are only complete without overflow pages; see Table.cellValue.
 func (n *leafNode) getCell(sizer DataSizer, index cellptr) (key KeyType, value []byte) {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
 	}
 
 	bin := n.getCellBin(sizer, index)
-	key = keyFromBytes(bin)
-	value = bin[keySize:]
-	return key, value
+	return getBinKey(bin), n.getBinValue(sizer, bin)
 }
 
 // getCellKey returns the key of the key-value pair stored in a cell at the given index.
+// The key shares memory with the cell.
 func (n *leafNode) getCellKey(sizer DataSizer, index cellptr) KeyType {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
 	}
 
-	bin := n.getCellBin(sizer, index)
-	return keyFromBytes(bin)
+	return getBinKey(n.getCellBin(sizer, index))
 }
 
 // getCellValue returns the value of the key-value pair stored in a cell at the given index.
+// Values in slotted leaves are only complete without overflow pages; see Table.cellValue.
 func (n *leafNode) getCellValue(sizer DataSizer, index cellptr) []byte {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
 	}
 
-	bin := n.getCellBin(sizer, index)
-	return bin[keySize:]
+	return n.getBinValue(sizer, n.getCellBin(sizer, index))
+}
+
+// getBinKey returns the key of a cell, which follows its length in either layout.
+func getBinKey(bin []byte) KeyType {
+	return bin[keyLengthSize : keyLengthSize+keyLength(bin)]
 }
 
-// putCell sets the key-value pair stored in a particular cell.
+// getBinValue returns the part of the value of a cell that is held in the cell.
+func (n *leafNode) getBinValue(sizer DataSizer, bin []byte) []byte {
+	if isSlotted(sizer) {
+		return bin[slottedCellHeaderSize+keyLength(bin):]
+	}
+	return bin[keyLengthSize+uintptr(sizer.MaxKeySize()):]
+}
+
+// putCell sets the key-value pair stored in a particular cell of fixed-size data.
 func (n *leafNode) putCell(sizer DataSizer, index cellptr, key KeyType, value []byte) {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
+		_assert(!isSlotted(sizer), "cannot put cells of slotted leaves")
 	}
 
 	bin := n.getCellBin(sizer, index)
-	encodeKeyToBytes(key, bin)
-	copy(bin[keySize:], value)
+	putKeyLength(bin, key)
+	copy(bin[keyLengthSize:], key)
+	copy(bin[keyLengthSize+uintptr(sizer.MaxKeySize()):], value)
 }
 
 // getNodeMaxKey gets the highest key in this node.
@@ -151,6 +323,15 @@ func (n *leafNode) getMaxKey(sizer DataSizer) KeyType {
 	return n.getCellKey(sizer, n.numCells-1)
 }
 
+// getMinKey gets the lowest key in this node.
+func (n *leafNode) getMinKey(sizer DataSizer) KeyType {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	return n.getCellKey(sizer, 0)
+}
+
 // getSplitCounts gets the amount of cells to put in the old and new nodes after a split.
 func (n *leafNode) getSplitCounts(sizer DataSizer) (oldSplitCount, newSplitCount cellptr) {
 	if makeAssertions {
@@ -174,11 +355,11 @@ func (n *leafNode) findKeyIndex(sizer DataSizer, key KeyType) cellptr {
 	onePastMaxIndex := n.numCells
 	for onePastMaxIndex != minIndex {
 		index := (minIndex + onePastMaxIndex) / 2
-		keyAtIndex := n.getCellKey(sizer, index)
-		if key == keyAtIndex {
+		cmp := bytes.Compare(key, n.getCellKey(sizer, index))
+		if cmp == 0 {
 			return index
 		}
-		if key < keyAtIndex {
+		if cmp < 0 {
 			onePastMaxIndex = index
 		} else {
 			minIndex = index + 1
@@ -210,34 +391,178 @@ func (n *leafNode) makeRoomForInsert(sizer DataSizer, pos cellptr) {
 	copy(n.cellData[dstStart:dstEnd], n.cellData[srcStart:srcEnd])
 }
 
-// insertDirect inserts a key-value into the cursor position
-// for a node that has space to insert the value directly.
-func (n *leafNode) insertDirect(sizer DataSizer, pos cellptr, key KeyType, value []byte) {
+// insertCellBin inserts the bytes of a cell at a position
+// for a node that has room to insert the cell directly.
+// Does not sync.
+func (n *leafNode) insertCellBin(sizer DataSizer, pos cellptr, bin []byte) {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
-		_assert(n.numCells < n.getMaxNumCells(sizer), "leaf node too big for direct insert")
+		_assert(n.hasRoomFor(sizer, len(bin)), "leaf node too big for direct insert")
 	}
 
+	if isSlotted(sizer) {
+		n.getSlotted().insert(n.numCells, pos, bin)
+		n.numCells++
+		return
+	}
 	n.makeRoomForInsert(sizer, pos)
-	n.putCell(sizer, pos, key, value)
+	copy(n.getCellBin(sizer, pos), bin)
 	n.numCells++
 }
 
+// removeDirect removes the key-value at a position, sliding cells to the left.
+// Does not free overflow pages or sync.
+func (n *leafNode) removeDirect(sizer DataSizer, pos cellptr) {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+		_assert(pos < n.numCells, "tried to remove cell %d >= numCells %d", pos, n.numCells)
+	}
+
+	if isSlotted(sizer) {
+		n.getSlotted().remove(n.numCells, pos, uintptr(len(n.getSlottedCellBin(pos))))
+		n.numCells--
+		return
+	}
+	cellSize := cellptr(n.getCellSize(sizer))
+	copy(n.cellData[pos*cellSize:n.numCells*cellSize], n.cellData[(pos+1)*cellSize:n.numCells*cellSize])
+	n.numCells--
+}
+
+// replaceCellBin replaces the cell at a position with the bytes of a cell of any size,
+// keeping its position, for a node that has room for the new cell.
+// Does not free overflow pages or sync.
+func (n *leafNode) replaceCellBin(sizer DataSizer, pos cellptr, bin []byte) {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+		_assert(n.hasRoomToReplace(sizer, pos, len(bin)), "leaf node too big for direct replace")
+	}
+
+	if isSlotted(sizer) {
+		n.getSlotted().replace(n.numCells, pos, uintptr(len(n.getSlottedCellBin(pos))), bin)
+		return
+	}
+	copy(n.getCellBin(sizer, pos), bin)
+}
+
+// delete removes the key-value at the cursor position and frees its overflow pages. If the leaf
+// underflows, it borrows from or merges with a sibling, rebalancing the tree up to the root.
+func (n *leafNode) delete(cursor *Cursor) error {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	table := cursor.table
+	pager := table.pager
+
+	if err := table.freeOverflow(n.getCellBin(table, cursor.cellNum)); err != nil {
+		return wrap(err, "unable to free value")
+	}
+	n.removeDirect(table, cursor.cellNum)
+	if err := pager.sync1(cursor.pageNum); err != nil {
+		return wrap(err, "unable to sync page")
+	}
+	return n.rebalance(table, cursor.pageNum)
+}
+
+// update replaces the cell at the cursor position with the bytes of a cell with the same key,
+// and then frees the overflow pages of the old cell. If the old cell cannot be read, the overflow
+// pages of the new cell are freed instead and the leaf is unchanged. The new cell takes the place
+// of the old one when the leaf has room for it, and if the leaf then underflows, it borrows from
+// or merges with a sibling, rebalancing the tree up to the root.
+// The content of a slotted leaf is always compact, so a new cell without room cannot be made to
+// fit by compacting the leaf. The old cell is removed and the new cell inserted with a split
+// instead, which may move the record to the new leaf. Like an insert, a failed split is not undone.
+func (n *leafNode) update(cursor *Cursor, bin []byte) error {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
+	table := cursor.table
+	oldPageNums, err := table.overflowPages(n.getCellBin(table, cursor.cellNum))
+	if err != nil {
+		return wrap2(err, table.freeOverflow(bin), "unable to get old value")
+	}
+	if !n.hasRoomToReplace(table, cursor.cellNum, len(bin)) {
+		n.removeDirect(table, cursor.cellNum)
+		if err := n.insertCellBinAt(cursor, bin); err != nil {
+			return wrap(err, "unable to insert cell")
+		}
+		return wrap(table.freeOverflowPages(oldPageNums), "unable to free old value")
+	}
+	n.replaceCellBin(table, cursor.cellNum, bin)
+	if err := table.pager.sync1(cursor.pageNum); err != nil {
+		return wrap(err, "unable to sync page")
+	}
+	if err := table.freeOverflowPages(oldPageNums); err != nil {
+		return wrap(err, "unable to free old value")
+	}
+	return n.rebalance(table, cursor.pageNum)
+}
+
+// rebalance borrows from or merges with a sibling if this leaf underflows,
+// rebalancing the tree up to the root.
+func (n *leafNode) rebalance(table *Table, pageNum PagePointer) error {
+	if n.isRoot || !n.isUnderflowing(table) {
+		return nil
+	}
+
+	parentPageNum := n.parentPointer
+	parentPage, err := table.pager.GetPage(parentPageNum)
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	parentBranch := pageToBranchNode(parentPage)
+	if err := parentBranch.rebalanceChild(table, parentPageNum, parentBranch.findChildIndex(pageNum)); err != nil {
+		return wrap(err, "unable to rebalance leaf")
+	}
+	return nil
+}
+
+// getSlottedSplitCount gets the amount of cells to put in the old node when splitting cells
+// of a slotted leaf: the fewest cells using at least half of the space.
+func getSlottedSplitCount(bins [][]byte) int {
+	total := 0
+	for _, bin := range bins {
+		total += slotSize + len(bin)
+	}
+	used := 0
+	for index, bin := range bins {
+		used += slotSize + len(bin)
+		if 2*used >= total {
+			return index + 1
+		}
+	}
+	return len(bins)
+}
+
 func (n *leafNode) insert(cursor *Cursor, key KeyType, value []byte) error {
 	if makeAssertions {
 		_assert(n.isLeaf, "not a leaf")
 	}
 
+	bin, err := cursor.table.newCellBin(key, value)
+	if err != nil {
+		return wrap(err, "unable to create cell")
+	}
+	return n.insertCellBinAt(cursor, bin)
+}
+
+// insertCellBinAt inserts the bytes of a cell at the cursor position,
+// splitting the leaf and its parents if necessary.
+func (n *leafNode) insertCellBinAt(cursor *Cursor, bin []byte) error {
+	if makeAssertions {
+		_assert(n.isLeaf, "not a leaf")
+	}
+
 	table := cursor.table
 	pager := table.pager
 	var sizer DataSizer = table
 
 	leftLeafPageNum := cursor.pageNum
 	leftLeaf := n
-	leafMaxCells := leftLeaf.getMaxNumCells(sizer)
 	// If the leaf node still has space, we can insert the key-value directly into the leaf.
-	if leftLeaf.numCells < leafMaxCells {
-		leftLeaf.insertDirect(sizer, cursor.cellNum, key, value)
+	if leftLeaf.hasRoomFor(sizer, len(bin)) {
+		leftLeaf.insertCellBin(sizer, cursor.cellNum, bin)
 		if err := cursor.table.pager.sync1(leftLeafPageNum); err != nil {
 			return wrap(err, "unable to sync page")
 		}
@@ -246,8 +571,6 @@ func (n *leafNode) insert(cursor *Cursor, key KeyType, value []byte) error {
 
 	/* We need to split the leaf. */
 
-	leftLeafOldMaxKey := leftLeaf.getMaxKey(sizer)
-
 	// Create a new leaf to split into.
 	rightLeafPageNum, err := pager.GetUnusedPageNum()
 	if err != nil {
@@ -266,33 +589,32 @@ func (n *leafNode) insert(cursor *Cursor, key KeyType, value []byte) error {
 	rightLeaf.nextLeaf = leftLeaf.nextLeaf
 	leftLeaf.nextLeaf = rightLeafPageNum
 
-	leftLeafSplitSize, rightLeafSplitSize := leftLeaf.getSplitCounts(sizer)
-
-	// Does the value go into the left or right node?
-	newEntryGoesLeft := leftLeaf.getCellKey(sizer, leftLeafSplitSize-1) > key
-
-	// Copy the larger keys to the new node.
-	cellSize := cellptr(leftLeaf.getCellSize(sizer))
-	splitCellStart := cellSize * leftLeafSplitSize
-	if newEntryGoesLeft {
-		splitCellStart -= cellSize
-		// New key-value will be added to the left leaf.
-		leftLeaf.numCells = leftLeafSplitSize - 1
-		rightLeaf.numCells = rightLeafSplitSize
-	} else {
-		leftLeaf.numCells = leftLeafSplitSize
-		// New key-value will be added to the right leaf.
-		rightLeaf.numCells = rightLeafSplitSize - 1
+	// Collect the cells in order, including the new cell.
+	bins := make([][]byte, 0, leftLeaf.numCells+1)
+	for index := cellptr(0); index < leftLeaf.numCells; index++ {
+		if index == cursor.cellNum {
+			bins = append(bins, bin)
+		}
+		bins = append(bins, append([]byte(nil), leftLeaf.getCellBin(sizer, index)...))
+	}
+	if cursor.cellNum == leftLeaf.numCells {
+		bins = append(bins, bin)
 	}
-	copy(rightLeaf.cellData[:], leftLeaf.cellData[splitCellStart:])
 
-	// Insert the new key-value into the correct leaf.
-	if newEntryGoesLeft {
-		index := leftLeaf.findKeyIndex(sizer, key)
-		leftLeaf.insertDirect(sizer, index, key, value)
+	// Divide the cells between the leaves.
+	var leftLeafSplitSize int
+	if isSlotted(sizer) {
+		leftLeafSplitSize = getSlottedSplitCount(bins)
 	} else {
-		index := rightLeaf.findKeyIndex(sizer, key)
-		rightLeaf.insertDirect(sizer, index, key, value)
+		splitSize, _ := leftLeaf.getSplitCounts(sizer)
+		leftLeafSplitSize = int(splitSize)
+	}
+	leftLeaf.clear(sizer)
+	for index, bin := range bins[:leftLeafSplitSize] {
+		leftLeaf.insertCellBin(sizer, cellptr(index), bin)
+	}
+	for index, bin := range bins[leftLeafSplitSize:] {
+		rightLeaf.insertCellBin(sizer, cellptr(index), bin)
 	}
 
 	if err := cursor.table.pager.sync2(leftLeafPageNum, rightLeafPageNum); err != nil {
@@ -301,78 +623,51 @@ func (n *leafNode) insert(cursor *Cursor, key KeyType, value []byte) error {
 
 	/* Modify the parent */
 
-	if key == 24 {
-		sink()
-	}
+	// The parent separates the leaves by the shortest key between them.
+	separator := separatorKey(leftLeaf.getMaxKey(sizer), rightLeaf.getMinKey(sizer))
 
 	// In the simple case, we're already at the root. We just need to parent
 	// the left and right node to a new root.
 	if leftLeaf.isRoot {
-
-		// If the old leaf is a root node, we need a new root.
-		// We want to preserve the table's root node position.
-		// Lets keep the root at the same page as the leaf, so
-		// we copy our left leaf onto a new page.
-
-		// We'll need our leftLeaf as a page for conversion.
-		leftLeafPage := (*Page)(unsafe.Pointer(leftLeaf))
-
-		// Create the new left leaf to copy into.
-		newLeftLeafPageNum, err := pager.GetUnusedPageNum()
+		// Create the new root, which moves the root of the table.
+		rootPageNum, err := pager.GetUnusedPageNum()
 		if err != nil {
 			return wrap(err, "unable to get free page")
 		}
-		newLeftLeafPage, err := pager.GetPage(newLeftLeafPageNum)
+		rootPage, err := pager.GetPage(rootPageNum)
 		if err != nil {
 			return wrap(err, "unable to get page")
 		}
-		newLeftLeaf := pageToLeafNode(newLeftLeafPage)
-
-		// Copy the leaf to the new leaf.
-		copy(newLeftLeafPage[:], leftLeafPage[:])
-		newLeftLeaf.isRoot = false
-		newLeftLeaf.parentPointer = leftLeafPageNum
-
-		// Convert the leftLeaf to a root.
-		root := pageToBranchNode(leftLeafPage)
+		root := pageToBranchNode(rootPage)
 		root.init()
 		root.isRoot = true
-		root.numCells = 1
-		root.cells[0].key = newLeftLeaf.getMaxKey(sizer)
-		root.cells[0].child = newLeftLeafPageNum
-		root.rightChild = rightLeafPageNum
+		root.setCells([]branchNodeCell{{child: leftLeafPageNum, key: separator}}, rightLeafPageNum)
+		leftLeaf.isRoot = false
+		leftLeaf.parentPointer = rootPageNum
+		rightLeaf.parentPointer = rootPageNum
 		// At this point we have the following configuration:
-		//          branch pg0: [child 1, key max(1), child 2]
-		//                        /                   \
-		// leaf pg2: [0-50% key-values]  ->  leaf pg1: [51-100% key-values]
+		//            branch: [left, separator, right]
+		//                     /                  \
+		// left leaf: [0-50% key-values]  ->  right leaf: [51-100% key-values]
 
 		// Sync the changes.
-		rootPageNum := leftLeafPageNum
-		if err := cursor.table.pager.sync2(rootPageNum, newLeftLeafPageNum); err != nil {
+		if err := cursor.table.pager.sync3(rootPageNum, leftLeafPageNum, rightLeafPageNum); err != nil {
 			return wrap(err, "unable to sync pages")
 		}
+		cursor.table.rootPageNum = rootPageNum
 		return nil
-	} else {
-		// If our destination is not the root, we need to update the parents,
-		// possibly all the way up to the root where we may yet split the root again.
-		// Get the parent of these two nodes and update it appropriately.
-		parentPageNum := leftLeaf.parentPointer
-		parentPage, err := pager.GetPage(parentPageNum)
-		if err != nil {
-			return wrap(err, "unable to get page")
-		}
-		parentBranch := pageToBranchNode(parentPage)
-		leftLeafNewMaxKey := leftLeaf.getMaxKey(sizer)
-
-		fmt.Println("after leaf split")
-		table.printTree()
+	}
 
-		if err := parentBranch.insertAfterSplit(table, sizer, pager, parentPageNum, leftLeafOldMaxKey, leftLeafNewMaxKey, rightLeafPageNum); err != nil {
-			return wrap(err, "unable to update parent branch")
-		}
-		fmt.Println("after leaf split insert")
-		table.printTree()
-		sink()
+	// If our destination is not the root, the parent needs to point to the new
+	// right leaf, possibly splitting all the way up to the root.
+	parentPageNum := leftLeaf.parentPointer
+	parentPage, err := pager.GetPage(parentPageNum)
+	if err != nil {
+		return wrap(err, "unable to get page")
+	}
+	parentBranch := pageToBranchNode(parentPage)
+	if err := parentBranch.insertChild(table, parentPageNum, leftLeafPageNum, separator, rightLeafPageNum); err != nil {
+		return wrap(err, "unable to update parent branch")
 	}
 	return nil
 }
@@ -386,7 +681,7 @@ func (n *leafNode) String(sizer DataSizer) string {
 	_, _ = fmt.Fprintf(buf, "{leafNodeHeader:%s,cells:[", n.leafNodeHeader.String())
 	for index := cellptr(0); index < n.numCells; index++ {
 		key := n.getCellKey(sizer, index)
-		_, _ = fmt.Fprintf(buf, "{#%d:%d}", index, key)
+		_, _ = fmt.Fprintf(buf, "{#%d:%x}", index, key)
 	}
 	buf.WriteString("]}")
 	return buf.String()
@@ -401,7 +696,7 @@ func (n *leafNode) StringVerbose(sizer DataSizer, valueString func([]byte) strin
 	_, _ = fmt.Fprintf(buf, "{leafNodeHeader:%s,cells:[", n.leafNodeHeader.String())
 	for index := cellptr(0); index < n.numCells; index++ {
 		key, value := n.getCell(sizer, index)
-		_, _ = fmt.Fprintf(buf, "{#%d:%d=%s}", index, key, valueString(value))
+		_, _ = fmt.Fprintf(buf, "{#%d:%x=%s}", index, key, valueString(value))
 	}
 	buf.WriteString("]}")
 	return buf.String()
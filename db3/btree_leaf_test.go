@@ -12,21 +12,21 @@ func TestLeafNode_putGetCell(t *testing.T) {
 	const (
 		dataSize = 11
 	)
-	sizer := dataSizer{dataSize}
+	sizer := dataSizer{testKeySize, dataSize}
 	leaf := &leafNode{}
 	leaf.init()
 
-	leaf.putCell(sizer, 2, 8, []byte("hello world"))
-	leaf.putCell(sizer, 3, 9, []byte("dlrow olleh"))
+	leaf.putCell(sizer, 2, testKey(8), []byte("hello world"))
+	leaf.putCell(sizer, 3, KeyType{9}, []byte("dlrow olleh"))
 
 	k1, v1 := leaf.getCell(sizer, 2)
-	assert.Equal(t, KeyType(8), k1)
+	assert.Equal(t, testKey(8), k1)
 	assert.Equal(t, []byte("hello world"), v1)
 	assert.Equal(t, k1, leaf.getCellKey(sizer, 2))
 	assert.Equal(t, v1, leaf.getCellValue(sizer, 2))
 
 	k2, v2 := leaf.getCell(sizer, 3)
-	assert.Equal(t, KeyType(9), k2)
+	assert.Equal(t, KeyType{9}, k2)
 	assert.Equal(t, []byte("dlrow olleh"), v2)
 	assert.Equal(t, k2, leaf.getCellKey(sizer, 3))
 	assert.Equal(t, v2, leaf.getCellValue(sizer, 3))
@@ -36,11 +36,11 @@ func TestLeafNode_numCells(t *testing.T) {
 	const (
 		dataSize = 11
 	)
-	sizer := dataSizer{dataSize}
+	sizer := dataSizer{testKeySize, dataSize}
 	leaf := &leafNode{}
 	leaf.init()
 
-	expectedNumCells := (PageSize - unsafe.Sizeof(leafNodeHeader{})) / (unsafe.Sizeof(KeyType(0)) + dataSize)
+	expectedNumCells := (PageSize - unsafe.Sizeof(leafNodeHeader{})) / (keyLengthSize + testKeySize + dataSize)
 	assert.Equal(t, cellptr(expectedNumCells), leaf.getMaxNumCells(sizer))
 }
 
@@ -49,9 +49,9 @@ func TestLeafNode_insert_withCellSpaceRemaining(t *testing.T) {
 		dataSize = 11
 	)
 	var (
-		key1 = KeyType(9)
+		key1 = testKey(9)
 		val1 = []byte("hello world")
-		key2 = KeyType(10)
+		key2 = testKey(10)
 		val2 = []byte("hey world!!")
 	)
 	testWithLimitedTable(t, dataSize, func(t *testing.T, table *Table) {
@@ -65,7 +65,7 @@ func TestLeafNode_insert_withCellSpaceRemaining(t *testing.T) {
 		must(t, leaf.insert(cursor, key1, val1))
 
 		assert.Equal(t, cellptr(2), leaf.numCells)
-		expected := makeBytes(t, key1, val1, key2, val2)
+		expected := makeBytes(t, uint16(testKeySize), key1, val1, uint16(testKeySize), key2, val2)
 		assert.Equal(t, expected, leaf.cellData[:len(expected)])
 	})
 }
@@ -76,14 +76,14 @@ func TestLeafNodeInsert_withSpace(t *testing.T) {
 		leaf.init()
 
 		cursor := &Cursor{table: table, cellNum: 0}
-		must(t, leaf.insert(cursor, 6, makeUint64Value(0x66)))
+		must(t, leaf.insert(cursor, testKey(6), makeUint64Value(0x66)))
 		if !verifyCellData(t, table, leaf,
 			celldata{6, 0x66}) {
 			return
 		}
 
 		cursor = &Cursor{table: table, cellNum: 1}
-		must(t, leaf.insert(cursor, 8, makeUint64Value(0x88)))
+		must(t, leaf.insert(cursor, testKey(8), makeUint64Value(0x88)))
 		if !verifyCellData(t, table, leaf,
 			celldata{6, 0x66},
 			celldata{8, 0x88}) {
@@ -91,7 +91,7 @@ func TestLeafNodeInsert_withSpace(t *testing.T) {
 		}
 
 		cursor = &Cursor{table: table, cellNum: 1}
-		must(t, leaf.insert(cursor, 7, makeUint64Value(0x77)))
+		must(t, leaf.insert(cursor, testKey(7), makeUint64Value(0x77)))
 		if !verifyCellData(t, table, leaf,
 			celldata{6, 0x66},
 			celldata{7, 0x77},
@@ -112,7 +112,7 @@ func getUint64Value(b []byte) uint64 {
 }
 
 type celldata struct {
-	key   KeyType
+	key   uint32
 	value uint64
 }
 
@@ -121,14 +121,14 @@ func verifyCellData(t *testing.T, table *Table, leaf *leafNode, data ...celldata
 	result = assert.Equal(t, cellptr(len(data)), leaf.numCells)
 	for index, dat := range data {
 		cell := cellptr(index)
-		result = assert.Equal(t, dat.key, leaf.getCellKey(table, cell)) && result
+		result = assert.Equal(t, testKey(dat.key), leaf.getCellKey(table, cell)) && result
 		result = assert.Equal(t, dat.value, getUint64Value(leaf.getCellValue(table, cell))) && result
 	}
 	return result
 }
 
 func TestLeafNodeInsert_withoutSpace_insertLeftNode(t *testing.T) {
-	const size = leafNodeMaxCellData/3 - keySize
+	const size = leafNodeMaxCellData/3 - keyLengthSize - testKeySize
 
 	testWithLimitedTable(t, uint16(size), func(t *testing.T, table *Table) {
 		mustPage := func(pg PagePointer) *Page {
@@ -151,11 +151,11 @@ func TestLeafNodeInsert_withoutSpace_insertLeftNode(t *testing.T) {
 		}
 
 		cursor := &Cursor{table: table, cellNum: 0}
-		must(t, leaf.insert(cursor, 3, makeUint64Value(0x33)))
+		must(t, leaf.insert(cursor, testKey(3), makeUint64Value(0x33)))
 		cursor = &Cursor{table: table, cellNum: 1}
-		must(t, leaf.insert(cursor, 5, makeUint64Value(0x55)))
+		must(t, leaf.insert(cursor, testKey(5), makeUint64Value(0x55)))
 		cursor = &Cursor{table: table, cellNum: 2}
-		must(t, leaf.insert(cursor, 7, makeUint64Value(0x77)))
+		must(t, leaf.insert(cursor, testKey(7), makeUint64Value(0x77)))
 		if !verifyCellData(t, table, leaf,
 			celldata{3, 0x33},
 			celldata{5, 0x55},
@@ -164,16 +164,18 @@ func TestLeafNodeInsert_withoutSpace_insertLeftNode(t *testing.T) {
 			return
 		}
 
+		leftPageNum := table.rootPageNum
 		cursor = &Cursor{table: table, cellNum: 0}
-		must(t, leaf.insert(cursor, 1, makeUint64Value(0x11)))
-		leaf = mustLeaf(2)
+		must(t, leaf.insert(cursor, testKey(1), makeUint64Value(0x11)))
+		assert.NotEqual(t, leftPageNum, table.rootPageNum, "the root moves to a new branch")
+		leaf = mustLeaf(leftPageNum)
 		if !verifyCellData(t, table, leaf,
 			celldata{1, 0x11},
 			celldata{3, 0x33}) {
 			fmt.Println(leaf.String(table))
 			return
 		}
-		leaf = mustLeaf(1)
+		leaf = mustLeaf(leaf.nextLeaf)
 		if !verifyCellData(t, table, leaf,
 			celldata{5, 0x55},
 			celldata{7, 0x77}) {
@@ -184,7 +186,7 @@ func TestLeafNodeInsert_withoutSpace_insertLeftNode(t *testing.T) {
 }
 
 func TestLeafNodeInsert_withoutSpace_insertRightNode(t *testing.T) {
-	const size = leafNodeMaxCellData/3 - keySize
+	const size = leafNodeMaxCellData/3 - keyLengthSize - testKeySize
 
 	testWithLimitedTable(t, uint16(size), func(t *testing.T, table *Table) {
 		mustPage := func(pg PagePointer) *Page {
@@ -207,11 +209,11 @@ func TestLeafNodeInsert_withoutSpace_insertRightNode(t *testing.T) {
 		}
 
 		cursor := &Cursor{table: table, cellNum: 0}
-		must(t, leaf.insert(cursor, 3, makeUint64Value(0x33)))
+		must(t, leaf.insert(cursor, testKey(3), makeUint64Value(0x33)))
 		cursor = &Cursor{table: table, cellNum: 1}
-		must(t, leaf.insert(cursor, 5, makeUint64Value(0x55)))
+		must(t, leaf.insert(cursor, testKey(5), makeUint64Value(0x55)))
 		cursor = &Cursor{table: table, cellNum: 2}
-		must(t, leaf.insert(cursor, 7, makeUint64Value(0x77)))
+		must(t, leaf.insert(cursor, testKey(7), makeUint64Value(0x77)))
 		if !verifyCellData(t, table, leaf,
 			celldata{3, 0x33},
 			celldata{5, 0x55},
@@ -219,16 +221,18 @@ func TestLeafNodeInsert_withoutSpace_insertRightNode(t *testing.T) {
 			return
 		}
 
+		leftPageNum := table.rootPageNum
 		cursor = &Cursor{table: table, cellNum: 3}
-		must(t, leaf.insert(cursor, 9, makeUint64Value(0x99)))
-		leaf = mustLeaf(2)
+		must(t, leaf.insert(cursor, testKey(9), makeUint64Value(0x99)))
+		assert.NotEqual(t, leftPageNum, table.rootPageNum, "the root moves to a new branch")
+		leaf = mustLeaf(leftPageNum)
 		if !verifyCellData(t, table, leaf,
 			celldata{3, 0x33},
 			celldata{5, 0x55}) {
 			return
 		}
 
-		leaf = mustLeaf(1)
+		leaf = mustLeaf(leaf.nextLeaf)
 		if !verifyCellData(t, table, leaf,
 			celldata{7, 0x77},
 			celldata{9, 0x99}) {
@@ -0,0 +1,173 @@
+package db3
+
+import (
+	"encoding/binary"
+)
+
+// Leaves of tables with VariableDataSize, and all branches, hold cells of varying sizes in a
+// slotted layout. The cell data starts with the size of the cell content, followed by the
+// offset of each cell in order of their keys. The cell content fills the end of the cell data:
+//
+//	[content size][slot 0][slot 1]...[free space]...[cell 1][cell 0]
+//
+// The content is compacted whenever a cell is removed, so the free space is contiguous.
+// A cell of a slotted leaf is {uint16(key length), key, uint32(value size), value}. Values
+// too large to fit in a cell keep a prefix in the cell, followed by the first of the
+// overflow pages holding the rest of the value.
+
+const (
+	// slottedHeaderSize is the size of the content size at the start of the cell data.
+	slottedHeaderSize = 2
+	// slotSize is the size of the offset of a cell.
+	slotSize = 2
+	// slottedCellHeaderSize is the size of the key length and value size of a cell of a slotted leaf.
+	slottedCellHeaderSize = keyLengthSize + 4
+	// slottedMaxCellData is the amount of cell data of a slotted leaf reserved for slots and cells.
+	slottedMaxCellData = leafNodeMaxCellData - slottedHeaderSize
+	// slottedMaxCellSize is the largest size of a cell of a slotted leaf and its slot,
+	// so that a leaf holds at least four cells.
+	slottedMaxCellSize = slottedMaxCellData / 4
+)
+
+// slottedCells is cell data in a slotted layout.
+type slottedCells []byte
+
+// getCapacity returns the amount of cell data reserved for slots and cells.
+func (s slottedCells) getCapacity() uintptr {
+	return uintptr(len(s)) - slottedHeaderSize
+}
+
+// getContentSize returns the size of the cell content.
+func (s slottedCells) getContentSize() uintptr {
+	return uintptr(binary.LittleEndian.Uint16(s))
+}
+
+// setContentSize sets the size of the cell content.
+func (s slottedCells) setContentSize(size uintptr) {
+	binary.LittleEndian.PutUint16(s, uint16(size))
+}
+
+// getSlot returns the offset of the cell at the given index.
+func (s slottedCells) getSlot(index cellptr) uintptr {
+	return uintptr(binary.LittleEndian.Uint16(s[slottedHeaderSize+uintptr(index)*slotSize:]))
+}
+
+// setSlot sets the offset of the cell at the given index.
+func (s slottedCells) setSlot(index cellptr, offset uintptr) {
+	binary.LittleEndian.PutUint16(s[slottedHeaderSize+uintptr(index)*slotSize:], uint16(offset))
+}
+
+// getUsedSpace returns the amount of cell data used by the slots and cells.
+func (s slottedCells) getUsedSpace(numCells cellptr) uintptr {
+	return uintptr(numCells)*slotSize + s.getContentSize()
+}
+
+// insert inserts the bytes of a cell at a position, given the number of cells before the insertion.
+// There must be space for the cell and its slot.
+func (s slottedCells) insert(numCells, pos cellptr, bin []byte) {
+	if makeAssertions {
+		_assert(s.getUsedSpace(numCells)+slotSize+uintptr(len(bin)) <= s.getCapacity(), "slotted cells too full for insert")
+	}
+
+	offset := s.addContent(bin)
+
+	// Slide the slots to the right to make room for the slot of the cell.
+	slots := s[slottedHeaderSize:]
+	start, end := uintptr(pos)*slotSize, uintptr(numCells)*slotSize
+	copy(slots[start+slotSize:end+slotSize], slots[start:end])
+	s.setSlot(pos, offset)
+}
+
+// remove removes the cell of a given size at a position, given the number of cells before the
+// removal, compacting the content.
+func (s slottedCells) remove(numCells, pos cellptr, size uintptr) {
+	s.removeContent(numCells, pos, size)
+
+	// Slide the slots after the cell to the left.
+	slots := s[slottedHeaderSize:]
+	start, end := uintptr(pos)*slotSize, uintptr(numCells)*slotSize
+	copy(slots[start:end-slotSize], slots[start+slotSize:end])
+}
+
+// replace replaces the cell of a given size at a position with the bytes of another cell,
+// keeping its slot, given the number of cells. There must be space for the new cell once
+// the old cell is removed.
+func (s slottedCells) replace(numCells, pos cellptr, size uintptr, bin []byte) {
+	if makeAssertions {
+		_assert(s.getUsedSpace(numCells)-size+uintptr(len(bin)) <= s.getCapacity(), "slotted cells too full for replace")
+	}
+
+	s.removeContent(numCells, pos, size)
+	s.setSlot(pos, s.addContent(bin))
+}
+
+// addContent adds the bytes of a cell to the start of the content, returning its offset.
+func (s slottedCells) addContent(bin []byte) uintptr {
+	contentSize := s.getContentSize() + uintptr(len(bin))
+	offset := uintptr(len(s)) - contentSize
+	copy(s[offset:], bin)
+	s.setContentSize(contentSize)
+	return offset
+}
+
+// removeContent removes the bytes of the cell of a given size at a position from the content,
+// given the number of cells, sliding the content before the cell over it. The slot of the cell
+// is left unchanged.
+func (s slottedCells) removeContent(numCells, pos cellptr, size uintptr) {
+	offset := s.getSlot(pos)
+	contentSize := s.getContentSize()
+	contentStart := uintptr(len(s)) - contentSize
+	copy(s[contentStart+size:offset+size], s[contentStart:offset])
+	s.setContentSize(contentSize - size)
+	for index := cellptr(0); index < numCells; index++ {
+		if slot := s.getSlot(index); slot < offset {
+			s.setSlot(index, slot+size)
+		}
+	}
+}
+
+// getMaxInlineValueSize returns the largest value with a key of a given length
+// that is held entirely in its cell.
+func getMaxInlineValueSize(keySize int) int {
+	return int(slottedMaxCellSize-slotSize-slottedCellHeaderSize) - keySize
+}
+
+// getOverflowPrefixSize returns the size of the prefix of a value with a key of a given length
+// held in its cell when the rest of the value is held in overflow pages.
+func getOverflowPrefixSize(keySize int) int {
+	return getMaxInlineValueSize(keySize) - int(pagePointerSize)
+}
+
+// slottedCellSize returns the size of the cell for a key and value of given sizes, excluding its slot.
+func slottedCellSize(keySize, valueSize int) int {
+	if maxInlineValueSize := getMaxInlineValueSize(keySize); valueSize > maxInlineValueSize {
+		valueSize = maxInlineValueSize
+	}
+	return int(slottedCellHeaderSize) + keySize + valueSize
+}
+
+// getSlotted returns the cell data of a slotted leaf.
+func (n *leafNode) getSlotted() slottedCells {
+	return n.cellData[:]
+}
+
+// getSlottedCellBin returns the bytes of the cell at the given index of a slotted leaf.
+func (n *leafNode) getSlottedCellBin(index cellptr) []byte {
+	if makeAssertions {
+		_assert(index < n.numCells, "tried to access cell %d >= numCells %d", index, n.numCells)
+	}
+
+	offset := n.getSlotted().getSlot(index)
+	cell := n.cellData[offset:]
+	return cell[:slottedCellSize(keyLength(cell), getBinValueSize(cell))]
+}
+
+// getBinValueSize returns the size of the value of a cell of a slotted leaf.
+func getBinValueSize(bin []byte) int {
+	return int(binary.LittleEndian.Uint32(bin[keyLengthSize+keyLength(bin):]))
+}
+
+// putBinValueSize writes the size of the value of a cell of a slotted leaf after its key.
+func putBinValueSize(bin []byte, size int) {
+	binary.LittleEndian.PutUint32(bin[keyLengthSize+keyLength(bin):], uint32(size))
+}
@@ -0,0 +1,79 @@
+package db3
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLeafNode_slottedInsertRemove(t *testing.T) {
+	sizer := dataSizer{testKeySize, VariableDataSize}
+	leaf := &leafNode{}
+	leaf.init()
+
+	cell := func(key KeyType, value string) []byte {
+		bin := make([]byte, slottedCellSize(len(key), len(value)))
+		putKeyLength(bin, key)
+		copy(bin[keyLengthSize:], key)
+		putBinValueSize(bin, len(value))
+		copy(bin[slottedCellHeaderSize+uintptr(len(key)):], value)
+		return bin
+	}
+	cells := func() map[string]string {
+		values := make(map[string]string)
+		for index := cellptr(0); index < leaf.numCells; index++ {
+			key, value := leaf.getCell(sizer, index)
+			values[string(key)] = string(value)
+		}
+		return values
+	}
+
+	leaf.insertCellBin(sizer, 0, cell(KeyType("c"), "three"))
+	leaf.insertCellBin(sizer, 0, cell(KeyType("a"), "one"))
+	leaf.insertCellBin(sizer, 1, cell(KeyType("bb"), ""))
+	leaf.insertCellBin(sizer, 3, cell(KeyType("dddd"), "four"))
+	assert.Equal(t, cellptr(4), leaf.numCells)
+	assert.Equal(t, []KeyType{KeyType("a"), KeyType("bb"), KeyType("c"), KeyType("dddd")}, leafKeys(sizer, leaf))
+	assert.Equal(t, map[string]string{"a": "one", "bb": "", "c": "three", "dddd": "four"}, cells())
+	used := 4*(slotSize+slottedCellHeaderSize) + uintptr(len("abbcdddd")) + uintptr(len("onethreefour"))
+	assert.Equal(t, used, leaf.getUsedSpace(sizer))
+
+	leaf.removeDirect(sizer, 2)
+	leaf.removeDirect(sizer, 0)
+	assert.Equal(t, []KeyType{KeyType("bb"), KeyType("dddd")}, leafKeys(sizer, leaf))
+	assert.Equal(t, map[string]string{"bb": "", "dddd": "four"}, cells())
+	assert.Equal(t, 2*(slotSize+slottedCellHeaderSize)+uintptr(len("bbdddd")+len("four")), leaf.getUsedSpace(sizer))
+	slotted := leaf.getSlotted()
+	contentStart := slotted.getSlot(0)
+	if slot := slotted.getSlot(1); slot < contentStart {
+		contentStart = slot
+	}
+	assert.Equal(t, leafNodeMaxCellData-slotted.getContentSize(), contentStart, "content is compacted")
+}
+
+func TestLeafNode_slottedHasRoomFor(t *testing.T) {
+	sizer := dataSizer{KeySizeLimit, VariableDataSize}
+	leaf := &leafNode{}
+	leaf.init()
+
+	// A leaf holds at least four of the largest cells.
+	key := make(KeyType, KeySizeLimit)
+	largest := make([]byte, slottedCellSize(len(key), getMaxInlineValueSize(len(key))))
+	putKeyLength(largest, key)
+	putBinValueSize(largest, getMaxInlineValueSize(len(key)))
+	for index := cellptr(0); index < 4; index++ {
+		if !assert.True(t, leaf.hasRoomFor(sizer, len(largest)), "cell %d", index) {
+			return
+		}
+		largest[keyLengthSize] = byte(index)
+		leaf.insertCellBin(sizer, index, largest)
+	}
+	assert.False(t, leaf.hasRoomFor(sizer, slottedCellSize(0, 0)))
+}
+
+func leafKeys(sizer DataSizer, leaf *leafNode) []KeyType {
+	keys := make([]KeyType, leaf.numCells)
+	for index := range keys {
+		keys[index] = leaf.getCellKey(sizer, cellptr(index))
+	}
+	return keys
+}
@@ -1,7 +1,9 @@
 package db3
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
+	"math/rand"
 	"testing"
 	"unsafe"
 )
@@ -11,15 +13,47 @@ func TestBtreeSizes(t *testing.T) {
 	assert.True(t, unsafe.Sizeof(leafNode{}) <= PageSize, "A leaf node should be able to fit in a single page.")
 }
 
-func TestKeyFromBytes(t *testing.T) {
-	bin := []byte{0xff, 0xee, 0xdd, 0xcc}
-	key := keyFromBytes(bin)
-	assert.Equal(t, KeyType(0xccddeeff), key)
+func TestSeparatorKey(t *testing.T) {
+	cases := []struct {
+		name        string
+		left, right string
+		expected    string
+	}{
+		{"truncated", "abcx", "abz", "abd"},
+		{"next-byte", "abxyz", "abyz", "aby"},
+		{"adjacent-bytes", "abcx", "abd", "abcx"},
+		{"last-byte", "abc", "abd", "abc"},
+		{"prefix", "ab", "abc", "ab"},
+		{"empty", "", "a", ""},
+		{"first-byte", "a\xff\xff", "c", "b"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, string(separatorKey(KeyType(tc.left), KeyType(tc.right))))
+		})
+	}
 }
 
-func TestKey_encodeToBytes(t *testing.T) {
-	bin := make([]byte, unsafe.Sizeof(KeyType(0)))
-	key := KeyType(0xccddeeff)
-	encodeKeyToBytes(key, bin)
-	assert.Equal(t, []byte{0xff, 0xee, 0xdd, 0xcc}, bin)
+func TestSeparatorKey_random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomKey := func() KeyType {
+		key := make(KeyType, r.Intn(6))
+		for index := range key {
+			key[index] = byte(r.Intn(3)) + 0xfd*byte(r.Intn(2))
+		}
+		return key
+	}
+	for i := 0; i < 10000; i++ {
+		left, right := randomKey(), randomKey()
+		if bytes.Compare(left, right) > 0 {
+			left, right = right, left
+		} else if bytes.Equal(left, right) {
+			continue
+		}
+		separator := separatorKey(left, right)
+		if !assert.True(t, bytes.Compare(left, separator) <= 0 && bytes.Compare(separator, right) < 0, "%x <= %x < %x", left, separator, right) ||
+			!assert.True(t, len(separator) <= len(left), "%x is longer than %x", separator, left) {
+			return
+		}
+	}
 }
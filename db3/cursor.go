@@ -19,10 +19,11 @@ type Cursor struct {
 }
 
 // Value gets the value pointed to by this cursor.
+// The key and value are only valid until the table changes.
 func (c *Cursor) Value() (key KeyType, value []byte, err error) {
 	// Return any previous error we've encountered.
 	if c.advanceError != nil {
-		return zeroKey, nil, c.advanceError
+		return nil, nil, c.advanceError
 	}
 
 	// Get the current page.
@@ -30,16 +31,22 @@ func (c *Cursor) Value() (key KeyType, value []byte, err error) {
 	if err != nil {
 		// Save this error.
 		c.advanceError = errors.Wrap(err, "unable to get page")
-		return zeroKey, nil, c.advanceError
+		return nil, nil, c.advanceError
 	}
 
 	// We always point to a leaf node.
 	leaf := pageToLeafNode(page)
 
 	//  Get the cell data.
-	key, value = leaf.getCell(c.table, c.cellNum)
+	bin := leaf.getCellBin(c.table, c.cellNum)
+	value, err = c.table.cellValue(bin)
+	if err != nil {
+		// Save this error.
+		c.advanceError = errors.Wrap(err, "unable to get value")
+		return nil, nil, c.advanceError
+	}
 
-	return key, value, nil
+	return getBinKey(bin), value, nil
 }
 
 // Next advances the cursor to the next position.
@@ -51,6 +51,15 @@ func _setMaxKeysPerBranchOverride(n int) error {
 	return nil
 }
 
+func _clearMaxKeysPerBranchOverride() {
+	if !debug {
+		panic("not available outside of debug mode")
+	}
+	if err := os.Unsetenv(envBranchMaxCellsEnv); err != nil {
+		panic(err)
+	}
+}
+
 func _maxKeysPerBranchOverride() cellptr {
 	const (
 		defaultBranchNodeMaxCells = cellptr(branchNodeMaxCells)
@@ -0,0 +1,157 @@
+package db3
+
+import (
+	"context"
+	"explodes/github.com/binq"
+	"fmt"
+)
+
+// QueryKeyRanges derives the ranges of keys that records must have to match a query, and the
+// residual predicate that remains to be evaluated for records within the ranges, as
+// binq.ExtractKeyRanges does. Comparisons of components of keys that are unsigned big-endian
+// integers, such as the tenant and timestamp of KEY(0, U32BE) and KEY(4, U64BE), are ordered like
+// the keys themselves, so they narrow the ranges of keys that are visited.
+func QueryKeyRanges(query *binq.Query) ([]KeyRange, *binq.Predicate, error) {
+	keyRanges, residual, err := binq.ExtractKeyRanges(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ranges []KeyRange
+	for _, r := range keyRanges {
+		ranges = append(ranges, KeyRange{Start: r.Start, End: r.End, MinLength: r.MinLength})
+	}
+	return ranges, residual, nil
+}
+
+// InvalidQueryError is the error of Execute for a query that cannot be executed,
+// such as one whose predicate is not well typed.
+type InvalidQueryError struct {
+	// Err is the problem with the query.
+	Err error
+}
+
+func (e *InvalidQueryError) Error() string {
+	return fmt.Sprintf("invalid query: %v", e.Err)
+}
+
+// ResultIterator streams the records matched by a query.
+type ResultIterator interface {
+	// Value gets the key and value of the current result.
+	// The value is only valid until the iterator advances.
+	Value() (key KeyType, value []byte, err error)
+	// Next advances the iterator to the next result.
+	Next()
+	// End indicates if this iterator can no longer advance.
+	End() bool
+	// Err returns the error that ended this iterator, if any.
+	Err() error
+}
+
+var _ ResultIterator = (*queryIterator)(nil)
+
+// queryIterator is a ResultIterator that filters the rows of a RangeCursor.
+type queryIterator struct {
+	// ctx cancels the iteration.
+	ctx context.Context
+	// cursor navigates the rows within the key ranges of the query.
+	cursor *RangeCursor
+	// matcher matches the rows with the residual predicate of the query.
+	matcher binq.RecordMatcher
+	// limit is the maximum number of results, or 0 for no limit.
+	limit uint64
+	// count is the number of results reached so far, including the current one.
+	count uint64
+	// done indicates that the limit was reached.
+	done bool
+	// err stores any error encountered when advancing.
+	err error
+}
+
+// Execute runs a query against a table, returning an iterator of the records
+// with keys from the start of the query, inclusive, to its end, exclusive,
+// that match its predicate. Only the leaves holding keys within the ranges
+// of QueryKeyRanges are visited. Queries that cannot be executed return an *InvalidQueryError.
+func Execute(ctx context.Context, table *Table, query *binq.Query) (ResultIterator, error) {
+	ranges, residual, err := QueryKeyRanges(query)
+	if err != nil {
+		return nil, &InvalidQueryError{Err: err}
+	}
+	matcher, err := binq.PredicateToRecordMatcher(residual)
+	if err != nil {
+		return nil, wrap(err, "unable to compile predicate")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, wrap(err, "query cancelled")
+	}
+	cursor, err := table.SeekRanges(ranges)
+	if err != nil {
+		return nil, wrap(err, "unable to seek")
+	}
+	it := &queryIterator{
+		ctx:     ctx,
+		cursor:  cursor,
+		matcher: matcher,
+		limit:   query.GetQueryOptions().GetLimit(),
+	}
+	it.settle()
+	return it, nil
+}
+
+// settle moves the cursor forward to the first row that matches the predicate.
+func (it *queryIterator) settle() {
+	for ; !it.cursor.End(); it.cursor.Next() {
+		if err := it.ctx.Err(); err != nil {
+			it.err = wrap(err, "query cancelled")
+			return
+		}
+		key, value, err := it.cursor.Value()
+		if err != nil {
+			it.err = wrap(err, "unable to get row")
+			return
+		}
+		matches, err := it.matcher.MatchRecord(key, value)
+		if err != nil {
+			it.err = wrap(err, "unable to match row")
+			return
+		}
+		if matches {
+			it.count++
+			return
+		}
+	}
+	if err := it.cursor.Err(); err != nil {
+		it.err = wrap(err, "unable to advance")
+	}
+}
+
+// Value gets the key and value of the current result.
+func (it *queryIterator) Value() (key KeyType, value []byte, err error) {
+	if it.err != nil {
+		return nil, nil, it.err
+	}
+	// Recursive call, do not wrap error.
+	return it.cursor.Value()
+}
+
+// Next advances the iterator to the next result.
+func (it *queryIterator) Next() {
+	if it.End() {
+		return
+	}
+	if it.limit > 0 && it.count >= it.limit {
+		it.done = true
+		return
+	}
+	it.cursor.Next()
+	it.settle()
+}
+
+// End indicates if this iterator can no longer advance.
+func (it *queryIterator) End() bool {
+	return it.err != nil || it.done || it.cursor.End()
+}
+
+// Err returns the error that ended this iterator, if any.
+func (it *queryIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,196 @@
+package db3
+
+import (
+	"context"
+	"encoding/binary"
+	"explodes/github.com/binq"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func resultKeys(t *testing.T, it ResultIterator) []KeyType {
+	t.Helper()
+	var keys []KeyType
+	for ; !it.End(); it.Next() {
+		key, value, err := it.Value()
+		must(t, err)
+		assert.True(t, parseSentinelValue(t, value).wellFormed(t, testKeyNumber(key)))
+		keys = append(keys, append(KeyType(nil), key...))
+	}
+	must(t, it.Err())
+	return keys
+}
+
+func TestExecute(t *testing.T) {
+	cases := []struct {
+		name       string
+		start, end []byte
+		limit      uint64
+		predicate  string
+		expected   []KeyType
+	}{
+		{"all", nil, nil, 0, "", testKeys(10, 20, 30, 40, 50)},
+		{"start", testKey(20), nil, 0, "", testKeys(20, 30, 40, 50)},
+		{"end-exclusive", nil, testKey(40), 0, "", testKeys(10, 20, 30)},
+		{"start-end", testKey(15), testKey(45), 0, "", testKeys(20, 30, 40)},
+		{"start-prefix", testKey(20)[:3], testKey(20)[:3], 0, "", nil},
+		{"limit", nil, nil, 2, "", testKeys(10, 20)},
+		{"limit-beyond", nil, nil, 10, "", testKeys(10, 20, 30, 40, 50)},
+		{"key-range", nil, nil, 0, "KEY(0, U32BE) > U32(20) AND KEY(0, U32BE) <= U32(40)", testKeys(30, 40)},
+		{"key-range-start", testKey(35), nil, 0, "KEY(0, U32BE) > U32(20) AND KEY(0, U32BE) <= U32(40)", testKeys(40)},
+		{"value", nil, nil, 0, "VALUE(0, U32LE) % U32(20) = U32(0)", testKeys(20, 40)},
+		{"residual-limit", testKey(20), nil, 1, "VALUE(0, U32LE) != U32(20) AND KEY(0, U32BE) != U32(30)", testKeys(40)},
+		{"keys", nil, nil, 0, "KEY(0, U32BE) = U32(10) OR KEY(0, U32BE) = U32(50) OR KEY(0, U32BE) = U32(60)", testKeys(10, 50)},
+		{"none", nil, nil, 0, "KEY(0, U32BE) > U32(50)", nil},
+	}
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		insertKeys(t, table, rangeNumKeys)
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				query := &binq.Query{Start: tc.start, End: tc.end, QueryOptions: &binq.Options{Limit: tc.limit}}
+				if tc.predicate != "" {
+					pred, err := binq.NewParser(tc.predicate).ReadPredicate()
+					must(t, err)
+					query.Predicate = pred
+				}
+				it, err := Execute(context.Background(), table, query)
+				must(t, err)
+				assert.Equal(t, tc.expected, resultKeys(t, it))
+			})
+		}
+	})
+}
+
+func TestExecute_errors(t *testing.T) {
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		insertKeys(t, table, rangeNumKeys)
+
+		pred, err := binq.NewParser("VALUE(0, U32LE) / (VALUE(0, U32LE) - U32(30)) > U32(0)").ReadPredicate()
+		must(t, err)
+		it, err := Execute(context.Background(), table, &binq.Query{Predicate: pred})
+		must(t, err)
+		assert.True(t, it.End(), "division by zero at key 30")
+		assert.Error(t, it.Err())
+		_, _, err = it.Value()
+		assert.Error(t, err)
+
+		_, err = Execute(context.Background(), table, &binq.Query{Predicate: &binq.Predicate{}})
+		assert.IsType(t, &InvalidQueryError{}, err, "invalid predicate")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		it, err = Execute(ctx, table, &binq.Query{})
+		must(t, err)
+		assert.False(t, it.End())
+		cancel()
+		it.Next()
+		assert.True(t, it.End())
+		assert.Equal(t, context.Canceled, errors.Cause(it.Err()))
+
+		_, err = Execute(ctx, table, &binq.Query{})
+		assert.Equal(t, context.Canceled, errors.Cause(err))
+	})
+}
+
+func TestQueryKeyRanges(t *testing.T) {
+	cases := []struct {
+		name       string
+		start, end []byte
+		predicate  string
+		expected   []KeyRange
+	}{
+		{"all", nil, nil, "", []KeyRange{{}}},
+		{"bounds", []byte{1}, []byte{2, 3}, "", []KeyRange{{Start: []byte{1}, End: []byte{2, 3}}}},
+		{"value", nil, nil, "VALUE(0, U32LE) = U32(1)", []KeyRange{{}}},
+		{"prefix", nil, nil, "KEY(0, U32BE) = U32(2)", []KeyRange{{Start: testKey(2), End: testKey(3), MinLength: testKeySize}}},
+		{"prefix-max", nil, nil, "KEY(0, U32BE) >= U32(4294967295)", []KeyRange{{Start: testKey(math.MaxUint32), MinLength: testKeySize}}},
+		{"prefixes", nil, nil, "KEY(0, U32BE) < U32(2) OR KEY(0, U32BE) > U32(5)", []KeyRange{
+			{Start: testKey(0), End: testKey(2), MinLength: testKeySize},
+			{Start: testKey(6), MinLength: testKeySize},
+		}},
+		{"prefixes-bounds", []byte{0, 0, 0, 1, 7}, testKey(7), "KEY(0, U32BE) < U32(2) OR KEY(0, U32BE) > U32(5)", []KeyRange{
+			{Start: []byte{0, 0, 0, 1, 7}, End: testKey(2), MinLength: testKeySize},
+			{Start: testKey(6), End: testKey(7), MinLength: testKeySize},
+		}},
+		{"disjoint", testKey(3), nil, "KEY(0, U32BE) < U32(2)", nil},
+		{"composite", nil, nil, "KEY(0, U32BE) = U32(2) AND KEY(4, U64BE) >= U64(200)", []KeyRange{
+			{Start: compositeKey(2, 200), End: testKey(3), MinLength: 12},
+		}},
+		{"composites", nil, nil, "(KEY(0, U32BE) = U32(1) OR KEY(0, U32BE) = U32(3)) AND KEY(4, U64BE) < U64(200)", []KeyRange{
+			{Start: compositeKey(1, 0), End: compositeKey(1, 200), MinLength: 12},
+			{Start: compositeKey(3, 0), End: compositeKey(3, 200), MinLength: 12},
+		}},
+		{"composite-range", nil, nil, "KEY(0, U32BE) >= U32(2) AND KEY(4, U64BE) < U64(200)", []KeyRange{
+			{Start: testKey(2), MinLength: testKeySize},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := &binq.Query{Start: tc.start, End: tc.end}
+			if tc.predicate != "" {
+				pred, err := binq.NewParser(tc.predicate).ReadPredicate()
+				must(t, err)
+				query.Predicate = pred
+			}
+			ranges, _, err := QueryKeyRanges(query)
+			must(t, err)
+			assert.Equal(t, tc.expected, ranges)
+		})
+	}
+
+	_, _, err := QueryKeyRanges(&binq.Query{Predicate: &binq.Predicate{}})
+	assert.Error(t, err, "invalid predicate")
+}
+
+// compositeKey returns a key made of a tenant followed by a timestamp,
+// so that the keys of each tenant are ordered by time.
+func compositeKey(tenant uint32, timestamp uint64) KeyType {
+	key := make(KeyType, 12)
+	binary.BigEndian.PutUint32(key, tenant)
+	binary.BigEndian.PutUint64(key[4:], timestamp)
+	return key
+}
+
+func TestExecute_compositeKeys(t *testing.T) {
+	cases := []struct {
+		name       string
+		start, end []byte
+		predicate  string
+		expected   []KeyType
+	}{
+		{"tenant", nil, nil, "KEY(0, U32BE) = U32(2)", []KeyType{compositeKey(2, 100), compositeKey(2, 200), compositeKey(2, 300)}},
+		{"tenant-time", nil, nil, "KEY(0, U32BE) = U32(2) AND KEY(4, U64BE) >= U64(200)", []KeyType{compositeKey(2, 200), compositeKey(2, 300)}},
+		{"tenants", nil, nil, "KEY(0, U32BE) >= U32(2) AND KEY(4, U64BE) < U64(200)", []KeyType{compositeKey(2, 100), compositeKey(3, 100)}},
+		{"time-bounds", compositeKey(1, 200), compositeKey(2, 200), "", []KeyType{compositeKey(1, 200), compositeKey(1, 300), compositeKey(2, 100)}},
+		{"tenant-bounds", compositeKey(1, 200), compositeKey(2, 200), "KEY(0, U32BE) = U32(2)", []KeyType{compositeKey(2, 100)}},
+	}
+	must(t, _setMaxKeysPerBranchOverride(maxKeys))
+	testWithTable(t, 12, 8, func(t *testing.T, table *Table) {
+		for tenant := uint32(1); tenant <= 3; tenant++ {
+			for timestamp := uint64(100); timestamp <= 300; timestamp += 100 {
+				must(t, table.Insert(compositeKey(tenant, timestamp), make([]byte, 8)))
+			}
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				query := &binq.Query{Start: tc.start, End: tc.end}
+				if tc.predicate != "" {
+					pred, err := binq.NewParser(tc.predicate).ReadPredicate()
+					must(t, err)
+					query.Predicate = pred
+				}
+				it, err := Execute(context.Background(), table, query)
+				must(t, err)
+				var keys []KeyType
+				for ; !it.End(); it.Next() {
+					key, _, err := it.Value()
+					must(t, err)
+					keys = append(keys, append(KeyType(nil), key...))
+				}
+				must(t, it.Err())
+				assert.Equal(t, tc.expected, keys)
+			})
+		}
+	})
+}
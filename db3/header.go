@@ -0,0 +1,96 @@
+package db3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"hash/crc32"
+)
+
+// The first page of a database file is a header describing the layout of the file,
+// so that a file is only opened as a table with the layout it was created with:
+//
+//	[magic][version][page size][max key size][data size][root page][free list head][checksum]
+//
+// The checksum is the CRC-32 (IEEE) of the header before it.
+
+const (
+	// headerPageNum is the page holding the header. It is never a node or free,
+	// so page pointers of 0 mark the absence of a page.
+	headerPageNum PagePointer = 0
+	// fileFormatVersion is the version of the layout of database files.
+	fileFormatVersion = 1
+)
+
+// fileMagic identifies database files.
+var fileMagic = [8]byte{'b', 'i', 'n', 'q', 'd', 'b', '3', 0}
+
+const (
+	headerVersionOffset      = uintptr(len(fileMagic))
+	headerPageSizeOffset     = headerVersionOffset + 4
+	headerMaxKeySizeOffset   = headerPageSizeOffset + 4
+	headerDataSizeOffset     = headerMaxKeySizeOffset + 2
+	headerRootPageNumOffset  = headerDataSizeOffset + 2
+	headerFreeListHeadOffset = headerRootPageNumOffset + pagePointerSize
+	headerChecksumOffset     = headerFreeListHeadOffset + pagePointerSize
+)
+
+// fileHeader is the contents of the header page.
+type fileHeader struct {
+	// version is the version of the layout of the file.
+	version uint32
+	// pageSize is the size of the pages of the file.
+	pageSize uint32
+	// maxKeySize is the maximum length of keys of the table.
+	maxKeySize uint16
+	// dataSize is the size of values of the table, or VariableDataSize.
+	dataSize uint16
+	// rootPageNum is the page of the root node of the table.
+	rootPageNum PagePointer
+	// freeListHead is the most recently freed page, or 0 if no page is free.
+	freeListHead PagePointer
+}
+
+// write writes the header to a page.
+func (h *fileHeader) write(page *Page) {
+	copy(page[:], fileMagic[:])
+	binary.LittleEndian.PutUint32(page[headerVersionOffset:], h.version)
+	binary.LittleEndian.PutUint32(page[headerPageSizeOffset:], h.pageSize)
+	binary.LittleEndian.PutUint16(page[headerMaxKeySizeOffset:], h.maxKeySize)
+	binary.LittleEndian.PutUint16(page[headerDataSizeOffset:], h.dataSize)
+	binary.LittleEndian.PutUint32(page[headerRootPageNumOffset:], h.rootPageNum)
+	binary.LittleEndian.PutUint32(page[headerFreeListHeadOffset:], h.freeListHead)
+	binary.LittleEndian.PutUint32(page[headerChecksumOffset:], crc32.ChecksumIEEE(page[:headerChecksumOffset]))
+}
+
+// readHeader reads and verifies the header of a file of a number of pages from its first page.
+func readHeader(page *Page, numPages PagePointer) (fileHeader, error) {
+	if !bytes.HasPrefix(page[:], fileMagic[:]) {
+		return fileHeader{}, errors.New("not a database file: missing magic number")
+	}
+	checksum := binary.LittleEndian.Uint32(page[headerChecksumOffset:])
+	if expected := crc32.ChecksumIEEE(page[:headerChecksumOffset]); checksum != expected {
+		return fileHeader{}, errors.Errorf("file corruption: header checksum %08x, want %08x", checksum, expected)
+	}
+	h := fileHeader{
+		version:      binary.LittleEndian.Uint32(page[headerVersionOffset:]),
+		pageSize:     binary.LittleEndian.Uint32(page[headerPageSizeOffset:]),
+		maxKeySize:   binary.LittleEndian.Uint16(page[headerMaxKeySizeOffset:]),
+		dataSize:     binary.LittleEndian.Uint16(page[headerDataSizeOffset:]),
+		rootPageNum:  binary.LittleEndian.Uint32(page[headerRootPageNumOffset:]),
+		freeListHead: binary.LittleEndian.Uint32(page[headerFreeListHeadOffset:]),
+	}
+	if h.version != fileFormatVersion {
+		return fileHeader{}, errors.Errorf("unsupported file format version %d, want %d", h.version, fileFormatVersion)
+	}
+	if h.pageSize != PageSize {
+		return fileHeader{}, errors.Errorf("page size %d, want %d", h.pageSize, PageSize)
+	}
+	if h.rootPageNum == headerPageNum || h.rootPageNum >= numPages {
+		return fileHeader{}, errors.Errorf("file corruption: root page %d of %d pages", h.rootPageNum, numPages)
+	}
+	if h.freeListHead >= numPages {
+		return fileHeader{}, errors.Errorf("file corruption: free page %d of %d pages", h.freeListHead, numPages)
+	}
+	return h, nil
+}
@@ -0,0 +1,72 @@
+package db3
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReadHeader(t *testing.T) {
+	const numPages = 10
+	valid := fileHeader{
+		version:      fileFormatVersion,
+		pageSize:     PageSize,
+		maxKeySize:   12,
+		dataSize:     VariableDataSize,
+		rootPageNum:  3,
+		freeListHead: 9,
+	}
+	cases := []struct {
+		name   string
+		modify func(page *Page)
+		header fileHeader
+		valid  bool
+	}{
+		{"valid", func(page *Page) {}, valid, true},
+		{"empty", func(page *Page) { *page = Page{} }, fileHeader{}, false},
+		{"magic", func(page *Page) { page[0] = 'B' }, fileHeader{}, false},
+		{"checksum", func(page *Page) { page[headerMaxKeySizeOffset]++ }, fileHeader{}, false},
+		{"version", func(page *Page) {
+			h := valid
+			h.version++
+			h.write(page)
+		}, fileHeader{}, false},
+		{"page-size", func(page *Page) {
+			h := valid
+			h.pageSize = 2 * PageSize
+			h.write(page)
+		}, fileHeader{}, false},
+		{"root-header", func(page *Page) {
+			h := valid
+			h.rootPageNum = headerPageNum
+			h.write(page)
+		}, fileHeader{}, false},
+		{"root-beyond", func(page *Page) {
+			h := valid
+			h.rootPageNum = numPages
+			h.write(page)
+		}, fileHeader{}, false},
+		{"free-beyond", func(page *Page) {
+			h := valid
+			h.freeListHead = numPages
+			h.write(page)
+		}, fileHeader{}, false},
+		{"after-checksum", func(page *Page) {
+			binary.LittleEndian.PutUint32(page[headerChecksumOffset+4:], 1)
+		}, valid, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page := new(Page)
+			valid.write(page)
+			tc.modify(page)
+			header, err := readHeader(page, numPages)
+			if tc.valid {
+				must(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assert.Equal(t, tc.header, header)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package db3
+
+import (
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"unsafe"
+)
+
+const (
+	// pagePointerSize is the size of a PagePointer.
+	pagePointerSize = unsafe.Sizeof(PagePointer(0))
+	// overflowPageDataSize is the amount of a value held in an overflow page.
+	// An overflow page is {PagePointer(next), [overflowPageDataSize]byte},
+	// where a next page of 0 ends the chain.
+	overflowPageDataSize = PageSize - pagePointerSize
+)
+
+// newCellBin returns the bytes of the cell for a key-value. The part of a value that does
+// not fit in a cell of a slotted leaf is written to a new chain of overflow pages.
+func (t *Table) newCellBin(key KeyType, value []byte) ([]byte, error) {
+	if !isSlotted(t) {
+		bin := make([]byte, fixedCellSize(t))
+		putKeyLength(bin, key)
+		copy(bin[keyLengthSize:], key)
+		copy(bin[keyLengthSize+uintptr(t.maxKeySize):], value)
+		return bin, nil
+	}
+
+	bin := make([]byte, slottedCellSize(len(key), len(value)))
+	putKeyLength(bin, key)
+	copy(bin[keyLengthSize:], key)
+	putBinValueSize(bin, len(value))
+	payload := bin[slottedCellHeaderSize+len(key):]
+	if len(value) <= getMaxInlineValueSize(len(key)) {
+		copy(payload, value)
+		return bin, nil
+	}
+	prefixSize := getOverflowPrefixSize(len(key))
+	copy(payload, value[:prefixSize])
+	overflowPageNum, err := t.writeOverflow(value[prefixSize:])
+	if err != nil {
+		return nil, wrap(err, "unable to write overflow pages")
+	}
+	binary.LittleEndian.PutUint32(payload[prefixSize:], overflowPageNum)
+	return bin, nil
+}
+
+// writeOverflow writes data to a new chain of overflow pages and returns its first page.
+// If the chain cannot be written, the pages written so far are freed.
+func (t *Table) writeOverflow(data []byte) (PagePointer, error) {
+	// Write the chain from its end, so that each page can point to the next.
+	var pageNums []PagePointer
+	next := PagePointer(0)
+	for end := len(data); end > 0; {
+		start := (end - 1) / int(overflowPageDataSize) * int(overflowPageDataSize)
+		pageNum, err := t.pager.GetUnusedPageNum()
+		if err != nil {
+			return 0, wrap2(err, t.freeOverflowPages(pageNums), "unable to get free page")
+		}
+		pageNums = append(pageNums, pageNum)
+		page, err := t.pager.GetPage(pageNum)
+		if err != nil {
+			return 0, wrap2(err, t.freeOverflowPages(pageNums), "unable to get page")
+		}
+		binary.LittleEndian.PutUint32(page[:], next)
+		copy(page[pagePointerSize:], data[start:end])
+		if err := t.pager.sync1(pageNum); err != nil {
+			return 0, wrap2(err, t.freeOverflowPages(pageNums), "unable to sync page")
+		}
+		next = pageNum
+		end = start
+	}
+	return next, nil
+}
+
+// cellValue returns the value of a cell, reading the part of the value held in overflow pages.
+// Values without overflow pages share memory with the cell.
+func (t *Table) cellValue(bin []byte) ([]byte, error) {
+	if !isSlotted(t) {
+		return bin[keyLengthSize+uintptr(t.maxKeySize):], nil
+	}
+
+	keySize := keyLength(bin)
+	size := getBinValueSize(bin)
+	payload := bin[slottedCellHeaderSize+keySize:]
+	if size <= getMaxInlineValueSize(keySize) {
+		return payload[:size], nil
+	}
+	prefixSize := getOverflowPrefixSize(keySize)
+	value := make([]byte, size)
+	copy(value, payload[:prefixSize])
+	pageNums, err := t.overflowPages(bin)
+	if err != nil {
+		return nil, err
+	}
+	for index, pageNum := range pageNums {
+		page, err := t.pager.GetPage(pageNum)
+		if err != nil {
+			return nil, wrap(err, "unable to get page")
+		}
+		copy(value[prefixSize+index*int(overflowPageDataSize):], page[pagePointerSize:])
+	}
+	return value, nil
+}
+
+// overflowPages returns the overflow pages of a cell in order.
+func (t *Table) overflowPages(bin []byte) ([]PagePointer, error) {
+	if !isSlotted(t) {
+		return nil, nil
+	}
+
+	keySize := keyLength(bin)
+	size := getBinValueSize(bin)
+	if size <= getMaxInlineValueSize(keySize) {
+		return nil, nil
+	}
+	prefixSize := getOverflowPrefixSize(keySize)
+	overflowSize := size - prefixSize
+	pageNums := make([]PagePointer, 0, (overflowSize+int(overflowPageDataSize)-1)/int(overflowPageDataSize))
+	pageNum := binary.LittleEndian.Uint32(bin[int(slottedCellHeaderSize)+keySize+prefixSize:])
+	for offset := 0; offset < overflowSize; offset += int(overflowPageDataSize) {
+		if pageNum == 0 {
+			return nil, errors.Errorf("file corruption: overflow chain ends after %d of %d bytes", offset, overflowSize)
+		}
+		pageNums = append(pageNums, pageNum)
+		page, err := t.pager.GetPage(pageNum)
+		if err != nil {
+			return nil, wrap(err, "unable to get page")
+		}
+		pageNum = binary.LittleEndian.Uint32(page[:])
+	}
+	return pageNums, nil
+}
+
+// freeOverflow frees the overflow pages of a cell.
+func (t *Table) freeOverflow(bin []byte) error {
+	pageNums, err := t.overflowPages(bin)
+	if err != nil {
+		return err
+	}
+	return t.freeOverflowPages(pageNums)
+}
+
+// freeOverflowPages frees overflow pages.
+func (t *Table) freeOverflowPages(pageNums []PagePointer) error {
+	for _, pageNum := range pageNums {
+		if err := t.pager.FreePage(pageNum); err != nil {
+			return wrap(err, "unable to free overflow page")
+		}
+	}
+	return nil
+}
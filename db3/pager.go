@@ -1,6 +1,7 @@
 package db3
 
 import (
+	"encoding/binary"
 	"github.com/pkg/errors"
 	"io"
 	"syscall"
@@ -19,6 +20,13 @@ type Pager struct {
 	fileLength uint32
 	pages      []*Page
 	numPages   PagePointer
+	// freePages are the pages returned by FreePage that have not been reused,
+	// in the order they were freed. Each free page starts with the page freed before it,
+	// or 0 for the first, so that the free pages are found again from the last one.
+	freePages []PagePointer
+	// freeListChanged, if set, records a new FreeListHead on disk each time a page
+	// is freed or reused, so that the free pages on disk are never in use.
+	freeListChanged func(head PagePointer) error
 }
 
 func OpenPager(path string, mode int, perm uint32) (*Pager, error) {
@@ -77,11 +85,88 @@ func (p *Pager) GetPage(pageIndex PagePointer) (*Page, error) {
 
 // GetUnusedPageNum returns the next available page.
 func (p *Pager) GetUnusedPageNum() (PagePointer, error) {
-	// Until we start recycling free pages, new pages will always go
-	// onto the end of the database file.
+	// Recycle free pages before growing the database file.
+	if n := len(p.freePages); n > 0 {
+		pageIndex := p.freePages[n-1]
+		page, err := p.GetPage(pageIndex)
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to get page")
+		}
+		// Take the page off the free pages on disk before it is written.
+		p.freePages = p.freePages[:n-1]
+		if err := p.syncFreeList(); err != nil {
+			p.freePages = append(p.freePages, pageIndex)
+			return 0, errors.Wrap(err, "unable to sync free pages")
+		}
+		*page = Page{}
+		return pageIndex, nil
+	}
+	// New pages go onto the end of the database file.
 	return p.numPages, nil
 }
 
+// FreePage returns a page that is no longer used, clearing it so that
+// GetUnusedPageNum can reuse it. The page keeps only a pointer to the page
+// freed before it, so that LoadFreePages finds the free pages again from
+// FreeListHead after the pager is reopened.
+func (p *Pager) FreePage(pageIndex PagePointer) error {
+	page, err := p.GetPage(pageIndex)
+	if err != nil {
+		return errors.Wrap(err, "unable to get page")
+	}
+	*page = Page{}
+	binary.LittleEndian.PutUint32(page[:], p.FreeListHead())
+	if err := p.Flush(pageIndex, true); err != nil {
+		return errors.Wrap(err, "unable to clear page")
+	}
+	p.freePages = append(p.freePages, pageIndex)
+	if err := p.syncFreeList(); err != nil {
+		p.freePages = p.freePages[:len(p.freePages)-1]
+		return errors.Wrap(err, "unable to sync free pages")
+	}
+	return nil
+}
+
+// syncFreeList records the FreeListHead on disk, if the pager has freeListChanged.
+func (p *Pager) syncFreeList() error {
+	if p.freeListChanged == nil {
+		return nil
+	}
+	return p.freeListChanged(p.FreeListHead())
+}
+
+// FreeListHead returns the most recently freed page that has not been reused,
+// or 0 if there is none.
+func (p *Pager) FreeListHead() PagePointer {
+	if n := len(p.freePages); n > 0 {
+		return p.freePages[n-1]
+	}
+	return 0
+}
+
+// LoadFreePages finds the free pages of the file from the most recently freed
+// page, head, which is 0 if there is none. Page 0 is never free.
+func (p *Pager) LoadFreePages(head PagePointer) error {
+	var freePages []PagePointer
+	for pageIndex := head; pageIndex != 0; {
+		if pageIndex >= p.numPages || PagePointer(len(freePages)) >= p.numPages {
+			return errors.Errorf("file corruption: free page %d of %d pages", pageIndex, p.numPages)
+		}
+		page, err := p.GetPage(pageIndex)
+		if err != nil {
+			return errors.Wrap(err, "unable to get page")
+		}
+		freePages = append(freePages, pageIndex)
+		pageIndex = binary.LittleEndian.Uint32(page[:])
+	}
+	// The free pages are found in the reverse of the order they were freed.
+	for i, j := 0, len(freePages)-1; i < j; i, j = i+1, j-1 {
+		freePages[i], freePages[j] = freePages[j], freePages[i]
+	}
+	p.freePages = freePages
+	return nil
+}
+
 // NumPages returns the number of pages on disk.
 func (p *Pager) NumPages() PagePointer {
 	return p.numPages
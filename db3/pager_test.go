@@ -71,3 +71,81 @@ func TestPager(t *testing.T) {
 	}()
 
 }
+
+func TestPager_FreePage(t *testing.T) {
+	file := NewTempFile(t)
+	defer file.Delete()
+
+	pager, err := OpenPager(file.FullPath(), os.O_RDWR|os.O_CREATE, userReadWrite)
+	must(t, err)
+	defer func() {
+		must(t, pager.Close())
+	}()
+
+	for pageIndex := PagePointer(0); pageIndex < 3; pageIndex++ {
+		page, err := pager.GetPage(pageIndex)
+		must(t, err)
+		page[0] = 1
+		must(t, pager.Flush(pageIndex, true))
+	}
+
+	must(t, pager.FreePage(1))
+	page, err := pager.GetPage(1)
+	must(t, err)
+	assert.Equal(t, Page{}, *page, "free pages are cleared")
+
+	nextPage, err := pager.GetUnusedPageNum()
+	must(t, err)
+	assert.Equal(t, PagePointer(1), nextPage, "free pages are reused")
+	nextPage, err = pager.GetUnusedPageNum()
+	must(t, err)
+	assert.Equal(t, PagePointer(3), nextPage)
+	assert.Equal(t, PagePointer(3), pager.NumPages())
+}
+
+func TestPager_LoadFreePages(t *testing.T) {
+	file := NewTempFile(t)
+	defer file.Delete()
+
+	func() {
+		pager, err := OpenPager(file.FullPath(), os.O_RDWR|os.O_CREATE, userReadWrite)
+		must(t, err)
+		defer func() {
+			must(t, pager.Close())
+		}()
+
+		for pageIndex := PagePointer(0); pageIndex < 5; pageIndex++ {
+			page, err := pager.GetPage(pageIndex)
+			must(t, err)
+			page[0] = 1
+			must(t, pager.Flush(pageIndex, true))
+		}
+		assert.Equal(t, PagePointer(0), pager.FreeListHead())
+		must(t, pager.FreePage(3))
+		must(t, pager.FreePage(1))
+		assert.Equal(t, PagePointer(1), pager.FreeListHead())
+	}()
+
+	pager, err := OpenPager(file.FullPath(), os.O_RDWR, userReadWrite)
+	must(t, err)
+	defer func() {
+		must(t, pager.Close())
+	}()
+
+	assert.Error(t, pager.LoadFreePages(5), "free page beyond the file")
+	page, err := pager.GetPage(2)
+	must(t, err)
+	page[0] = 2
+	assert.Error(t, pager.LoadFreePages(2), "cycle of free pages")
+
+	must(t, pager.LoadFreePages(1))
+	assert.Equal(t, []PagePointer{3, 1}, pager.freePages)
+	for _, expected := range []PagePointer{1, 3, 5} {
+		nextPage, err := pager.GetUnusedPageNum()
+		must(t, err)
+		assert.Equal(t, expected, nextPage)
+		page, err := pager.GetPage(nextPage)
+		must(t, err)
+		assert.Equal(t, Page{}, *page, "reused pages are cleared")
+	}
+}
@@ -7,12 +7,12 @@ import (
 
 const (
 	sentinelsPerLeaf  = maxValues
-	sentinelPadSize   = leafNodeMaxCellData/sentinelsPerLeaf - unsafe.Sizeof(KeyType(0)) - unsafe.Sizeof(sentinelHeader{}) -10
+	sentinelPadSize   = leafNodeMaxCellData/sentinelsPerLeaf - keyLengthSize - testKeySize - unsafe.Sizeof(sentinelHeader{}) -10
 	sentinelValueSize = unsafe.Sizeof(sentinelValue{})
 )
 
 func init() {
-	table := &Table{dataSize: uint16(sentinelValueSize)}
+	table := &Table{maxKeySize: testKeySize, dataSize: uint16(sentinelValueSize)}
 	leaf := &leafNode{}
 	leaf.init()
 	if leaf.getMaxNumCells(table) != sentinelsPerLeaf {
@@ -21,7 +21,7 @@ func init() {
 }
 
 type sentinelHeader struct {
-	expectedKey   KeyType
+	expectedKey   uint32
 	sentinelStart uint64
 	sentinelEnd   uint64
 }
@@ -31,7 +31,7 @@ type sentinelValue struct {
 	pad [sentinelPadSize]byte
 }
 
-func newSentinelValue(t testType, key KeyType) *sentinelValue {
+func newSentinelValue(t testType, key uint32) *sentinelValue {
 	t.Helper()
 
 	sentinelNumber := uint64(^(2 * key))
@@ -56,7 +56,7 @@ func parseSentinelValue(t testType, b []byte) *sentinelValue {
 	return x
 }
 
-func (v sentinelValue) wellFormed(t testType, expectedKey KeyType) bool {
+func (v sentinelValue) wellFormed(t testType, expectedKey uint32) bool {
 	t.Helper()
 	if v.expectedKey != expectedKey {
 		t.Errorf("unexpected key, got %d want %d", v.expectedKey, expectedKey)
@@ -91,7 +91,7 @@ func (v *sentinelValue) toInsertStatement(t testType, table *Table) *insertState
 
 	return &insertStatement{
 		table: table,
-		key:   v.expectedKey,
+		key:   testKey(v.expectedKey),
 		value: v.toBytes(t),
 	}
 }
@@ -23,6 +23,12 @@ func testWithLimitedTable(t *testing.T, rowSize uint16, f func(t *testing.T, tab
 	if err := _setMaxKeysPerBranchOverride(maxKeys); err != nil {
 		t.Fatal(err)
 	}
+	testWithTable(t, testKeySize, rowSize, f)
+}
+
+// testWithTable runs a test with a table without changing the limit of keys per branch.
+func testWithTable(t *testing.T, maxKeySize, rowSize uint16, f func(t *testing.T, table *Table)) {
+	t.Helper()
 
 	file := NewTempFile(t)
 	defer file.Delete()
@@ -33,7 +39,7 @@ func testWithLimitedTable(t *testing.T, rowSize uint16, f func(t *testing.T, tab
 		must(t, pager.Close())
 	}()
 
-	table, err := Open(pager, rowSize)
+	table, err := Open(pager, maxKeySize, rowSize)
 	must(t, err)
 
 	f(t, table)
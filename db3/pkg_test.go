@@ -14,10 +14,30 @@ type testType interface {
 	Errorf(format string, args ...interface{})
 }
 
+// testKeySize is the maximum key size of tables in tests, whose keys are big-endian uint32s.
+const testKeySize = 4
+
+// testKey returns the key of tests for a number.
+func testKey(n uint32) KeyType {
+	key := make(KeyType, testKeySize)
+	binary.BigEndian.PutUint32(key, n)
+	return key
+}
+
+// testKeyNumber returns the number of a key of tests.
+func testKeyNumber(key KeyType) uint32 {
+	return binary.BigEndian.Uint32(key)
+}
+
 var _ DataSizer = dataSizer{}
 
 type dataSizer struct {
-	size uint16
+	maxKeySize uint16
+	size       uint16
+}
+
+func (d dataSizer) MaxKeySize() uint16 {
+	return d.maxKeySize
 }
 
 func (d dataSizer) DataSize() uint16 {
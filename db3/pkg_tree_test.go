@@ -0,0 +1,180 @@
+package db3
+
+import (
+	"bytes"
+)
+
+// treeChecker verifies the invariants of the B+Tree of a table.
+type treeChecker struct {
+	t     testType
+	table *Table
+	// leafDepth is the depth of every leaf, or -1 before the first leaf.
+	leafDepth int
+	// leaves are the leaf pages in order of their keys.
+	leaves []PagePointer
+	// keys are the keys of the leaves in order.
+	keys []KeyType
+	// visited are the pages in the tree.
+	visited map[PagePointer]bool
+}
+
+// checkTree verifies the invariants of the tree of a table and returns its keys in order:
+// nodes are at most full and, except for the root, not underflowing; keys are ordered
+// and within the bounds of the separator keys of their parents; leaves are at the same
+// depth and linked in order; parent pointers point to parents; and every page is either
+// in the tree, an overflow page of a single cell, or free. Returns false if an invariant
+// does not hold.
+func checkTree(t testType, table *Table) ([]KeyType, bool) {
+	t.Helper()
+	c := &treeChecker{
+		t:         t,
+		table:     table,
+		leafDepth: -1,
+		visited:   make(map[PagePointer]bool),
+	}
+	if !c.checkNode(table.rootPageNum, table.rootPageNum, 0, nil, nil) {
+		return nil, false
+	}
+
+	// Leaves are linked in order.
+	for index, pageNum := range c.leaves {
+		leaf := c.leaf(pageNum)
+		next := PagePointer(0)
+		if index+1 < len(c.leaves) {
+			next = c.leaves[index+1]
+		}
+		if leaf.nextLeaf != next {
+			t.Errorf("leaf %d: next leaf %d, want %d", pageNum, leaf.nextLeaf, next)
+			return nil, false
+		}
+	}
+
+	// Every page is either in the tree or free.
+	free := make(map[PagePointer]bool)
+	for _, pageNum := range table.pager.freePages {
+		if c.visited[pageNum] || free[pageNum] {
+			t.Errorf("page %d: free page is in use", pageNum)
+			return nil, false
+		}
+		free[pageNum] = true
+	}
+	if numPages := PagePointer(1 + len(c.visited) + len(free)); numPages != table.pager.NumPages() {
+		t.Errorf("header, %d pages in the tree and %d free, want %d pages", len(c.visited), len(free), table.pager.NumPages())
+		return nil, false
+	}
+
+	// The header locates the root and the free pages.
+	header, err := readHeader(c.page(headerPageNum), table.pager.NumPages())
+	if err != nil {
+		t.Errorf("header: %v", err)
+		return nil, false
+	}
+	if header.rootPageNum != table.rootPageNum || header.freeListHead != table.pager.FreeListHead() {
+		t.Errorf("header: root %d and free list head %d, want %d and %d", header.rootPageNum, header.freeListHead, table.rootPageNum, table.pager.FreeListHead())
+		return nil, false
+	}
+	return c.keys, true
+}
+
+func (c *treeChecker) page(pageNum PagePointer) *Page {
+	c.t.Helper()
+	page, err := c.table.pager.GetPage(pageNum)
+	must(c.t, err)
+	return page
+}
+
+func (c *treeChecker) leaf(pageNum PagePointer) *leafNode {
+	c.t.Helper()
+	return pageToLeafNode(c.page(pageNum))
+}
+
+// checkOverflow verifies that the overflow pages of a cell are not used elsewhere.
+func (c *treeChecker) checkOverflow(pageNum PagePointer, bin []byte) bool {
+	c.t.Helper()
+	overflowPageNums, err := c.table.overflowPages(bin)
+	if err != nil {
+		c.t.Errorf("leaf %d: %v", pageNum, err)
+		return false
+	}
+	for _, overflowPageNum := range overflowPageNums {
+		if c.visited[overflowPageNum] {
+			c.t.Errorf("leaf %d: overflow page %d visited twice", pageNum, overflowPageNum)
+			return false
+		}
+		c.visited[overflowPageNum] = true
+	}
+	return true
+}
+
+// checkNode verifies a node and its children, whose keys must be within (minKey, maxKey],
+// where nil bounds are unbounded.
+func (c *treeChecker) checkNode(pageNum, parentPageNum PagePointer, depth int, minKey, maxKey KeyType) bool {
+	c.t.Helper()
+	if c.visited[pageNum] {
+		c.t.Errorf("page %d: visited twice", pageNum)
+		return false
+	}
+	c.visited[pageNum] = true
+
+	page := c.page(pageNum)
+	node := pageToNodeHeader(page)
+	isRoot := pageNum == c.table.rootPageNum
+	if node.isRoot != isRoot {
+		c.t.Errorf("page %d: isRoot %v, want %v", pageNum, node.isRoot, isRoot)
+		return false
+	}
+	if !isRoot && node.parentPointer != parentPageNum {
+		c.t.Errorf("page %d: parent %d, want %d", pageNum, node.parentPointer, parentPageNum)
+		return false
+	}
+	checkKey := func(key, prev KeyType) bool {
+		if (prev != nil && bytes.Compare(key, prev) <= 0) || (maxKey != nil && bytes.Compare(key, maxKey) > 0) {
+			c.t.Errorf("page %d: key %x out of order or bounds (%x, %x]", pageNum, key, prev, maxKey)
+			return false
+		}
+		return true
+	}
+
+	if node.isLeaf {
+		leaf := pageToLeafNode(page)
+		if used := leaf.getUsedSpace(c.table); used > leaf.getCapacity(c.table) || (!isRoot && leaf.isUnderflowing(c.table)) {
+			c.t.Errorf("leaf %d: %d cells using %d bytes", pageNum, leaf.numCells, used)
+			return false
+		}
+		if c.leafDepth == -1 {
+			c.leafDepth = depth
+		} else if depth != c.leafDepth {
+			c.t.Errorf("leaf %d: depth %d, want %d", pageNum, depth, c.leafDepth)
+			return false
+		}
+		prev := minKey
+		for index := cellptr(0); index < leaf.numCells; index++ {
+			key := append(KeyType{}, leaf.getCellKey(c.table, index)...)
+			if !checkKey(key, prev) {
+				return false
+			}
+			prev = key
+			c.keys = append(c.keys, key)
+			if !c.checkOverflow(pageNum, leaf.getCellBin(c.table, index)) {
+				return false
+			}
+		}
+		c.leaves = append(c.leaves, pageNum)
+		return true
+	}
+
+	branch := pageToBranchNode(page)
+	used := branch.getSlotted().getUsedSpace(branch.numCells)
+	if branch.numCells > branch.getMaxNumCells() || used > branchNodeCapacity || (isRoot && branch.numCells == 0) || (!isRoot && branch.isUnderflowing()) {
+		c.t.Errorf("branch %d: %d cells using %d bytes", pageNum, branch.numCells, used)
+		return false
+	}
+	prev := minKey
+	for _, cell := range branch.getCells() {
+		if !checkKey(cell.key, prev) || !c.checkNode(cell.child, pageNum, depth+1, prev, cell.key) {
+			return false
+		}
+		prev = cell.key
+	}
+	return c.checkNode(branch.rightChild, pageNum, depth+1, prev, maxKey)
+}
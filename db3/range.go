@@ -0,0 +1,170 @@
+package db3
+
+import (
+	"bytes"
+)
+
+// Seek returns a cursor pointing to the first record with a key of at least
+// the given key, or the end of the table if there is none.
+func (t *Table) Seek(key KeyType) (*Cursor, error) {
+	cursor, err := t.Find(key)
+	if err != nil {
+		return nil, wrap(err, "unable to find key")
+	}
+
+	// Find points one past the last cell of a leaf when the key is greater
+	// than every key in the leaf, so move to the start of the next leaf.
+	page, err := t.pager.GetPage(cursor.pageNum)
+	if err != nil {
+		return nil, wrap(err, "unable to get page")
+	}
+	leaf := pageToLeafNode(page)
+	if cursor.cellNum >= leaf.numCells {
+		if leaf.nextLeaf == 0 {
+			cursor.endOfTable = true
+		} else {
+			cursor.pageNum = leaf.nextLeaf
+			cursor.cellNum = 0
+		}
+	}
+	return cursor, nil
+}
+
+// KeyRange is a range of keys from Start, inclusive, to End, exclusive, where an empty End
+// is unbounded. Keys shorter than MinLength within the range are skipped.
+type KeyRange struct {
+	Start, End KeyType
+	MinLength  int
+}
+
+// isBefore determines if a key is before the end of the range.
+func (r KeyRange) isBefore(key KeyType) bool {
+	return len(r.End) == 0 || bytes.Compare(key, r.End) < 0
+}
+
+// intersect returns the keys in both ranges, reporting if there may be any.
+func (r KeyRange) intersect(other KeyRange) (KeyRange, bool) {
+	if bytes.Compare(other.Start, r.Start) > 0 {
+		r.Start = other.Start
+	}
+	if len(other.End) > 0 && r.isBefore(other.End) {
+		r.End = other.End
+	}
+	if other.MinLength > r.MinLength {
+		r.MinLength = other.MinLength
+	}
+	return r, r.isBefore(r.Start)
+}
+
+// RangeCursor is an object used to navigate the rows of a Table with keys
+// within key ranges. It seeks to the start of each range, so that leaves
+// between ranges are not visited.
+type RangeCursor struct {
+	// table is the table to navigate.
+	table *Table
+	// ranges are the ranges that have not been navigated yet,
+	// the first of which contains the current row.
+	ranges []KeyRange
+	// cursor points at the current row.
+	cursor *Cursor
+	// seekError stores any error encountered when seeking.
+	seekError error
+}
+
+// SeekRanges returns a cursor pointing to the first record with a key
+// within the ranges, which must be sorted and must not overlap.
+func (t *Table) SeekRanges(ranges []KeyRange) (*RangeCursor, error) {
+	c := &RangeCursor{table: t, ranges: ranges}
+	if len(c.ranges) == 0 {
+		return c, nil
+	}
+	cursor, err := t.Seek(c.ranges[0].Start)
+	if err != nil {
+		return nil, wrap(err, "unable to seek to first range")
+	}
+	c.cursor = cursor
+	c.settle()
+	if c.seekError != nil {
+		return nil, c.seekError
+	}
+	return c, nil
+}
+
+// settle moves the cursor forward to the first row within the remaining ranges,
+// dropping the ranges that it has passed.
+func (c *RangeCursor) settle() {
+	sought := false
+	for len(c.ranges) > 0 {
+		if c.cursor.End() {
+			c.ranges = nil
+			return
+		}
+		key, _, err := c.cursor.Value()
+		if err != nil {
+			// The cursor keeps its error.
+			return
+		}
+		for len(c.ranges) > 0 && !c.ranges[0].isBefore(key) {
+			c.ranges = c.ranges[1:]
+		}
+		if len(c.ranges) == 0 {
+			return
+		}
+		if bytes.Compare(key, c.ranges[0].Start) >= 0 {
+			if len(key) >= c.ranges[0].MinLength {
+				return
+			}
+			// The row is too short for the range, step past it.
+			sought = false
+			c.cursor.Next()
+			continue
+		}
+		if sought {
+			// Seeking did not pass the row, step past it instead.
+			c.cursor.Next()
+			continue
+		}
+		// The next row is before the next range, seek to it.
+		sought = true
+		cursor, err := c.table.Seek(c.ranges[0].Start)
+		if err != nil {
+			c.seekError = wrap(err, "unable to seek to range")
+			return
+		}
+		c.cursor = cursor
+	}
+}
+
+// Value gets the value pointed to by this cursor.
+func (c *RangeCursor) Value() (key KeyType, value []byte, err error) {
+	if c.seekError != nil {
+		return nil, nil, c.seekError
+	}
+	// Recursive call, do not wrap error.
+	return c.cursor.Value()
+}
+
+// Next advances the cursor to the next row within the ranges.
+func (c *RangeCursor) Next() {
+	if c.End() {
+		return
+	}
+	c.cursor.Next()
+	c.settle()
+}
+
+// End indicates if this cursor can no longer advance.
+func (c *RangeCursor) End() bool {
+	return len(c.ranges) == 0 || c.seekError != nil || c.cursor.advanceError != nil
+}
+
+// Err returns the error that ended this cursor, if any.
+func (c *RangeCursor) Err() error {
+	if c.seekError != nil {
+		return c.seekError
+	}
+	if c.cursor != nil {
+		return c.cursor.advanceError
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package db3
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// rangeNumKeys is the number of keys inserted to test seeking, spanning three leaves.
+const rangeNumKeys = 5
+
+// insertKeys inserts sentinels with keys from 10 in steps of 10.
+func insertKeys(t *testing.T, table *Table, numKeys int) {
+	t.Helper()
+	for i := 1; i <= numKeys; i++ {
+		must(t, newSentinelValue(t, uint32(10*i)).toInsertStatement(t, table).Execute())
+	}
+}
+
+func rangeCursorKeys(t *testing.T, c *RangeCursor) []KeyType {
+	t.Helper()
+	var keys []KeyType
+	for ; !c.End(); c.Next() {
+		key, value, err := c.Value()
+		must(t, err)
+		if len(key) == testKeySize {
+			assert.True(t, parseSentinelValue(t, value).wellFormed(t, testKeyNumber(key)))
+		}
+		keys = append(keys, append(KeyType(nil), key...))
+	}
+	return keys
+}
+
+func TestTable_Seek(t *testing.T) {
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		cursor, err := table.Seek(testKey(5))
+		must(t, err)
+		assert.True(t, cursor.End(), "empty table")
+
+		insertKeys(t, table, rangeNumKeys)
+		cases := []struct {
+			key      KeyType
+			expected uint32
+		}{
+			{nil, 10},
+			{testKey(0), 10},
+			{testKey(10), 10},
+			{testKey(11), 20},
+			{testKey(20)[:3], 10},
+			{append(testKey(20), 0), 30},
+			{testKey(29), 30},
+			{testKey(30), 30},
+			{testKey(31), 40},
+			{testKey(41), 50},
+			{testKey(50), 50},
+		}
+		for _, tc := range cases {
+			cursor, err := table.Seek(tc.key)
+			must(t, err)
+			if assert.False(t, cursor.End(), "seek %x", tc.key) {
+				got, _, err := cursor.Value()
+				must(t, err)
+				assert.Equal(t, testKey(tc.expected), got, "seek %x", tc.key)
+			}
+		}
+		cursor, err = table.Seek(testKey(51))
+		must(t, err)
+		assert.True(t, cursor.End())
+	})
+}
+
+func TestTable_SeekRanges(t *testing.T) {
+	cases := []struct {
+		name     string
+		ranges   []KeyRange
+		expected []KeyType
+	}{
+		{"none", nil, nil},
+		{"all", []KeyRange{{}}, append(testKeys(10, 20, 30, 40, 50), []byte{0, 0, 1}, []byte{0, 1})},
+		{"one", []KeyRange{{Start: testKey(30), End: testKey(31)}}, testKeys(30)},
+		{"missing", []KeyRange{{Start: testKey(31), End: testKey(40)}}, nil},
+		{"interval", []KeyRange{{Start: testKey(25), End: testKey(56)}}, testKeys(30, 40, 50)},
+		{"intervals", []KeyRange{
+			{Start: testKey(0), End: testKey(11)},
+			{Start: testKey(35), End: testKey(46)},
+			{Start: testKey(46), End: testKey(50)},
+			{Start: testKey(50), End: testKey(61)},
+		}, testKeys(10, 40, 50)},
+		{"after-end", []KeyRange{{Start: testKey(45), End: testKey(201)}, {Start: testKey(300), End: testKey(401)}}, testKeys(50)},
+		{"prefix", []KeyRange{{Start: testKey(20)[:3], End: []byte{0, 0, 0, 21}}}, testKeys(10, 20)},
+		{"short", []KeyRange{{Start: []byte{0, 0, 0, 40, 0}, End: []byte{1}}}, append(testKeys(50), []byte{0, 0, 1}, []byte{0, 1})},
+		{"min-length", []KeyRange{{Start: testKey(30), End: []byte{1}, MinLength: testKeySize}}, testKeys(30, 40, 50)},
+	}
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		insertKeys(t, table, rangeNumKeys)
+		for _, key := range []KeyType{{0, 0, 1}, {0, 1}} {
+			must(t, table.Insert(key, newSentinelValue(t, 0).toBytes(t)))
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				cursor, err := table.SeekRanges(tc.ranges)
+				must(t, err)
+				assert.Equal(t, tc.expected, rangeCursorKeys(t, cursor))
+			})
+		}
+	})
+}
+
+func TestKeyRange_intersect(t *testing.T) {
+	cases := []struct {
+		name     string
+		r, other KeyRange
+		expected KeyRange
+		ok       bool
+	}{
+		{"unbounded", KeyRange{}, KeyRange{}, KeyRange{}, true},
+		{"start", KeyRange{Start: []byte{1}}, KeyRange{Start: []byte{1, 0}}, KeyRange{Start: []byte{1, 0}}, true},
+		{"end", KeyRange{End: []byte{2}}, KeyRange{End: []byte{1, 5}, MinLength: 4}, KeyRange{End: []byte{1, 5}, MinLength: 4}, true},
+		{"inside", KeyRange{Start: []byte{1}, End: []byte{3}}, KeyRange{Start: []byte{0}, End: []byte{4}}, KeyRange{Start: []byte{1}, End: []byte{3}}, true},
+		{"disjoint", KeyRange{Start: []byte{1}, End: []byte{2}}, KeyRange{Start: []byte{2}}, KeyRange{Start: []byte{2}, End: []byte{2}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.r.intersect(tc.other)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
@@ -16,6 +16,9 @@ type Query interface {
 	Query() (*Cursor, error)
 }
 
+// ErrDuplicateKey indicates an insert of a key that is already in the table.
+var ErrDuplicateKey = errors.New("duplicate key")
+
 var _ Statement = (*insertStatement)(nil)
 
 // insertStatement is a statement that inserts data into a specific table.
@@ -31,31 +34,22 @@ type insertStatement struct {
 // Execute executes this insert statement.
 func (s *insertStatement) Execute() error {
 	// Validate the input data.
-	if len(s.value) != int(s.table.dataSize) {
-		return errors.Errorf("invalid insert data length %d, want %d", len(s.value), s.table.dataSize)
+	if err := s.table.validateKey(s.key); err != nil {
+		return wrap(err, "invalid insert key")
+	}
+	if err := s.table.validateValueSize(len(s.value)); err != nil {
+		return wrap(err, "invalid insert data")
 	}
 
 	// Find the location to insert a record.
-	cursor, err := s.table.Find(s.key)
+	cursor, leaf, found, err := s.table.findLeaf(s.key)
 	if err != nil {
 		return wrap(err, "unable to get cursor")
 	}
 
-	// Get the page pointed to by the cursor.
-	insertPage, err := s.table.pager.GetPage(cursor.pageNum)
-	if err != nil {
-		return wrap(err, "unable to get page")
-	}
-	leaf := pageToLeafNode(insertPage)
-
 	// Check for a duplicate key.
-	if cursor.cellNum < leaf.numCells {
-		// The position of our cursor on an identical key?
-		keyAtCursor := leaf.getCellKey(s.table, cursor.cellNum)
-		if keyAtCursor == s.key {
-			// Duplicate key found.
-			return errors.Errorf("cannot insert duplicate key %v", keyAtCursor)
-		}
+	if found {
+		return errors.Wrapf(ErrDuplicateKey, "cannot insert key %x", s.key)
 	}
 
 	// Insert the data.
@@ -63,7 +57,181 @@ func (s *insertStatement) Execute() error {
 		return wrap(err, "unable to insert record")
 	}
 
-	return nil
+	// Record any pages taken from the free pages.
+	return wrap(s.table.syncHeader(), "unable to sync header")
+}
+
+// Insert inserts a record into the table. The key must not
+// already exist and must be at most the maximum key size, and the value
+// must be as large as the data size unless the table has VariableDataSize.
+// Inserting a key that already exists returns an error whose cause is ErrDuplicateKey.
+func (t *Table) Insert(key KeyType, value []byte) error {
+	insert := &insertStatement{
+		table: t,
+		key:   key,
+		value: value,
+	}
+	return insert.Execute()
+}
+
+var _ Statement = (*updateStatement)(nil)
+
+// updateStatement is a statement that changes data in place in a specific table.
+type updateStatement struct {
+	// table is the table to update.
+	table *Table
+	// key is the key of the data to update.
+	key KeyType
+	// update returns the new data from a copy of the old data,
+	// which is nil if the key is missing.
+	update func(old []byte) ([]byte, error)
+	// upsert indicates that the data is inserted if the key is missing.
+	upsert bool
+}
+
+// Execute executes this update statement.
+func (s *updateStatement) Execute() error {
+	// Validate the key, which may be inserted.
+	if err := s.table.validateKey(s.key); err != nil {
+		return wrap(err, "invalid update key")
+	}
+
+	// Find the location of the record.
+	cursor, leaf, found, err := s.table.findLeaf(s.key)
+	if err != nil {
+		return wrap(err, "unable to get cursor")
+	}
+
+	// Check that the key exists.
+	if !found && !s.upsert {
+		return errors.Errorf("cannot update missing key %x", s.key)
+	}
+
+	// Get the new data. The update gets a copy of the old data so that
+	// the record is unchanged if the update fails.
+	var old []byte
+	if found {
+		value, err := s.table.cellValue(leaf.getCellBin(s.table, cursor.cellNum))
+		if err != nil {
+			return wrap(err, "unable to get record")
+		}
+		old = append([]byte(nil), value...)
+	}
+	value, err := s.update(old)
+	if err != nil {
+		return wrap(err, "unable to update record")
+	}
+
+	// Validate the new data.
+	if err := s.table.validateValueSize(len(value)); err != nil {
+		return wrap(err, "invalid update data")
+	}
+
+	// Insert the data if the key is missing.
+	if !found {
+		if err := leaf.insert(cursor, s.key, value); err != nil {
+			return wrap(err, "unable to insert record")
+		}
+		return wrap(s.table.syncHeader(), "unable to sync header")
+	}
+
+	// Replace the cell, freeing the old overflow pages once the new cell is in place.
+	// The overflow pages of the new cell are freed if the old cell cannot be replaced.
+	newBin, err := s.table.newCellBin(s.key, value)
+	if err != nil {
+		return wrap(err, "unable to create cell")
+	}
+	if err := leaf.update(cursor, newBin); err != nil {
+		return wrap(err, "unable to update record")
+	}
+
+	// Record any overflow pages that were freed or reused.
+	return wrap(s.table.syncHeader(), "unable to sync header")
+}
+
+// Update replaces the value of a record in the table. The key must
+// already exist, and the value must be as large as the data size
+// unless the table has VariableDataSize.
+func (t *Table) Update(key KeyType, value []byte) error {
+	update := &updateStatement{
+		table: t,
+		key:   key,
+		update: func([]byte) ([]byte, error) {
+			return value, nil
+		},
+	}
+	return update.Execute()
+}
+
+// Upsert replaces the value of a record in the table, or inserts the
+// record if the key does not exist, in which case it must be at most the maximum
+// key size. The value must be as large as the data size unless the table has
+// VariableDataSize.
+func (t *Table) Upsert(key KeyType, value []byte) error {
+	upsert := &updateStatement{
+		table: t,
+		key:   key,
+		update: func([]byte) ([]byte, error) {
+			return value, nil
+		},
+		upsert: true,
+	}
+	return upsert.Execute()
+}
+
+// UpdateFunc replaces the value of a record in the table with the value
+// returned by update for a copy of its old value. The key must already exist,
+// and the value must be as large as the data size unless the table has
+// VariableDataSize. update must not modify the table; if it returns an error,
+// the record is unchanged.
+func (t *Table) UpdateFunc(key KeyType, update func(old []byte) ([]byte, error)) error {
+	updateFunc := &updateStatement{
+		table:  t,
+		key:    key,
+		update: update,
+	}
+	return updateFunc.Execute()
+}
+
+var _ Statement = (*deleteStatement)(nil)
+
+// deleteStatement is a statement that deletes data from a specific table.
+type deleteStatement struct {
+	// table is the table to delete from.
+	table *Table
+	// key is the key of the data to delete.
+	key KeyType
+}
+
+// Execute executes this delete statement.
+func (s *deleteStatement) Execute() error {
+	// Find the location of the record.
+	cursor, leaf, found, err := s.table.findLeaf(s.key)
+	if err != nil {
+		return wrap(err, "unable to get cursor")
+	}
+
+	// Check that the key exists.
+	if !found {
+		return errors.Errorf("cannot delete missing key %x", s.key)
+	}
+
+	// Delete the data.
+	if err := leaf.delete(cursor); err != nil {
+		return wrap(err, "unable to delete record")
+	}
+
+	// Record any freed pages and a new root.
+	return wrap(s.table.syncHeader(), "unable to sync header")
+}
+
+// Delete deletes the record with a key from the table. The key must exist.
+func (t *Table) Delete(key KeyType) error {
+	del := &deleteStatement{
+		table: t,
+		key:   key,
+	}
+	return del.Execute()
 }
 
 var _ Query = (*selectStatement)(nil)
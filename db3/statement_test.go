@@ -1,7 +1,10 @@
 package db3
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"testing"
@@ -28,16 +31,16 @@ func TestInsertSelectStatement(t *testing.T) {
 		name    string
 		numKeys int
 	}{
-		//{"empty", 0},
-		//{"depth1", maxValues},
-		//{"depth1+1", 1 + maxValues},
-		//{"depth2", maxChildren * maxValues},
-		//{"depth2+1", 1 + maxChildren*maxValues},
+		{"empty", 0},
+		{"depth1", maxValues},
+		{"depth1+1", 1 + maxValues},
+		{"depth2", maxChildren * maxValues},
+		{"depth2+1", 1 + maxChildren*maxValues},
 		{"depth3", maxChildren * maxChildren * maxValues},
 	}
 	for _, tc := range cases {
 		t.Run(fmt.Sprintf("linear_%s_%d", tc.name, tc.numKeys), func(t *testing.T) {
-			//testInsertedValuesAreOrdered(t, tc.numKeys)
+			testInsertedValuesAreOrdered(t, tc.numKeys)
 		})
 		t.Run(fmt.Sprintf("shuffled_%s_%d", tc.name, tc.numKeys), func(t *testing.T) {
 			testShuffledInsertedValuesAreOrdered(t, tc.numKeys)
@@ -48,10 +51,10 @@ func TestInsertSelectStatement(t *testing.T) {
 func testInsertedValuesAreOrdered(t *testing.T, numKeys int) {
 	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
 		const (
-			startKey = KeyType(1)
+			startKey = uint32(1)
 		)
 		for i := 0; i < numKeys; i++ {
-			key := startKey + KeyType(i)
+			key := startKey + uint32(i)
 			sentinel := newSentinelValue(t, key)
 			insert := sentinel.toInsertStatement(t, table)
 			err := insert.Execute()
@@ -74,12 +77,12 @@ func testInsertedValuesAreOrdered(t *testing.T, numKeys int) {
 func testShuffledInsertedValuesAreOrdered(t *testing.T, numKeys int) {
 	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
 		const (
-			startKey = KeyType(1)
+			startKey = uint32(1)
 		)
-		keys := make([]KeyType, numKeys)
+		keys := make([]uint32, numKeys)
 
 		for i := 0; i < numKeys; i++ {
-			key := startKey + KeyType(i)
+			key := startKey + uint32(i)
 			keys[i] = key
 		}
 		rand.New(rand.NewSource(42)).Shuffle(numKeys, func(i, j int) {
@@ -93,7 +96,6 @@ func testShuffledInsertedValuesAreOrdered(t *testing.T, numKeys int) {
 			if err != nil {
 				t.Fatalf("error at insert #%d (key %d): %v", i, key, err)
 			}
-			dumpTable(table)
 		}
 		if !assertOrdered(t, table, startKey, numKeys) {
 			//dumpTable(table)
@@ -101,7 +103,7 @@ func testShuffledInsertedValuesAreOrdered(t *testing.T, numKeys int) {
 	})
 }
 
-func assertOrdered(t testType, table *Table, startKey KeyType, numKeys int) bool {
+func assertOrdered(t testType, table *Table, startKey uint32, numKeys int) bool {
 	cursor, err := selectEntireTable(table).Query()
 	must(t, err)
 	values := cursorConsume(t, cursor, numKeys)
@@ -112,11 +114,445 @@ func assertOrdered(t testType, table *Table, startKey KeyType, numKeys int) bool
 	}
 	for i := 0; i < numKeys; i++ {
 		selected := parseSentinelValue(t, values[i].value)
-		key := startKey + KeyType(i)
-		if !assert.True(t, selected.wellFormed(t, key)) {
+		key := startKey + uint32(i)
+		if !assert.True(t, selected.wellFormed(t, key) && assert.Equal(t, testKey(key), values[i].key)) {
 			fmt.Printf("unexpected row at %d\n", i)
 			return false
 		}
 	}
 	return true
 }
+
+func TestDeleteStatement_Execute(t *testing.T) {
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		insertKeys(t, table, rangeNumKeys)
+
+		must(t, table.Delete(testKey(30)))
+		must(t, table.Delete(testKey(10)))
+		assert.Error(t, table.Delete(testKey(30)), "deleted key")
+		assert.Error(t, table.Delete(testKey(35)), "missing key")
+		assert.Error(t, table.Delete(testKey(40)[1:]), "missing key prefix")
+
+		keys, ok := checkTree(t, table)
+		if !ok {
+			return
+		}
+		assert.Equal(t, testKeys(20, 40, 50), keys)
+		cursor, err := selectEntireTable(table).Query()
+		must(t, err)
+		for _, value := range cursorConsume(t, cursor, len(keys)) {
+			assert.True(t, parseSentinelValue(t, value.value).wellFormed(t, testKeyNumber(value.key)))
+		}
+
+		for _, key := range keys {
+			must(t, table.Delete(key))
+		}
+		keys, ok = checkTree(t, table)
+		if !ok {
+			return
+		}
+		assert.Empty(t, keys)
+		assert.Len(t, table.pager.freePages, int(table.pager.NumPages())-2, "only the header and the root are in use")
+
+		// Free pages are reused.
+		numPages := table.pager.NumPages()
+		insertKeys(t, table, rangeNumKeys)
+		_, ok = checkTree(t, table)
+		assert.True(t, ok)
+		assert.Equal(t, numPages, table.pager.NumPages())
+	})
+}
+
+func TestDeleteStatement_randomInsertDelete(t *testing.T) {
+	const (
+		numOps   = 2000
+		keySpace = 200
+	)
+	for _, seed := range []int64{1, 2, 3, 4, 5} {
+		seed := seed
+		t.Run(fmt.Sprintf("seed_%d", seed), func(t *testing.T) {
+			testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+				r := rand.New(rand.NewSource(seed))
+				present := make(map[uint32]bool)
+				check := func(op string) bool {
+					t.Helper()
+					keys, ok := checkTree(t, table)
+					if !ok {
+						t.Logf("after %s", op)
+						return false
+					}
+					expected := make([]KeyType, 0, len(present))
+					for key := uint32(0); key < keySpace; key++ {
+						if present[key] {
+							expected = append(expected, testKey(key))
+						}
+					}
+					return assert.Equal(t, expected, append([]KeyType{}, keys...), "after %s", op)
+				}
+
+				for op := 0; op < numOps; op++ {
+					key := uint32(r.Intn(keySpace))
+					// Grow the tree during the first half and shrink it during the second.
+					insert := r.Intn(10) < 7
+					if op >= numOps/2 {
+						insert = !insert
+					}
+					if insert {
+						err := newSentinelValue(t, key).toInsertStatement(t, table).Execute()
+						if present[key] {
+							assert.Equal(t, ErrDuplicateKey, errors.Cause(err), "duplicate key %d", key)
+						} else if assert.NoError(t, err, "insert %d", key) {
+							present[key] = true
+						}
+					} else {
+						err := table.Delete(testKey(key))
+						if !present[key] {
+							assert.Error(t, err, "missing key %d", key)
+						} else if assert.NoError(t, err, "delete %d", key) {
+							delete(present, key)
+						}
+					}
+					if !check(fmt.Sprintf("op #%d (key %d, insert %v)", op, key, insert)) {
+						return
+					}
+				}
+
+				// Delete the remaining keys until only an empty root remains.
+				for _, key := range r.Perm(keySpace) {
+					if present[uint32(key)] {
+						must(t, table.Delete(testKey(uint32(key))))
+						delete(present, uint32(key))
+						if !check(fmt.Sprintf("delete %d", key)) {
+							return
+						}
+					}
+				}
+				assert.Len(t, table.pager.freePages, int(table.pager.NumPages())-2, "only the header and the root are in use")
+			})
+		})
+	}
+}
+
+// testKeys returns the keys of tests for numbers.
+func testKeys(numbers ...uint32) []KeyType {
+	keys := make([]KeyType, len(numbers))
+	for index, n := range numbers {
+		keys[index] = testKey(n)
+	}
+	return keys
+}
+
+// tableValues returns the values of the records of a table by their keys.
+func tableValues(t *testing.T, table *Table) map[string][]byte {
+	t.Helper()
+	cursor, err := selectEntireTable(table).Query()
+	must(t, err)
+	values := make(map[string][]byte)
+	for ; !cursor.End(); cursor.Next() {
+		key, value, err := cursor.Value()
+		must(t, err)
+		values[string(key)] = append([]byte(nil), value...)
+	}
+	return values
+}
+
+func TestUpdateStatement_Execute(t *testing.T) {
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		insertKeys(t, table, rangeNumKeys)
+		numPages := table.pager.NumPages()
+		updated := newSentinelValue(t, 99).toBytes(t)
+
+		must(t, table.Update(testKey(30), updated))
+		assert.Error(t, table.Update(testKey(35), updated), "missing key")
+		assert.Error(t, table.Update(testKey(40), updated[1:]), "short value")
+
+		values := tableValues(t, table)
+		assert.Len(t, values, rangeNumKeys)
+		assert.Equal(t, updated, values[string(testKey(30))])
+		assert.True(t, parseSentinelValue(t, values[string(testKey(40))]).wellFormed(t, 40), "unchanged after a failed update")
+		assert.Equal(t, numPages, table.pager.NumPages(), "updated in place")
+
+		// Updates see a copy of the old value.
+		must(t, table.UpdateFunc(testKey(20), func(old []byte) ([]byte, error) {
+			assert.True(t, parseSentinelValue(t, old).wellFormed(t, 20))
+			old[0]++
+			return old, nil
+		}))
+		assert.Error(t, table.UpdateFunc(testKey(10), func(old []byte) ([]byte, error) {
+			old[0]++
+			return nil, errors.New("failed")
+		}))
+		assert.Error(t, table.UpdateFunc(testKey(15), func(old []byte) ([]byte, error) {
+			t.Error("called for a missing key")
+			return old, nil
+		}))
+		values = tableValues(t, table)
+		expected := newSentinelValue(t, 20).toBytes(t)
+		expected[0]++
+		assert.Equal(t, expected, values[string(testKey(20))])
+		assert.True(t, parseSentinelValue(t, values[string(testKey(10))]).wellFormed(t, 10), "unchanged after a failed update")
+	})
+}
+
+func TestUpsertStatement_Execute(t *testing.T) {
+	testWithLimitedTable(t, uint16(sentinelValueSize), func(t *testing.T, table *Table) {
+		insertKeys(t, table, rangeNumKeys)
+
+		must(t, table.Upsert(testKey(20), newSentinelValue(t, 25).toBytes(t)))
+		must(t, table.Upsert(testKey(25), newSentinelValue(t, 25).toBytes(t)))
+		assert.Error(t, table.Upsert(testKey(35), nil), "short value")
+		assert.Error(t, table.Upsert(append(testKey(35), 0), newSentinelValue(t, 35).toBytes(t)), "long key")
+
+		keys, ok := checkTree(t, table)
+		if !ok {
+			return
+		}
+		assert.Equal(t, testKeys(10, 20, 25, 30, 40, 50), keys)
+		values := tableValues(t, table)
+		assert.Equal(t, newSentinelValue(t, 25).toBytes(t), values[string(testKey(20))])
+		assert.Equal(t, newSentinelValue(t, 25).toBytes(t), values[string(testKey(25))])
+	})
+}
+
+// randomValue returns a value of a random length for a key of a given length,
+// mostly fitting in a cell, but sometimes large enough for overflow pages.
+func randomValue(r *rand.Rand, keySize int) []byte {
+	var size int
+	switch n := r.Intn(10); {
+	case n < 6:
+		size = r.Intn(64)
+	case n < 8:
+		size = r.Intn(getMaxInlineValueSize(keySize) + 1)
+	default:
+		size = r.Intn(3 * PageSize)
+	}
+	if size == 0 {
+		// Empty values are read as nil.
+		return nil
+	}
+	value := make([]byte, size)
+	r.Read(value)
+	return value
+}
+
+func TestVariableDataSize_randomInsertUpdateDelete(t *testing.T) {
+	const (
+		numOps   = 1500
+		keySpace = 150
+	)
+	for _, seed := range []int64{1, 2, 3} {
+		seed := seed
+		t.Run(fmt.Sprintf("seed_%d", seed), func(t *testing.T) {
+			testWithLimitedTable(t, VariableDataSize, func(t *testing.T, table *Table) {
+				testRandomInsertUpdateDelete(t, table, seed, numOps, func(r *rand.Rand) KeyType {
+					return testKey(uint32(r.Intn(keySpace)))
+				})
+			})
+		})
+	}
+}
+
+func TestVariableKeySize_randomInsertUpdateDelete(t *testing.T) {
+	const (
+		numOps   = 3000
+		keySpace = 400
+	)
+	// Keys of varying lengths share long prefixes, so that the separator keys in branches
+	// are large and the branches are split and rebalanced by their used space.
+	wideKey := func(n uint32) KeyType {
+		key := bytes.Repeat([]byte{0xab}, int(n%4)*120)
+		key = append(key, testKey(n)...)
+		return append(key, bytes.Repeat([]byte{byte(n)}, int(n%5)*20)...)
+	}
+	for _, seed := range []int64{1, 2, 3} {
+		seed := seed
+		t.Run(fmt.Sprintf("seed_%d", seed), func(t *testing.T) {
+			_clearMaxKeysPerBranchOverride()
+			testWithTable(t, KeySizeLimit, VariableDataSize, func(t *testing.T, table *Table) {
+				testRandomInsertUpdateDelete(t, table, seed, numOps, func(r *rand.Rand) KeyType {
+					return wideKey(uint32(r.Intn(keySpace)))
+				})
+			})
+		})
+	}
+}
+
+// testRandomInsertUpdateDelete applies random inserts, upserts and deletes of keys from newKey
+// to a table, checking the tree after each, then deletes all of the keys.
+func testRandomInsertUpdateDelete(t *testing.T, table *Table, seed int64, numOps int, newKey func(r *rand.Rand) KeyType) {
+	r := rand.New(rand.NewSource(seed))
+	present := make(map[string][]byte)
+	check := func(op string) bool {
+		t.Helper()
+		if _, ok := checkTree(t, table); !ok {
+			t.Logf("after %s", op)
+			return false
+		}
+		return assert.Equal(t, present, tableValues(t, table), "after %s", op)
+	}
+
+	for op := 0; op < numOps; op++ {
+		key := newKey(r)
+		value := randomValue(r, len(key))
+		// Grow the tree during the first half and shrink it during the second.
+		n := r.Intn(10)
+		if op >= numOps/2 {
+			n = 9 - n
+		}
+		var desc string
+		switch {
+		case n < 4:
+			desc = "insert"
+			err := table.Insert(key, value)
+			if _, ok := present[string(key)]; ok {
+				assert.Equal(t, ErrDuplicateKey, errors.Cause(err), "duplicate key %x", key)
+			} else if assert.NoError(t, err, "insert %x", key) {
+				present[string(key)] = value
+			}
+		case n < 7:
+			desc = "upsert"
+			if assert.NoError(t, table.Upsert(key, value), "upsert %x", key) {
+				present[string(key)] = value
+			}
+		default:
+			desc = "delete"
+			err := table.Delete(key)
+			if _, ok := present[string(key)]; !ok {
+				assert.Error(t, err, "missing key %x", key)
+			} else if assert.NoError(t, err, "delete %x", key) {
+				delete(present, string(key))
+			}
+		}
+		if !check(fmt.Sprintf("op #%d (%s %x with %d bytes)", op, desc, key, len(value))) {
+			return
+		}
+	}
+
+	// Delete the remaining keys until only an empty root remains.
+	for key := range present {
+		must(t, table.Delete(KeyType(key)))
+		delete(present, key)
+		if !check(fmt.Sprintf("delete %x", key)) {
+			return
+		}
+	}
+	assert.Len(t, table.pager.freePages, int(table.pager.NumPages())-2, "only the header and the root are in use")
+}
+
+func TestVariableDataSize_update(t *testing.T) {
+	testWithLimitedTable(t, VariableDataSize, func(t *testing.T, table *Table) {
+		large := bytes.Repeat([]byte("large"), PageSize)
+		must(t, table.Insert(testKey(1), []byte("one")))
+		must(t, table.Insert(testKey(2), large))
+		must(t, table.Insert(testKey(3), nil))
+		numPages := table.pager.NumPages()
+
+		must(t, table.Update(testKey(1), []byte("two")))
+		assert.Equal(t, numPages, table.pager.NumPages(), "updated in place")
+		must(t, table.Update(testKey(2), bytes.ToUpper(large)))
+		must(t, table.Update(testKey(1), large))
+		must(t, table.Update(testKey(2), []byte("small")))
+		must(t, table.UpdateFunc(testKey(3), func(old []byte) ([]byte, error) {
+			assert.Empty(t, old)
+			return append(old, "three"...), nil
+		}))
+
+		if _, ok := checkTree(t, table); !ok {
+			return
+		}
+		assert.Equal(t, map[string][]byte{
+			string(testKey(1)): large,
+			string(testKey(2)): []byte("small"),
+			string(testKey(3)): []byte("three"),
+		}, tableValues(t, table))
+	})
+}
+
+func TestVariableDataSize_updateResize(t *testing.T) {
+	testWithLimitedTable(t, VariableDataSize, func(t *testing.T, table *Table) {
+		values := make(map[string][]byte)
+		for key := uint32(1); key <= 4; key++ {
+			value := bytes.Repeat([]byte{byte(key)}, 100)
+			must(t, table.Insert(testKey(key), value))
+			values[string(testKey(key))] = value
+		}
+		cursor, _, _, err := table.findLeaf(testKey(2))
+		must(t, err)
+		numPages := table.pager.NumPages()
+
+		// Cells that still fit are resized within their leaf.
+		maxInline := getMaxInlineValueSize(testKeySize)
+		for _, size := range []int{300, 10, 0, maxInline, 1} {
+			value := bytes.Repeat([]byte{0xff}, size)
+			if size == 0 {
+				// Empty values are read as nil.
+				value = nil
+			}
+			must(t, table.Update(testKey(2), value))
+			values[string(testKey(2))] = value
+
+			resized, _, found, err := table.findLeaf(testKey(2))
+			must(t, err)
+			assert.True(t, found)
+			assert.Equal(t, cursor.pageNum, resized.pageNum, "same leaf for %d bytes", size)
+			assert.Equal(t, cursor.cellNum, resized.cellNum, "same cell for %d bytes", size)
+			assert.Equal(t, numPages, table.pager.NumPages(), "no new pages for %d bytes", size)
+			if _, ok := checkTree(t, table); !ok {
+				return
+			}
+			assert.Equal(t, values, tableValues(t, table), "%d bytes", size)
+		}
+
+		// A cell that no longer fits splits the leaf.
+		for key := uint32(1); key <= 3; key++ {
+			value := bytes.Repeat([]byte{byte(key)}, maxInline)
+			must(t, table.Update(testKey(key), value))
+			values[string(testKey(key))] = value
+		}
+		must(t, table.Insert(testKey(5), []byte("five")))
+		values[string(testKey(5))] = []byte("five")
+		cursor, _, _, err = table.findLeaf(testKey(4))
+		must(t, err)
+		assert.Equal(t, table.rootPageNum, cursor.pageNum)
+		value := bytes.Repeat([]byte{4}, maxInline)
+		must(t, table.Update(testKey(4), value))
+		values[string(testKey(4))] = value
+		split, _, found, err := table.findLeaf(testKey(4))
+		must(t, err)
+		assert.True(t, found)
+		assert.NotEqual(t, table.rootPageNum, split.pageNum, "the root leaf is split and the record moves to the new leaf")
+		if _, ok := checkTree(t, table); !ok {
+			return
+		}
+		assert.Equal(t, values, tableValues(t, table))
+	})
+}
+
+// TestVariableDataSize_updateError asserts that a leaf that cannot replace a cell
+// frees the overflow pages of the new cell.
+func TestVariableDataSize_updateError(t *testing.T) {
+	testWithLimitedTable(t, VariableDataSize, func(t *testing.T, table *Table) {
+		large := bytes.Repeat([]byte("large"), PageSize)
+		must(t, table.Insert(testKey(1), large))
+		cursor, leaf, found, err := table.findLeaf(testKey(1))
+		must(t, err)
+		assert.True(t, found)
+
+		// End the overflow chain of the old cell early, so that it cannot be read.
+		bin := leaf.getCellBin(table, cursor.cellNum)
+		overflowPointer := bin[int(slottedCellHeaderSize)+testKeySize+getOverflowPrefixSize(testKeySize):]
+		binary.LittleEndian.PutUint32(overflowPointer, 0)
+		corrupted := append([]byte(nil), bin...)
+		numFreePages := len(table.pager.freePages)
+
+		newBin, err := table.newCellBin(testKey(1), bytes.ToUpper(large))
+		must(t, err)
+		newPageNums, err := table.overflowPages(newBin)
+		must(t, err)
+		assert.NotEmpty(t, newPageNums)
+
+		assert.Error(t, leaf.update(cursor, newBin))
+		assert.Equal(t, corrupted, leaf.getCellBin(table, cursor.cellNum), "the old cell is unchanged")
+		assert.Equal(t, newPageNums, table.pager.freePages[numFreePages:], "the new overflow pages are freed")
+	})
+}
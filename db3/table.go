@@ -1,56 +1,181 @@
 package db3
 
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"math"
+)
+
 var _ DataSizer = (*Table)(nil)
 
+const (
+	// VariableDataSize is the data size of tables whose values vary in length.
+	// Their leaves hold cells in a slotted layout, and the part of a value that
+	// does not fit in a cell is held in a chain of overflow pages.
+	VariableDataSize uint16 = 0
+	// MaxVariableDataSize is the length of the largest value of a table with VariableDataSize.
+	MaxVariableDataSize = math.MaxUint32
+	// minFixedCellsPerLeaf is the fewest cells of fixed-size data that a leaf must hold.
+	minFixedCellsPerLeaf = 2
+)
+
 // Table is a B+Tree manager backed by a file.
 type Table struct {
 	// pager is responsible for managing reading and
 	// writing changes to and from disk.
 	pager *Pager
+	// maxKeySize is the maximum length of keys within cells in the B+Tree.
+	maxKeySize uint16
 	// dataSize is the size of data within cells in the B+Tree
 	dataSize uint16
+	// header is the header of the file as last written.
+	header fileHeader
 	// rootPageNum is the page index where the root node
 	// is stored in the pager.
 	rootPageNum PagePointer
 }
 
 // Open opens a database table file with the given pager.
-// dataSize is the amount of bytes used in B+Tree cells for rows of data.
-func Open(pager *Pager, dataSize uint16) (*Table, error) {
+// maxKeySize is the maximum length of keys, from 1 to KeySizeLimit. Keys are byte strings
+// ordered like bytes.Compare, so composite keys of big-endian integers are ordered by their
+// components. Fixed-size data reserves maxKeySize bytes for the key of each record.
+// dataSize is the amount of bytes used in B+Tree cells for rows of data,
+// or VariableDataSize for values of any length up to MaxVariableDataSize.
+// Fixed-size data holds the most records per page.
+// A new file gets a header page recording the sizes, and an existing file must have
+// been created with the same sizes.
+func Open(pager *Pager, maxKeySize, dataSize uint16) (*Table, error) {
 	const (
-		rootPageNum = 0
+		newRootPageNum = headerPageNum + 1
 	)
+	if maxKeySize == 0 || maxKeySize > KeySizeLimit {
+		return nil, errors.Errorf("invalid max key size %d, want 1 to %d", maxKeySize, KeySizeLimit)
+	}
+	table := &Table{
+		pager:      pager,
+		maxKeySize: maxKeySize,
+		dataSize:   dataSize,
+	}
+	if !isSlotted(table) && leafNodeMaxCellData/fixedCellSize(table) < minFixedCellsPerLeaf {
+		return nil, errors.Errorf("max key size %d and data size %d are too large for %d records per page", maxKeySize, dataSize, minFixedCellsPerLeaf)
+	}
 	if pager.NumPages() == 0 {
 		// This is a new database file.
-		// Initialize page 0 as a leaf node.
-		page, err := pager.GetPage(rootPageNum)
+		// Initialize the page after the header as a leaf node.
+		page, err := pager.GetPage(newRootPageNum)
 		if err != nil {
 			return nil, wrap(err, "unable to get root page")
 		}
 		leaf := pageToLeafNode(page)
 		leaf.init()
 		leaf.isRoot = true
-		if err := pager.sync1(rootPageNum); err != nil {
+		if err := pager.sync1(newRootPageNum); err != nil {
 			return nil, wrap(err, "unable to save new database")
 		}
+		table.rootPageNum = newRootPageNum
+		if err := table.syncHeader(); err != nil {
+			return nil, wrap(err, "unable to save new database")
+		}
+		pager.freeListChanged = table.syncFreeListHead
+		return table, nil
 	}
-	table := &Table{
-		pager:       pager,
-		dataSize:    dataSize,
-		rootPageNum: rootPageNum,
+
+	// Check that the file has the layout of the table.
+	page, err := pager.GetPage(headerPageNum)
+	if err != nil {
+		return nil, wrap(err, "unable to get header page")
 	}
+	header, err := readHeader(page, pager.NumPages())
+	if err != nil {
+		return nil, wrap(err, "invalid header")
+	}
+	if header.maxKeySize != maxKeySize || header.dataSize != dataSize {
+		return nil, errors.Errorf("file has max key size %d and data size %d, want %d and %d", header.maxKeySize, header.dataSize, maxKeySize, dataSize)
+	}
+	if err := pager.LoadFreePages(header.freeListHead); err != nil {
+		return nil, wrap(err, "unable to load free pages")
+	}
+	table.header = header
+	table.rootPageNum = header.rootPageNum
+	pager.freeListChanged = table.syncFreeListHead
 	return table, nil
 }
 
+// syncHeader writes the header of the file if the root or the free pages changed
+// since it was last written.
+func (t *Table) syncHeader() error {
+	return t.writeHeader(fileHeader{
+		version:      fileFormatVersion,
+		pageSize:     PageSize,
+		maxKeySize:   t.maxKeySize,
+		dataSize:     t.dataSize,
+		rootPageNum:  t.rootPageNum,
+		freeListHead: t.pager.FreeListHead(),
+	})
+}
+
+// syncFreeListHead writes the header of the file with a new head of the free pages as soon
+// as a page is freed or reused. The root is kept as last written, since the tree may be
+// part way through a change until syncHeader is called at the end of the statement.
+func (t *Table) syncFreeListHead(head PagePointer) error {
+	header := t.header
+	header.freeListHead = head
+	return t.writeHeader(header)
+}
+
+// writeHeader writes a header of the file if it differs from the header last written.
+func (t *Table) writeHeader(header fileHeader) error {
+	if header == t.header {
+		return nil
+	}
+	page, err := t.pager.GetPage(headerPageNum)
+	if err != nil {
+		return wrap(err, "unable to get header page")
+	}
+	header.write(page)
+	if err := t.pager.sync1(headerPageNum); err != nil {
+		return wrap(err, "unable to sync header")
+	}
+	t.header = header
+	return nil
+}
+
+// MaxKeySize satisfies the DataSizer interface for B+Tree paging.
+func (t *Table) MaxKeySize() uint16 {
+	return t.maxKeySize
+}
+
 // DataSize satisfies the DataSizer interface for B+Tree paging.
 func (t *Table) DataSize() uint16 {
 	return t.dataSize
 }
 
+// validateKey checks that a key can be stored in this table.
+func (t *Table) validateKey(key KeyType) error {
+	if len(key) > int(t.maxKeySize) {
+		return errors.Errorf("length %d, want at most %d", len(key), t.maxKeySize)
+	}
+	return nil
+}
+
+// validateValueSize checks that a value of a given length can be stored in this table.
+func (t *Table) validateValueSize(size int) error {
+	if isSlotted(t) {
+		if uint64(size) > MaxVariableDataSize {
+			return errors.Errorf("length %d, want at most %d", size, uint64(MaxVariableDataSize))
+		}
+		return nil
+	}
+	if size != int(t.dataSize) {
+		return errors.Errorf("length %d, want %d", size, t.dataSize)
+	}
+	return nil
+}
+
 // Start returns a cursor pointing to the first record in the database.
 func (t *Table) Start() (*Cursor, error) {
 	// Seek out the target page.
-	cursor, err := t.Find(0)
+	cursor, err := t.Find(nil)
 	if err != nil {
 		return nil, wrap(err, "unable to find start of table")
 	}
@@ -81,6 +206,22 @@ func (t *Table) Find(key KeyType) (*Cursor, error) {
 	return t.findInPage(root, t.rootPageNum, key)
 }
 
+// findLeaf returns the position of the given key like Find, along with
+// the leaf node pointed to and whether the key is at that position.
+func (t *Table) findLeaf(key KeyType) (cursor *Cursor, leaf *leafNode, found bool, err error) {
+	cursor, err = t.Find(key)
+	if err != nil {
+		return nil, nil, false, wrap(err, "unable to find key")
+	}
+	page, err := t.pager.GetPage(cursor.pageNum)
+	if err != nil {
+		return nil, nil, false, wrap(err, "unable to get page")
+	}
+	leaf = pageToLeafNode(page)
+	found = cursor.cellNum < leaf.numCells && bytes.Equal(leaf.getCellKey(t, cursor.cellNum), key)
+	return cursor, leaf, found, nil
+}
+
 // findInPage recursively searches a page for the given key.
 func (t *Table) findInPage(page *Page, pageNum PagePointer, key KeyType) (*Cursor, error) {
 	node := pageToNodeHeader(page)
@@ -26,7 +26,7 @@ func (t *Table) printTreeHelper(visited map[PagePointer]struct{}, pageNum PagePo
 		t.printIndent(indentationLevel)
 		fmt.Printf("- leaf#%d (size %d) keys:", pageNum, leaf.numCells)
 		for i := cellptr(0); i < leaf.numCells; i++ {
-			fmt.Printf("%d,", leaf.getCellKey(t, i))
+			fmt.Printf("%x,", leaf.getCellKey(t, i))
 		}
 		fmt.Println()
 	} else {
@@ -34,10 +34,10 @@ func (t *Table) printTreeHelper(visited map[PagePointer]struct{}, pageNum PagePo
 		t.printIndent(indentationLevel)
 		fmt.Printf("- branch#%d (size %d)\n", pageNum, branch.numCells)
 		for i := cellptr(0); i < branch.numCells; i++ {
-			child := branch.cells[i].child
+			child := branch.getChildPage(i)
 			t.printTreeHelper(visited, child, indentationLevel+1)
 			t.printIndent(indentationLevel + 1)
-			fmt.Printf("- key %d\n", branch.cells[i].key)
+			fmt.Printf("- key %x\n", branch.getKey(i))
 		}
 		t.printTreeHelper(visited, branch.rightChild, indentationLevel+1)
 	}
@@ -53,6 +53,10 @@ func (t *Table) printIndent(indentationLevel int) {
 // printPages dumps all the pages in this table.
 func (t *Table) printPages() {
 	for pageNum, p := range t.pager.pages {
+		if PagePointer(pageNum) == headerPageNum {
+			fmt.Printf("%d header%+v\n", pageNum, t.header)
+			continue
+		}
 		if pageToNodeHeader(p).isLeaf {
 			fmt.Println(pageNum, pageToLeafNode(p).String(t))
 		} else {
@@ -1,7 +1,10 @@
 package db3
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -18,3 +21,156 @@ func TestTableStart(t *testing.T) {
 		assert.NotNil(t, cursor)
 	})
 }
+
+func TestTableOpen_reopen(t *testing.T) {
+	must(t, _setMaxKeysPerBranchOverride(maxKeys))
+	file := NewTempFile(t)
+	defer file.Delete()
+	withTable := func(maxKeySize, dataSize uint16, f func(table *Table)) error {
+		pager, err := OpenPager(file.FullPath(), os.O_RDWR|os.O_CREATE, userReadWrite)
+		must(t, err)
+		defer func() {
+			must(t, pager.Close())
+		}()
+		table, err := Open(pager, maxKeySize, dataSize)
+		if err != nil {
+			return err
+		}
+		f(table)
+		return nil
+	}
+
+	// Fill a table, then delete records to free pages.
+	var keys []KeyType
+	var rootPageNum PagePointer
+	var freePages []PagePointer
+	must(t, withTable(testKeySize, VariableDataSize, func(table *Table) {
+		for key := uint32(0); key < 100; key++ {
+			must(t, table.Insert(testKey(key), bytes.Repeat([]byte{byte(key)}, int(key))))
+		}
+		assert.NotEqual(t, headerPageNum+1, table.rootPageNum, "root splits move the root")
+		for key := uint32(0); key < 100; key++ {
+			if key%4 != 0 {
+				must(t, table.Delete(testKey(key)))
+			}
+		}
+		var ok bool
+		if keys, ok = checkTree(t, table); !ok {
+			t.FailNow()
+		}
+		rootPageNum = table.rootPageNum
+		freePages = append([]PagePointer(nil), table.pager.freePages...)
+	}))
+	assert.NotEmpty(t, freePages)
+
+	// The table is found again with its free pages.
+	must(t, withTable(testKeySize, VariableDataSize, func(table *Table) {
+		reopened, ok := checkTree(t, table)
+		if !ok {
+			return
+		}
+		assert.Equal(t, keys, reopened)
+		assert.Equal(t, rootPageNum, table.rootPageNum)
+		assert.Equal(t, freePages, table.pager.freePages)
+
+		numPages := table.pager.NumPages()
+		must(t, table.Insert(testKey(1), nil))
+		_, ok = checkTree(t, table)
+		assert.True(t, ok)
+		assert.Equal(t, numPages, table.pager.NumPages(), "free pages are reused")
+	}))
+
+	// The table is only opened with the sizes it was created with.
+	noop := func(table *Table) {}
+	assert.Error(t, withTable(testKeySize+1, VariableDataSize, noop), "max key size")
+	assert.Error(t, withTable(testKeySize, 8, noop), "data size")
+
+	// A corrupt header is detected.
+	f, err := os.OpenFile(file.FullPath(), os.O_RDWR, userReadWrite)
+	must(t, err)
+	_, err = f.WriteAt([]byte{0xff}, int64(headerRootPageNumOffset))
+	must(t, err)
+	must(t, f.Close())
+	assert.Error(t, withTable(testKeySize, VariableDataSize, noop), "checksum")
+}
+
+func TestTableOpen_notDatabase(t *testing.T) {
+	file := NewTempFile(t)
+	defer file.Delete()
+	must(t, ioutil.WriteFile(file.FullPath(), bytes.Repeat([]byte("binq"), PageSize), userReadWrite))
+
+	pager, err := OpenPager(file.FullPath(), os.O_RDWR, userReadWrite)
+	must(t, err)
+	defer func() {
+		must(t, pager.Close())
+	}()
+	_, err = Open(pager, testKeySize, VariableDataSize)
+	assert.Error(t, err)
+}
+
+func TestTableOpen_partialWrite(t *testing.T) {
+	must(t, _setMaxKeysPerBranchOverride(maxKeys))
+	file := NewTempFile(t)
+	defer file.Delete()
+	withTable := func(f func(table *Table)) {
+		pager, err := OpenPager(file.FullPath(), os.O_RDWR|os.O_CREATE, userReadWrite)
+		must(t, err)
+		defer func() {
+			must(t, pager.Close())
+		}()
+		table, err := Open(pager, testKeySize, VariableDataSize)
+		must(t, err)
+		f(table)
+	}
+
+	// Free pages, then reuse them and free another page without finishing a statement,
+	// as if the process stopped part way through writing.
+	var keys []KeyType
+	var reusedPageNums []PagePointer
+	var freedPageNum PagePointer
+	withTable(func(table *Table) {
+		for key := uint32(0); key < 100; key++ {
+			must(t, table.Insert(testKey(key), bytes.Repeat([]byte{byte(key)}, int(key))))
+		}
+		for key := uint32(0); key < 100; key++ {
+			if key%4 != 0 {
+				must(t, table.Delete(testKey(key)))
+			}
+		}
+		var ok bool
+		if keys, ok = checkTree(t, table); !ok {
+			t.FailNow()
+		}
+		for len(table.pager.freePages) > 0 {
+			pageNum, err := table.pager.GetUnusedPageNum()
+			must(t, err)
+			page, err := table.pager.GetPage(pageNum)
+			must(t, err)
+			copy(page[:], bytes.Repeat([]byte{0xff}, PageSize))
+			must(t, table.pager.sync1(pageNum))
+			reusedPageNums = append(reusedPageNums, pageNum)
+		}
+		var err error
+		freedPageNum, err = table.pager.GetUnusedPageNum()
+		must(t, err)
+		_, err = table.pager.GetPage(freedPageNum)
+		must(t, err)
+		must(t, table.pager.sync1(freedPageNum))
+		must(t, table.pager.FreePage(freedPageNum))
+	})
+
+	// Reused pages are no longer free, and the freed page is.
+	// The reused pages are not in the tree, so they are lost until freed again.
+	assert.NotEmpty(t, reusedPageNums)
+	withTable(func(table *Table) {
+		assert.Equal(t, []PagePointer{freedPageNum}, table.pager.freePages)
+		for _, pageNum := range reusedPageNums {
+			must(t, table.pager.FreePage(pageNum))
+		}
+		reopened, ok := checkTree(t, table)
+		if !ok {
+			return
+		}
+		assert.Equal(t, keys, reopened)
+	})
+}
@@ -0,0 +1,160 @@
+package binq
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Position is a location within filter text.
+// Line and Column are zero based and Column counts runes, like ParserValue.Line and ParserValue.LinePos.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) before(other Position) bool {
+	return p.Line < other.Line || (p.Line == other.Line && p.Column < other.Column)
+}
+
+// Span is a range of filter text from Start up to, but not including, End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// union returns the smallest span containing both spans.
+func (s Span) union(other Span) Span {
+	if other.Start.before(s.Start) {
+		s.Start = other.Start
+	}
+	if s.End.before(other.End) {
+		s.End = other.End
+	}
+	return s
+}
+
+// Severity is how serious a Diagnostic is.
+type Severity int
+
+const (
+	// SeverityError is a problem that prevents the filter from being read.
+	SeverityError Severity = iota + 1
+	// SeverityWarning is a problem that does not prevent the filter from being read.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "<unknown>"
+	}
+}
+
+// Diagnostic is a single problem found in filter text.
+type Diagnostic struct {
+	// Span is the text the problem applies to.
+	Span Span
+	// Severity is how serious the problem is.
+	Severity Severity
+	// Message describes the problem.
+	Message string
+	// Snippet is the first line of the span, numbered and underlined with ^~~~.
+	Snippet string
+}
+
+// String renders the diagnostic as "line:column: severity: message", counting lines and columns from 1.
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Span.Start.Line+1, d.Span.Start.Column+1, d.Severity, d.Message)
+}
+
+// Diagnostics are the problems found in filter text, ordered by position.
+type Diagnostics []*Diagnostic
+
+var _ error = Diagnostics(nil)
+
+func (d Diagnostics) Error() string {
+	parts := make([]string, len(d))
+	for index, diagnostic := range d {
+		parts[index] = diagnostic.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// HasErrors reports whether any diagnostic is an error.
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnose reads the entire input like ReadPredicate, but continues past problems
+// to report every lexical, syntactic and type error in the filter.
+// The Predicate is nil if there are any errors.
+func (p *Parser) Diagnose() (*Predicate, Diagnostics) {
+	var errs errorList
+	pred, _ := p.readPredicate(&errs)
+
+	lines := strings.Split(string(p.s), "\n")
+	diagnostics := make(Diagnostics, len(errs))
+	for index, err := range errs {
+		diagnostic := &Diagnostic{Severity: SeverityError, Message: err.Error()}
+		if posErr, ok := err.(positionalError); ok {
+			diagnostic.Span = posErr.span()
+			diagnostic.Message = posErr.err.Error()
+		}
+		diagnostic.Snippet = renderSnippet(lines, diagnostic.Span)
+		diagnostics[index] = diagnostic
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Span.Start.before(diagnostics[j].Span.Start)
+	})
+	return pred, diagnostics
+}
+
+// renderSnippet renders the first line of a span with the span underlined, for example:
+//
+//	1 | VALUE(0, U64LE) = I64(1)
+//	  |                   ^~~~~~
+//
+// Spans over multiple lines are underlined to the end of their first line.
+func renderSnippet(lines []string, s Span) string {
+	if s.Start.Line < 0 || s.Start.Line >= len(lines) {
+		return ""
+	}
+	line := []rune(strings.TrimRight(lines[s.Start.Line], "\r"))
+	start := s.Start.Column
+	if start > len(line) {
+		start = len(line)
+	}
+	end := len(line)
+	if s.End.Line == s.Start.Line && s.End.Column < end {
+		end = s.End.Column
+	}
+	width := end - start
+	if width < 1 {
+		width = 1
+	}
+
+	// Keep tabs in the padding so that the underline lines up with the text above it.
+	var pad strings.Builder
+	for _, r := range line[:start] {
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	number := strconv.Itoa(s.Start.Line + 1)
+	gutter := strings.Repeat(" ", len(number))
+	return number + " | " + string(line) + "\n" +
+		gutter + " | " + pad.String() + "^" + strings.Repeat("~", width-1) + "\n"
+}
@@ -0,0 +1,185 @@
+package binq
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_Diagnose(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"valid",
+			"VALUE(0, U64LE) = U64(100) AND NOT KEY(0, U8) < U32(2)",
+			nil,
+		},
+		{
+			"empty",
+			"# nothing",
+			[]string{"1:10: error: empty predicate"},
+		},
+		{
+			"lexical",
+			`foo = "abc AND bar`,
+			[]string{`1:1: error: invalid numeric literal "foo"`, "1:7: error: unterminated string literal"},
+		},
+		{
+			"unsupported",
+			"U8(1) = U32(1) OR VALUE(0, BOOL) = true",
+			[]string{
+				`1:1: error: token SCALAR_U8 "U8" is currently not supported`,
+				`1:28: error: token BOOL "BOOL" is currently not supported`,
+			},
+		},
+		{
+			"unknown-function",
+			"VALUE(0, U64LE) = FOO(100, 7) AND VALUE(8, U64LE) = 7",
+			[]string{
+				`1:19: error: unexpected function call "FOO"`,
+				`1:53: error: untyped integer literal "7", use a scalar function such as U64(7)`,
+			},
+		},
+		{
+			"syntax",
+			"(VALUE(0, U64LE) = AND U64(1) U64(2)",
+			[]string{
+				"1:1: error: unmatched parenthesis",
+				"1:18: error: not enough arguments for EQUAL, want 2",
+				"1:24: error: expression is not a boolean expression: got RETURN_TYPE_U64",
+			},
+		},
+		{
+			"types",
+			"VALUE(0, U64LE) = I64(1) OR U64(1) OR NOT U32(2) OR (F64(1) & F64(2)) = F64(0)",
+			[]string{
+				"1:17: error: invalid expression: BINARY_OP_CODE_EQ cannot be applied to RETURN_TYPE_U64 and RETURN_TYPE_I64",
+				"1:29: error: expression is not a boolean expression: got RETURN_TYPE_U64",
+				"1:43: error: expression is not a boolean expression: got RETURN_TYPE_U32",
+				"1:61: error: invalid expression: BINARY_OP_CODE_BIT_AND cannot be applied to RETURN_TYPE_F64",
+			},
+		},
+		{
+			"multi-line",
+			"VALUE(0, U64LE) = 100\nAND\n  (U64(1) + U64(2))\n  AND U32(99999999999) = U32(1))",
+			[]string{
+				`1:19: error: untyped integer literal "100", use a scalar function such as U64(100)`,
+				"3:4: error: expression is not a boolean expression: got RETURN_TYPE_U64",
+				`4:11: error: strconv.ParseUint: parsing "99999999999": value out of range`,
+				"4:32: error: unmatched parenthesis",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pred, diagnostics := NewParser(tc.input).Diagnose()
+			var got []string
+			for _, diagnostic := range diagnostics {
+				got = append(got, diagnostic.String())
+			}
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, len(tc.expected) > 0, diagnostics.HasErrors())
+
+			// The first error found is the one returned by ReadPredicate.
+			expected, err := NewParser(tc.input).ReadPredicate()
+			if len(tc.expected) > 0 {
+				assert.Nil(t, pred)
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.True(t, proto.Equal(expected, pred))
+			}
+		})
+	}
+}
+
+func TestParser_Diagnose_Spans(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected Span
+	}{
+		{"token", "VALUE(0, U64LE) = 100", Span{Position{0, 18}, Position{0, 21}}},
+		{"function", "VALUE(0, U64LE) = U64(100) AND U64( 1 )", Span{Position{0, 31}, Position{0, 39}}},
+		{"operation", "true AND (U32(1) + VALUE(0, U8))", Span{Position{0, 10}, Position{0, 31}}},
+		{"multi-line", "true AND (U32(1) +\n\tVALUE(0, U8))", Span{Position{0, 10}, Position{1, 13}}},
+		{"string", "VALUE(0, BYTES(2)) = \"a\\qb\"", Span{Position{0, 21}, Position{0, 27}}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, diagnostics := NewParser(tc.input).Diagnose()
+			if assert.Len(t, diagnostics, 1) {
+				assert.Equal(t, tc.expected, diagnostics[0].Span)
+			}
+		})
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"VALUE(0, U64LE) = I64(1)", "\tAND U64(1)\r", "", "", "", "", "", "", "", "x"}
+	cases := []struct {
+		name     string
+		span     Span
+		expected string
+	}{
+		{
+			"single",
+			Span{Position{0, 16}, Position{0, 17}},
+			"1 | VALUE(0, U64LE) = I64(1)\n  |                 ^\n",
+		},
+		{
+			"underline",
+			Span{Position{0, 18}, Position{0, 24}},
+			"1 | VALUE(0, U64LE) = I64(1)\n  |                   ^~~~~~\n",
+		},
+		{
+			"tab",
+			Span{Position{1, 5}, Position{1, 11}},
+			"2 | \tAND U64(1)\n  | \t    ^~~~~~\n",
+		},
+		{
+			"multi-line",
+			Span{Position{0, 18}, Position{1, 3}},
+			"1 | VALUE(0, U64LE) = I64(1)\n  |                   ^~~~~~\n",
+		},
+		{
+			"empty",
+			Span{Position{0, 24}, Position{0, 24}},
+			"1 | VALUE(0, U64LE) = I64(1)\n  |                         ^\n",
+		},
+		{
+			"wide-gutter",
+			Span{Position{9, 0}, Position{9, 1}},
+			"10 | x\n   | ^\n",
+		},
+		{
+			"out-of-range",
+			Span{Position{10, 0}, Position{10, 1}},
+			"",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, renderSnippet(lines, tc.span))
+		})
+	}
+}
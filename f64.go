@@ -0,0 +1,62 @@
+package binq
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// GetF64le gets the little-endian IEEE-754 double value in the byte slice.
+func GetF64le(bytes []byte) (interface{}, error) {
+	return readF64le(bytes)
+}
+
+// readF64le is GetF64le without boxing the value.
+func readF64le(bytes []byte) (float64, error) {
+	if len(bytes) < 8 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := math.Float64frombits(binary.LittleEndian.Uint64(bytes))
+	return bytesValue, nil
+}
+
+// GetF64be gets the big-endian IEEE-754 double value in the byte slice.
+func GetF64be(bytes []byte) (interface{}, error) {
+	return readF64be(bytes)
+}
+
+// readF64be is GetF64be without boxing the value.
+func readF64be(bytes []byte) (float64, error) {
+	if len(bytes) < 8 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := math.Float64frombits(binary.BigEndian.Uint64(bytes))
+	return bytesValue, nil
+}
+
+// GetF32le gets the little-endian IEEE-754 float value in the byte slice.
+func GetF32le(bytes []byte) (interface{}, error) {
+	return readF32le(bytes)
+}
+
+// readF32le is GetF32le without boxing the value.
+func readF32le(bytes []byte) (float32, error) {
+	if len(bytes) < 4 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := math.Float32frombits(binary.LittleEndian.Uint32(bytes))
+	return bytesValue, nil
+}
+
+// GetF32be gets the big-endian IEEE-754 float value in the byte slice.
+func GetF32be(bytes []byte) (interface{}, error) {
+	return readF32be(bytes)
+}
+
+// readF32be is GetF32be without boxing the value.
+func readF32be(bytes []byte) (float32, error) {
+	if len(bytes) < 4 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := math.Float32frombits(binary.BigEndian.Uint32(bytes))
+	return bytesValue, nil
+}
@@ -0,0 +1,479 @@
+package binq
+
+import (
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatOptions controls how predicates are rendered as filter text.
+type FormatOptions struct {
+	// Indent, when not empty, places each operand of AND and OR on its own line,
+	// indented by Indent once for each level of nesting.
+	Indent string
+}
+
+// atomPrecedence is the precedence of values that never need parenthesis.
+const atomPrecedence = math.MaxInt32
+
+// FormatPredicate renders a Predicate as canonical filter text that a Parser reads back
+// into the same Predicate.
+//
+// An Any or All with a single expression is rendered as that expression, and
+// an empty Any or All is rendered as false or true respectively.
+func FormatPredicate(pred *Predicate, opts FormatOptions) (string, error) {
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		return FormatExpression(t.Expression, opts)
+	case *Predicate_Any:
+		return formatJunction(TokenOr, t.Any.GetExpressions(), opts)
+	case *Predicate_All:
+		return formatJunction(TokenAnd, t.All.GetExpressions(), opts)
+	default:
+		return "", unhandledType("predicate type", t)
+	}
+}
+
+// FormatExpression renders an Expression as canonical filter text.
+func FormatExpression(ex *Expression, opts FormatOptions) (string, error) {
+	text, _, err := formatExpression(ex, opts)
+	return text, err
+}
+
+// FormatProjection renders a projection as text that Parser.ReadProjection reads back
+// into the same projection.
+func FormatProjection(projection []*Projection) (string, error) {
+	fields := make([]string, len(projection))
+	for index, field := range projection {
+		text, err := formatValue(field.GetValue())
+		if err != nil {
+			return "", err
+		}
+		if field.GetAlias() != "" {
+			text += " " + aliasKeyword + " " + field.GetAlias()
+		}
+		fields[index] = text
+	}
+	return strings.Join(fields, ", "), nil
+}
+
+// FormatAggregates renders aggregates as text that Parser.ReadAggregates reads back
+// into the same aggregates.
+func FormatAggregates(aggregates []*Aggregate) (string, error) {
+	fields := make([]string, len(aggregates))
+	for index, aggregate := range aggregates {
+		text, err := formatAggregate(aggregate)
+		if err != nil {
+			return "", err
+		}
+		fields[index] = text
+	}
+	return strings.Join(fields, ", "), nil
+}
+
+// formatAggregate renders an aggregate function call and its alias, if any.
+func formatAggregate(aggregate *Aggregate) (string, error) {
+	var arg string
+	if aggregate.GetExpression() != nil {
+		text, err := FormatExpression(aggregate.GetExpression(), FormatOptions{})
+		if err != nil {
+			return "", err
+		}
+		arg = text
+	}
+	text := aggregateFunctionText(aggregate.GetFunction()) + "(" + arg + ")"
+	if aggregate.GetAlias() != "" {
+		text += " " + aliasKeyword + " " + aggregate.GetAlias()
+	}
+	return text, nil
+}
+
+// FormatText reads filter text and renders it canonically, keeping every comment.
+//
+// Comments on lines of their own stay before the operand of the AND or OR that follows them,
+// and other comments move to the end of the line of the operand they follow.
+// Without an Indent the filter is a single line, so comments on lines of their own
+// all move before it and the others move to its end.
+func FormatText(s string, opts FormatOptions) (string, error) {
+	values, err := NewParser(s).ReadValues()
+	if err != nil {
+		return "", err
+	}
+	var errs errorList
+	pred, spans := NewParser(s).readPredicate(&errs)
+	if err := errs.first(); err != nil {
+		return "", err
+	}
+	token, parts, err := formatPredicateOperands(pred, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// leading and trailing are the comments of each operand, after those following the filter.
+	leading := make([][]string, len(spans))
+	trailing := make([][]string, len(spans))
+	var after []string
+	last := spans[len(spans)-1]
+	for _, value := range values {
+		if value.token != TokenComment {
+			continue
+		}
+		comment := strings.TrimRight(value.value, " \t\r")
+		start := value.span().Start
+		next := sort.Search(len(spans), func(i int) bool { return start.before(spans[i].Start) })
+		switch {
+		case next == len(spans) && last.End.Line < start.Line:
+			after = append(after, comment)
+		case next < len(spans) && (next == 0 || spans[next-1].End.Line < start.Line):
+			leading[next] = append(leading[next], comment)
+		default:
+			trailing[next-1] = append(trailing[next-1], comment)
+		}
+	}
+
+	var b strings.Builder
+	writeLines := func(indent string, comments []string) {
+		for _, comment := range comments {
+			b.WriteString(indent + comment + "\n")
+		}
+	}
+	if opts.Indent == "" {
+		var comments []string
+		for _, operand := range leading {
+			writeLines("", operand)
+		}
+		for _, operand := range trailing {
+			comments = append(comments, operand...)
+		}
+		parts = []string{strings.Join(parts, " "+token.keyword()+" ")}
+		leading = [][]string{nil}
+		trailing = [][]string{comments}
+	}
+	for index, part := range parts {
+		if index > 0 {
+			writeLines(opts.Indent, leading[index])
+			part = opts.Indent + token.keyword() + " " + part
+		} else {
+			writeLines("", leading[index])
+		}
+		b.WriteString(strings.Join(append([]string{part}, trailing[index]...), " "))
+		b.WriteByte('\n')
+	}
+	writeLines("", after)
+	return b.String(), nil
+}
+
+// formatPredicateOperands renders each expression of an Any or All with more than one expression,
+// or else the whole predicate as a single operand.
+func formatPredicateOperands(pred *Predicate, opts FormatOptions) (Token, []string, error) {
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Any:
+		if len(t.Any.GetExpressions()) > 1 {
+			parts, err := formatJunctionOperands(TokenOr, t.Any.GetExpressions(), opts)
+			return TokenOr, parts, err
+		}
+	case *Predicate_All:
+		if len(t.All.GetExpressions()) > 1 {
+			parts, err := formatJunctionOperands(TokenAnd, t.All.GetExpressions(), opts)
+			return TokenAnd, parts, err
+		}
+	}
+	text, err := FormatPredicate(pred, opts)
+	return TokenUnknown, []string{text}, err
+}
+
+// formatJunction renders expressions joined by AND or OR.
+func formatJunction(token Token, exs []*Expression, opts FormatOptions) (string, error) {
+	switch len(exs) {
+	case 0:
+		// An empty All matches everything, an empty Any matches nothing.
+		return strconv.FormatBool(token == TokenAnd), nil
+	case 1:
+		return FormatExpression(exs[0], opts)
+	}
+
+	separator := " " + token.keyword() + " "
+	if opts.Indent != "" {
+		separator = "\n" + opts.Indent + token.keyword() + " "
+	}
+	parts, err := formatJunctionOperands(token, exs, opts)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(parts, separator), nil
+}
+
+// formatJunctionOperands renders each of the expressions joined by AND or OR,
+// adding parenthesis where the junction requires them.
+func formatJunctionOperands(token Token, exs []*Expression, opts FormatOptions) ([]string, error) {
+	prec := token.Precedence()
+	parts := make([]string, len(exs))
+	for index, ex := range exs {
+		text, exPrec, err := formatExpression(ex, opts)
+		if err != nil {
+			return nil, err
+		}
+		// The junction is left associative, so only the first operand may share its precedence.
+		if exPrec < prec || (index > 0 && exPrec == prec) {
+			text = "(" + text + ")"
+		}
+		parts[index] = text
+	}
+	return parts, nil
+}
+
+// formatExpression renders an Expression and reports the precedence of its outermost operator.
+func formatExpression(ex *Expression, opts FormatOptions) (string, int, error) {
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Scalar:
+		text, err := formatScalar(t.Scalar)
+		return text, atomPrecedence, err
+	case *Expression_Value:
+		text, err := formatValue(t.Value)
+		return text, atomPrecedence, err
+	case *Expression_BinaryOperation:
+		return formatBinaryOperation(t.BinaryOperation, opts)
+	case *Expression_UnaryOperation:
+		return formatUnaryOperation(t.UnaryOperation, opts)
+	default:
+		return "", 0, unhandledType("expression type", t)
+	}
+}
+
+// formatBinaryOperation renders a BinaryOperation, adding parenthesis only where
+// precedence and associativity require them.
+func formatBinaryOperation(op *BinaryOperation, opts FormatOptions) (string, int, error) {
+	token, err := binaryOpCodeToken(op.BinaryOpCode)
+	if err != nil {
+		return "", 0, err
+	}
+	left, leftPrec, err := formatExpression(op.Left, opts)
+	if err != nil {
+		return "", 0, err
+	}
+	right, rightPrec, err := formatExpression(op.Right, opts)
+	if err != nil {
+		return "", 0, err
+	}
+	prec := token.Precedence()
+	leftAssociative := token.IsLeftAssociative()
+	if leftPrec < prec || (leftPrec == prec && !leftAssociative) {
+		left = "(" + left + ")"
+	}
+	if rightPrec < prec || (rightPrec == prec && leftAssociative) {
+		right = "(" + right + ")"
+	}
+	return left + " " + token.keyword() + " " + right, prec, nil
+}
+
+// formatUnaryOperation renders a UnaryOperation as a prefix operator.
+func formatUnaryOperation(op *UnaryOperation, opts FormatOptions) (string, int, error) {
+	if op.UnaryOpCode != UnaryOpCode_UNARY_OP_CODE_NOT {
+		return "", 0, unhandledEnum("unary op code", op.UnaryOpCode)
+	}
+	operand, operandPrec, err := formatExpression(op.Operand, opts)
+	if err != nil {
+		return "", 0, err
+	}
+	prec := TokenNot.Precedence()
+	// Only another prefix operator may share the precedence without parenthesis.
+	_, unary := op.Operand.GetExpression().(*Expression_UnaryOperation)
+	if operandPrec < prec || (operandPrec == prec && !unary) {
+		operand = "(" + operand + ")"
+	}
+	return TokenNot.keyword() + " " + operand, prec, nil
+}
+
+func formatScalar(s *Scalar) (string, error) {
+	switch t := s.GetValue().(type) {
+	case *Scalar_Bool:
+		return strconv.FormatBool(t.Bool), nil
+	case *Scalar_U64:
+		return "U64(" + strconv.FormatUint(t.U64, 10) + ")", nil
+	case *Scalar_U32:
+		return "U32(" + strconv.FormatUint(uint64(t.U32), 10) + ")", nil
+	case *Scalar_I64:
+		return "I64(" + strconv.FormatInt(t.I64, 10) + ")", nil
+	case *Scalar_I32:
+		return "I32(" + strconv.FormatInt(int64(t.I32), 10) + ")", nil
+	case *Scalar_F64:
+		if math.IsNaN(t.F64) || math.IsInf(t.F64, 0) {
+			return "", errors.Errorf("cannot format non-finite F64 %v", t.F64)
+		}
+		return "F64(" + strconv.FormatFloat(t.F64, 'g', -1, 64) + ")", nil
+	case *Scalar_F32:
+		if math.IsNaN(float64(t.F32)) || math.IsInf(float64(t.F32), 0) {
+			return "", errors.Errorf("cannot format non-finite F32 %v", t.F32)
+		}
+		return "F32(" + strconv.FormatFloat(float64(t.F32), 'g', -1, 32) + ")", nil
+	case *Scalar_Bytes:
+		return formatBytes(t.Bytes), nil
+	default:
+		return "", unhandledType("scalar type", t)
+	}
+}
+
+func formatValue(v *Value) (string, error) {
+	jump, err := formatJump(v.GetJump())
+	if err != nil {
+		return "", err
+	}
+	valueType, err := formatValueType(v)
+	if err != nil {
+		return "", err
+	}
+	switch v.Target {
+	case Target_TARGET_VALUE:
+		return "VALUE(" + jump + ", " + valueType + ")", nil
+	case Target_TARGET_KEY:
+		return "KEY(" + jump + ", " + valueType + ")", nil
+	default:
+		return "", unhandledEnum("value target", v.Target)
+	}
+}
+
+func formatJump(j *Jump) (string, error) {
+	var offset uint64
+	var addressType string
+	switch t := j.GetJump().(type) {
+	case *Jump_Offset:
+		return strconv.FormatUint(t.Offset, 10), nil
+	case *Jump_U64Le:
+		offset, addressType = t.U64Le, "U64LE"
+	case *Jump_U64Be:
+		offset, addressType = t.U64Be, "U64BE"
+	case *Jump_U32Le:
+		offset, addressType = t.U32Le, "U32LE"
+	case *Jump_U32Be:
+		offset, addressType = t.U32Be, "U32BE"
+	case *Jump_U16Le:
+		offset, addressType = t.U16Le, "U16LE"
+	case *Jump_U16Be:
+		offset, addressType = t.U16Be, "U16BE"
+	case *Jump_U8:
+		offset, addressType = t.U8, "U8"
+	default:
+		return "", unhandledType("jump type", t)
+	}
+	return "JUMP(" + strconv.FormatUint(offset, 10) + ", " + addressType + ")", nil
+}
+
+// formatBytes renders bytes as a string literal when they are valid UTF-8,
+// otherwise as a hex literal.
+func formatBytes(b []byte) string {
+	if utf8.Valid(b) {
+		return strconv.Quote(string(b))
+	}
+	return `x"` + hex.EncodeToString(b) + `"`
+}
+
+// formatValueType renders the type argument of KEY or VALUE.
+func formatValueType(v *Value) (string, error) {
+	switch v.Type {
+	case ValueType_VALUE_TYPE_BYTES:
+		return "BYTES(" + strconv.FormatUint(v.Length, 10) + ")", nil
+	case ValueType_VALUE_TYPE_BYTES_U8:
+		return "BYTES(U8)", nil
+	case ValueType_VALUE_TYPE_BYTES_U16LE:
+		return "BYTES(U16LE)", nil
+	case ValueType_VALUE_TYPE_BYTES_U16BE:
+		return "BYTES(U16BE)", nil
+	case ValueType_VALUE_TYPE_BYTES_U32LE:
+		return "BYTES(U32LE)", nil
+	case ValueType_VALUE_TYPE_BYTES_U32BE:
+		return "BYTES(U32BE)", nil
+	default:
+		return valueTypeKeyword(v.Type)
+	}
+}
+
+func valueTypeKeyword(valueType ValueType) (string, error) {
+	switch valueType {
+	case ValueType_VALUE_TYPE_U64LE:
+		return "U64LE", nil
+	case ValueType_VALUE_TYPE_U64BE:
+		return "U64BE", nil
+	case ValueType_VALUE_TYPE_U32LE:
+		return "U32LE", nil
+	case ValueType_VALUE_TYPE_U32BE:
+		return "U32BE", nil
+	case ValueType_VALUE_TYPE_U16LE:
+		return "U16LE", nil
+	case ValueType_VALUE_TYPE_U16BE:
+		return "U16BE", nil
+	case ValueType_VALUE_TYPE_U8:
+		return "U8", nil
+	case ValueType_VALUE_TYPE_I64LE:
+		return "I64LE", nil
+	case ValueType_VALUE_TYPE_I64BE:
+		return "I64BE", nil
+	case ValueType_VALUE_TYPE_I32LE:
+		return "I32LE", nil
+	case ValueType_VALUE_TYPE_I32BE:
+		return "I32BE", nil
+	case ValueType_VALUE_TYPE_I16LE:
+		return "I16LE", nil
+	case ValueType_VALUE_TYPE_I16BE:
+		return "I16BE", nil
+	case ValueType_VALUE_TYPE_I8:
+		return "I8", nil
+	case ValueType_VALUE_TYPE_F64LE:
+		return "F64LE", nil
+	case ValueType_VALUE_TYPE_F64BE:
+		return "F64BE", nil
+	case ValueType_VALUE_TYPE_F32LE:
+		return "F32LE", nil
+	case ValueType_VALUE_TYPE_F32BE:
+		return "F32BE", nil
+	default:
+		return "", unhandledEnum("value type", valueType)
+	}
+}
+
+// binaryOpCodeToken returns the operator token for a BinaryOpCode.
+func binaryOpCodeToken(code BinaryOpCode) (Token, error) {
+	switch code {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		return TokenEq, nil
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		return TokenNeq, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		return TokenLess, nil
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return TokenLessEq, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		return TokenGreater, nil
+	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		return TokenGreaterEq, nil
+	case BinaryOpCode_BINARY_OP_CODE_ADD:
+		return TokenAdd, nil
+	case BinaryOpCode_BINARY_OP_CODE_SUB:
+		return TokenSub, nil
+	case BinaryOpCode_BINARY_OP_CODE_MUL:
+		return TokenMul, nil
+	case BinaryOpCode_BINARY_OP_CODE_DIV:
+		return TokenDiv, nil
+	case BinaryOpCode_BINARY_OP_CODE_MOD:
+		return TokenMod, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_AND:
+		return TokenBitAnd, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_OR:
+		return TokenBitOr, nil
+	case BinaryOpCode_BINARY_OP_CODE_BIT_XOR:
+		return TokenBitXor, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHL:
+		return TokenShl, nil
+	case BinaryOpCode_BINARY_OP_CODE_SHR:
+		return TokenShr, nil
+	case BinaryOpCode_BINARY_OP_CODE_AND:
+		return TokenAnd, nil
+	case BinaryOpCode_BINARY_OP_CODE_OR:
+		return TokenOr, nil
+	default:
+		return TokenUnknown, unhandledEnum("binary op code", code)
+	}
+}
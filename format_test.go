@@ -0,0 +1,465 @@
+package binq
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFormatPredicate(t *testing.T) {
+	t.Parallel()
+
+	u64le0 := makeValueExpression(ValueType_VALUE_TYPE_U64LE, 0)
+	u64le0EqU64100 := makeBinaryOperationExpression(u64le0, BinaryOpCode_BINARY_OP_CODE_EQ, makeScalarExpression(t, uint64(100)))
+	u64le0Key := makeValueExpression(ValueType_VALUE_TYPE_U64LE, 0)
+	u64le0Key.GetValue().Target = Target_TARGET_KEY
+	jumped := &Expression{
+		Expression: &Expression_Value{
+			Value: &Value{
+				Jump: &Jump{Jump: &Jump_U16Be{U16Be: 2}},
+				Type: ValueType_VALUE_TYPE_U8,
+			},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		pred     *Predicate
+		opts     FormatOptions
+		expected string
+	}{
+		{
+			"expression",
+			&Predicate{Predicate: &Predicate_Expression{Expression: u64le0EqU64100}},
+			FormatOptions{},
+			"VALUE(0, U64LE) = U64(100)",
+		},
+		{
+			"jump",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				jumped, BinaryOpCode_BINARY_OP_CODE_GREATER_EQ, makeScalarExpression(t, uint32(7)))}},
+			FormatOptions{},
+			"VALUE(JUMP(2, U16BE), U8) >= U32(7)",
+		},
+		{
+			"key",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				u64le0Key, BinaryOpCode_BINARY_OP_CODE_LESS, u64le0)}},
+			FormatOptions{},
+			"KEY(0, U64LE) < VALUE(0, U64LE)",
+		},
+		{
+			"left-nested",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				u64le0EqU64100, BinaryOpCode_BINARY_OP_CODE_NEQ, makeScalarExpression(t, false))}},
+			FormatOptions{},
+			"VALUE(0, U64LE) = U64(100) != false",
+		},
+		{
+			"right-nested",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeScalarExpression(t, true), BinaryOpCode_BINARY_OP_CODE_EQ, u64le0EqU64100)}},
+			FormatOptions{},
+			"true = (VALUE(0, U64LE) = U64(100))",
+		},
+		{
+			"all",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				u64le0EqU64100, makeScalarExpression(t, true), u64le0EqU64100,
+			}}}},
+			FormatOptions{},
+			"VALUE(0, U64LE) = U64(100) AND true AND VALUE(0, U64LE) = U64(100)",
+		},
+		{
+			"any-indented",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: []*Expression{
+				makeScalarExpression(t, true), u64le0EqU64100,
+			}}}},
+			FormatOptions{Indent: "  "},
+			"true\n  OR VALUE(0, U64LE) = U64(100)",
+		},
+		{
+			"single-any",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: []*Expression{u64le0EqU64100}}}},
+			FormatOptions{},
+			"VALUE(0, U64LE) = U64(100)",
+		},
+		{
+			"bytes",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				&Expression{Expression: &Expression_Value{Value: &Value{
+					Jump:   &Jump{Jump: &Jump_Offset{Offset: 4}},
+					Type:   ValueType_VALUE_TYPE_BYTES,
+					Length: 3,
+				}}},
+				BinaryOpCode_BINARY_OP_CODE_EQ,
+				makeScalarExpression(t, []byte("a \"#\n")))}},
+			FormatOptions{},
+			`VALUE(4, BYTES(3)) = "a \"#\n"`,
+		},
+		{
+			"hex-bytes",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				&Expression{Expression: &Expression_Value{Value: &Value{
+					Jump: &Jump{Jump: &Jump_Offset{Offset: 0}},
+					Type: ValueType_VALUE_TYPE_BYTES_U16BE,
+				}}},
+				BinaryOpCode_BINARY_OP_CODE_LESS,
+				makeScalarExpression(t, []byte{0xde, 0xad, 0xbe, 0xef}))}},
+			FormatOptions{},
+			`VALUE(0, BYTES(U16BE)) < x"deadbeef"`,
+		},
+		{
+			"not",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeUnaryOperationExpression(
+				makeUnaryOperationExpression(u64le0EqU64100))}},
+			FormatOptions{},
+			"NOT NOT VALUE(0, U64LE) = U64(100)",
+		},
+		{
+			"nested-or",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				makeScalarExpression(t, true),
+				makeBinaryOperationExpression(makeScalarExpression(t, false), BinaryOpCode_BINARY_OP_CODE_OR, u64le0EqU64100),
+			}}}},
+			FormatOptions{},
+			"true AND (false OR VALUE(0, U64LE) = U64(100))",
+		},
+		{
+			"empty-all",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{}}},
+			FormatOptions{},
+			"true",
+		},
+		{
+			"empty-any",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{}}},
+			FormatOptions{},
+			"false",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			text, err := FormatPredicate(tc.pred, tc.opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expected, text)
+		})
+	}
+}
+
+func TestFormatPredicate_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		pred *Predicate
+	}{
+		{"empty", &Predicate{}},
+		{"empty-expression", &Predicate{Predicate: &Predicate_Expression{Expression: &Expression{}}}},
+		{"unknown-value-type", &Predicate{Predicate: &Predicate_Expression{Expression: makeValueExpression(ValueType_VALUE_TYPE_UNKNOWN, 0)}}},
+		{"nan", &Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+			makeValueExpression(ValueType_VALUE_TYPE_F64LE, 0), BinaryOpCode_BINARY_OP_CODE_EQ, makeScalarExpression(t, math.NaN()))}}},
+		{"unknown-op-code", &Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+			makeScalarExpression(t, true), BinaryOpCode_BINARY_OP_CODE_UNKNOWN, makeScalarExpression(t, true))}}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := FormatPredicate(tc.pred, FormatOptions{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		opts     FormatOptions
+		expected string
+	}{
+		{
+			name: "indent",
+			input: `
+# Header comment.
+ # Second line.
+(VALUE(0, U64LE) = U64(7)   # Inner comment.
+	)  AND   (value(JUMP(0, u16le), U32) != U32(10))
+`,
+			opts: FormatOptions{Indent: "\t"},
+			expected: `# Header comment.
+# Second line.
+VALUE(0, U64LE) = U64(7) # Inner comment.
+	AND VALUE(JUMP(0, U16LE), U32LE) != U32(10)
+`,
+		},
+		{
+			name:  "trailing and leading",
+			input: "# lead\nVALUE(0,U8) = U32(1) # trailing\n# inner\nAND VALUE(1,U8) = U32(2)\n# end\n",
+			opts:  FormatOptions{Indent: "\t"},
+			expected: `# lead
+VALUE(0, U8) = U32(1) # trailing
+	# inner
+	AND VALUE(1, U8) = U32(2)
+# end
+`,
+		},
+		{
+			name:  "single line",
+			input: "# lead\nVALUE(0,U8) = U32(1) # trailing\n# inner\nAND VALUE(1,U8) = U32(2) # last\n",
+			expected: `# lead
+# inner
+VALUE(0, U8) = U32(1) AND VALUE(1, U8) = U32(2) # trailing # last
+`,
+		},
+		{
+			name:  "expression",
+			input: "VALUE(0,U8) # inside\n= U32(1) # after\n",
+			opts:  FormatOptions{Indent: "\t"},
+			expected: `VALUE(0, U8) = U32(1) # inside # after
+`,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			text, err := FormatText(c.input, c.opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, c.expected, text)
+
+			// Formatting is idempotent.
+			again, err := FormatText(text, c.opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, text, again)
+		})
+	}
+}
+
+func TestFormatText_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := FormatText("VALUE(0, U8) =\n", FormatOptions{})
+	if !assert.Error(t, err) {
+		return
+	}
+	_, diagnostics := NewParser("VALUE(0, U8) =\n").Diagnose()
+	if !assert.Len(t, diagnostics, 1) {
+		return
+	}
+	// Errors and diagnostics count lines and positions from 1.
+	start := diagnostics[0].Span.Start
+	assert.Contains(t, err.Error(), fmt.Sprintf("line %d position %d", start.Line+1, start.Column+1))
+}
+
+// TestFormatPredicate_RoundTrip asserts that formatted predicates read back into identical predicates.
+func TestFormatPredicate_RoundTrip(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		pred := randomPredicate(r)
+		for _, opts := range []FormatOptions{{}, {Indent: "\t"}} {
+			text, err := FormatPredicate(pred, opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+			parsed, err := NewParser(text).ReadPredicate()
+			if !assert.NoError(t, err, text) {
+				return
+			}
+			if !assert.True(t, proto.Equal(pred, parsed), "%s\nwant %s\ngot  %s", text, proto.CompactTextString(pred), proto.CompactTextString(parsed)) {
+				return
+			}
+		}
+	}
+}
+
+// randomPredicate creates a predicate in the canonical form produced by a Parser.
+// Operands are either all unsigned or all signed, since U64 cannot be compared with signed types.
+func randomPredicate(r *rand.Rand) *Predicate {
+	signed := r.Intn(2) == 0
+	n := r.Intn(4) + 1
+	if n == 1 {
+		ex := randomBooleanExpression(r, 3, signed)
+		ex = excludeLogicalOperation(ex, BinaryOpCode_BINARY_OP_CODE_AND)
+		ex = excludeLogicalOperation(ex, BinaryOpCode_BINARY_OP_CODE_OR)
+		return &Predicate{Predicate: &Predicate_Expression{Expression: ex}}
+	}
+	opCode := BinaryOpCode_BINARY_OP_CODE_AND
+	if r.Intn(2) == 0 {
+		opCode = BinaryOpCode_BINARY_OP_CODE_OR
+	}
+	expressions := &Expressions{}
+	for i := 0; i < n; i++ {
+		ex := excludeLogicalOperation(randomBooleanExpression(r, 3, signed), opCode)
+		expressions.Expressions = append(expressions.Expressions, ex)
+	}
+	if opCode == BinaryOpCode_BINARY_OP_CODE_AND {
+		return &Predicate{Predicate: &Predicate_All{All: expressions}}
+	}
+	return &Predicate{Predicate: &Predicate_Any{Any: expressions}}
+}
+
+// excludeLogicalOperation negates ex if it is an AND or OR operation with opCode,
+// since a Parser flattens those into the enclosing junction.
+func excludeLogicalOperation(ex *Expression, opCode BinaryOpCode) *Expression {
+	if ex.GetBinaryOperation().GetBinaryOpCode() != opCode {
+		return ex
+	}
+	return makeUnaryOperationExpression(ex)
+}
+
+func randomBooleanExpression(r *rand.Rand, depth int, signed bool) *Expression {
+	if depth == 0 || r.Intn(4) == 0 {
+		return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_Bool{Bool: r.Intn(2) == 0}}}}
+	}
+	ops := []BinaryOpCode{
+		BinaryOpCode_BINARY_OP_CODE_EQ,
+		BinaryOpCode_BINARY_OP_CODE_NEQ,
+		BinaryOpCode_BINARY_OP_CODE_LESS,
+		BinaryOpCode_BINARY_OP_CODE_LESS_EQ,
+		BinaryOpCode_BINARY_OP_CODE_GREATER,
+		BinaryOpCode_BINARY_OP_CODE_GREATER_EQ,
+	}
+	switch r.Intn(8) {
+	case 0:
+		return makeBinaryOperationExpression(randomBytesExpression(r), ops[r.Intn(len(ops))], randomBytesExpression(r))
+	case 1:
+		return makeUnaryOperationExpression(randomBooleanExpression(r, depth-1, signed))
+	case 2:
+		opCode := BinaryOpCode_BINARY_OP_CODE_AND
+		if r.Intn(2) == 0 {
+			opCode = BinaryOpCode_BINARY_OP_CODE_OR
+		}
+		return makeBinaryOperationExpression(
+			excludeLogicalOperation(randomBooleanExpression(r, depth-1, signed), opCode),
+			opCode,
+			excludeLogicalOperation(randomBooleanExpression(r, depth-1, signed), opCode),
+		)
+	}
+	return makeBinaryOperationExpression(
+		randomOperandExpression(r, depth-1, signed),
+		ops[r.Intn(len(ops))],
+		randomOperandExpression(r, depth-1, signed),
+	)
+}
+
+// randomIntegerExpression creates an integer expression, which may use arithmetic and bitwise operators.
+func randomIntegerExpression(r *rand.Rand, depth int, signed bool) *Expression {
+	if depth > 0 && r.Intn(2) == 0 {
+		ops := []BinaryOpCode{
+			BinaryOpCode_BINARY_OP_CODE_ADD,
+			BinaryOpCode_BINARY_OP_CODE_SUB,
+			BinaryOpCode_BINARY_OP_CODE_MUL,
+			BinaryOpCode_BINARY_OP_CODE_DIV,
+			BinaryOpCode_BINARY_OP_CODE_MOD,
+			BinaryOpCode_BINARY_OP_CODE_BIT_AND,
+			BinaryOpCode_BINARY_OP_CODE_BIT_OR,
+			BinaryOpCode_BINARY_OP_CODE_BIT_XOR,
+			BinaryOpCode_BINARY_OP_CODE_SHL,
+			BinaryOpCode_BINARY_OP_CODE_SHR,
+		}
+		return makeBinaryOperationExpression(
+			randomIntegerExpression(r, depth-1, signed),
+			ops[r.Intn(len(ops))],
+			randomIntegerExpression(r, depth-1, signed),
+		)
+	}
+	valueTypes := uintValueTypes
+	if signed {
+		valueTypes = intValueTypes
+	}
+	switch r.Intn(3) {
+	case 0:
+		if signed {
+			return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_I32{I32: int32(r.Uint32())}}}}
+		}
+		return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_U64{U64: r.Uint64()}}}}
+	default:
+		return &Expression{Expression: &Expression_Value{Value: &Value{
+			Jump: &Jump{Jump: &Jump_Offset{Offset: uint64(r.Intn(64))}},
+			Type: valueTypes[r.Intn(len(valueTypes))],
+		}}}
+	}
+}
+
+func randomBytesExpression(r *rand.Rand) *Expression {
+	if r.Intn(2) == 0 {
+		b := make([]byte, r.Intn(8))
+		r.Read(b)
+		return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_Bytes{Bytes: b}}}}
+	}
+	valueTypes := []ValueType{
+		ValueType_VALUE_TYPE_BYTES,
+		ValueType_VALUE_TYPE_BYTES_U8,
+		ValueType_VALUE_TYPE_BYTES_U16LE,
+		ValueType_VALUE_TYPE_BYTES_U16BE,
+		ValueType_VALUE_TYPE_BYTES_U32LE,
+		ValueType_VALUE_TYPE_BYTES_U32BE,
+	}
+	v := &Value{
+		Jump:   &Jump{Jump: &Jump_Offset{Offset: uint64(r.Intn(64))}},
+		Type:   valueTypes[r.Intn(len(valueTypes))],
+		Target: Target(r.Intn(2)),
+	}
+	if v.Type == ValueType_VALUE_TYPE_BYTES {
+		v.Length = uint64(r.Intn(16))
+	}
+	return &Expression{Expression: &Expression_Value{Value: v}}
+}
+
+func randomOperandExpression(r *rand.Rand, depth int, signed bool) *Expression {
+	switch r.Intn(6) {
+	case 0:
+		return randomBooleanExpression(r, depth, signed)
+	case 4:
+		return randomIntegerExpression(r, depth, signed)
+	case 1:
+		if signed {
+			return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_I64{I64: r.Int63() - r.Int63()}}}}
+		}
+		return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_U64{U64: r.Uint64()}}}}
+	case 2:
+		if signed {
+			return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_I32{I32: int32(r.Uint32())}}}}
+		}
+		return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_U32{U32: r.Uint32()}}}}
+	case 3:
+		if r.Intn(2) == 0 {
+			return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_F32{F32: float32(r.NormFloat64() * 1e6)}}}}
+		}
+		return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_F64{F64: r.NormFloat64() * math.Pow(10, float64(r.Intn(60)-30))}}}}
+	default:
+		jumps := []*Jump{
+			{Jump: &Jump_Offset{Offset: uint64(r.Intn(64))}},
+			{Jump: &Jump_U64Le{U64Le: uint64(r.Intn(64))}},
+			{Jump: &Jump_U32Be{U32Be: uint64(r.Intn(64))}},
+			{Jump: &Jump_U8{U8: uint64(r.Intn(64))}},
+		}
+		valueTypes := append([]ValueType{ValueType_VALUE_TYPE_F32BE, ValueType_VALUE_TYPE_F64LE}, uintValueTypes...)
+		if signed {
+			valueTypes = append([]ValueType{ValueType_VALUE_TYPE_F32LE, ValueType_VALUE_TYPE_F64BE}, intValueTypes...)
+		}
+		return &Expression{Expression: &Expression_Value{Value: &Value{
+			Jump:   jumps[r.Intn(len(jumps))],
+			Type:   valueTypes[r.Intn(len(valueTypes))],
+			Target: Target(r.Intn(2)),
+		}}}
+	}
+}
@@ -0,0 +1,101 @@
+package binq
+
+import "encoding/binary"
+
+// GetI64le gets the little-endian int64 value in the byte slice.
+func GetI64le(bytes []byte) (interface{}, error) {
+	return readI64le(bytes)
+}
+
+// readI64le is GetI64le without boxing the value.
+func readI64le(bytes []byte) (int64, error) {
+	if len(bytes) < 8 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int64(binary.LittleEndian.Uint64(bytes))
+	return bytesValue, nil
+}
+
+// GetI64be gets the big-endian int64 value in the byte slice.
+func GetI64be(bytes []byte) (interface{}, error) {
+	return readI64be(bytes)
+}
+
+// readI64be is GetI64be without boxing the value.
+func readI64be(bytes []byte) (int64, error) {
+	if len(bytes) < 8 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int64(binary.BigEndian.Uint64(bytes))
+	return bytesValue, nil
+}
+
+// GetI32le gets the little-endian int32 value in the byte slice.
+func GetI32le(bytes []byte) (interface{}, error) {
+	return readI32le(bytes)
+}
+
+// readI32le is GetI32le without boxing the value.
+func readI32le(bytes []byte) (int32, error) {
+	if len(bytes) < 4 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int32(binary.LittleEndian.Uint32(bytes))
+	return bytesValue, nil
+}
+
+// GetI32be gets the big-endian int32 value in the byte slice.
+func GetI32be(bytes []byte) (interface{}, error) {
+	return readI32be(bytes)
+}
+
+// readI32be is GetI32be without boxing the value.
+func readI32be(bytes []byte) (int32, error) {
+	if len(bytes) < 4 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int32(binary.BigEndian.Uint32(bytes))
+	return bytesValue, nil
+}
+
+// GetI16le gets the little-endian int16 value in the byte slice.
+func GetI16le(bytes []byte) (interface{}, error) {
+	return readI16le(bytes)
+}
+
+// readI16le is GetI16le without boxing the value.
+func readI16le(bytes []byte) (int16, error) {
+	if len(bytes) < 2 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int16(binary.LittleEndian.Uint16(bytes))
+	return bytesValue, nil
+}
+
+// GetI16be gets the big-endian int16 value in the byte slice.
+func GetI16be(bytes []byte) (interface{}, error) {
+	return readI16be(bytes)
+}
+
+// readI16be is GetI16be without boxing the value.
+func readI16be(bytes []byte) (int16, error) {
+	if len(bytes) < 2 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int16(binary.BigEndian.Uint16(bytes))
+	return bytesValue, nil
+}
+
+// GetI8 gets the int8 value in the byte slice.
+func GetI8(bytes []byte) (interface{}, error) {
+	return readI8(bytes)
+}
+
+// readI8 is GetI8 without boxing the value.
+func readI8(bytes []byte) (int8, error) {
+	if len(bytes) < 1 {
+		return 0, ErrBytesTooSmall
+	}
+	bytesValue := int8(bytes[0])
+	return bytesValue, nil
+}
@@ -61,12 +61,12 @@ func JumpToU64le(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU64le(jumpedBytes)
+		jumpAddr, err := readU64le(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint64 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset64(jumpAddr.(uint64), bytes)
+		newBytes, err := jumpOffset64(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
@@ -84,12 +84,12 @@ func JumpToU64be(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU64be(jumpedBytes)
+		jumpAddr, err := readU64be(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint64 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset64(jumpAddr.(uint64), bytes)
+		newBytes, err := jumpOffset64(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
@@ -107,12 +107,12 @@ func JumpToU32le(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU32le(jumpedBytes)
+		jumpAddr, err := readU32le(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint32 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset32(jumpAddr.(uint32), bytes)
+		newBytes, err := jumpOffset32(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
@@ -130,12 +130,12 @@ func JumpToU32be(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU32be(jumpedBytes)
+		jumpAddr, err := readU32be(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint32 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset32(jumpAddr.(uint32), bytes)
+		newBytes, err := jumpOffset32(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
@@ -153,12 +153,12 @@ func JumpToU16le(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU16le(jumpedBytes)
+		jumpAddr, err := readU16le(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint16 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset16(jumpAddr.(uint16), bytes)
+		newBytes, err := jumpOffset16(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
@@ -176,12 +176,12 @@ func JumpToU16be(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU16be(jumpedBytes)
+		jumpAddr, err := readU16be(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint16 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset16(jumpAddr.(uint16), bytes)
+		newBytes, err := jumpOffset16(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
@@ -199,12 +199,12 @@ func JumpToU8(offset uint64) JumperFunc {
 			return nil, wrap(err, "unable to jump to address")
 		}
 		// Decode the jump address.
-		jumpAddr, err := GetU8(jumpedBytes)
+		jumpAddr, err := readU8(jumpedBytes)
 		if err != nil {
 			return nil, wrap(err, "unable to decode uint8 jump address")
 		}
 		// Jump to that position in the original bytes.
-		newBytes, err := jumpOffset8(jumpAddr.(uint8), bytes)
+		newBytes, err := jumpOffset8(jumpAddr, bytes)
 		if err != nil {
 			return nil, wrap(err, "unable to jump to address")
 		}
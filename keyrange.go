@@ -0,0 +1,544 @@
+package binq
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"math"
+	"sort"
+	"strings"
+)
+
+// KeyRange is a range of keys, which are ordered like byte strings, from Start, inclusive,
+// to End, exclusive, where an empty End is unbounded. Keys shorter than MinLength are not
+// within the range.
+type KeyRange struct {
+	Start, End []byte
+	MinLength  int
+}
+
+func (r KeyRange) String() string {
+	s := fmt.Sprintf("[%x, %x)", r.Start, r.End)
+	if r.MinLength > 0 {
+		s += fmt.Sprintf(" min %d", r.MinLength)
+	}
+	return s
+}
+
+// isBefore determines if a key is before the end of the range.
+func (r KeyRange) isBefore(key []byte) bool {
+	return len(r.End) == 0 || bytes.Compare(key, r.End) < 0
+}
+
+// Contains determines if a key is within the range.
+func (r KeyRange) Contains(key []byte) bool {
+	return bytes.Compare(key, r.Start) >= 0 && r.isBefore(key) && len(key) >= r.MinLength
+}
+
+// endBefore determines if the end of a range is before the end of another.
+func endBefore(end, other []byte) bool {
+	return len(end) > 0 && (len(other) == 0 || bytes.Compare(end, other) < 0)
+}
+
+// KeyRanges are sorted ranges of keys, which do not overlap.
+// No ranges contain no keys.
+type KeyRanges []KeyRange
+
+func (rs KeyRanges) String() string {
+	parts := make([]string, len(rs))
+	for index, r := range rs {
+		parts[index] = r.String()
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Contains determines if a key is within the ranges.
+func (rs KeyRanges) Contains(key []byte) bool {
+	for _, r := range rs {
+		if r.Contains(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns the keys in either ranges, neither of which may have a MinLength.
+func (rs KeyRanges) union(other KeyRanges) KeyRanges {
+	var out KeyRanges
+	add := func(r KeyRange) {
+		if last := len(out) - 1; last >= 0 && (len(out[last].End) == 0 || bytes.Compare(r.Start, out[last].End) <= 0) {
+			if endBefore(out[last].End, r.End) {
+				out[last].End = r.End
+			}
+			return
+		}
+		out = append(out, r)
+	}
+	i, j := 0, 0
+	for i < len(rs) || j < len(other) {
+		if j == len(other) || (i < len(rs) && bytes.Compare(rs[i].Start, other[j].Start) <= 0) {
+			add(rs[i])
+			i++
+		} else {
+			add(other[j])
+			j++
+		}
+	}
+	return out
+}
+
+// intersect returns the keys in both ranges.
+func (rs KeyRanges) intersect(other KeyRanges) KeyRanges {
+	var out KeyRanges
+	i, j := 0, 0
+	for i < len(rs) && j < len(other) {
+		r := rs[i]
+		if bytes.Compare(other[j].Start, r.Start) > 0 {
+			r.Start = other[j].Start
+		}
+		if endBefore(other[j].End, r.End) {
+			r.End = other[j].End
+		}
+		if other[j].MinLength > r.MinLength {
+			r.MinLength = other[j].MinLength
+		}
+		if r.isBefore(r.Start) {
+			out = append(out, r)
+		}
+		if endBefore(rs[i].End, other[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// complement returns the keys that are not in the ranges, which may not have a MinLength.
+func (rs KeyRanges) complement() KeyRanges {
+	var out KeyRanges
+	var next []byte
+	for _, r := range rs {
+		if bytes.Compare(r.Start, next) > 0 {
+			out = append(out, KeyRange{Start: next, End: r.Start})
+		}
+		if len(r.End) == 0 {
+			return out
+		}
+		next = r.End
+	}
+	return append(out, KeyRange{Start: next})
+}
+
+// ExtractKeyRanges derives the ranges of keys that records must have to match a Query,
+// from its start and end and from comparisons in its predicate, and the residual predicate that
+// remains to be evaluated for records within the ranges.
+//
+// Keys are ordered like byte strings, so the start and end of the query bound them like bytes.Compare.
+// A key is made of components, which are read by KEY(offset, type). The components that are unsigned
+// big-endian integers, read by U8, U16BE, U32BE or U64BE, are ordered like the keys that share the
+// bytes before them. Comparisons of the first component with integer scalars are replaced by the
+// ranges where the predicate requires them, that is at the top level or in AND operations.
+// Comparisons of a later component are too, in AND operations that fix each of the components before
+// it to one of a few values, such as KEY(0, U32BE) = U32(7) AND KEY(4, U64BE) >= U64(1000) for keys
+// made of a tenant followed by a timestamp. The residual predicate is an empty All if every record
+// within the ranges matches.
+//
+// Records outside of the ranges are not evaluated, so records for which the predicate would fail,
+// such as those with keys too short for a component, may instead be skipped.
+func ExtractKeyRanges(query *Query) (KeyRanges, *Predicate, error) {
+	var bounds KeyRanges
+	if len(query.GetEnd()) == 0 || bytes.Compare(query.GetStart(), query.GetEnd()) < 0 {
+		bounds = KeyRanges{{Start: query.GetStart(), End: query.GetEnd()}}
+	}
+	pred := query.GetPredicate()
+	if pred == nil {
+		return bounds, &Predicate{Predicate: &Predicate_All{All: &Expressions{}}}, nil
+	}
+	if errs := Check(pred); len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	// The residual keeps the terms of the top level conjunction whose ranges are not exact.
+	var conjunction []*Expression
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		conjunction = chainExpressions(t.Expression, BinaryOpCode_BINARY_OP_CODE_AND)
+	case *Predicate_All:
+		for _, ex := range t.All.GetExpressions() {
+			conjunction = append(conjunction, chainExpressions(ex, BinaryOpCode_BINARY_OP_CODE_AND)...)
+		}
+	case *Predicate_Any:
+		keys, exact := keyDisjunction(t.Any.GetExpressions())
+		if exact {
+			return keys.keyRanges().intersect(bounds), &Predicate{Predicate: &Predicate_All{All: &Expressions{}}}, nil
+		}
+		return keys.keyRanges().intersect(bounds), proto.Clone(pred).(*Predicate), nil
+	default:
+		return nil, nil, unhandledType("predicate type", t)
+	}
+	keys, exact := keyConjunction(conjunction)
+	residual := &Expressions{}
+	for index, ex := range conjunction {
+		if !exact[index] {
+			residual.Expressions = append(residual.Expressions, proto.Clone(ex).(*Expression))
+		}
+	}
+	ranges := keys.keyRanges().intersect(bounds)
+	if len(residual.Expressions) == 1 {
+		return ranges, &Predicate{Predicate: &Predicate_Expression{Expression: residual.Expressions[0]}}, nil
+	}
+	return ranges, &Predicate{Predicate: &Predicate_All{All: residual}}, nil
+}
+
+// keySet is the keys within ranges that are at least length long.
+type keySet struct {
+	// ranges are the ranges of keys, without a MinLength.
+	ranges KeyRanges
+	length int
+	// strict is set if the expression of the keys fails for every key shorter than length.
+	strict bool
+}
+
+func allKeys() keySet {
+	return keySet{ranges: KeyRanges{{}}, strict: true}
+}
+
+func noKeys() keySet {
+	return keySet{strict: true}
+}
+
+// keyRanges returns the ranges of the keys with their MinLength.
+func (s keySet) keyRanges() KeyRanges {
+	ranges := make(KeyRanges, len(s.ranges))
+	for index, r := range s.ranges {
+		r.MinLength = s.length
+		ranges[index] = r
+	}
+	return ranges
+}
+
+// isAll determines if the set is every key.
+func (s keySet) isAll() bool {
+	return len(s.ranges) == 1 && len(s.ranges[0].Start) == 0 && len(s.ranges[0].End) == 0 && s.length == 0
+}
+
+// intersect returns the keys in both sets.
+func (s keySet) intersect(other keySet) keySet {
+	switch {
+	case s.isAll():
+		return other
+	case other.isAll():
+		return s
+	}
+	length := s.length
+	if other.length > length {
+		length = other.length
+	}
+	return keySet{
+		ranges: s.ranges.intersect(other.ranges),
+		length: length,
+		strict: s.strict && other.strict && s.length == other.length,
+	}
+}
+
+// union returns the keys in either set, and reports if it is exactly those keys.
+// Unless the sets have the same length, the union also has shorter keys from the set with the greater length.
+func (s keySet) union(other keySet) (keySet, bool) {
+	switch {
+	case len(s.ranges) == 0:
+		return other, true
+	case len(other.ranges) == 0:
+		return s, true
+	case s.length == other.length:
+		return keySet{ranges: s.ranges.union(other.ranges), length: s.length, strict: s.strict && other.strict}, true
+	}
+	length := s.length
+	if other.length < length {
+		length = other.length
+	}
+	return keySet{ranges: s.ranges.union(other.ranges), length: length}, false
+}
+
+// complement returns the keys of at least the length that are not in the set.
+func (s keySet) complement() keySet {
+	return keySet{ranges: s.ranges.complement(), length: s.length, strict: s.strict}
+}
+
+// keyExpression derives the keys for which a boolean expression may be true.
+// exact is true if the expression is true for exactly those keys, regardless of the value.
+func keyExpression(ex *Expression) (keys keySet, exact bool) {
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Scalar:
+		if value, ok := t.Scalar.GetValue().(*Scalar_Bool); ok {
+			if value.Bool {
+				return allKeys(), true
+			}
+			return noKeys(), true
+		}
+	case *Expression_UnaryOperation:
+		// Keys too short for the operand fail it, so they are outside of both the operand and its complement.
+		operand, operandExact := keyExpression(t.UnaryOperation.Operand)
+		if operandExact && operand.strict {
+			return operand.complement(), true
+		}
+	case *Expression_BinaryOperation:
+		switch t.BinaryOperation.BinaryOpCode {
+		case BinaryOpCode_BINARY_OP_CODE_AND:
+			keys, termsExact := keyConjunction(chainExpressions(ex, BinaryOpCode_BINARY_OP_CODE_AND))
+			exact := true
+			for _, termExact := range termsExact {
+				exact = exact && termExact
+			}
+			return keys, exact
+		case BinaryOpCode_BINARY_OP_CODE_OR:
+			return keyDisjunction(chainExpressions(ex, BinaryOpCode_BINARY_OP_CODE_OR))
+		}
+		if c, ok := newKeyComparison(ex); ok {
+			if keys, ok := c.within(allKeys()); ok {
+				return keys, true
+			}
+		}
+	}
+	return allKeys(), false
+}
+
+// keyDisjunction derives the keys for which any of the expressions may be true.
+func keyDisjunction(exs []*Expression) (keySet, bool) {
+	keys, exact := noKeys(), true
+	for _, ex := range exs {
+		for _, term := range chainExpressions(ex, BinaryOpCode_BINARY_OP_CODE_OR) {
+			terms, termExact := keyExpression(term)
+			var unionExact bool
+			keys, unionExact = keys.union(terms)
+			exact = exact && termExact && unionExact
+		}
+	}
+	return keys, exact
+}
+
+// keyConjunction derives the keys for which all of the expressions may be true, and reports
+// which of the expressions are true for exactly those keys.
+//
+// Comparisons of later components of keys are derived last, in order of their offset,
+// within the keys of the components before them.
+func keyConjunction(exs []*Expression) (keySet, []bool) {
+	keys := allKeys()
+	exact := make([]bool, len(exs))
+	var comparisons []int
+	components := make(map[int]keyComparison)
+	for index, ex := range exs {
+		if c, ok := newKeyComparison(ex); ok {
+			comparisons = append(comparisons, index)
+			components[index] = c
+			continue
+		}
+		var terms keySet
+		terms, exact[index] = keyExpression(ex)
+		keys = keys.intersect(terms)
+	}
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		return components[comparisons[i]].offset < components[comparisons[j]].offset
+	})
+
+	// prefixes are the keys before the comparisons of the component at the current offset.
+	prefixes, offset := keys, -1
+	for _, index := range comparisons {
+		c := components[index]
+		if c.offset != offset {
+			prefixes, offset = keys, c.offset
+		}
+		terms, ok := c.within(prefixes)
+		if !ok {
+			// The first component does not need the keys before it.
+			terms, ok = c.within(allKeys())
+		}
+		if ok {
+			keys = keys.intersect(terms)
+		}
+		exact[index] = ok
+	}
+	return keys, exact
+}
+
+// keyComponentSizes are the sizes of the types of components of keys that are ordered like the keys.
+var keyComponentSizes = map[ValueType]int{
+	ValueType_VALUE_TYPE_U8:    1,
+	ValueType_VALUE_TYPE_U16BE: 2,
+	ValueType_VALUE_TYPE_U32BE: 4,
+	ValueType_VALUE_TYPE_U64BE: 8,
+}
+
+// keyComparison is a comparison of a component of keys with an integer scalar.
+type keyComparison struct {
+	// offset and size are the position of the component within keys.
+	offset, size int
+	// values are the sorted intervals of values of the component for which the comparison is true.
+	values []valueInterval
+}
+
+// valueInterval is an interval of values of a component, from min to max inclusive.
+type valueInterval struct {
+	min, max uint64
+}
+
+// newKeyComparison reads a comparison between a component of keys and an integer scalar,
+// if the comparison compares their values exactly.
+func newKeyComparison(ex *Expression) (keyComparison, bool) {
+	op := ex.GetBinaryOperation()
+	if _, isComparison := booleanOps[op.GetBinaryOpCode()]; op == nil || !isComparison {
+		return keyComparison{}, false
+	}
+	opCode, left, right := op.BinaryOpCode, op.Left, op.Right
+	if left.GetScalar() != nil {
+		opCode, left, right = flipComparison(opCode), right, left
+	}
+	value := left.GetValue()
+	size, isComponent := keyComponentSizes[value.GetType()]
+	offset, isOffset := value.GetJump().GetJump().(*Jump_Offset)
+	if !isComponent || !isOffset || offset.Offset > math.MaxInt32 || value.GetTarget() != Target_TARGET_KEY || right.GetScalar() == nil {
+		return keyComparison{}, false
+	}
+	scalar, err := compileScalar(right.GetScalar())
+	if err != nil || !isIntegerType(scalar.returnType) {
+		return keyComparison{}, false
+	}
+	keyType := valueTypeReturnTypes[value.GetType()]
+	upscaledType, err := getUpscaledType(keyType, scalar.returnType)
+	if err != nil {
+		return keyComparison{}, false
+	}
+	// Components keep their value in the upscaled type if it is wide enough.
+	keyBits, upscaledBits := typeBits(keyType), typeBits(upscaledType)
+	if upscaledBits < keyBits || (isSignedType(upscaledType) && upscaledBits == keyBits) {
+		return keyComparison{}, false
+	}
+	scalar, err = convert(scalar, upscaledType)
+	if err != nil {
+		return keyComparison{}, false
+	}
+
+	c := keyComparison{offset: int(offset.Offset), size: size}
+	max := uint64(math.MaxUint64) >> (64 - keyBits)
+	if isSignedType(upscaledType) && scalar.constInt < 0 {
+		// Every component is greater than a negative value.
+		switch opCode {
+		case BinaryOpCode_BINARY_OP_CODE_NEQ, BinaryOpCode_BINARY_OP_CODE_GREATER, BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+			c.values = []valueInterval{{min: 0, max: max}}
+		}
+		return c, true
+	}
+	scalarValue := scalar.constUint
+	if isSignedType(upscaledType) {
+		scalarValue = uint64(scalar.constInt)
+	}
+	c.values = compareValues(opCode, scalarValue, max)
+	return c, true
+}
+
+// compareValues returns the values up to max that compare with a value.
+func compareValues(opCode BinaryOpCode, value, max uint64) []valueInterval {
+	atMost := func(hi uint64) []valueInterval {
+		if hi > max {
+			hi = max
+		}
+		return []valueInterval{{min: 0, max: hi}}
+	}
+	atLeast := func(lo uint64) []valueInterval {
+		if lo > max {
+			return nil
+		}
+		return []valueInterval{{min: lo, max: max}}
+	}
+	switch opCode {
+	case BinaryOpCode_BINARY_OP_CODE_EQ:
+		if value > max {
+			return nil
+		}
+		return []valueInterval{{min: value, max: value}}
+	case BinaryOpCode_BINARY_OP_CODE_NEQ:
+		if value > max {
+			return atMost(max)
+		}
+		var values []valueInterval
+		if value > 0 {
+			values = append(values, valueInterval{min: 0, max: value - 1})
+		}
+		if value < max {
+			values = append(values, valueInterval{min: value + 1, max: max})
+		}
+		return values
+	case BinaryOpCode_BINARY_OP_CODE_LESS:
+		if value == 0 {
+			return nil
+		}
+		return atMost(value - 1)
+	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
+		return atMost(value)
+	case BinaryOpCode_BINARY_OP_CODE_GREATER:
+		if value == math.MaxUint64 {
+			return nil
+		}
+		return atLeast(value + 1)
+	default:
+		return atLeast(value)
+	}
+}
+
+// maxKeyPrefixes is the most prefixes of keys within which comparisons of a later component are derived.
+const maxKeyPrefixes = 256
+
+// within returns the keys for which the comparison is true that start with one of the prefixes,
+// if each range of the prefixes is every key that starts with one of a few prefixes as long as
+// the offset of the component.
+func (c keyComparison) within(prefixes keySet) (keySet, bool) {
+	if prefixes.length > c.offset {
+		return keySet{}, false
+	}
+	keys := keySet{length: c.offset + c.size, strict: true}
+	count := 0
+	for _, r := range prefixes.ranges {
+		if len(r.Start) != c.offset || (len(r.End) != c.offset && len(r.End) != 0) {
+			return keySet{}, false
+		}
+		for prefix := r.Start; ; {
+			if count++; count > maxKeyPrefixes {
+				return keySet{}, false
+			}
+			for _, v := range c.values {
+				keys.ranges = append(keys.ranges, KeyRange{
+					Start: c.encode(prefix, v.min),
+					End:   increment(c.encode(prefix, v.max)),
+				})
+			}
+			if prefix = increment(prefix); len(prefix) == 0 || bytes.Equal(prefix, r.End) {
+				break
+			}
+		}
+	}
+	return keys, true
+}
+
+// encode appends a value of the component to a prefix of keys.
+func (c keyComparison) encode(prefix []byte, value uint64) []byte {
+	b := make([]byte, len(prefix)+c.size)
+	copy(b, prefix)
+	for i := 1; i <= c.size; i++ {
+		b[len(b)-i] = byte(value)
+		value >>= 8
+	}
+	return b
+}
+
+// increment returns the first byte string of the same length after b, or nil if there is none.
+// Every key that starts with b is before it.
+func increment(b []byte) []byte {
+	next := append([]byte(nil), b...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package binq
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestExtractKeyRanges(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		start    []interface{}
+		end      []interface{}
+		input    string
+		expected string
+		residual string
+	}{
+		{
+			"interval",
+			nil, nil,
+			"KEY(0, U32BE) >= U32(100) AND KEY(0, U32BE) < U32(200)",
+			"{[00000064, 000000c8) min 4}",
+			"true",
+		},
+		{
+			"interval-and-value",
+			nil, nil,
+			"KEY(0, U32BE) >= U32(100) AND VALUE(0, U8) = U32(1) AND KEY(0, U32BE) < U32(200)",
+			"{[00000064, 000000c8) min 4}",
+			"VALUE(0, U8) = U32(1)",
+		},
+		{
+			"start-end",
+			[]interface{}{u32be(150)}, []interface{}{u32be(300)},
+			"KEY(0, U32BE) >= U32(100) AND KEY(0, U32BE) < U32(200)",
+			"{[00000096, 000000c8) min 4}",
+			"true",
+		},
+		{
+			"start-end-only",
+			[]interface{}{u32be(150)}, []interface{}{u32be(300)},
+			"VALUE(0, U8) = U32(1)",
+			"{[00000096, 0000012c)}",
+			"VALUE(0, U8) = U32(1)",
+		},
+		{
+			"empty-bounds",
+			[]interface{}{u32be(5)}, []interface{}{u32be(5)},
+			"true",
+			"{}",
+			"true",
+		},
+		{
+			"flipped",
+			nil, nil,
+			"U32(100) < KEY(0, U32BE)",
+			"{[00000065, ) min 4}",
+			"true",
+		},
+		{
+			"union",
+			nil, nil,
+			"KEY(0, U32BE) = U32(5) OR KEY(0, U32BE) > U32(7) AND KEY(0, U32BE) <= U32(9) OR KEY(0, U32BE) = U32(6)",
+			"{[00000005, 00000007) min 4, [00000008, 0000000a) min 4}",
+			"true",
+		},
+		{
+			"not-equal",
+			nil, nil,
+			"KEY(0, U32BE) != U32(5) AND KEY(0, U32BE) < U32(10)",
+			"{[00000000, 00000005) min 4, [00000006, 0000000a) min 4}",
+			"true",
+		},
+		{
+			"not",
+			nil, nil,
+			"NOT (KEY(0, U32BE) > U32(5) AND KEY(0, U32BE) < U32(10))",
+			"{[, 00000006) min 4, [0000000a, ) min 4}",
+			"true",
+		},
+		{
+			"not-mixed-lengths",
+			nil, nil,
+			"NOT (KEY(0, U8) = U32(1) AND KEY(0, U32BE) < U32(10))",
+			"{[, )}",
+			"NOT (KEY(0, U8) = U32(1) AND KEY(0, U32BE) < U32(10))",
+		},
+		{
+			"inexact-or",
+			nil, nil,
+			"KEY(0, U32BE) < U32(5) OR VALUE(0, U8) = U32(1)",
+			"{[, )}",
+			"KEY(0, U32BE) < U32(5) OR VALUE(0, U8) = U32(1)",
+		},
+		{
+			"nested-inexact",
+			nil, nil,
+			"(KEY(0, U32BE) < U32(5) AND VALUE(0, U8) = U32(1)) OR KEY(0, U32BE) = U32(9)",
+			"{[00000000, 00000005) min 4, [00000009, 0000000a) min 4}",
+			"KEY(0, U32BE) < U32(5) AND VALUE(0, U8) = U32(1) OR KEY(0, U32BE) = U32(9)",
+		},
+		{
+			"negative",
+			nil, nil,
+			"KEY(0, U32BE) > I32(-1) AND KEY(0, U32BE) <= I64(3)",
+			"{[00000000, 00000004) min 4}",
+			"true",
+		},
+		{
+			"contradiction",
+			nil, nil,
+			"KEY(0, U32BE) < I32(-1) OR KEY(0, U32BE) > U64(5000000000)",
+			"{}",
+			"true",
+		},
+		{
+			"little-endian",
+			nil, nil,
+			"KEY(0, U32BE) = U32(5) AND KEY(0, U16LE) = U32(5)",
+			"{[00000005, 00000006) min 4}",
+			"KEY(0, U16LE) = U32(5)",
+		},
+		{
+			"float",
+			nil, nil,
+			"KEY(0, U32BE) = F64(5)",
+			"{[, )}",
+			"KEY(0, U32BE) = F64(5)",
+		},
+		{
+			"any",
+			nil, nil,
+			"KEY(0, U32BE) = U32(5) OR KEY(0, U32BE) = U32(9)",
+			"{[00000005, 00000006) min 4, [00000009, 0000000a) min 4}",
+			"true",
+		},
+		{
+			"composite",
+			nil, nil,
+			"KEY(0, U32BE) = U32(7) AND KEY(4, U64BE) >= U64(1000) AND KEY(4, U64BE) < U64(2000)",
+			"{[0000000700000000000003e8, 0000000700000000000007d0) min 12}",
+			"true",
+		},
+		{
+			"composite-tenants",
+			nil, nil,
+			"(KEY(0, U32BE) = U32(1) OR KEY(0, U32BE) = U32(2)) AND KEY(4, U64BE) > U64(5)",
+			"{[000000010000000000000006, 000000020000000000000000) min 12, [000000020000000000000006, 00000003) min 12}",
+			"true",
+		},
+		{
+			"composite-range",
+			nil, nil,
+			"KEY(0, U32BE) > U32(1) AND KEY(4, U64BE) = U64(5)",
+			"{[00000002, ) min 4}",
+			"KEY(4, U64BE) = U64(5)",
+		},
+		{
+			"composite-nested",
+			nil, nil,
+			"KEY(0, U32BE) = U32(1) AND KEY(4, U64BE) = U64(5) OR KEY(0, U32BE) = U32(2)",
+			"{[000000010000000000000005, 000000010000000000000006) min 4, [00000002, 00000003) min 4}",
+			"KEY(0, U32BE) = U32(1) AND KEY(4, U64BE) = U64(5) OR KEY(0, U32BE) = U32(2)",
+		},
+		{
+			"composite-bytes",
+			nil, nil,
+			"KEY(0, U8) = U32(1) AND KEY(1, U16BE) <= U32(2) AND KEY(0, U8) = U32(1)",
+			"{[010000, 010003) min 3}",
+			"true",
+		},
+		{
+			"later-component",
+			nil, nil,
+			"KEY(4, U64BE) = U64(5)",
+			"{[, )}",
+			"KEY(4, U64BE) = U64(5)",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pred, err := NewParser(tc.input).ReadPredicate()
+			if !assert.NoError(t, err) {
+				return
+			}
+			query := &Query{Start: makeBytes(t, tc.start...), End: makeBytes(t, tc.end...), Predicate: pred}
+			ranges, residual, err := ExtractKeyRanges(query)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expected, ranges.String())
+			text, err := FormatPredicate(residual, FormatOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.residual, text)
+		})
+	}
+}
+
+func TestExtractKeyRanges_Invalid(t *testing.T) {
+	t.Parallel()
+	_, _, err := ExtractKeyRanges(&Query{Predicate: &Predicate{}})
+	assert.Equal(t, Check(&Predicate{}), err)
+}
+
+func TestKeyRanges(t *testing.T) {
+	t.Parallel()
+	a := KeyRanges{{End: []byte{3}}, {Start: []byte{10}, End: []byte{20}}, {Start: []byte{30}}}
+	b := KeyRanges{{Start: []byte{2}, End: []byte{11}}, {Start: []byte{21}, End: []byte{29}}}
+	assert.Equal(t, KeyRanges{{End: []byte{20}}, {Start: []byte{21}, End: []byte{29}}, {Start: []byte{30}}}, a.union(b))
+	assert.Equal(t, KeyRanges{{Start: []byte{2}, End: []byte{3}}, {Start: []byte{10}, End: []byte{11}}}, a.intersect(b))
+	assert.Equal(t, KeyRanges{{Start: []byte{3}, End: []byte{10}}, {Start: []byte{20}, End: []byte{30}}}, a.complement())
+	assert.Equal(t, KeyRanges{{End: []byte{2}}, {Start: []byte{11}, End: []byte{21}}, {Start: []byte{29}}}, b.complement())
+	assert.True(t, a.Contains([]byte{2, 255}))
+	assert.False(t, a.Contains([]byte{3}))
+	assert.True(t, a.Contains([]byte{255, 255}))
+
+	short := KeyRanges{{Start: []byte{1}, MinLength: 2}}
+	assert.False(t, short.Contains([]byte{1}))
+	assert.True(t, short.Contains([]byte{1, 0}))
+	assert.Equal(t, []byte{1, 0}, increment([]byte{0, 255}))
+	assert.Nil(t, increment([]byte{255, 255}))
+}
+
+// TestExtractKeyRanges_RecordMatcher asserts that random predicates over keys and values only match
+// records within their ranges, and that their residual predicates match the same records within them.
+func TestExtractKeyRanges_RecordMatcher(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(7))
+	tenants := []uint32{0, 1, 2, 5, 6, 7, 99, 100, 101, math.MaxInt32, math.MaxUint32 - 1, math.MaxUint32}
+	timestamps := []uint64{0, 1, 5, 6, 100, math.MaxUint32, math.MaxUint64 - 1, math.MaxUint64}
+	// Keys are a tenant followed by a timestamp, some of which are cut short.
+	randomKey := func() []byte {
+		key := makeBytes(t, u32be(tenants[r.Intn(len(tenants))]), u64be(timestamps[r.Intn(len(timestamps))]))
+		if r.Intn(8) == 0 {
+			return key[:r.Intn(len(key))]
+		}
+		return key
+	}
+	for i := 0; i < 2000; i++ {
+		query := &Query{Predicate: &Predicate{Predicate: &Predicate_Expression{Expression: randomKeyExpression(r, 3)}}}
+		if r.Intn(4) == 0 {
+			query.Start = randomKey()
+		}
+		if r.Intn(4) == 0 {
+			query.End = randomKey()
+		}
+		ranges, residual, err := ExtractKeyRanges(query)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		matcher, err := PredicateToRecordMatcher(query.Predicate)
+		assert.NoError(t, err)
+		residualMatcher, err := PredicateToRecordMatcher(residual)
+		assert.NoError(t, err)
+
+		for j := 0; j < 16; j++ {
+			key, value := randomKey(), randomRecordBytes(r)
+			inBounds := bytes.Compare(key, query.Start) >= 0 && (len(query.End) == 0 || bytes.Compare(key, query.End) < 0)
+			expected, expectedErr := matcher.MatchRecord(key, value)
+			expected = expected && inBounds
+			if !ranges.Contains(key) {
+				assert.False(t, expected && expectedErr == nil, "%v matched %x outside of %v", query.Predicate, key, ranges)
+				continue
+			}
+			got, err := residualMatcher.MatchRecord(key, value)
+			if expectedErr == nil && err == nil {
+				assert.Equal(t, expected, got, "%v: %v", query.Predicate, residual)
+			}
+		}
+	}
+}
+
+// randomKeyExpression creates a boolean expression of comparisons of keys and values.
+func randomKeyExpression(r *rand.Rand, depth int) *Expression {
+	if depth > 0 && r.Intn(3) > 0 {
+		left := randomKeyExpression(r, depth-1)
+		switch r.Intn(5) {
+		case 0:
+			return makeUnaryOperationExpression(left)
+		case 1, 2:
+			return makeBinaryOperationExpression(left, BinaryOpCode_BINARY_OP_CODE_AND, randomKeyExpression(r, depth-1))
+		default:
+			return makeBinaryOperationExpression(left, BinaryOpCode_BINARY_OP_CODE_OR, randomKeyExpression(r, depth-1))
+		}
+	}
+	opCode := BinaryOpCode(r.Intn(len(booleanOps)) + int(BinaryOpCode_BINARY_OP_CODE_EQ))
+	if r.Intn(4) == 0 {
+		value := &Expression{Expression: &Expression_Value{Value: &Value{Jump: &Jump{Jump: &Jump_Offset{}}, Type: ValueType_VALUE_TYPE_U8}}}
+		return makeBinaryOperationExpression(value, opCode, &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_U32{U32: uint32(r.Intn(4))}}}})
+	}
+	// The key is either the tenant or the timestamp of composite keys.
+	// U64 cannot be compared with signed types, so the timestamp is only compared with unsigned scalars.
+	key := &Expression{Expression: &Expression_Value{Value: &Value{Jump: &Jump{Jump: &Jump_Offset{}}, Type: ValueType_VALUE_TYPE_U32BE, Target: Target_TARGET_KEY}}}
+	scalarTypes := 4
+	if r.Intn(2) == 0 {
+		key = &Expression{Expression: &Expression_Value{Value: &Value{Jump: &Jump{Jump: &Jump_Offset{Offset: 4}}, Type: ValueType_VALUE_TYPE_U64BE, Target: Target_TARGET_KEY}}}
+		scalarTypes = 2
+	}
+	integers := []int64{-1, 0, 1, 5, 6, 100, math.MaxInt32, math.MaxUint32 - 1, math.MaxUint32, math.MaxUint32 + 1}
+	integer := integers[r.Intn(len(integers))]
+	var scalar *Scalar
+	switch r.Intn(scalarTypes) {
+	case 0:
+		scalar = &Scalar{Value: &Scalar_U32{U32: uint32(integer)}}
+	case 1:
+		scalar = &Scalar{Value: &Scalar_U64{U64: uint64(integer)}}
+	case 2:
+		scalar = &Scalar{Value: &Scalar_I32{I32: int32(integer)}}
+	default:
+		scalar = &Scalar{Value: &Scalar_I64{I64: integer}}
+	}
+	scalarExpression := &Expression{Expression: &Expression_Scalar{Scalar: scalar}}
+	if r.Intn(2) == 0 {
+		return makeBinaryOperationExpression(scalarExpression, opCode, key)
+	}
+	return makeBinaryOperationExpression(key, opCode, scalarExpression)
+}
@@ -67,6 +67,68 @@ func Len(minLength int) MatcherFunc {
 	}
 }
 
+// RecordMatcher is the interface for matching patterns on key-value records.
+type RecordMatcher interface {
+	// MatchRecord returns true if the key and value data matches.
+	MatchRecord(key, value []byte) (bool, error)
+}
+
+var _ RecordMatcher = (RecordMatcherFunc)(nil)
+
+// RecordMatcherFunc is a RecordMatcher composed of a single function.
+type RecordMatcherFunc func(key, value []byte) (bool, error)
+
+// MatchRecord satisfies the RecordMatcher interface.
+func (f RecordMatcherFunc) MatchRecord(key, value []byte) (bool, error) {
+	return f(key, value)
+}
+
+// MatchValue creates a RecordMatcher that applies a Matcher to the value of records.
+func MatchValue(matcher Matcher) RecordMatcherFunc {
+	return func(_, value []byte) (bool, error) {
+		return matcher.Match(value)
+	}
+}
+
+// MatchKey creates a RecordMatcher that applies a Matcher to the key of records.
+func MatchKey(matcher Matcher) RecordMatcherFunc {
+	return func(key, _ []byte) (bool, error) {
+		return matcher.Match(key)
+	}
+}
+
+// AllRecords creates a RecordMatcher that matches if all RecordMatcher predicates are satisfied.
+func AllRecords(funcs ...RecordMatcher) RecordMatcherFunc {
+	return func(key, value []byte) (bool, error) {
+		for _, f := range funcs {
+			result, err := f.MatchRecord(key, value)
+			if err != nil {
+				return false, wrap(err, "unable to run matcher")
+			}
+			if !result {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// AnyRecords creates a RecordMatcher that matches if at least one RecordMatcher predicate is satisfied.
+func AnyRecords(funcs ...RecordMatcher) RecordMatcherFunc {
+	return func(key, value []byte) (bool, error) {
+		for _, f := range funcs {
+			result, err := f.MatchRecord(key, value)
+			if err != nil {
+				return false, wrap(err, "unable to run matcher")
+			}
+			if result {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
 type Evaluator interface {
 	Evaluate([]byte) (interface{}, ReturnType, error)
 }
@@ -78,3 +140,33 @@ type EvaluatorFunc func([]byte) (interface{}, ReturnType, error)
 func (e EvaluatorFunc) Evaluate(b []byte) (interface{}, ReturnType, error) {
 	return e(b)
 }
+
+// RecordEvaluator is the interface for computing values from key-value records.
+type RecordEvaluator interface {
+	// Evaluate computes a value from the key and value data.
+	Evaluate(key, value []byte) (interface{}, ReturnType, error)
+}
+
+var _ RecordEvaluator = (RecordEvaluatorFunc)(nil)
+
+// RecordEvaluatorFunc is a RecordEvaluator composed of a single function.
+type RecordEvaluatorFunc func(key, value []byte) (interface{}, ReturnType, error)
+
+// Evaluate satisfies the RecordEvaluator interface.
+func (f RecordEvaluatorFunc) Evaluate(key, value []byte) (interface{}, ReturnType, error) {
+	return f(key, value)
+}
+
+// EvaluateValue creates a RecordEvaluator that applies an Evaluator to the value of records.
+func EvaluateValue(evaluator Evaluator) RecordEvaluatorFunc {
+	return func(_, value []byte) (interface{}, ReturnType, error) {
+		return evaluator.Evaluate(value)
+	}
+}
+
+// EvaluateKey creates a RecordEvaluator that applies an Evaluator to the key of records.
+func EvaluateKey(evaluator Evaluator) RecordEvaluatorFunc {
+	return func(key, _ []byte) (interface{}, ReturnType, error) {
+		return evaluator.Evaluate(key)
+	}
+}
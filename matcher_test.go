@@ -92,3 +92,94 @@ func TestLen(t *testing.T) {
 		})
 	}
 }
+
+func TestAllRecords(t *testing.T) {
+	t.Parallel()
+	var testKey, testValue []byte
+
+	cases := []struct {
+		name          string
+		matchers      []RecordMatcher
+		expectedMatch bool
+		expectedErr   bool
+	}{
+		{"empty", []RecordMatcher{}, true, false},
+		{"single-match", []RecordMatcher{MatchValue(matchesAny())}, true, false},
+		{"single-no-match", []RecordMatcher{MatchKey(matchesNone())}, false, false},
+		{"single-err", []RecordMatcher{MatchValue(matchesErr())}, false, true},
+		{"multi-match", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesAny())}, true, false},
+		{"multi-no-match", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesNone())}, false, false},
+		{"multi-err", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesErr())}, false, true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := AllRecords(tc.matchers...)(testKey, testValue)
+			assert.Equal(t, tc.expectedErr, err != nil, "(un)expected error")
+			assert.Equal(t, tc.expectedMatch, matches, "(un)expected match")
+		})
+	}
+}
+
+func TestAnyRecords(t *testing.T) {
+	t.Parallel()
+	var testKey, testValue []byte
+
+	cases := []struct {
+		name          string
+		matchers      []RecordMatcher
+		expectedMatch bool
+		expectedErr   bool
+	}{
+		{"empty", []RecordMatcher{}, false, false},
+		{"single-match", []RecordMatcher{MatchValue(matchesAny())}, true, false},
+		{"single-no-match", []RecordMatcher{MatchKey(matchesNone())}, false, false},
+		{"single-err", []RecordMatcher{MatchValue(matchesErr())}, false, true},
+		{"multi-one-match", []RecordMatcher{MatchKey(matchesNone()), MatchValue(matchesAny())}, true, false},
+		{"multi-no-match", []RecordMatcher{MatchKey(matchesNone()), MatchValue(matchesNone())}, false, false},
+		{"multi-match-err", []RecordMatcher{MatchKey(matchesAny()), MatchValue(matchesErr())}, true, false},
+		{"multi-no-match-err", []RecordMatcher{MatchKey(matchesNone()), MatchValue(matchesErr())}, false, true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := AnyRecords(tc.matchers...)(testKey, testValue)
+			assert.Equal(t, tc.expectedErr, err != nil, "(un)expected error")
+			assert.Equal(t, tc.expectedMatch, matches, "(un)expected match")
+		})
+	}
+}
+
+func TestMatchKeyAndValue(t *testing.T) {
+	t.Parallel()
+	key := []byte{1, 2, 3, 4}
+	value := []byte{1, 2}
+
+	matches, err := MatchKey(Len(4))(key, value)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+
+	matches, err = MatchValue(Len(4))(key, value)
+	assert.NoError(t, err)
+	assert.False(t, matches)
+}
+
+func TestEvaluateKeyAndValue(t *testing.T) {
+	t.Parallel()
+	key := []byte{1, 2, 3, 4}
+	value := []byte{1, 2}
+	length := EvaluatorFunc(func(b []byte) (interface{}, ReturnType, error) {
+		return uint32(len(b)), ReturnType_RETURN_TYPE_U32, nil
+	})
+
+	result, returnType, err := EvaluateKey(length).Evaluate(key, value)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), result)
+	assert.Equal(t, ReturnType_RETURN_TYPE_U32, returnType)
+
+	result, _, err = EvaluateValue(length).Evaluate(key, value)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), result)
+}
@@ -1,114 +1,61 @@
 package binq
 
-func performBinaryOperation(valueType ReturnType, valueA, valueB interface{}, op BinaryOpCode) (interface{}, error) {
-	switch valueType {
-	case ReturnType_RETURN_TYPE_U64:
-		return performOpU64(valueA.(uint64), valueB.(uint64), op)
-	case ReturnType_RETURN_TYPE_U32:
-		return performOpU32(valueA.(uint32), valueB.(uint32), op)
-	case ReturnType_RETURN_TYPE_U16:
-		return performOpU16(valueA.(uint16), valueB.(uint16), op)
-	case ReturnType_RETURN_TYPE_U8:
-		return performOpU8(valueA.(uint8), valueB.(uint8), op)
-	case ReturnType_RETURN_TYPE_BOOL:
-		return performOpBool(valueA.(bool), valueB.(bool), op)
-	default:
-		return nil, unhandledEnum("binary op value type", valueType)
-	}
-}
+import "github.com/pkg/errors"
 
-func performOpU64(a, b uint64, op BinaryOpCode) (interface{}, error) {
-	switch op {
-	case BinaryOpCode_BINARY_OP_CODE_EQ:
-		return a == b, nil
-	case BinaryOpCode_BINARY_OP_CODE_NEQ:
-		return a != b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS:
-		return a < b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
-		return a <= b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER:
-		return a > b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
-		return a >= b, nil
-	default:
-		return nil, unhandledEnum("u64 op code", op)
-	}
-}
+var (
+	// ErrDivideByZero indicates an integer division or modulo by zero.
+	ErrDivideByZero = errors.New("integer divide by zero")
+	// ErrNegativeShift indicates a shift by a negative number of bits.
+	ErrNegativeShift = errors.New("negative shift count")
 
-func performOpU32(a, b uint32, op BinaryOpCode) (interface{}, error) {
-	switch op {
-	case BinaryOpCode_BINARY_OP_CODE_EQ:
-		return a == b, nil
-	case BinaryOpCode_BINARY_OP_CODE_NEQ:
-		return a != b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS:
-		return a < b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
-		return a <= b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER:
-		return a > b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
-		return a >= b, nil
-	default:
-		return nil, unhandledEnum("u32 op code", op)
+	arithmeticOps = map[BinaryOpCode]struct{}{
+		BinaryOpCode_BINARY_OP_CODE_ADD: {},
+		BinaryOpCode_BINARY_OP_CODE_SUB: {},
+		BinaryOpCode_BINARY_OP_CODE_MUL: {},
+		BinaryOpCode_BINARY_OP_CODE_DIV: {},
+		BinaryOpCode_BINARY_OP_CODE_MOD: {},
 	}
-}
-
-func performOpU16(a, b uint16, op BinaryOpCode) (interface{}, error) {
-	switch op {
-	case BinaryOpCode_BINARY_OP_CODE_EQ:
-		return a == b, nil
-	case BinaryOpCode_BINARY_OP_CODE_NEQ:
-		return a != b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS:
-		return a < b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
-		return a <= b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER:
-		return a > b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
-		return a >= b, nil
-	default:
-		return nil, unhandledEnum("u16 op code", op)
+	bitwiseOps = map[BinaryOpCode]struct{}{
+		BinaryOpCode_BINARY_OP_CODE_BIT_AND: {},
+		BinaryOpCode_BINARY_OP_CODE_BIT_OR:  {},
+		BinaryOpCode_BINARY_OP_CODE_BIT_XOR: {},
+		BinaryOpCode_BINARY_OP_CODE_SHL:     {},
+		BinaryOpCode_BINARY_OP_CODE_SHR:     {},
 	}
-}
+)
 
-func performOpU8(a, b uint8, op BinaryOpCode) (interface{}, error) {
-	switch op {
-	case BinaryOpCode_BINARY_OP_CODE_EQ:
-		return a == b, nil
-	case BinaryOpCode_BINARY_OP_CODE_NEQ:
-		return a != b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS:
-		return a < b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
-		return a <= b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER:
-		return a > b, nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
-		return a >= b, nil
+// checkBinaryOperation returns an error if an operation cannot be performed on values of a type.
+// Comparisons apply to every type, arithmetic to integer and float types and bitwise
+// operations to integer types only.
+//
+// Integer arithmetic wraps around on overflow as two's complement, shifts by at least the
+// width of the type result in 0, or -1 for right shifts of negative values.
+// Integer division or modulo by zero and shifts by negative counts fail when evaluated.
+func checkBinaryOperation(valueType ReturnType, op BinaryOpCode) error {
+	if _, ok := booleanOps[op]; ok {
+		return nil
+	}
+	_, isArithmetic := arithmeticOps[op]
+	_, isBitwise := bitwiseOps[op]
+	switch {
+	case isArithmetic && (isIntegerType(valueType) || isFloatType(valueType)):
+		return nil
+	case isBitwise && isIntegerType(valueType):
+		return nil
+	case isArithmetic || isBitwise:
+		return errors.Errorf("%s cannot be applied to %s", op, valueType)
 	default:
-		return nil, unhandledEnum("u16 op code", op)
+		return unhandledEnum("binary op code", op)
 	}
 }
 
-func performOpBool(a, b bool, op BinaryOpCode) (interface{}, error) {
-	switch op {
-	case BinaryOpCode_BINARY_OP_CODE_EQ:
-		return a == b, nil
-	case BinaryOpCode_BINARY_OP_CODE_NEQ:
-		return a != b, nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS:
-		return boolInt(a) < boolInt(b), nil
-	case BinaryOpCode_BINARY_OP_CODE_LESS_EQ:
-		return boolInt(a) <= boolInt(b), nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER:
-		return boolInt(a) > boolInt(b), nil
-	case BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
-		return boolInt(a) >= boolInt(b), nil
+func isIntegerType(valueType ReturnType) bool {
+	switch valueType {
+	case ReturnType_RETURN_TYPE_U64, ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_U8,
+		ReturnType_RETURN_TYPE_I64, ReturnType_RETURN_TYPE_I32, ReturnType_RETURN_TYPE_I16, ReturnType_RETURN_TYPE_I8:
+		return true
 	default:
-		return nil, unhandledEnum("bool op code", op)
+		return false
 	}
 }
 
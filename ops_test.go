@@ -1,12 +1,61 @@
 package binq
 
 import (
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
-func TestPerformBinaryOperationUnknownType(t *testing.T) {
-	result, err := performBinaryOperation(ReturnType_RETURN_TYPE_UNKNOWN, 0, 0, BinaryOpCode_BINARY_OP_CODE_EQ)
+// performOperation performs an operation on constants of a type with a compiled expression,
+// and converts its result to the Go type of its ReturnType.
+func performOperation(t *testing.T, valueType ReturnType, a, b interface{}, op BinaryOpCode) (interface{}, error) {
+	t.Helper()
+	c, err := compileOperation(op, makeConstant(valueType, a), makeConstant(valueType, b))
+	if err != nil {
+		return nil, err
+	}
+	result, err := evaluateCompiled(c, nil, nil)
+	if err != nil {
+		return nil, errors.Cause(err)
+	}
+	return result, nil
+}
+
+// makeConstant creates a compiled constant of a type from a value of its Go type.
+func makeConstant(valueType ReturnType, value interface{}) compiled {
+	switch v := value.(type) {
+	case bool:
+		return constBool(v)
+	case uint8:
+		return constUint(valueType, uint64(v))
+	case uint16:
+		return constUint(valueType, uint64(v))
+	case uint32:
+		return constUint(valueType, uint64(v))
+	case uint64:
+		return constUint(valueType, v)
+	case int8:
+		return constInt(valueType, int64(v))
+	case int16:
+		return constInt(valueType, int64(v))
+	case int32:
+		return constInt(valueType, int64(v))
+	case int64:
+		return constInt(valueType, v)
+	case float32:
+		return constFloat(valueType, float64(v))
+	case float64:
+		return constFloat(valueType, v)
+	case []byte:
+		return constBytes(v)
+	default:
+		return compiled{returnType: valueType}
+	}
+}
+
+func TestPerformOperationUnknownType(t *testing.T) {
+	result, err := performOperation(t, ReturnType_RETURN_TYPE_UNKNOWN, 0, 0, BinaryOpCode_BINARY_OP_CODE_EQ)
 	assert.Error(t, err)
 	assert.Nil(t, result)
 }
@@ -18,7 +67,7 @@ func TestUintUnknownOps(t *testing.T) {
 		t.Run(valueType.String(), func(t *testing.T) {
 			t.Parallel()
 			value := makeReturnTypeValue(t, valueType)
-			result, err := performBinaryOperation(valueType, value, value, BinaryOpCode_BINARY_OP_CODE_UNKNOWN)
+			result, err := performOperation(t, valueType, value, value, BinaryOpCode_BINARY_OP_CODE_UNKNOWN)
 			assert.Error(t, err)
 			assert.Nil(t, result)
 		})
@@ -36,7 +85,7 @@ func TestUintBooleanOps(t *testing.T) {
 				t.Run(op.String(), func(t *testing.T) {
 					t.Parallel()
 					value := makeReturnTypeValue(t, valueType)
-					result, err := performBinaryOperation(valueType, value, value, op)
+					result, err := performOperation(t, valueType, value, value, op)
 					assert.NoError(t, err)
 					assert.IsType(t, false, result)
 				})
@@ -44,3 +93,96 @@ func TestUintBooleanOps(t *testing.T) {
 		})
 	}
 }
+
+func TestFloatNaNOps(t *testing.T) {
+	t.Parallel()
+	nan := math.NaN()
+	cases := []struct {
+		op       BinaryOpCode
+		expected bool
+	}{
+		{BinaryOpCode_BINARY_OP_CODE_EQ, false},
+		{BinaryOpCode_BINARY_OP_CODE_NEQ, true},
+		{BinaryOpCode_BINARY_OP_CODE_LESS, false},
+		{BinaryOpCode_BINARY_OP_CODE_LESS_EQ, false},
+		{BinaryOpCode_BINARY_OP_CODE_GREATER, false},
+		{BinaryOpCode_BINARY_OP_CODE_GREATER_EQ, false},
+	}
+	for _, tc := range cases {
+		for _, pair := range [][2]float64{{nan, nan}, {nan, 1}, {1, nan}} {
+			result, err := performOperation(t, ReturnType_RETURN_TYPE_F64, pair[0], pair[1], tc.op)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result, "f64 %v %s %v", pair[0], tc.op, pair[1])
+			result, err = performOperation(t, ReturnType_RETURN_TYPE_F32, float32(pair[0]), float32(pair[1]), tc.op)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result, "f32 %v %s %v", pair[0], tc.op, pair[1])
+		}
+	}
+	_, err := performOperation(t, ReturnType_RETURN_TYPE_F64, nan, nan, BinaryOpCode_BINARY_OP_CODE_UNKNOWN)
+	assert.Error(t, err)
+}
+
+func TestIntegerArithmeticOps(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name        string
+		valueType   ReturnType
+		a, b        interface{}
+		op          BinaryOpCode
+		expected    interface{}
+		expectedErr error
+	}{
+		{"u64-add", ReturnType_RETURN_TYPE_U64, uint64(2), uint64(3), BinaryOpCode_BINARY_OP_CODE_ADD, uint64(5), nil},
+		{"u64-sub-wraps", ReturnType_RETURN_TYPE_U64, uint64(2), uint64(3), BinaryOpCode_BINARY_OP_CODE_SUB, uint64(math.MaxUint64), nil},
+		{"u8-add-wraps", ReturnType_RETURN_TYPE_U8, uint8(255), uint8(1), BinaryOpCode_BINARY_OP_CODE_ADD, uint8(0), nil},
+		{"i8-add-wraps", ReturnType_RETURN_TYPE_I8, int8(127), int8(1), BinaryOpCode_BINARY_OP_CODE_ADD, int8(-128), nil},
+		{"u32-mul-wraps", ReturnType_RETURN_TYPE_U32, uint32(1 << 31), uint32(2), BinaryOpCode_BINARY_OP_CODE_MUL, uint32(0), nil},
+		{"i32-div-truncates", ReturnType_RETURN_TYPE_I32, int32(-7), int32(2), BinaryOpCode_BINARY_OP_CODE_DIV, int32(-3), nil},
+		{"i32-mod-sign", ReturnType_RETURN_TYPE_I32, int32(-7), int32(2), BinaryOpCode_BINARY_OP_CODE_MOD, int32(-1), nil},
+		{"i64-min-div-negative-one", ReturnType_RETURN_TYPE_I64, int64(math.MinInt64), int64(-1), BinaryOpCode_BINARY_OP_CODE_DIV, int64(math.MinInt64), nil},
+		{"u16-div-zero", ReturnType_RETURN_TYPE_U16, uint16(1), uint16(0), BinaryOpCode_BINARY_OP_CODE_DIV, nil, ErrDivideByZero},
+		{"i16-mod-zero", ReturnType_RETURN_TYPE_I16, int16(1), int16(0), BinaryOpCode_BINARY_OP_CODE_MOD, nil, ErrDivideByZero},
+		{"u32-bit-and", ReturnType_RETURN_TYPE_U32, uint32(0x8001), uint32(0x8000), BinaryOpCode_BINARY_OP_CODE_BIT_AND, uint32(0x8000), nil},
+		{"u8-bit-or", ReturnType_RETURN_TYPE_U8, uint8(0x0f), uint8(0xf0), BinaryOpCode_BINARY_OP_CODE_BIT_OR, uint8(0xff), nil},
+		{"i16-bit-xor", ReturnType_RETURN_TYPE_I16, int16(-1), int16(1), BinaryOpCode_BINARY_OP_CODE_BIT_XOR, int16(-2), nil},
+		{"u64-shl", ReturnType_RETURN_TYPE_U64, uint64(1), uint64(63), BinaryOpCode_BINARY_OP_CODE_SHL, uint64(1 << 63), nil},
+		{"u64-shl-width", ReturnType_RETURN_TYPE_U64, uint64(1), uint64(64), BinaryOpCode_BINARY_OP_CODE_SHL, uint64(0), nil},
+		{"i8-shr-negative", ReturnType_RETURN_TYPE_I8, int8(-128), int8(100), BinaryOpCode_BINARY_OP_CODE_SHR, int8(-1), nil},
+		{"i32-shl-negative-count", ReturnType_RETURN_TYPE_I32, int32(1), int32(-1), BinaryOpCode_BINARY_OP_CODE_SHL, nil, ErrNegativeShift},
+		{"f64-div-zero", ReturnType_RETURN_TYPE_F64, float64(1), float64(0), BinaryOpCode_BINARY_OP_CODE_DIV, math.Inf(1), nil},
+		{"f32-mod", ReturnType_RETURN_TYPE_F32, float32(5.5), float32(2), BinaryOpCode_BINARY_OP_CODE_MOD, float32(1.5), nil},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if !assert.NoError(t, checkBinaryOperation(tc.valueType, tc.op)) {
+				return
+			}
+			result, err := performOperation(t, tc.valueType, tc.a, tc.b, tc.op)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestCheckBinaryOperation(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		valueType   ReturnType
+		op          BinaryOpCode
+		expectedErr bool
+	}{
+		{ReturnType_RETURN_TYPE_U8, BinaryOpCode_BINARY_OP_CODE_SHR, false},
+		{ReturnType_RETURN_TYPE_F64, BinaryOpCode_BINARY_OP_CODE_MUL, false},
+		{ReturnType_RETURN_TYPE_F64, BinaryOpCode_BINARY_OP_CODE_BIT_AND, true},
+		{ReturnType_RETURN_TYPE_BOOL, BinaryOpCode_BINARY_OP_CODE_ADD, true},
+		{ReturnType_RETURN_TYPE_BYTES, BinaryOpCode_BINARY_OP_CODE_ADD, true},
+		{ReturnType_RETURN_TYPE_BYTES, BinaryOpCode_BINARY_OP_CODE_LESS, false},
+		{ReturnType_RETURN_TYPE_U64, BinaryOpCode_BINARY_OP_CODE_UNKNOWN, true},
+	}
+	for _, tc := range cases {
+		err := checkBinaryOperation(tc.valueType, tc.op)
+		assert.Equal(t, tc.expectedErr, err != nil, "%s %s: %v", tc.valueType, tc.op, err)
+	}
+}
@@ -0,0 +1,397 @@
+package binq
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"strings"
+)
+
+// RewriteRule names a simplification made by Optimize.
+type RewriteRule string
+
+// Rewrite rules of Optimize.
+const (
+	// RewriteFold replaces an operation on scalars with its result.
+	RewriteFold RewriteRule = "fold"
+	// RewriteTautology removes an operand that is always true, or an AND or OR decided by one,
+	// and replaces a comparison of an expression with itself that is always true.
+	RewriteTautology RewriteRule = "tautology"
+	// RewriteContradiction is RewriteTautology for operands that are always false.
+	RewriteContradiction RewriteRule = "contradiction"
+	// RewriteDoubleNegation replaces NOT NOT of an expression with the expression.
+	RewriteDoubleNegation RewriteRule = "double-negation"
+	// RewriteDeduplicate removes an operand of AND or OR that repeats an earlier operand.
+	RewriteDeduplicate RewriteRule = "deduplicate"
+	// RewriteFlatten moves the operands of AND in an All, or of OR in an Any, into the junction.
+	RewriteFlatten RewriteRule = "flatten"
+	// RewriteCollapse replaces an Any or All of one expression, or of none, with an expression.
+	RewriteCollapse RewriteRule = "collapse"
+)
+
+// Rewrite is a simplification made by Optimize.
+type Rewrite struct {
+	// Path locates the rewritten expression in the predicate, like the Path of a CheckError.
+	Path string
+	Rule RewriteRule
+	// Before and After are filter text of the expression before and after the rewrite.
+	// Before includes earlier rewrites of sub-expressions, After is empty if the expression was removed.
+	Before string
+	After  string
+}
+
+func (r *Rewrite) String() string {
+	if r.After == "" {
+		return fmt.Sprintf("%s: %s: removed %s", r.Path, r.Rule, r.Before)
+	}
+	return fmt.Sprintf("%s: %s: %s => %s", r.Path, r.Rule, r.Before, r.After)
+}
+
+// OptimizeReport lists the rewrites made by Optimize in the order they were made.
+type OptimizeReport struct {
+	Rewrites []*Rewrite
+}
+
+func (r *OptimizeReport) String() string {
+	var b strings.Builder
+	for _, rewrite := range r.Rewrites {
+		b.WriteString(rewrite.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Optimize returns a simplified copy of a Predicate, which matches the same records and fails on
+// the same records, and a report of the rewrites it made. Type errors are reported as CheckErrors.
+//
+// Operations on scalars are folded into scalars, unless they fail, such as dividing by zero.
+// Operands of AND, OR, All and Any that cannot change the result are removed, as are the operands
+// after one that decides the result. Operands before a deciding one are only removed if they cannot
+// fail, since reading a value from a short record or dividing by zero fails the predicate.
+func Optimize(pred *Predicate) (*Predicate, *OptimizeReport, error) {
+	if errs := Check(pred); len(errs) > 0 {
+		return nil, nil, errs
+	}
+	o := &optimizer{report: &OptimizeReport{}}
+	field := oneofName(pred, pred.GetPredicate())
+	var optimized *Predicate
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		optimized = &Predicate{Predicate: &Predicate_Expression{Expression: o.expression(field, t.Expression)}}
+	case *Predicate_Any:
+		optimized = o.junctionPredicate(field, BinaryOpCode_BINARY_OP_CODE_OR, t.Any.GetExpressions())
+	case *Predicate_All:
+		optimized = o.junctionPredicate(field, BinaryOpCode_BINARY_OP_CODE_AND, t.All.GetExpressions())
+	default:
+		return nil, nil, unhandledType("predicate type", t)
+	}
+	return optimized, o.report, nil
+}
+
+// optimizer simplifies expressions, recording the rewrites it makes.
+type optimizer struct {
+	report *OptimizeReport
+}
+
+// operand is an operand of a junction and its path in the predicate.
+type operand struct {
+	path string
+	ex   *Expression
+}
+
+func (o *optimizer) rewrite(path string, rule RewriteRule, before, after string) {
+	o.report.Rewrites = append(o.report.Rewrites, &Rewrite{Path: path, Rule: rule, Before: before, After: after})
+}
+
+// junctionPredicate simplifies the expressions of an All or Any.
+func (o *optimizer) junctionPredicate(path string, opCode BinaryOpCode, exs []*Expression) *Predicate {
+	var operands []operand
+	for index, ex := range exs {
+		operands = append(operands, chainOperands(fmt.Sprintf("%s.expressions[%d]", path, index), ex, opCode)...)
+	}
+	if len(operands) > len(exs) {
+		o.rewrite(path, RewriteFlatten, formatExpressions(opCode, exs), formatOperands(opCode, operands))
+	}
+	simplified := o.junction(path, opCode, o.operands(opCode, operands))
+
+	if len(simplified) <= 1 {
+		ex := joinOperands(opCode, simplified)
+		o.rewrite(path, RewriteCollapse, formatOperands(opCode, simplified), formatText(ex))
+		return &Predicate{Predicate: &Predicate_Expression{Expression: ex}}
+	}
+	expressions := &Expressions{Expressions: make([]*Expression, len(simplified))}
+	for index, op := range simplified {
+		expressions.Expressions[index] = op.ex
+	}
+	if opCode == BinaryOpCode_BINARY_OP_CODE_AND {
+		return &Predicate{Predicate: &Predicate_All{All: expressions}}
+	}
+	return &Predicate{Predicate: &Predicate_Any{Any: expressions}}
+}
+
+// expression simplifies an expression. The result does not share messages with the expression.
+func (o *optimizer) expression(path string, ex *Expression) *Expression {
+	fieldPath := joinPath(path, oneofName(ex, ex.GetExpression()))
+	switch t := ex.GetExpression().(type) {
+	case *Expression_BinaryOperation:
+		opCode := t.BinaryOperation.BinaryOpCode
+		if _, isLogical := logicalOps[opCode]; isLogical {
+			return o.logicalOperation(path, opCode, chainOperands(path, ex, opCode))
+		}
+		simplified := &Expression{Expression: &Expression_BinaryOperation{BinaryOperation: &BinaryOperation{
+			Left:         o.expression(joinPath(fieldPath, "left"), t.BinaryOperation.Left),
+			BinaryOpCode: opCode,
+			Right:        o.expression(joinPath(fieldPath, "right"), t.BinaryOperation.Right),
+		}}}
+		return o.binaryOperation(path, simplified)
+	case *Expression_UnaryOperation:
+		operand := o.expression(joinPath(fieldPath, "operand"), t.UnaryOperation.Operand)
+		simplified := &Expression{Expression: &Expression_UnaryOperation{UnaryOperation: &UnaryOperation{
+			UnaryOpCode: t.UnaryOperation.UnaryOpCode,
+			Operand:     operand,
+		}}}
+		if inner, ok := operand.GetExpression().(*Expression_UnaryOperation); ok {
+			o.rewrite(path, RewriteDoubleNegation, formatText(simplified), formatText(inner.UnaryOperation.Operand))
+			return inner.UnaryOperation.Operand
+		}
+		if _, ok := operand.GetExpression().(*Expression_Scalar); ok {
+			return o.fold(path, simplified)
+		}
+		return simplified
+	default:
+		return proto.Clone(ex).(*Expression)
+	}
+}
+
+// binaryOperation simplifies a comparison, arithmetic or bitwise operation with simplified operands.
+func (o *optimizer) binaryOperation(path string, ex *Expression) *Expression {
+	op := ex.GetBinaryOperation()
+	_, leftScalar := op.Left.GetExpression().(*Expression_Scalar)
+	_, rightScalar := op.Right.GetExpression().(*Expression_Scalar)
+	if leftScalar && rightScalar {
+		return o.fold(path, ex)
+	}
+	if _, isComparison := booleanOps[op.BinaryOpCode]; !isComparison || !proto.Equal(op.Left, op.Right) || canFail(op.Left) {
+		return ex
+	}
+	// NaN is not equal to itself, so only comparisons of other types are decided.
+	operandType, _ := (&checker{}).checkExpression("", op.Left)
+	if isFloatType(operandType) {
+		return ex
+	}
+	switch op.BinaryOpCode {
+	case BinaryOpCode_BINARY_OP_CODE_EQ, BinaryOpCode_BINARY_OP_CODE_LESS_EQ, BinaryOpCode_BINARY_OP_CODE_GREATER_EQ:
+		result := boolExpression(true)
+		o.rewrite(path, RewriteTautology, formatText(ex), formatText(result))
+		return result
+	default:
+		result := boolExpression(false)
+		o.rewrite(path, RewriteContradiction, formatText(ex), formatText(result))
+		return result
+	}
+}
+
+// fold evaluates an operation on scalars and replaces it with the result.
+// Operations that fail are kept, so that they fail when the predicate is evaluated.
+func (o *optimizer) fold(path string, ex *Expression) *Expression {
+	c, err := compileExpression(ex)
+	if err != nil {
+		return ex
+	}
+	result, err := constantOf(c)
+	if err != nil {
+		return ex
+	}
+	scalar, ok := constantToScalar(result)
+	if !ok {
+		return ex
+	}
+	folded := &Expression{Expression: &Expression_Scalar{Scalar: scalar}}
+	o.rewrite(path, RewriteFold, formatText(ex), formatText(folded))
+	return folded
+}
+
+// logicalOperation simplifies the operands of a chain of AND or OR and joins them again.
+func (o *optimizer) logicalOperation(path string, opCode BinaryOpCode, operands []operand) *Expression {
+	return joinOperands(opCode, o.junction(path, opCode, o.operands(opCode, operands)))
+}
+
+// operands simplifies the operands of AND or OR. Simplified operands may be chains of the same
+// operation, such as NOT NOT (a AND b), which are flattened.
+func (o *optimizer) operands(opCode BinaryOpCode, operands []operand) []operand {
+	var simplified []operand
+	for _, op := range operands {
+		simplified = append(simplified, chainOperands(op.path, o.expression(op.path, op.ex), opCode)...)
+	}
+	return simplified
+}
+
+// junction simplifies the operands of AND or OR, which are evaluated in order until one decides the result.
+// An operand that is the same as an earlier operand has the same result, so it can only be evaluated
+// when the earlier operand did not decide the result and is removed.
+func (o *optimizer) junction(path string, opCode BinaryOpCode, operands []operand) []operand {
+	// decides is the result of an operand that decides the result of the junction.
+	decides := opCode == BinaryOpCode_BINARY_OP_CODE_OR
+	rule := func(value bool) RewriteRule {
+		if value {
+			return RewriteTautology
+		}
+		return RewriteContradiction
+	}
+
+	var kept []operand
+	for index, op := range operands {
+		if scalar, ok := op.ex.GetScalar().GetValue().(*Scalar_Bool); ok {
+			if scalar.Bool != decides {
+				o.rewrite(op.path, rule(scalar.Bool), formatText(op.ex), "")
+				continue
+			}
+			// The remaining operands are never evaluated.
+			before := append(append([]operand{}, kept...), operands[index:]...)
+			result := append(kept, op)
+			if !operandsCanFail(kept) {
+				result = []operand{{path: path, ex: op.ex}}
+			}
+			if len(result) < len(before) {
+				o.rewrite(path, rule(decides), formatOperands(opCode, before), formatOperands(opCode, result))
+			}
+			return result
+		}
+		if findOperand(kept, op.ex) >= 0 {
+			o.rewrite(op.path, RewriteDeduplicate, formatText(op.ex), "")
+			continue
+		}
+		kept = append(kept, op)
+	}
+	return kept
+}
+
+func findOperand(operands []operand, ex *Expression) int {
+	for index, op := range operands {
+		if proto.Equal(op.ex, ex) {
+			return index
+		}
+	}
+	return -1
+}
+
+func operandsCanFail(operands []operand) bool {
+	for _, op := range operands {
+		if canFail(op.ex) {
+			return true
+		}
+	}
+	return false
+}
+
+// canFail determines if evaluating an expression may fail, by reading a value or by an operation
+// that fails for some operands.
+func canFail(ex *Expression) bool {
+	switch t := ex.GetExpression().(type) {
+	case *Expression_Value:
+		return true
+	case *Expression_BinaryOperation:
+		switch t.BinaryOperation.BinaryOpCode {
+		case BinaryOpCode_BINARY_OP_CODE_DIV, BinaryOpCode_BINARY_OP_CODE_MOD,
+			BinaryOpCode_BINARY_OP_CODE_SHL, BinaryOpCode_BINARY_OP_CODE_SHR:
+			return true
+		}
+		return canFail(t.BinaryOperation.Left) || canFail(t.BinaryOperation.Right)
+	case *Expression_UnaryOperation:
+		return canFail(t.UnaryOperation.Operand)
+	default:
+		return false
+	}
+}
+
+// chainOperands returns the operands of a chain of one logical operation, in the order they are
+// evaluated, or the expression itself if it is not that operation.
+func chainOperands(path string, ex *Expression, opCode BinaryOpCode) []operand {
+	op := ex.GetBinaryOperation()
+	if op == nil || op.BinaryOpCode != opCode {
+		return []operand{{path: path, ex: ex}}
+	}
+	fieldPath := joinPath(path, oneofName(ex, ex.GetExpression()))
+	return append(
+		chainOperands(joinPath(fieldPath, "left"), op.Left, opCode),
+		chainOperands(joinPath(fieldPath, "right"), op.Right, opCode)...,
+	)
+}
+
+// joinOperands joins operands with a logical operation, as the parser does. No operands are the
+// result of an empty All or Any.
+func joinOperands(opCode BinaryOpCode, operands []operand) *Expression {
+	if len(operands) == 0 {
+		return boolExpression(opCode == BinaryOpCode_BINARY_OP_CODE_AND)
+	}
+	ex := operands[0].ex
+	for _, op := range operands[1:] {
+		ex = makeLogicalExpression(ex, opCode, op.ex)
+	}
+	return ex
+}
+
+func makeLogicalExpression(left *Expression, opCode BinaryOpCode, right *Expression) *Expression {
+	return &Expression{Expression: &Expression_BinaryOperation{BinaryOperation: &BinaryOperation{
+		Left:         left,
+		BinaryOpCode: opCode,
+		Right:        right,
+	}}}
+}
+
+func boolExpression(value bool) *Expression {
+	return &Expression{Expression: &Expression_Scalar{Scalar: &Scalar{Value: &Scalar_Bool{Bool: value}}}}
+}
+
+// constantToScalar converts a constant into a Scalar, if there is a Scalar of its type.
+func constantToScalar(c compiled) (*Scalar, bool) {
+	switch c.returnType {
+	case ReturnType_RETURN_TYPE_BOOL:
+		return &Scalar{Value: &Scalar_Bool{Bool: c.constBool}}, true
+	case ReturnType_RETURN_TYPE_U32:
+		return &Scalar{Value: &Scalar_U32{U32: uint32(c.constUint)}}, true
+	case ReturnType_RETURN_TYPE_U64:
+		return &Scalar{Value: &Scalar_U64{U64: c.constUint}}, true
+	case ReturnType_RETURN_TYPE_I32:
+		return &Scalar{Value: &Scalar_I32{I32: int32(c.constInt)}}, true
+	case ReturnType_RETURN_TYPE_I64:
+		return &Scalar{Value: &Scalar_I64{I64: c.constInt}}, true
+	case ReturnType_RETURN_TYPE_F32:
+		return &Scalar{Value: &Scalar_F32{F32: float32(c.constFloat)}}, true
+	case ReturnType_RETURN_TYPE_F64:
+		return &Scalar{Value: &Scalar_F64{F64: c.constFloat}}, true
+	case ReturnType_RETURN_TYPE_BYTES:
+		return &Scalar{Value: &Scalar_Bytes{Bytes: c.constBytes}}, true
+	default:
+		return nil, false
+	}
+}
+
+// formatText renders an expression for a Rewrite.
+func formatText(ex *Expression) string {
+	text, err := FormatExpression(ex, FormatOptions{})
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return text
+}
+
+// formatOperands renders the operands of a junction for a Rewrite.
+func formatOperands(opCode BinaryOpCode, operands []operand) string {
+	exs := make([]*Expression, len(operands))
+	for index, op := range operands {
+		exs[index] = op.ex
+	}
+	return formatExpressions(opCode, exs)
+}
+
+func formatExpressions(opCode BinaryOpCode, exs []*Expression) string {
+	token := TokenOr
+	if opCode == BinaryOpCode_BINARY_OP_CODE_AND {
+		token = TokenAnd
+	}
+	text, err := formatJunction(token, exs, FormatOptions{})
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return text
+}
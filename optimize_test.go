@@ -0,0 +1,258 @@
+package binq
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		rules    []RewriteRule
+	}{
+		{
+			"unchanged",
+			"VALUE(0, U8) = U32(1) AND VALUE(1, U8) > U32(2)",
+			"VALUE(0, U8) = U32(1) AND VALUE(1, U8) > U32(2)",
+			nil,
+		},
+		{
+			"fold-tautology",
+			"U64(7) = U64(7) AND VALUE(0, U8) = U32(1)",
+			"VALUE(0, U8) = U32(1)",
+			[]RewriteRule{RewriteFold, RewriteTautology, RewriteCollapse},
+		},
+		{
+			"fold-arithmetic",
+			"VALUE(0, U64LE) > (U32(2) + U64(3)) * U64(4)",
+			"VALUE(0, U64LE) > U64(20)",
+			[]RewriteRule{RewriteFold, RewriteFold},
+		},
+		{
+			"fold-keeps-divide-by-zero",
+			"VALUE(0, U64LE) > U64(1) / U64(0)",
+			"VALUE(0, U64LE) > U64(1) / U64(0)",
+			nil,
+		},
+		{
+			"fold-not",
+			"NOT true OR VALUE(0, U8) = U32(1)",
+			"VALUE(0, U8) = U32(1)",
+			[]RewriteRule{RewriteFold, RewriteContradiction, RewriteCollapse},
+		},
+		{
+			"leading-contradiction",
+			"false AND VALUE(0, U8) = U32(1)",
+			"false",
+			[]RewriteRule{RewriteContradiction, RewriteCollapse},
+		},
+		{
+			"trailing-contradiction-after-value",
+			"VALUE(0, U8) = U32(1) AND false AND VALUE(1, U8) = U32(1)",
+			"VALUE(0, U8) = U32(1) AND false",
+			[]RewriteRule{RewriteContradiction},
+		},
+		{
+			"tautology-after-scalar",
+			"U32(1) < U32(2) OR VALUE(0, U8) = U32(1)",
+			"true",
+			[]RewriteRule{RewriteFold, RewriteTautology, RewriteCollapse},
+		},
+		{
+			"nested-tautology",
+			"(VALUE(0, U8) = U32(1) OR true) AND VALUE(1, U8) = U32(1)",
+			"(VALUE(0, U8) = U32(1) OR true) AND VALUE(1, U8) = U32(1)",
+			nil,
+		},
+		{
+			"compare-with-itself",
+			"U32(1) + U32(2) * I32(3) != U32(1) + U32(2) * I32(3) OR KEY(0, U8) = U32(0)",
+			"KEY(0, U8) = U32(0)",
+			[]RewriteRule{RewriteFold, RewriteFold, RewriteFold, RewriteFold, RewriteFold, RewriteContradiction, RewriteCollapse},
+		},
+		{
+			"compare-float-with-itself",
+			"F64(0) / F64(0) = F64(0) / F64(0)",
+			"false",
+			[]RewriteRule{RewriteFold, RewriteFold, RewriteFold},
+		},
+		{
+			"double-negation",
+			"NOT NOT VALUE(0, U8) = U32(1)",
+			"VALUE(0, U8) = U32(1)",
+			[]RewriteRule{RewriteDoubleNegation},
+		},
+		{
+			"deduplicate",
+			"VALUE(0, U8) = U32(1) OR VALUE(1, U8) = U32(1) OR VALUE(0, U8) = U32(1)",
+			"VALUE(0, U8) = U32(1) OR VALUE(1, U8) = U32(1)",
+			[]RewriteRule{RewriteDeduplicate},
+		},
+		{
+			"deduplicate-nested",
+			"NOT (VALUE(0, U8) = U32(1) AND VALUE(0, U8) = U32(1))",
+			"NOT VALUE(0, U8) = U32(1)",
+			[]RewriteRule{RewriteDeduplicate},
+		},
+		{
+			"flatten-double-negation",
+			"NOT NOT (VALUE(0, U8) = U32(1) OR VALUE(1, U8) = U32(1)) OR VALUE(2, U8) = U32(1)",
+			"VALUE(0, U8) = U32(1) OR VALUE(1, U8) = U32(1) OR VALUE(2, U8) = U32(1)",
+			[]RewriteRule{RewriteDoubleNegation},
+		},
+		{
+			"expression",
+			"(VALUE(0, U8) = U32(1) OR VALUE(0, U8) = U32(1)) = (U32(1) = U32(1))",
+			"VALUE(0, U8) = U32(1) = true",
+			[]RewriteRule{RewriteDeduplicate, RewriteFold},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pred, err := NewParser(tc.input).ReadPredicate()
+			if !assert.NoError(t, err) {
+				return
+			}
+			original := proto.Clone(pred)
+			optimized, report, err := Optimize(pred)
+			if !assert.NoError(t, err) {
+				return
+			}
+			text, err := FormatPredicate(optimized, FormatOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, text, "%v", report)
+			var rules []RewriteRule
+			for _, rewrite := range report.Rewrites {
+				rules = append(rules, rewrite.Rule)
+			}
+			assert.Equal(t, tc.rules, rules, "%v", report)
+			assert.True(t, proto.Equal(original, pred), "the predicate must not be modified")
+		})
+	}
+}
+
+func TestOptimize_Report(t *testing.T) {
+	t.Parallel()
+	pred, err := NewParser("VALUE(0, U8) = U32(1) AND (U64(7) = U64(7) AND VALUE(0, U8) = U32(1))").ReadPredicate()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, report, err := Optimize(pred)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"all.expressions[1]: fold: U64(7) = U64(7) => true\n"+
+			"all.expressions[1]: tautology: removed true\n"+
+			"all.expressions[2]: deduplicate: removed VALUE(0, U8) = U32(1)\n"+
+			"all: collapse: VALUE(0, U8) = U32(1) => VALUE(0, U8) = U32(1)\n",
+		report.String())
+}
+
+// TestOptimize_Flatten flattens an All that a Parser would not create.
+func TestOptimize_Flatten(t *testing.T) {
+	t.Parallel()
+	comparison := func(offset uint64) *Expression {
+		return makeBinaryOperationExpression(makeValueExpression(ValueType_VALUE_TYPE_U8, offset), BinaryOpCode_BINARY_OP_CODE_EQ, makeScalarExpression(t, uint32(1)))
+	}
+	nested := makeBinaryOperationExpression(comparison(1), BinaryOpCode_BINARY_OP_CODE_AND, comparison(2))
+	pred := &Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{comparison(0), nested}}}}
+	optimized, report, err := Optimize(pred)
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected := &Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{comparison(0), comparison(1), comparison(2)}}}}
+	assert.True(t, proto.Equal(expected, optimized), "%v", optimized)
+	assert.Equal(t,
+		"all: flatten: VALUE(0, U8) = U32(1) AND (VALUE(1, U8) = U32(1) AND VALUE(2, U8) = U32(1)) => "+
+			"VALUE(0, U8) = U32(1) AND VALUE(1, U8) = U32(1) AND VALUE(2, U8) = U32(1)\n",
+		report.String())
+}
+
+func TestOptimize_CheckErrors(t *testing.T) {
+	t.Parallel()
+	pred := &Predicate{Predicate: &Predicate_Expression{Expression: makeValueExpression(ValueType_VALUE_TYPE_U64LE, 0)}}
+	_, _, err := Optimize(pred)
+	assert.Equal(t, Check(pred), err)
+}
+
+func TestPredicateToMatcher_WithOptimize(t *testing.T) {
+	t.Parallel()
+	pred, err := NewParser("U64(1) / U64(0) = U64(0) OR VALUE(0, U8) = U32(1)").ReadPredicate()
+	if !assert.NoError(t, err) {
+		return
+	}
+	matcher, err := PredicateToMatcher(pred, WithOptimize())
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = matcher.Match(makeBytes(t, u8(1)))
+	assert.Equal(t, ErrDivideByZero, errors.Cause(err))
+}
+
+// TestOptimize_RecordMatcher asserts that optimized random predicates match the same records
+// as the predicates, and fail on the same records.
+func TestOptimize_RecordMatcher(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(5))
+	valid, rewritten := 0, 0
+	for i := 0; i < 20000 && valid < 2000; i++ {
+		var pred *Predicate
+		switch r.Intn(4) {
+		case 0:
+			pred = randomPredicate(r)
+		case 1:
+			// Junctions of random expressions, which may be nested junctions.
+			expressions := &Expressions{}
+			for n := r.Intn(4); n > 0; n-- {
+				expressions.Expressions = append(expressions.Expressions, randomTypedExpression(r, 3))
+			}
+			pred = &Predicate{Predicate: &Predicate_All{All: expressions}}
+			if r.Intn(2) == 0 {
+				pred = &Predicate{Predicate: &Predicate_Any{Any: expressions}}
+			}
+		default:
+			pred = &Predicate{Predicate: &Predicate_Expression{Expression: randomTypedExpression(r, 4)}}
+		}
+		if len(Check(pred)) > 0 {
+			continue
+		}
+		valid++
+		matcher, err := PredicateToRecordMatcher(pred)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		optimized, report, err := Optimize(pred)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		if len(report.Rewrites) > 0 {
+			rewritten++
+		}
+		optimizedMatcher, err := PredicateToRecordMatcher(optimized)
+		if !assert.NoError(t, err, "%v\n%v", optimized, report) {
+			continue
+		}
+
+		for j := 0; j < 8; j++ {
+			key, value := randomRecordBytes(r), randomRecordBytes(r)
+			expected, expectedErr := matcher.MatchRecord(key, value)
+			got, err := optimizedMatcher.MatchRecord(key, value)
+			if expectedErr != nil || err != nil {
+				assert.Equal(t, errors.Cause(expectedErr), errors.Cause(err), "%v: %v, %v\n%v", pred, expectedErr, err, report)
+				continue
+			}
+			assert.Equal(t, expected, got, "%v\n%v", pred, report)
+		}
+	}
+	assert.Equal(t, 2000, valid)
+	assert.True(t, rewritten > 500, "only %d rewritten predicates", rewritten)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"io"
-	"strconv"
 	"strings"
 	"unicode"
 )
@@ -31,6 +30,23 @@ type ParserValue struct {
 	line int
 	// linePos is the position within the value's line this value starts at.
 	linePos int
+	// closer is the right parenthesis that ends a function's arguments, set by ToPostfix.
+	closer *ParserValue
+}
+
+// span returns the text covered by this value.
+func (v *ParserValue) span() Span {
+	start := Position{Line: v.line, Column: v.linePos}
+	end := start
+	for _, r := range v.value {
+		if r == '\n' {
+			end.Line++
+			end.Column = 0
+		} else {
+			end.Column++
+		}
+	}
+	return Span{Start: start, End: end}
 }
 
 func (v *ParserValue) setUnknownValue(r []rune) *ParserValue {
@@ -65,18 +81,30 @@ type positionalError struct {
 	line int
 	// linePos is the position within the error's line this error starts at.
 	linePos int
+	// end is the position just past the text the error applies to.
+	end Position
 }
 
 func newPositionalError(v *ParserValue, err error) error {
+	return newSpanError(v.span(), err)
+}
+
+func newSpanError(s Span, err error) error {
 	return positionalError{
 		err:     err,
-		line:    v.line,
-		linePos: v.linePos,
+		line:    s.Start.Line,
+		linePos: s.Start.Column,
+		end:     s.End,
 	}
 }
 
+func (e positionalError) span() Span {
+	return Span{Start: Position{Line: e.line, Column: e.linePos}, End: e.end}
+}
+
 func (e positionalError) Error() string {
-	return fmt.Sprintf("error at line %d position %d: %v", e.line, e.linePos, e.err)
+	// Lines and positions are counted from 1, like Diagnostic.
+	return fmt.Sprintf("error at line %d position %d: %v", e.line+1, e.linePos+1, e.err)
 }
 
 type Parser struct {
@@ -101,7 +129,22 @@ func NewParser(s string) *Parser {
 	}
 }
 
-func (p *Parser) classifyUnknownTokens(values []*ParserValue) error {
+// errorList collects the errors found while reading, so that reading can continue past them.
+type errorList []error
+
+func (l *errorList) add(err error) {
+	*l = append(*l, err)
+}
+
+// first returns the first error found, or nil if there were none.
+func (l errorList) first() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[0]
+}
+
+func (p *Parser) classifyUnknownTokens(values []*ParserValue, errs *errorList) {
 	for index, value := range values {
 		if value.token != TokenUnknown {
 			continue
@@ -114,38 +157,59 @@ func (p *Parser) classifyUnknownTokens(values []*ParserValue) error {
 		}
 		// Classify the token.
 		// If there is no error, token is NOT TokenUnknown, congratulations.
+		// Values that cannot be classified are left as TokenUnknown.
 		token, err := classifyToken(value.value, nextToken)
 		if err != nil {
-			return newPositionalError(value, err)
+			errs.add(newPositionalError(value, err))
+			continue
 		}
 		value.token = token
 	}
-	return nil
 }
 
 func (p *Parser) ReadValues() (values []*ParserValue, err error) {
-	values, err = p.ReadUnsupportedValues()
-	if err != nil {
+	var errs errorList
+	values = p.readValues(&errs)
+	if err := errs.first(); err != nil {
 		return nil, err
 	}
+	return values, nil
+}
+
+func (p *Parser) readValues(errs *errorList) []*ParserValue {
+	values := p.readUnsupportedValues(errs)
 	for _, value := range values {
 		// Some tokens are parsable but not supported yet.
 		if isUnsupportedToken[value.token] {
-			return nil, newPositionalError(value, errors.Errorf(`token %s "%s" is currently not supported`, value.token, value.value))
+			errs.add(newPositionalError(value, errors.Errorf(`token %s "%s" is currently not supported`, value.token, value.value)))
 		}
 	}
-	return values, err
+	return values
 }
 
 func (p *Parser) ReadUnsupportedValues() (values []*ParserValue, err error) {
-	values, err = p.consumeValues()
-	if err != nil {
+	var errs errorList
+	values = p.readUnsupportedValues(&errs)
+	if err := errs.first(); err != nil {
 		return nil, err
 	}
-	if err := p.classifyUnknownTokens(values); err != nil {
-		return nil, err
+	return values, nil
+}
+
+func (p *Parser) readUnsupportedValues(errs *errorList) []*ParserValue {
+	values, err := p.consumeValues()
+	if err != nil {
+		errs.add(err)
+		return nil
 	}
-	return values, err
+	p.classifyUnknownTokens(values, errs)
+	return values
+}
+
+// endSpan returns an empty span at the end of the input that has been read.
+func (p *Parser) endSpan() Span {
+	end := Position{Line: p.line, Column: p.linePos[len(p.linePos)-1]}
+	return Span{Start: end, End: end}
 }
 
 func (p *Parser) consumeValues() (values []*ParserValue, err error) {
@@ -201,6 +265,14 @@ func (p *Parser) consumeValue() (value *ParserValue, err error) {
 			}
 		}
 
+		// A quote begins a string literal, or a hex literal when prefixed by x.
+		// Quoted text may contain whitespace and special characters.
+		if r == '"' && (len(runes) == 0 || (len(runes) == 1 && (runes[0] == 'x' || runes[0] == 'X'))) {
+			runes = append(runes, r)
+			runes = append(runes, p.consumeQuoted()...)
+			return value.setUnknownValue(runes), nil
+		}
+
 		// If we encounter a special character, either we've ended
 		// our current token or begin a special sequence.
 		isSpecial := r == LeftParen || r == RightParen || r == Comment || r == Comma
@@ -246,6 +318,29 @@ func (p *Parser) consumeWhitespace() ([]rune, error) {
 	}
 }
 
+// consumeQuoted consumes the remainder of a quoted literal up to and including the closing quote.
+// Quotes escaped by a backslash do not close the literal. If the input ends first, the runes
+// read so far are returned and the literal is reported as unterminated when classified.
+func (p *Parser) consumeQuoted() []rune {
+	runes := make([]rune, 0, 16)
+	escaped := false
+	for {
+		r, err := p.advance()
+		if err != nil {
+			return runes
+		}
+		runes = append(runes, r)
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return runes
+		}
+	}
+}
+
 func (p *Parser) consumeLine() ([]rune, error) {
 	runes := make([]rune, 0, 16)
 	for {
@@ -289,17 +384,38 @@ func (p *Parser) unread() {
 	}
 }
 
+// ToPostfix reorders values into postfix (reverse polish) notation.
+// Ignored values are dropped, and functions are emitted after their arguments.
 func (p *Parser) ToPostfix(values []*ParserValue) ([]*ParserValue, error) {
+	var errs errorList
+	output := p.toPostfix(values, &errs)
+	if err := errs.first(); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (p *Parser) toPostfix(values []*ParserValue, errs *errorList) []*ParserValue {
 	var output, operators []*ParserValue
-	for _, value := range values {
+	// unknownCalls holds the length of output when each unknown function was called.
+	unknownCalls := make(map[*ParserValue]int)
+	for index, value := range values {
 		token := value.token
 		switch {
 		case token.IsIgnored():
 			continue
-		case token.IsLiteral() || token.IsTypeIdentifier():
+		case token == TokenUnknown && index+1 < len(values) && values[index+1].token == TokenLeftParen:
+			// An unknown function has already been reported. It is read as a single operand,
+			// and its arguments are discarded once they are closed.
+			unknownCalls[value] = len(output)
+			operators = append(operators, value)
+		case token.IsLiteral() || token.IsTypeIdentifier() || token == TokenUnknown:
 			output = append(output, value)
 		case token.IsFunction():
 			operators = append(operators, value)
+		case token.IsUnaryOperator():
+			// Prefix operators have no left operand, so they never pop other operators.
+			operators = append(operators, value)
 		case token.IsOperator():
 			for len(operators) > 0 {
 				top := operators[len(operators)-1]
@@ -307,10 +423,9 @@ func (p *Parser) ToPostfix(values []*ParserValue) ([]*ParserValue, error) {
 				if topToken != TokenLeftParen && (
 					(topToken.IsFunction()) ||
 						(topToken.IsOperator() && topToken.Precedence() > token.Precedence()) ||
-						(topToken.IsOperator() && topToken.Precedence() == token.Precedence() && topToken.IsLeftAssociative())) {
-					topOut := output[len(output)-1]
-					output = output[:len(output)-1]
-					operators = append(operators, topOut)
+						(topToken.IsOperator() && topToken.Precedence() == token.Precedence() && token.IsLeftAssociative())) {
+					operators = operators[:len(operators)-1]
+					output = append(output, top)
 				} else {
 					break
 				}
@@ -320,174 +435,885 @@ func (p *Parser) ToPostfix(values []*ParserValue) ([]*ParserValue, error) {
 			operators = append(operators, value)
 		case token == TokenRightParen:
 			for {
-				if len(operators) == 0 {
-					return nil, newPositionalError(value, errors.New("unmatched parenthesis"))
-				}
-				topOp := operators[len(operators)-1]
-				if topOp.token == TokenLeftParen {
+				if len(operators) == 0 || operators[len(operators)-1].token == TokenLeftParen {
 					break
 				}
+				output = append(output, operators[len(operators)-1])
 				operators = operators[:len(operators)-1]
-				output = append(output, topOp)
 			}
-			if len(operators) > 0 && operators[len(operators)-1].token == TokenLeftParen {
-				operators = operators[:len(operators)-1]
+			if len(operators) == 0 {
+				errs.add(newPositionalError(value, errors.New("unmatched parenthesis")))
+				continue
+			}
+			operators = operators[:len(operators)-1]
+			// A closed parenthesis directly after a function ends its argument list.
+			if len(operators) > 0 {
+				fn := operators[len(operators)-1]
+				if start, ok := unknownCalls[fn]; ok {
+					output = output[:start]
+				}
+				if fn.token.IsFunction() || fn.token == TokenUnknown {
+					fn.closer = value
+					output = append(output, fn)
+					operators = operators[:len(operators)-1]
+				}
 			}
 		default:
-			return nil, newPositionalError(value, errors.Errorf(`unhandled token "%s"`, value.value))
+			errs.add(newPositionalError(value, errors.Errorf(`unhandled token "%s"`, value.value)))
 		}
 	}
 	for len(operators) > 0 {
 		topOp := operators[len(operators)-1]
+		operators = operators[:len(operators)-1]
 		if topOp.token.IsParenthesis() {
-			return nil, newPositionalError(topOp, errors.New("unmatched parenthesis"))
+			errs.add(newPositionalError(topOp, errors.New("unmatched parenthesis")))
+			continue
 		}
-		operators = operators[:len(operators)-1]
 		output = append(output, topOp)
 	}
 
-	return output, nil
+	return output
+}
+
+// parserNode is an intermediate value on the stack while reading a predicate.
+type parserNode struct {
+	// value is the parser value that produced this node.
+	value *ParserValue
+	// span is the text covered by this node and is used for error positions.
+	span Span
+	// node is one of *ParserValue, *Jump, *parserBytesType, *parserExpression, *parserJunction or *parserInvalid.
+	node interface{}
+}
+
+// parserExpression is an expression with the type it returns, so that operators are type checked
+// from the types of their operands without checking the operands again.
+type parserExpression struct {
+	ex         *Expression
+	returnType ReturnType
+}
+
+// parserBytesType is the type of a byte string read by KEY or VALUE.
+type parserBytesType struct {
+	// valueType is one of the VALUE_TYPE_BYTES value types.
+	valueType ValueType
+	// length is the number of bytes read by VALUE_TYPE_BYTES.
+	length uint64
+}
+
+// parserJunction is a flat sequence of boolean expressions joined by AND or OR.
+type parserJunction struct {
+	// token is either TokenAnd or TokenOr.
+	token Token
+	// expressions are the boolean operands of the junction.
+	expressions []*Expression
+	// spans are the text of each of the expressions.
+	spans []Span
+}
+
+// parserInvalid stands in for a node that could not be read.
+// Its error has already been reported, so nodes using it report nothing further.
+type parserInvalid struct{}
+
+// ReadPredicate reads the entire input and compiles it into a Predicate.
+// Only the first error is returned, Diagnose reports every error.
+func (p *Parser) ReadPredicate() (*Predicate, error) {
+	var errs errorList
+	pred, _ := p.readPredicate(&errs)
+	if err := errs.first(); err != nil {
+		return nil, err
+	}
+	return pred, nil
 }
 
-func (p *Parser) ReadPredicate() (predicate *Predicate, err error) {
-	values, err := p.ReadUnsupportedValues()
+// readPredicate compiles the input into a Predicate, reading past errors to find as many as possible.
+// The spans are the text of each expression of an Any or All, or of the single expression.
+// Returns nil if there were any errors.
+func (p *Parser) readPredicate(errs *errorList) (*Predicate, []Span) {
+	values := p.readValues(errs)
+	values = p.toPostfix(values, errs)
+	if len(values) == 0 {
+		if len(*errs) == 0 {
+			errs.add(newSpanError(p.endSpan(), errors.New("empty predicate")))
+		}
+		return nil, nil
+	}
+
+	var stack []parserNode
+	for _, value := range values {
+		var args []parserNode
+		args, stack = p.popArgs(value, stack, errs)
+		stack = append(stack, p.readNode(value, args, errs))
+	}
+	invalid := false
+	for _, n := range stack {
+		_, ok := n.node.(*parserInvalid)
+		invalid = invalid || ok
+	}
+	if len(stack) > 1 && !invalid {
+		errs.add(newSpanError(stack[1].span, errors.New("unexpected value, expected an operator")))
+	}
+	if invalid || len(*errs) > 0 {
+		return nil, nil
+	}
+
+	pred, err := p.nodeToPredicate(stack[0])
+	if err != nil {
+		errs.add(err)
+		return nil, nil
+	}
+	if junction, ok := stack[0].node.(*parserJunction); ok {
+		return pred, junction.spans
+	}
+	return pred, []Span{stack[0].span}
+}
+
+// ReadProjection reads the entire input as a comma separated list of fields, each a KEY or VALUE
+// optionally followed by AS and an alias, such as: VALUE(0, U32LE) AS id, KEY(0, U32LE)
+// Only the first error is returned.
+func (p *Parser) ReadProjection() ([]*Projection, error) {
+	var projection []*Projection
+	err := p.readFields("projection", func(values []*ParserValue, errs *errorList) {
+		projection = append(projection, p.readField(values, errs))
+	})
 	if err != nil {
 		return nil, err
 	}
-	values, err = p.ToPostfix(values)
+	return projection, nil
+}
+
+// ReadAggregates reads the entire input as a comma separated list of aggregate functions, each
+// optionally followed by AS and an alias, such as: COUNT() AS n, MAX(VALUE(4, U32LE))
+// Only the first error is returned.
+func (p *Parser) ReadAggregates() ([]*Aggregate, error) {
+	var aggregates []*Aggregate
+	err := p.readFields("aggregates", func(values []*ParserValue, errs *errorList) {
+		aggregates = append(aggregates, p.readAggregate(values, errs))
+	})
 	if err != nil {
 		return nil, err
 	}
+	return aggregates, nil
+}
+
+// readFields reads the entire input as a comma separated list, reading the values of each item with read.
+// Returns the first error, or an error if the list is empty.
+func (p *Parser) readFields(name string, read func(values []*ParserValue, errs *errorList)) error {
+	values, err := p.consumeValues()
+	if err != nil {
+		return err
+	}
+	var errs errorList
+	fields := splitFields(values)
+	for _, field := range fields {
+		read(field, &errs)
+	}
+	if err := errs.first(); err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return newSpanError(p.endSpan(), errors.Errorf("empty %s", name))
+	}
+	return nil
+}
 
-	var arg1, arg2 interface{}
-	var stack []interface{}
+// splitFields splits values at the commas that are not within parenthesis, dropping
+// whitespace and comments.
+func splitFields(values []*ParserValue) [][]*ParserValue {
+	var fields [][]*ParserValue
+	var field []*ParserValue
+	depth := 0
 	for _, value := range values {
-		token := value.token
-		switch {
-		case token.IsLiteral():
-			stack = append(stack, value)
-		case token.IsFunction():
-			switch token.NumArgs() {
-			case 1:
-				arg1, stack, err = p.pop1(stack)
-				if err != nil {
-					return nil, err
-				}
-				f, err := p.valueToSingleArgFunc(value, arg1)
-				if err != nil {
-					return nil, err
-				}
-				stack = append(stack, f)
-			case 2:
-				arg1, arg2, stack, err = p.pop2(stack)
-				if err != nil {
-					return nil, err
+		switch value.token {
+		case TokenSpace, TokenComment:
+			continue
+		case TokenLeftParen:
+			depth++
+		case TokenRightParen:
+			depth--
+		case TokenComma:
+			if depth == 0 {
+				fields = append(fields, field)
+				field = nil
+				continue
+			}
+		}
+		field = append(field, value)
+	}
+	if len(field) > 0 || len(fields) > 0 {
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// readField reads a field of a projection. Errors are reported and produce a nil Projection.
+func (p *Parser) readField(values []*ParserValue, errs *errorList) *Projection {
+	alias, values, ok := p.readAlias(values, errs)
+	if !ok {
+		return nil
+	}
+	n, ok := p.readOperand(values, errs)
+	if !ok {
+		return nil
+	}
+	ex, isExpression := n.node.(*parserExpression)
+	if !isExpression || ex.ex.GetValue() == nil {
+		errs.add(newSpanError(n.span, errors.New("expected a KEY or VALUE")))
+		return nil
+	}
+	return &Projection{Value: ex.ex.GetValue(), Alias: alias}
+}
+
+// readAggregate reads an aggregate function call. Errors are reported and produce a nil Aggregate.
+func (p *Parser) readAggregate(values []*ParserValue, errs *errorList) *Aggregate {
+	alias, values, ok := p.readAlias(values, errs)
+	if !ok {
+		return nil
+	}
+	n := len(values)
+	if n < 3 || values[0].token != TokenUnknown || values[1].token != TokenLeftParen || values[n-1].token != TokenRightParen {
+		errs.add(newPositionalError(values[0], errors.New("expected an aggregate function, such as COUNT() or SUM(expression)")))
+		return nil
+	}
+	function, known := aggregateFunctions[strings.ToUpper(values[0].value)]
+	if !known {
+		errs.add(newPositionalError(values[0], errors.Errorf(`unknown aggregate function "%s"`, values[0].value)))
+		return nil
+	}
+	aggregate := &Aggregate{Function: function, Alias: alias}
+	if n == 3 {
+		return aggregate
+	}
+	arg, ok := p.readOperand(values[2:n-1], errs)
+	if !ok {
+		return nil
+	}
+	ex, _, err := p.valueToExpression(arg)
+	if err != nil {
+		errs.add(err)
+		return nil
+	}
+	aggregate.Expression = ex
+	return aggregate
+}
+
+// readAlias reads the alias that ends the values of a field, if any, returning the remaining values.
+// Errors are reported and ok is false.
+func (p *Parser) readAlias(values []*ParserValue, errs *errorList) (alias string, rest []*ParserValue, ok bool) {
+	isKeyword := func(value *ParserValue) bool {
+		return value.token == TokenUnknown && strings.EqualFold(value.value, aliasKeyword)
+	}
+	n := len(values)
+	switch {
+	case n == 0:
+		errs.add(newSpanError(p.endSpan(), errors.New("empty field")))
+		return "", nil, false
+	case isKeyword(values[n-1]):
+		errs.add(newPositionalError(values[n-1], errors.New("missing alias")))
+		return "", nil, false
+	case n >= 2 && isKeyword(values[n-2]):
+		value := values[n-1]
+		if value.token != TokenUnknown || !isIdentifier(value.value) {
+			errs.add(newPositionalError(value, errors.Errorf(`invalid alias "%s"`, value.value)))
+			return "", nil, false
+		}
+		if n == 2 {
+			errs.add(newPositionalError(values[0], errors.New("empty field")))
+			return "", nil, false
+		}
+		return value.value, values[:n-2], true
+	default:
+		return "", values, true
+	}
+}
+
+// readOperand reads the values of a field into a single node. Errors are reported and ok is false.
+func (p *Parser) readOperand(values []*ParserValue, errs *errorList) (n parserNode, ok bool) {
+	numErrs := len(*errs)
+	p.classifyUnknownTokens(values, errs)
+	for _, value := range values {
+		if isUnsupportedToken[value.token] {
+			errs.add(newPositionalError(value, errors.Errorf(`token %s "%s" is currently not supported`, value.token, value.value)))
+		}
+	}
+	values = p.toPostfix(values, errs)
+	var stack []parserNode
+	for _, value := range values {
+		var args []parserNode
+		args, stack = p.popArgs(value, stack, errs)
+		stack = append(stack, p.readNode(value, args, errs))
+	}
+	if len(*errs) > numErrs {
+		return parserNode{}, false
+	}
+	if len(stack) == 0 {
+		errs.add(newSpanError(p.endSpan(), errors.New("empty field")))
+		return parserNode{}, false
+	}
+	if len(stack) > 1 {
+		errs.add(newSpanError(stack[1].span, errors.New("unexpected value, expected a comma")))
+		return parserNode{}, false
+	}
+	return stack[0], true
+}
+
+// popArgs pops the arguments of a value from the stack, returned in the order they were pushed.
+// If there are too few, the error is reported and an invalid argument stands in for each missing one.
+func (p *Parser) popArgs(value *ParserValue, s []parserNode, errs *errorList) ([]parserNode, []parserNode) {
+	var n int
+	switch token := value.token; {
+	case token.IsFunction():
+		n = token.NumArgs()
+	case token.IsUnaryOperator():
+		n = 1
+	case token.IsBinaryOperator():
+		n = 2
+	}
+	if len(s) >= n {
+		args := make([]parserNode, n)
+		copy(args, s[len(s)-n:])
+		return args, s[:len(s)-n]
+	}
+	errs.add(newPositionalError(value, errors.Errorf("not enough arguments for %s, want %d", value.token, n)))
+	args := make([]parserNode, n)
+	for index := range args {
+		args[index] = parserNode{value: value, span: value.span(), node: &parserInvalid{}}
+	}
+	copy(args[n-len(s):], s)
+	return args, nil
+}
+
+// readNode applies a value to its arguments. Errors are reported and produce an invalid node.
+func (p *Parser) readNode(value *ParserValue, args []parserNode, errs *errorList) parserNode {
+	n := parserNode{value: value, span: value.span()}
+	if value.closer != nil {
+		n.span = n.span.union(value.closer.span())
+	}
+	invalid := value.token == TokenUnknown || isUnsupportedToken[value.token]
+	for _, arg := range args {
+		n.span = n.span.union(arg.span)
+		if _, ok := arg.node.(*parserInvalid); ok {
+			invalid = true
+		}
+	}
+	if invalid {
+		// The remaining operands of a logical operator can still be checked on their own.
+		if value.token == TokenAnd || value.token == TokenOr || value.token == TokenNot {
+			for _, arg := range args {
+				if _, ok := arg.node.(*parserInvalid); !ok {
+					if _, err := p.valueToBooleanExpression(arg); err != nil {
+						errs.add(err)
+					}
 				}
-			default:
-				panic("unhandled function args")
 			}
 		}
+		n.node = &parserInvalid{}
+		return n
 	}
 
-	return nil, nil
+	var err error
+	token := value.token
+	switch {
+	case token.IsLiteral() || token.IsTypeIdentifier():
+		n.node = value
+	case token.IsFunction():
+		n.node, err = p.valueToFunction(value, args)
+	case token.IsUnaryOperator():
+		n.node, err = p.valueToUnaryOperation(value, args[0])
+	case token.IsBinaryOperator():
+		n.node, err = p.valueToBinaryOperation(value, args[0], args[1])
+	default:
+		err = newPositionalError(value, errors.Errorf(`unhandled token "%s"`, value.value))
+	}
+	if err != nil {
+		errs.add(err)
+		n.node = &parserInvalid{}
+	}
+	return n
 }
 
-func (p *Parser) pop1(s []interface{}) (interface{}, []interface{}, error) {
-	if len(s) < 1 {
-		return nil, nil, errors.New("not enough arguments")
+func (p *Parser) unexpectedArg(got parserNode, want string) error {
+	return newSpanError(got.span, errors.Errorf("unexpected argument got %s want %s", got.value.token, want))
+}
+
+func (p *Parser) unexpectedArgToken(got *ParserValue, want Token) error {
+	return newPositionalError(got, errors.Errorf("unexpected argument got %s want %s", got.token, want))
+}
+
+// nodeToPredicate converts the final node on the stack into a Predicate.
+func (p *Parser) nodeToPredicate(n parserNode) (*Predicate, error) {
+	if junction, ok := n.node.(*parserJunction); ok {
+		expressions := &Expressions{Expressions: junction.expressions}
+		if junction.token == TokenAnd {
+			return &Predicate{Predicate: &Predicate_All{All: expressions}}, nil
+		}
+		return &Predicate{Predicate: &Predicate_Any{Any: expressions}}, nil
 	}
-	a := s[len(s)-1]
-	return a, s[:len(s)-1], nil
+	ex, err := p.valueToBooleanExpression(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Predicate{Predicate: &Predicate_Expression{Expression: ex}}, nil
 }
 
-func (p *Parser) pop2(s []interface{}) (interface{}, interface{}, []interface{}, error) {
-	if len(s) < 2 {
-		return nil, nil, nil, errors.New("not enough arguments")
+// valueToExpression converts a node into an expression operand and the type it returns.
+func (p *Parser) valueToExpression(n parserNode) (*Expression, ReturnType, error) {
+	switch t := n.node.(type) {
+	case *parserExpression:
+		return t.ex, t.returnType, nil
+	case *ParserValue:
+		if t.token == TokenBoolLiteral || t.token == TokenStringLiteral || t.token == TokenHexStringLiteral {
+			scalar, err := p.valueToScalar(t)
+			if err != nil {
+				return nil, ReturnType_RETURN_TYPE_UNKNOWN, err
+			}
+			ex := scalarToParserExpression(scalar)
+			return ex.ex, ex.returnType, nil
+		}
+		if t.token == TokenUnsignedIntegerLiteral {
+			return nil, ReturnType_RETURN_TYPE_UNKNOWN, newPositionalError(t, errors.Errorf(`untyped integer literal "%s", use a scalar function such as U64(%s)`, t.value, t.value))
+		}
+		if t.token == TokenSignedIntegerLiteral {
+			return nil, ReturnType_RETURN_TYPE_UNKNOWN, newPositionalError(t, errors.Errorf(`untyped integer literal "%s", use a scalar function such as I64(%s)`, t.value, t.value))
+		}
+		if t.token == TokenFloatLiteral {
+			return nil, ReturnType_RETURN_TYPE_UNKNOWN, newPositionalError(t, errors.Errorf(`untyped float literal "%s", use a scalar function such as F64(%s)`, t.value, t.value))
+		}
+		return nil, ReturnType_RETURN_TYPE_UNKNOWN, p.unexpectedArg(n, "an expression")
+	case *Jump:
+		return nil, ReturnType_RETURN_TYPE_UNKNOWN, newSpanError(n.span, errors.New("JUMP can only be used as the address of a KEY or VALUE"))
+	case *parserBytesType:
+		return nil, ReturnType_RETURN_TYPE_UNKNOWN, newSpanError(n.span, errors.New("BYTES can only be used as the type of a KEY or VALUE"))
+	case *parserJunction:
+		return junctionToExpression(t), ReturnType_RETURN_TYPE_BOOL, nil
+	default:
+		return nil, ReturnType_RETURN_TYPE_UNKNOWN, newSpanError(n.span, unhandledType("parser node", t))
 	}
-	a := s[len(s)-1]
-	b := s[len(s)-2]
-	return a, b, s[:len(s)-2], nil
 }
 
-func (p *Parser) unexpectedArg(value *ParserValue, got interface{}, want string) error {
-	return newPositionalError(value, errors.Errorf("unexpected argument got %T want %s", got, want))
+// valueToBooleanExpression converts a node into an expression and asserts that it evaluates to a boolean.
+func (p *Parser) valueToBooleanExpression(n parserNode) (*Expression, error) {
+	ex, returnType, err := p.valueToExpression(n)
+	if err != nil {
+		return nil, err
+	}
+	if returnType != ReturnType_RETURN_TYPE_BOOL {
+		return nil, newSpanError(n.span, errors.Errorf("expression is not a boolean expression: got %s", returnType))
+	}
+	return ex, nil
 }
 
-func (p *Parser) unexpectedArgToken(value *ParserValue, got *ParserValue, want Token) error {
-	return newPositionalError(value, errors.Errorf("unexpected argument got %s want %s", got.token, want))
+// valueToBinaryOperation applies a binary operator to its left and right operands.
+// Comparisons produce a BinaryOperation expression, AND and OR produce a parserJunction.
+func (p *Parser) valueToBinaryOperation(value *ParserValue, left, right parserNode) (interface{}, error) {
+	if value.token == TokenAnd || value.token == TokenOr {
+		return p.valueToJunction(value, left, right)
+	}
+
+	opCode, err := p.valueToBinaryOpCode(value)
+	if err != nil {
+		return nil, err
+	}
+	leftEx, leftType, err := p.valueToExpression(left)
+	if err != nil {
+		return nil, err
+	}
+	rightEx, rightType, err := p.valueToExpression(right)
+	if err != nil {
+		return nil, err
+	}
+	// Type check the operation now so that errors are reported at the operator.
+	c := &checker{}
+	returnType, _, _ := c.binaryOperationType("", opCode, leftType, true, rightType, true)
+	if err := c.err(); err != nil {
+		return nil, newPositionalError(value, wrap(err, "invalid expression"))
+	}
+	op := &BinaryOperation{
+		Left:         leftEx,
+		BinaryOpCode: opCode,
+		Right:        rightEx,
+	}
+	return &parserExpression{
+		ex:         &Expression{Expression: &Expression_BinaryOperation{BinaryOperation: op}},
+		returnType: returnType,
+	}, nil
+}
+
+// valueToJunction joins boolean operands with AND or OR, flattening chains of the same operator.
+func (p *Parser) valueToJunction(value *ParserValue, left, right parserNode) (*parserJunction, error) {
+	junction := &parserJunction{token: value.token}
+	for _, operand := range []parserNode{left, right} {
+		if nested, ok := operand.node.(*parserJunction); ok && nested.token == value.token {
+			junction.expressions = append(junction.expressions, nested.expressions...)
+			junction.spans = append(junction.spans, nested.spans...)
+			continue
+		}
+		ex, err := p.valueToBooleanExpression(operand)
+		if err != nil {
+			return nil, err
+		}
+		junction.expressions = append(junction.expressions, ex)
+		junction.spans = append(junction.spans, operand.span)
+	}
+	return junction, nil
 }
 
-func (p *Parser) valueToExpression(value *ParserValue) (*Expression, error) {
-	return nil, errors.New("not implemented")
+// junctionToExpression converts a junction nested within another operation into
+// a left-deep tree of AND or OR operations.
+func junctionToExpression(junction *parserJunction) *Expression {
+	opCode := BinaryOpCode_BINARY_OP_CODE_AND
+	if junction.token == TokenOr {
+		opCode = BinaryOpCode_BINARY_OP_CODE_OR
+	}
+	ex := junction.expressions[0]
+	for _, right := range junction.expressions[1:] {
+		ex = &Expression{Expression: &Expression_BinaryOperation{BinaryOperation: &BinaryOperation{
+			Left:         ex,
+			BinaryOpCode: opCode,
+			Right:        right,
+		}}}
+	}
+	return ex
 }
 
-func (p *Parser) valueToBinaryOperation(value *ParserValue) (*BinaryOperation, error) {
-	return nil, errors.New("not implemented")
+// valueToUnaryOperation applies a prefix operator to its operand.
+func (p *Parser) valueToUnaryOperation(value *ParserValue, operand parserNode) (*parserExpression, error) {
+	if value.token != TokenNot {
+		return nil, newPositionalError(value, errors.Errorf("unsupported operator %s", value.token))
+	}
+	ex, err := p.valueToBooleanExpression(operand)
+	if err != nil {
+		return nil, err
+	}
+	op := &UnaryOperation{
+		UnaryOpCode: UnaryOpCode_UNARY_OP_CODE_NOT,
+		Operand:     ex,
+	}
+	returnType, _ := (&checker{}).unaryOperationType("", op.UnaryOpCode, ReturnType_RETURN_TYPE_BOOL, true)
+	return &parserExpression{
+		ex:         &Expression{Expression: &Expression_UnaryOperation{UnaryOperation: op}},
+		returnType: returnType,
+	}, nil
 }
 
-func (p *Parser) valueToValue(value *ParserValue) (*Value, error) {
-	return nil, errors.New("not implemented")
+func (p *Parser) valueToBinaryOpCode(value *ParserValue) (BinaryOpCode, error) {
+	switch value.token {
+	case TokenEq:
+		return BinaryOpCode_BINARY_OP_CODE_EQ, nil
+	case TokenNeq:
+		return BinaryOpCode_BINARY_OP_CODE_NEQ, nil
+	case TokenLess:
+		return BinaryOpCode_BINARY_OP_CODE_LESS, nil
+	case TokenLessEq:
+		return BinaryOpCode_BINARY_OP_CODE_LESS_EQ, nil
+	case TokenGreater:
+		return BinaryOpCode_BINARY_OP_CODE_GREATER, nil
+	case TokenGreaterEq:
+		return BinaryOpCode_BINARY_OP_CODE_GREATER_EQ, nil
+	case TokenAdd:
+		return BinaryOpCode_BINARY_OP_CODE_ADD, nil
+	case TokenSub:
+		return BinaryOpCode_BINARY_OP_CODE_SUB, nil
+	case TokenMul:
+		return BinaryOpCode_BINARY_OP_CODE_MUL, nil
+	case TokenDiv:
+		return BinaryOpCode_BINARY_OP_CODE_DIV, nil
+	case TokenMod:
+		return BinaryOpCode_BINARY_OP_CODE_MOD, nil
+	case TokenBitAnd:
+		return BinaryOpCode_BINARY_OP_CODE_BIT_AND, nil
+	case TokenBitOr:
+		return BinaryOpCode_BINARY_OP_CODE_BIT_OR, nil
+	case TokenBitXor:
+		return BinaryOpCode_BINARY_OP_CODE_BIT_XOR, nil
+	case TokenShl:
+		return BinaryOpCode_BINARY_OP_CODE_SHL, nil
+	case TokenShr:
+		return BinaryOpCode_BINARY_OP_CODE_SHR, nil
+	default:
+		return BinaryOpCode_BINARY_OP_CODE_UNKNOWN, newPositionalError(value, errors.Errorf("unsupported operator %s", value.token))
+	}
 }
 
-func (p *Parser) valueToSingleArgFunc(value *ParserValue, arg1 interface{}) (interface{}, error) {
+// valueToFunction evaluates a function call with its arguments.
+func (p *Parser) valueToFunction(value *ParserValue, args []parserNode) (interface{}, error) {
 	switch value.token {
-	case TokenScalarU64:
-		argValue, ok := arg1.(*ParserValue)
-		if !ok {
-			return nil, p.unexpectedArg(value, arg1, "*ParserValue")
+	case TokenJump:
+		return p.valueToJump(args[0], args[1])
+	case TokenBytes:
+		return p.valueToBytesType(args[0])
+	case TokenKey:
+		v, err := p.valueToValue(Target_TARGET_KEY, args[0], args[1])
+		if err != nil {
+			return nil, err
 		}
-		if argValue.token != TokenUnsignedIntegerLiteral {
-			return nil, p.unexpectedArgToken(value, argValue, TokenUnsignedIntegerLiteral)
+		return valueToParserExpression(v), nil
+	case TokenValue:
+		v, err := p.valueToValue(Target_TARGET_VALUE, args[0], args[1])
+		if err != nil {
+			return nil, err
 		}
-		u64, err := strconv.ParseUint(argValue.value, 10, 64)
+		return valueToParserExpression(v), nil
+	default:
+		scalar, err := p.valueToSingleArgFunc(value, args[0])
 		if err != nil {
 			return nil, err
 		}
-		return &Scalar{
-			Value: &Scalar_U64{
-				U64: u64,
-			},
-		}, nil
-	case TokenScalarU32:
-		argValue, ok := arg1.(*ParserValue)
-		if !ok {
-			return nil, p.unexpectedArg(value, arg1, "*ParserValue")
+		return scalarToParserExpression(scalar), nil
+	}
+}
+
+// valueToParserExpression converts a KEY or VALUE into an expression returning its value type.
+func valueToParserExpression(v *Value) *parserExpression {
+	returnType, _ := (&checker{}).checkValue("", v)
+	return &parserExpression{
+		ex:         &Expression{Expression: &Expression_Value{Value: v}},
+		returnType: returnType,
+	}
+}
+
+// scalarToParserExpression converts a scalar into an expression returning its type.
+func scalarToParserExpression(scalar *Scalar) *parserExpression {
+	returnType, _ := (&checker{}).checkScalar("", scalar)
+	return &parserExpression{
+		ex:         &Expression{Expression: &Expression_Scalar{Scalar: scalar}},
+		returnType: returnType,
+	}
+}
+
+// valueToJump converts JUMP(offset, type) arguments into a Jump.
+func (p *Parser) valueToJump(offsetArg, typeArg parserNode) (*Jump, error) {
+	offset, err := p.valueToOffset(offsetArg)
+	if err != nil {
+		return nil, err
+	}
+	typeValue, ok := typeArg.node.(*ParserValue)
+	if !ok || !typeValue.token.IsTypeIdentifier() {
+		return nil, p.unexpectedArg(typeArg, "a type identifier")
+	}
+	switch typeValue.token {
+	case TokenTypeU64LE:
+		return &Jump{Jump: &Jump_U64Le{U64Le: offset}}, nil
+	case TokenTypeU64BE:
+		return &Jump{Jump: &Jump_U64Be{U64Be: offset}}, nil
+	case TokenTypeU32LE:
+		return &Jump{Jump: &Jump_U32Le{U32Le: offset}}, nil
+	case TokenTypeU32BE:
+		return &Jump{Jump: &Jump_U32Be{U32Be: offset}}, nil
+	case TokenTypeU16LE:
+		return &Jump{Jump: &Jump_U16Le{U16Le: offset}}, nil
+	case TokenTypeU16BE:
+		return &Jump{Jump: &Jump_U16Be{U16Be: offset}}, nil
+	case TokenTypeU8:
+		return &Jump{Jump: &Jump_U8{U8: offset}}, nil
+	default:
+		return nil, newPositionalError(typeValue, errors.Errorf("%s is not a valid jump address type", typeValue.token))
+	}
+}
+
+// valueToValue converts KEY(offset OR jump, type) or VALUE(offset OR jump, type) arguments into a Value.
+func (p *Parser) valueToValue(target Target, addressArg, typeArg parserNode) (*Value, error) {
+	var jump *Jump
+	if j, ok := addressArg.node.(*Jump); ok {
+		jump = j
+	} else {
+		offset, err := p.valueToOffset(addressArg)
+		if err != nil {
+			return nil, err
 		}
+		jump = &Jump{Jump: &Jump_Offset{Offset: offset}}
+	}
+	if bytesType, ok := typeArg.node.(*parserBytesType); ok {
+		return &Value{Jump: jump, Type: bytesType.valueType, Target: target, Length: bytesType.length}, nil
+	}
+	typeValue, ok := typeArg.node.(*ParserValue)
+	if !ok || !typeValue.token.IsTypeIdentifier() {
+		return nil, p.unexpectedArg(typeArg, "a type identifier")
+	}
+	var valueType ValueType
+	switch typeValue.token {
+	case TokenTypeU64LE:
+		valueType = ValueType_VALUE_TYPE_U64LE
+	case TokenTypeU64BE:
+		valueType = ValueType_VALUE_TYPE_U64BE
+	case TokenTypeU32LE:
+		valueType = ValueType_VALUE_TYPE_U32LE
+	case TokenTypeU32BE:
+		valueType = ValueType_VALUE_TYPE_U32BE
+	case TokenTypeU16LE:
+		valueType = ValueType_VALUE_TYPE_U16LE
+	case TokenTypeU16BE:
+		valueType = ValueType_VALUE_TYPE_U16BE
+	case TokenTypeU8:
+		valueType = ValueType_VALUE_TYPE_U8
+	case TokenTypeI64LE:
+		valueType = ValueType_VALUE_TYPE_I64LE
+	case TokenTypeI64BE:
+		valueType = ValueType_VALUE_TYPE_I64BE
+	case TokenTypeI32LE:
+		valueType = ValueType_VALUE_TYPE_I32LE
+	case TokenTypeI32BE:
+		valueType = ValueType_VALUE_TYPE_I32BE
+	case TokenTypeI16LE:
+		valueType = ValueType_VALUE_TYPE_I16LE
+	case TokenTypeI16BE:
+		valueType = ValueType_VALUE_TYPE_I16BE
+	case TokenTypeI8:
+		valueType = ValueType_VALUE_TYPE_I8
+	case TokenTypeF64LE:
+		valueType = ValueType_VALUE_TYPE_F64LE
+	case TokenTypeF64BE:
+		valueType = ValueType_VALUE_TYPE_F64BE
+	case TokenTypeF32LE:
+		valueType = ValueType_VALUE_TYPE_F32LE
+	case TokenTypeF32BE:
+		valueType = ValueType_VALUE_TYPE_F32BE
+	default:
+		return nil, newPositionalError(typeValue, errors.Errorf("%s is not a valid value type", typeValue.token))
+	}
+	return &Value{Jump: jump, Type: valueType, Target: target}, nil
+}
+
+// valueToBytesType converts BYTES(length) or BYTES(length type) arguments into a byte string type.
+func (p *Parser) valueToBytesType(lengthArg parserNode) (*parserBytesType, error) {
+	lengthValue, ok := lengthArg.node.(*ParserValue)
+	if !ok {
+		return nil, p.unexpectedArg(lengthArg, "a length or a length type identifier")
+	}
+	switch lengthValue.token {
+	case TokenUnsignedIntegerLiteral:
+		length, err := parseUintLiteral(lengthValue.value, 64)
+		if err != nil {
+			return nil, newPositionalError(lengthValue, err)
+		}
+		return &parserBytesType{valueType: ValueType_VALUE_TYPE_BYTES, length: length}, nil
+	case TokenTypeU8:
+		return &parserBytesType{valueType: ValueType_VALUE_TYPE_BYTES_U8}, nil
+	case TokenTypeU16LE:
+		return &parserBytesType{valueType: ValueType_VALUE_TYPE_BYTES_U16LE}, nil
+	case TokenTypeU16BE:
+		return &parserBytesType{valueType: ValueType_VALUE_TYPE_BYTES_U16BE}, nil
+	case TokenTypeU32LE:
+		return &parserBytesType{valueType: ValueType_VALUE_TYPE_BYTES_U32LE}, nil
+	case TokenTypeU32BE:
+		return &parserBytesType{valueType: ValueType_VALUE_TYPE_BYTES_U32BE}, nil
+	default:
+		return nil, newPositionalError(lengthValue, errors.Errorf("%s is not a valid length or length type", lengthValue.token))
+	}
+}
+
+// valueToOffset converts an unsigned integer literal argument into an offset.
+func (p *Parser) valueToOffset(arg parserNode) (uint64, error) {
+	argValue, ok := arg.node.(*ParserValue)
+	if !ok || argValue.token != TokenUnsignedIntegerLiteral {
+		return 0, p.unexpectedArg(arg, TokenUnsignedIntegerLiteral.String())
+	}
+	offset, err := parseUintLiteral(argValue.value, 64)
+	if err != nil {
+		return 0, newPositionalError(argValue, err)
+	}
+	return offset, nil
+}
+
+func (p *Parser) valueToSingleArgFunc(value *ParserValue, arg1 parserNode) (*Scalar, error) {
+	argValue, ok := arg1.node.(*ParserValue)
+	if !ok {
+		return nil, p.unexpectedArg(arg1, "a literal")
+	}
+	switch value.token {
+	case TokenScalarU64, TokenScalarU32:
 		if argValue.token != TokenUnsignedIntegerLiteral {
-			return nil, p.unexpectedArgToken(value, argValue, TokenUnsignedIntegerLiteral)
+			return nil, p.unexpectedArgToken(argValue, TokenUnsignedIntegerLiteral)
 		}
-		u32, err := strconv.ParseUint(argValue.value, 10, 64)
-		if err != nil {
-			return nil, err
+	case TokenScalarI64, TokenScalarI32:
+		if argValue.token != TokenUnsignedIntegerLiteral && argValue.token != TokenSignedIntegerLiteral {
+			return nil, p.unexpectedArgToken(argValue, TokenSignedIntegerLiteral)
 		}
-		return &Scalar{
-			Value: &Scalar_U32{
-				U32: uint32(u32),
-			},
-		}, nil
-	case TokenScalarBool:
-		argValue, ok := arg1.(*ParserValue)
-		if !ok {
-			return nil, p.unexpectedArg(value, arg1, "*ParserValue")
+	case TokenScalarF64, TokenScalarF32:
+		switch argValue.token {
+		case TokenUnsignedIntegerLiteral, TokenSignedIntegerLiteral, TokenFloatLiteral:
+		default:
+			return nil, p.unexpectedArgToken(argValue, TokenFloatLiteral)
 		}
+	case TokenScalarBool:
 		if argValue.token != TokenBoolLiteral {
-			return nil, p.unexpectedArgToken(value, argValue, TokenBoolLiteral)
-		}
-		isTrue := strings.EqualFold(argValue.value, trueString)
-		return &Scalar{
-			Value: &Scalar_Bool{
-				Bool: isTrue,
-			},
-		}, nil
-	case TokenScalarU16:
-		fallthrough
-	case TokenScalarU8:
-		fallthrough
+			return nil, p.unexpectedArgToken(argValue, TokenBoolLiteral)
+		}
+		return p.valueToScalar(argValue)
+	default:
+		return nil, newPositionalError(value, errors.Errorf("unsupported function %s", value.token.String()))
+	}
+
+	switch value.token {
+	case TokenScalarU64:
+		u64, err := parseUintLiteral(argValue.value, 64)
+		if err != nil {
+			return nil, newPositionalError(argValue, err)
+		}
+		return &Scalar{Value: &Scalar_U64{U64: u64}}, nil
+	case TokenScalarU32:
+		u32, err := parseUintLiteral(argValue.value, 32)
+		if err != nil {
+			return nil, newPositionalError(argValue, err)
+		}
+		return &Scalar{Value: &Scalar_U32{U32: uint32(u32)}}, nil
+	case TokenScalarI64:
+		i64, err := parseIntLiteral(argValue.value, 64)
+		if err != nil {
+			return nil, newPositionalError(argValue, err)
+		}
+		return &Scalar{Value: &Scalar_I64{I64: i64}}, nil
+	case TokenScalarI32:
+		i32, err := parseIntLiteral(argValue.value, 32)
+		if err != nil {
+			return nil, newPositionalError(argValue, err)
+		}
+		return &Scalar{Value: &Scalar_I32{I32: int32(i32)}}, nil
+	case TokenScalarF64:
+		f64, err := parseFloatLiteral(argValue.value, 64)
+		if err != nil {
+			return nil, newPositionalError(argValue, err)
+		}
+		return &Scalar{Value: &Scalar_F64{F64: f64}}, nil
 	default:
-		return nil, errors.Errorf("unsupported function %s", value.token.String())
+		f32, err := parseFloatLiteral(argValue.value, 32)
+		if err != nil {
+			return nil, newPositionalError(argValue, err)
+		}
+		return &Scalar{Value: &Scalar_F32{F32: float32(f32)}}, nil
 	}
 }
 
-func (p *Parser) valueToScalar(value *ParserValue, literal interface{}) (*Scalar, error) {
-	return nil, errors.New("not implemented")
+// valueToScalar converts a literal into a Scalar of the literal's natural type.
+func (p *Parser) valueToScalar(literal *ParserValue) (*Scalar, error) {
+	switch literal.token {
+	case TokenUnsignedIntegerLiteral:
+		u64, err := parseUintLiteral(literal.value, 64)
+		if err != nil {
+			return nil, newPositionalError(literal, err)
+		}
+		return &Scalar{Value: &Scalar_U64{U64: u64}}, nil
+	case TokenSignedIntegerLiteral:
+		i64, err := parseIntLiteral(literal.value, 64)
+		if err != nil {
+			return nil, newPositionalError(literal, err)
+		}
+		return &Scalar{Value: &Scalar_I64{I64: i64}}, nil
+	case TokenFloatLiteral:
+		f64, err := parseFloatLiteral(literal.value, 64)
+		if err != nil {
+			return nil, newPositionalError(literal, err)
+		}
+		return &Scalar{Value: &Scalar_F64{F64: f64}}, nil
+	case TokenStringLiteral, TokenHexStringLiteral:
+		b, err := stringLiteralBytes(literal.token, literal.value)
+		if err != nil {
+			return nil, newPositionalError(literal, err)
+		}
+		return &Scalar{Value: &Scalar_Bytes{Bytes: b}}, nil
+	case TokenBoolLiteral:
+		isTrue := strings.EqualFold(literal.value, trueString)
+		return &Scalar{Value: &Scalar_Bool{Bool: isTrue}}, nil
+	default:
+		return nil, newPositionalError(literal, errors.Errorf(`unsupported literal %s "%s"`, literal.token, literal.value))
+	}
 }
@@ -0,0 +1,548 @@
+package binq
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func makeBinaryOperationExpression(left *Expression, op BinaryOpCode, right *Expression) *Expression {
+	return &Expression{
+		Expression: &Expression_BinaryOperation{
+			BinaryOperation: &BinaryOperation{
+				Left:         left,
+				BinaryOpCode: op,
+				Right:        right,
+			},
+		},
+	}
+}
+
+func makeUnaryOperationExpression(operand *Expression) *Expression {
+	return &Expression{
+		Expression: &Expression_UnaryOperation{
+			UnaryOperation: &UnaryOperation{
+				UnaryOpCode: UnaryOpCode_UNARY_OP_CODE_NOT,
+				Operand:     operand,
+			},
+		},
+	}
+}
+
+func TestParser_ReadPredicate(t *testing.T) {
+	t.Parallel()
+
+	u64le0 := makeValueExpression(ValueType_VALUE_TYPE_U64LE, 0)
+	u32be8 := makeValueExpression(ValueType_VALUE_TYPE_U32BE, 8)
+	u64le0EqU64100 := makeBinaryOperationExpression(u64le0, BinaryOpCode_BINARY_OP_CODE_EQ, makeScalarExpression(t, uint64(100)))
+	u32be8LtU32 := makeBinaryOperationExpression(u32be8, BinaryOpCode_BINARY_OP_CODE_LESS, makeScalarExpression(t, uint32(5)))
+	jumped := &Expression{
+		Expression: &Expression_Value{
+			Value: &Value{
+				Jump: &Jump{Jump: &Jump_U16Le{U16Le: 2}},
+				Type: ValueType_VALUE_TYPE_U32LE,
+			},
+		},
+	}
+
+	keyJumped := proto.Clone(jumped).(*Expression)
+	keyJumped.GetValue().Target = Target_TARGET_KEY
+
+	cases := []struct {
+		name     string
+		input    string
+		expected *Predicate
+	}{
+		{
+			"expression",
+			"VALUE(0, U64LE) = U64(100)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: u64le0EqU64100}},
+		},
+		{
+			"default-little-endian",
+			"VALUE(0, U64) = U64(100)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: u64le0EqU64100}},
+		},
+		{
+			"case-insensitive",
+			"value(0, u64le) = u64(100)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: u64le0EqU64100}},
+		},
+		{
+			"comments-and-parenthesis",
+			"# comment\n((VALUE(0, U64LE) = U64(100))) # trailing comment",
+			&Predicate{Predicate: &Predicate_Expression{Expression: u64le0EqU64100}},
+		},
+		{
+			"jump",
+			"VALUE(JUMP(2, U16LE), U32LE) != U32(10)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				jumped, BinaryOpCode_BINARY_OP_CODE_NEQ, makeScalarExpression(t, uint32(10)))}},
+		},
+		{
+			"bool-literals",
+			"false != true",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeScalarExpression(t, false), BinaryOpCode_BINARY_OP_CODE_NEQ, makeScalarExpression(t, true))}},
+		},
+		{
+			"bool-scalar",
+			"BOOL(true) = VALUE(0, U64LE) >= U64(100)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeBinaryOperationExpression(makeScalarExpression(t, true), BinaryOpCode_BINARY_OP_CODE_EQ, u64le0),
+				BinaryOpCode_BINARY_OP_CODE_GREATER_EQ,
+				makeScalarExpression(t, uint64(100)))}},
+		},
+		{
+			"key",
+			"KEY(JUMP(2, U16LE), U32LE) != VALUE(8, U32BE)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				keyJumped, BinaryOpCode_BINARY_OP_CODE_NEQ, u32be8)}},
+		},
+		{
+			"signed",
+			"VALUE(0, I64LE) < I64(-5)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeValueExpression(ValueType_VALUE_TYPE_I64LE, 0), BinaryOpCode_BINARY_OP_CODE_LESS, makeScalarExpression(t, int64(-5)))}},
+		},
+		{
+			"signed-positive",
+			"VALUE(0, I16BE) >= I32(7)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeValueExpression(ValueType_VALUE_TYPE_I16BE, 0), BinaryOpCode_BINARY_OP_CODE_GREATER_EQ, makeScalarExpression(t, int32(7)))}},
+		},
+		{
+			"float",
+			"VALUE(0, F64LE) > F64(-1.5e-3)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeValueExpression(ValueType_VALUE_TYPE_F64LE, 0), BinaryOpCode_BINARY_OP_CODE_GREATER, makeScalarExpression(t, -1.5e-3))}},
+		},
+		{
+			"float-int-literal",
+			"VALUE(0, F32BE) != F32(10)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeValueExpression(ValueType_VALUE_TYPE_F32BE, 0), BinaryOpCode_BINARY_OP_CODE_NEQ, makeScalarExpression(t, float32(10)))}},
+		},
+		{
+			"float-int-promotion",
+			"VALUE(0, F64LE) > U32(10)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeValueExpression(ValueType_VALUE_TYPE_F64LE, 0), BinaryOpCode_BINARY_OP_CODE_GREATER, makeScalarExpression(t, uint32(10)))}},
+		},
+		{
+			"bytes",
+			`KEY(0, BYTES(3)) = "a(b"`,
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				&Expression{Expression: &Expression_Value{Value: &Value{
+					Jump:   &Jump{Jump: &Jump_Offset{Offset: 0}},
+					Type:   ValueType_VALUE_TYPE_BYTES,
+					Target: Target_TARGET_KEY,
+					Length: 3,
+				}}},
+				BinaryOpCode_BINARY_OP_CODE_EQ,
+				makeScalarExpression(t, "a(b"))}},
+		},
+		{
+			"bytes-prefixed",
+			`VALUE(JUMP(2, U16LE), BYTES(U16BE)) >= X"00fF"`,
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				&Expression{Expression: &Expression_Value{Value: &Value{
+					Jump: &Jump{Jump: &Jump_U16Le{U16Le: 2}},
+					Type: ValueType_VALUE_TYPE_BYTES_U16BE,
+				}}},
+				BinaryOpCode_BINARY_OP_CODE_GREATER_EQ,
+				makeScalarExpression(t, []byte{0x00, 0xff}))}},
+		},
+		{
+			"string-escapes",
+			`"a \"quoted\" # string\x00" != x""`,
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeScalarExpression(t, "a \"quoted\" # string\x00"),
+				BinaryOpCode_BINARY_OP_CODE_NEQ,
+				makeScalarExpression(t, []byte{}))}},
+		},
+		{
+			"bit-flags",
+			"VALUE(4, U32LE) & U32(0x8000) != U32(0)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeBinaryOperationExpression(
+					makeValueExpression(ValueType_VALUE_TYPE_U32LE, 4), BinaryOpCode_BINARY_OP_CODE_BIT_AND, makeScalarExpression(t, uint32(0x8000))),
+				BinaryOpCode_BINARY_OP_CODE_NEQ,
+				makeScalarExpression(t, uint32(0)))}},
+		},
+		{
+			"arithmetic-precedence",
+			"U64(1) + U64(2) * U64(3) - U64(4) = U64(3)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeBinaryOperationExpression(
+					makeBinaryOperationExpression(
+						makeScalarExpression(t, uint64(1)),
+						BinaryOpCode_BINARY_OP_CODE_ADD,
+						makeBinaryOperationExpression(makeScalarExpression(t, uint64(2)), BinaryOpCode_BINARY_OP_CODE_MUL, makeScalarExpression(t, uint64(3)))),
+					BinaryOpCode_BINARY_OP_CODE_SUB,
+					makeScalarExpression(t, uint64(4))),
+				BinaryOpCode_BINARY_OP_CODE_EQ,
+				makeScalarExpression(t, uint64(3)))}},
+		},
+		{
+			"signed-hex",
+			"I64(-0x10) < I32(0X7f)",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeScalarExpression(t, int64(-16)), BinaryOpCode_BINARY_OP_CODE_LESS, makeScalarExpression(t, int32(127)))}},
+		},
+		{
+			"and",
+			"(VALUE(0, U64LE) = U64(100)) AND (VALUE(8, U32BE) < U32(5)) AND true",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				u64le0EqU64100, u32be8LtU32, makeScalarExpression(t, true),
+			}}}},
+		},
+		{
+			"or",
+			"(VALUE(0, U64LE) = U64(100)) OR (VALUE(8, U32BE) < U32(5))",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: []*Expression{
+				u64le0EqU64100, u32be8LtU32,
+			}}}},
+		},
+		{
+			"not",
+			"NOT (VALUE(0, U64LE) = U64(100))",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeUnaryOperationExpression(u64le0EqU64100)}},
+		},
+		{
+			"not-not",
+			"NOT NOT true",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeUnaryOperationExpression(
+				makeUnaryOperationExpression(makeScalarExpression(t, true)))}},
+		},
+		{
+			"nested-or",
+			"((VALUE(0, U64LE) = U64(100)) OR (VALUE(8, U32BE) < U32(5)) OR false) AND NOT true",
+			&Predicate{Predicate: &Predicate_All{All: &Expressions{Expressions: []*Expression{
+				makeBinaryOperationExpression(
+					makeBinaryOperationExpression(u64le0EqU64100, BinaryOpCode_BINARY_OP_CODE_OR, u32be8LtU32),
+					BinaryOpCode_BINARY_OP_CODE_OR,
+					makeScalarExpression(t, false)),
+				makeUnaryOperationExpression(makeScalarExpression(t, true)),
+			}}}},
+		},
+		{
+			"nested-and",
+			"true OR (false AND (true AND false))",
+			&Predicate{Predicate: &Predicate_Any{Any: &Expressions{Expressions: []*Expression{
+				makeScalarExpression(t, true),
+				makeBinaryOperationExpression(
+					makeBinaryOperationExpression(makeScalarExpression(t, false), BinaryOpCode_BINARY_OP_CODE_AND, makeScalarExpression(t, true)),
+					BinaryOpCode_BINARY_OP_CODE_AND,
+					makeScalarExpression(t, false)),
+			}}}},
+		},
+		{
+			"compare-junction",
+			"(true AND false) = false",
+			&Predicate{Predicate: &Predicate_Expression{Expression: makeBinaryOperationExpression(
+				makeBinaryOperationExpression(makeScalarExpression(t, true), BinaryOpCode_BINARY_OP_CODE_AND, makeScalarExpression(t, false)),
+				BinaryOpCode_BINARY_OP_CODE_EQ,
+				makeScalarExpression(t, false))}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			predicate, err := NewParser(tc.input).ReadPredicate()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.True(t, proto.Equal(tc.expected, predicate), "got %s", proto.CompactTextString(predicate))
+			_, err = PredicateToRecordMatcher(predicate)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// formatGrouping renders a predicate as nested prefix lists, such as (AND (= a b) c),
+// so that the grouping chosen by a Parser is explicit.
+func formatGrouping(t *testing.T, pred *Predicate) string {
+	switch p := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		return formatExpressionGrouping(t, p.Expression)
+	case *Predicate_All:
+		return formatJunctionGrouping(t, TokenAnd, p.All.GetExpressions())
+	case *Predicate_Any:
+		return formatJunctionGrouping(t, TokenOr, p.Any.GetExpressions())
+	default:
+		t.Fatalf("unhandled predicate type %T", p)
+		return ""
+	}
+}
+
+func formatJunctionGrouping(t *testing.T, token Token, exs []*Expression) string {
+	parts := []string{token.keyword()}
+	for _, ex := range exs {
+		parts = append(parts, formatExpressionGrouping(t, ex))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func formatExpressionGrouping(t *testing.T, ex *Expression) string {
+	switch e := ex.GetExpression().(type) {
+	case *Expression_BinaryOperation:
+		token, err := binaryOpCodeToken(e.BinaryOperation.BinaryOpCode)
+		assert.NoError(t, err)
+		return "(" + token.keyword() + " " +
+			formatExpressionGrouping(t, e.BinaryOperation.Left) + " " +
+			formatExpressionGrouping(t, e.BinaryOperation.Right) + ")"
+	case *Expression_UnaryOperation:
+		return "(NOT " + formatExpressionGrouping(t, e.UnaryOperation.Operand) + ")"
+	default:
+		text, err := FormatExpression(ex, FormatOptions{})
+		assert.NoError(t, err)
+		return text
+	}
+}
+
+func TestParser_ReadPredicate_Grouping(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		// Comparisons bind tighter than AND and OR.
+		{"true = false AND false = true", "(AND (= true false) (= false true))"},
+		{"true != false OR false < true", "(OR (!= true false) (< false true))"},
+		{"VALUE(0, U8) = U32(1) OR VALUE(0, U8) = U32(2)", "(OR (= VALUE(0, U8) U32(1)) (= VALUE(0, U8) U32(2)))"},
+		// AND binds tighter than OR.
+		{"true OR false AND true", "(OR true (AND false true))"},
+		{"true AND false OR true", "(OR (AND true false) true)"},
+		{"true AND false OR true AND false", "(OR (AND true false) (AND true false))"},
+		{"true OR false OR true AND false", "(OR true false (AND true false))"},
+		{"true AND false AND true OR false", "(OR (AND (AND true false) true) false)"},
+		{"true OR false AND true OR false", "(OR true (AND false true) false)"},
+		// NOT binds looser than comparisons and tighter than AND.
+		{"NOT true", "(NOT true)"},
+		{"NOT true = false", "(NOT (= true false))"},
+		{"NOT true AND false", "(AND (NOT true) false)"},
+		{"true AND NOT false", "(AND true (NOT false))"},
+		{"NOT true OR false", "(OR (NOT true) false)"},
+		{"NOT NOT true OR false", "(OR (NOT (NOT true)) false)"},
+		{"NOT true = false OR true", "(OR (NOT (= true false)) true)"},
+		{"true AND NOT false = true", "(AND true (NOT (= false true)))"},
+		{"true = NOT false", "(= true (NOT false))"},
+		{"NOT U32(1) + U32(2) > U32(2)", "(NOT (> (+ U32(1) U32(2)) U32(2)))"},
+		// Comparisons are left associative.
+		{"U32(1) < U32(2) = true", "(= (< U32(1) U32(2)) true)"},
+		{"true = false != true", "(!= (= true false) true)"},
+		// Arithmetic binds tighter than comparisons.
+		{"U32(1) + U32(2) = U32(3)", "(= (+ U32(1) U32(2)) U32(3))"},
+		{"U32(3) = U32(1) + U32(2)", "(= U32(3) (+ U32(1) U32(2)))"},
+		{"U32(1) + U32(2) * U32(3) = U32(7)", "(= (+ U32(1) (* U32(2) U32(3))) U32(7))"},
+		{"U32(1) * U32(2) + U32(3) = U32(5)", "(= (+ (* U32(1) U32(2)) U32(3)) U32(5))"},
+		{"U32(8) - U32(2) - U32(1) = U32(5)", "(= (- (- U32(8) U32(2)) U32(1)) U32(5))"},
+		{"U32(8) / U32(4) / U32(2) = U32(1)", "(= (/ (/ U32(8) U32(4)) U32(2)) U32(1))"},
+		{"U32(7) % U32(4) * U32(2) = U32(6)", "(= (* (% U32(7) U32(4)) U32(2)) U32(6))"},
+		{"U32(1) << U32(2) + U32(1) = U32(5)", "(= (+ (<< U32(1) U32(2)) U32(1)) U32(5))"},
+		{"U32(1) + U32(16) >> U32(2) = U32(5)", "(= (+ U32(1) (>> U32(16) U32(2))) U32(5))"},
+		{"U32(6) & U32(3) | U32(8) = U32(10)", "(= (| (& U32(6) U32(3)) U32(8)) U32(10))"},
+		{"U32(8) | U32(6) & U32(3) = U32(10)", "(= (| U32(8) (& U32(6) U32(3))) U32(10))"},
+		{"U32(1) | U32(2) ^ U32(3) = U32(0)", "(= (^ (| U32(1) U32(2)) U32(3)) U32(0))"},
+		{"U32(1) - U32(2) + U32(3) = U32(2)", "(= (+ (- U32(1) U32(2)) U32(3)) U32(2))"},
+		{"VALUE(4, U32LE) & U32(0x8000) != U32(0)", "(!= (& VALUE(4, U32LE) U32(32768)) U32(0))"},
+		// Parenthesis override precedence.
+		{"(true OR false) AND true", "(AND (OR true false) true)"},
+		{"true AND (false OR true) AND NOT false", "(AND true (OR false true) (NOT false))"},
+		{"NOT (true OR false)", "(NOT (OR true false))"},
+		{"(NOT true) = false", "(= (NOT true) false)"},
+		{"(true AND false) = (false OR true)", "(= (AND true false) (OR false true))"},
+		{"true = (false = true)", "(= true (= false true))"},
+		{"U32(2) * (U32(3) + U32(4)) = U32(14)", "(= (* U32(2) (+ U32(3) U32(4))) U32(14))"},
+		{"(U32(8) - (U32(2) - U32(1))) = U32(7)", "(= (- U32(8) (- U32(2) U32(1))) U32(7))"},
+		// Everything together.
+		{
+			"VALUE(0, U8) + U32(1) > U32(2) AND NOT VALUE(1, U8) = U32(0) OR VALUE(2, U8) * U32(2) < U32(4)",
+			"(OR (AND (> (+ VALUE(0, U8) U32(1)) U32(2)) (NOT (= VALUE(1, U8) U32(0)))) (< (* VALUE(2, U8) U32(2)) U32(4)))",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			predicate, err := NewParser(tc.input).ReadPredicate()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expected, formatGrouping(t, predicate))
+
+			// The formatter must add the parenthesis needed to keep the same grouping.
+			text, err := FormatPredicate(predicate, FormatOptions{})
+			if !assert.NoError(t, err) {
+				return
+			}
+			reparsed, err := NewParser(text).ReadPredicate()
+			if !assert.NoError(t, err, text) {
+				return
+			}
+			assert.Equal(t, tc.expected, formatGrouping(t, reparsed), text)
+		})
+	}
+}
+
+func TestParser_ReadPredicate_Errors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		input   string
+		line    int
+		linePos int
+	}{
+		{"empty", "# nothing", -1, -1},
+		{"unmatched-left", "(VALUE(0, U64LE) = U64(100)", 0, 0},
+		{"unmatched-right", "VALUE(0, U64LE) = U64(100))", 0, 26},
+		{"unknown-function", "VALUE(0, U64LE) = FOO(100)", 0, 18},
+		{"non-boolean", "U64(100)", 0, 0},
+		{"untyped-literal", "VALUE(0, U64LE) = 100", 0, 18},
+		{"missing-operand", "VALUE(0, U64LE) =", 0, 16},
+		{"missing-operator", "U64(1) U64(1)", 0, 7},
+		{"bad-value-type", "VALUE(0, 7) = U64(1)", 0, 9},
+		{"bad-offset", "VALUE(U64LE, U64LE) = U64(1)", 0, 6},
+		{"bad-scalar-arg", "VALUE(0, U64LE) = U64(true)", 0, 22},
+		{"u32-overflow", "VALUE(0, U32LE) = U32(4294967296)", 0, 22},
+		{"i32-overflow", "VALUE(0, I32LE) = I32(-2147483649)", 0, 22},
+		{"i64-overflow", "VALUE(0, I64LE) = I64(9223372036854775808)", 0, 22},
+		{"untyped-signed-literal", "VALUE(0, I64LE) = -1", 0, 18},
+		{"u64-signed-literal", "VALUE(0, U64LE) = U64(-1)", 0, 22},
+		{"u64-and-signed", "VALUE(0, U64LE) = I64(1)", 0, 16},
+		{"untyped-float-literal", "VALUE(0, F64LE) = 1.5", 0, 18},
+		{"f32-overflow", "VALUE(0, F32LE) = F32(1e39)", 0, 22},
+		{"float-in-integer", "VALUE(0, I64LE) = I64(1.5)", 0, 22},
+		{"invalid-float", "VALUE(0, F64LE) = F64(1.5.2)", 0, 22},
+		{"unterminated-string", `VALUE(0, BYTES(3)) = "abc`, 0, 21},
+		{"invalid-escape", `VALUE(0, BYTES(3)) = "\q"`, 0, 21},
+		{"invalid-hex", `VALUE(0, BYTES(3)) = x"abc"`, 0, 21},
+		{"bytes-bad-length-type", `VALUE(0, BYTES(I16)) = "abc"`, 0, 15},
+		{"bytes-outside-value", `BYTES(3) = "abc"`, 0, 0},
+		{"bytes-and-integer", `VALUE(0, BYTES(3)) = U64(1)`, 0, 19},
+		{"float-bitwise", "F64(1) & F64(2) = F64(0)", 0, 7},
+		{"bool-arithmetic", "true + false", 0, 5},
+		{"invalid-hex", "U32(0xfg) = U32(1)", 0, 4},
+		{"hex-overflow", "U32(0x100000000) = U32(1)", 0, 4},
+		{"non-boolean-and", "U64(1) AND true", 0, 0},
+		{"not-non-boolean", "NOT U64(1)", 0, 4},
+		{"not-missing-operand", "NOT", 0, 0},
+		{"nested-non-boolean", "(true OR U64(1)) = true", 0, 9},
+		{"multi-line", "(VALUE(0, U64LE) = U64(1))\nAND\n  (U64(1) < 2)", 2, 12},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewParser(tc.input).ReadPredicate()
+			if !assert.Error(t, err) {
+				return
+			}
+			if tc.line < 0 {
+				return
+			}
+			posErr, ok := err.(positionalError)
+			if !assert.True(t, ok, "not a positional error: %v", err) {
+				return
+			}
+			assert.Equal(t, tc.line, posErr.line, "line: %v", err)
+			assert.Equal(t, tc.linePos, posErr.linePos, "line position: %v", err)
+		})
+	}
+}
+
+func TestParser_ReadPredicate_Matches(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		input         string
+		binary        []byte
+		expectedMatch bool
+	}{
+		{"eq", "VALUE(0, U64LE) = U64(100)", makeBytes(t, u64le(100)), true},
+		{"neq", "VALUE(0, U64LE) = U64(100)", makeBytes(t, u64le(101)), false},
+		{"all", "(VALUE(0, U32LE) < U32(10)) AND (VALUE(4, U8) >= VALUE(0, U32LE))", makeBytes(t, u32le(5), u8(5)), true},
+		{"all-fails", "(VALUE(0, U32LE) < U32(10)) AND (VALUE(4, U8) > VALUE(0, U32LE))", makeBytes(t, u32le(5), u8(5)), false},
+		{"any", "(VALUE(0, U32LE) > U32(10)) OR (VALUE(4, U8) = U32(5))", makeBytes(t, u32le(5), u8(5)), true},
+		{"signed-less", "VALUE(0, I64LE) < U32(5)", makeBytes(t, i64le(-1)), true},
+		{"signed-byte", "VALUE(0, I8) < VALUE(1, U8)", makeBytes(t, i8(-1), u8(255)), true},
+		{"signed-big-endian", "VALUE(0, I32BE) = I32(-70000)", makeBytes(t, i32be(-70000)), true},
+		{"signed-not-less", "VALUE(0, I16LE) < I64(-300)", makeBytes(t, i16le(-200)), false},
+		{"float-greater", "VALUE(0, F64LE) > U32(10)", makeBytes(t, f64le(10.5)), true},
+		{"float-not-greater", "VALUE(0, F64LE) > U32(10)", makeBytes(t, f64le(9.5)), false},
+		{"float-big-endian", "VALUE(0, F32BE) = F64(0.25)", makeBytes(t, f32be(0.25)), true},
+		{"float-negative", "VALUE(0, F64BE) < I32(-1)", makeBytes(t, f64be(-1.5)), true},
+		{"nan-eq", "VALUE(0, F64LE) = VALUE(0, F64LE)", makeBytes(t, f64le(math.NaN())), false},
+		{"nan-neq", "VALUE(0, F64LE) != VALUE(0, F64LE)", makeBytes(t, f64le(math.NaN())), true},
+		{"nan-less", "VALUE(0, F32LE) < F32(1)", makeBytes(t, f32le(math.NaN())), false},
+		{"nan-greater-eq", "VALUE(0, F32LE) >= F32(1)", makeBytes(t, f32le(math.NaN())), false},
+		{"bytes-eq", `VALUE(0, BYTES(3)) = "ABC"`, makeBytes(t, "ABCD"), true},
+		{"bytes-neq", `VALUE(1, BYTES(3)) = "ABC"`, makeBytes(t, "ABCD"), false},
+		{"bytes-less", `VALUE(0, BYTES(2)) < "AC"`, makeBytes(t, "ABCD"), true},
+		{"bytes-prefix-less", `VALUE(0, BYTES(U8)) < "ABC"`, makeBytes(t, u8(2), "AB"), true},
+		{"bytes-u16be", `VALUE(0, BYTES(U16BE)) = x"0102"`, makeBytes(t, u16be(2), []byte{1, 2}), true},
+		{"bytes-u32le", `VALUE(0, BYTES(U32LE)) > "ab"`, makeBytes(t, u32le(3), "abc"), true},
+		{"bytes-values", `VALUE(0, BYTES(2)) = VALUE(2, BYTES(U8))`, makeBytes(t, "xy", u8(2), "xy"), true},
+		{"flag-set", "VALUE(4, U32LE) & U32(0x8000) != U32(0)", makeBytes(t, u32le(0), u32le(0x8001)), true},
+		{"flag-unset", "VALUE(4, U32LE) & U32(0x8000) != U32(0)", makeBytes(t, u32le(0), u32le(0x7fff)), false},
+		{"duration", "VALUE(0, U64LE) - VALUE(8, U64LE) > U64(60)", makeBytes(t, u64le(1000), u64le(900)), true},
+		{"duration-short", "VALUE(0, U64LE) - VALUE(8, U64LE) > U64(60)", makeBytes(t, u64le(1000), u64le(950)), false},
+		{"shift-mod", "VALUE(0, U8) >> U32(4) % U32(3) = U32(2)", makeBytes(t, u8(0x20)), true},
+		{"float-arithmetic", "VALUE(0, F64LE) * F64(2) / F64(4) = F64(1.25)", makeBytes(t, f64le(2.5)), true},
+		{"jumped", "VALUE(JUMP(0, U8), U16BE) = U32(7)", makeBytes(t, u8(1), u16be(7)), true},
+		{"and-before-or", "VALUE(0, U8) = U32(1) OR VALUE(0, U8) = U32(2) AND VALUE(1, U8) = U32(0)", makeBytes(t, u8(1), u8(1)), true},
+		{"and-before-or-fails", "VALUE(0, U8) = U32(1) OR VALUE(0, U8) = U32(2) AND VALUE(1, U8) = U32(0)", makeBytes(t, u8(2), u8(1)), false},
+		{"not-before-and", "NOT VALUE(0, U8) = U32(1) AND VALUE(1, U8) = U32(1)", makeBytes(t, u8(2), u8(1)), true},
+		{"not", "NOT (VALUE(0, U8) = U32(1))", makeBytes(t, u8(2)), true},
+		{"not-fails", "NOT (VALUE(0, U8) = U32(1))", makeBytes(t, u8(1)), false},
+		{"nested", "((VALUE(0, U8) = U32(1)) OR (VALUE(0, U8) = U32(2))) AND NOT (VALUE(1, U8) = U32(0))", makeBytes(t, u8(2), u8(1)), true},
+		{"nested-fails", "((VALUE(0, U8) = U32(1)) OR (VALUE(0, U8) = U32(2))) AND NOT (VALUE(1, U8) = U32(0))", makeBytes(t, u8(3), u8(1)), false},
+		{"short-circuit-or", "NOT (true OR (VALUE(0, U64LE) / VALUE(8, U64LE) = U64(1)))", makeBytes(t, u64le(1), u64le(0)), false},
+		{"short-circuit-and", "(false AND (VALUE(100, U64LE) = U64(1))) = false", makeBytes(t, u64le(1)), true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			predicate, err := NewParser(tc.input).ReadPredicate()
+			if !assert.NoError(t, err) {
+				return
+			}
+			matcher, err := PredicateToMatcher(predicate)
+			if !assert.NoError(t, err) {
+				return
+			}
+			matches, err := matcher.Match(tc.binary)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedMatch, matches)
+		})
+	}
+}
+
+// BenchmarkParser_ReadPredicate_Chain reads long chains of additions, which take time
+// linear in the number of terms as each operator is type checked from its operands' types.
+func BenchmarkParser_ReadPredicate_Chain(b *testing.B) {
+	for _, terms := range []int{1000, 2000, 4000} {
+		input := strings.Repeat("VALUE(0, U32LE) + ", terms-1) + "VALUE(0, U32LE) = U32(0)"
+		b.Run(strconv.Itoa(terms), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewParser(input).ReadPredicate(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -34,6 +34,18 @@ func makeScalarExpression(t TestType, value interface{}) *Expression {
 		scalar.Value = &Scalar_U64{U64: uint64(vt)}
 	case u64be:
 		scalar.Value = &Scalar_U64{U64: uint64(vt)}
+	case int32:
+		scalar.Value = &Scalar_I32{I32: vt}
+	case int64:
+		scalar.Value = &Scalar_I64{I64: vt}
+	case float32:
+		scalar.Value = &Scalar_F32{F32: vt}
+	case float64:
+		scalar.Value = &Scalar_F64{F64: vt}
+	case []byte:
+		scalar.Value = &Scalar_Bytes{Bytes: vt}
+	case string:
+		scalar.Value = &Scalar_Bytes{Bytes: []byte(vt)}
 	default:
 		t.Fatal(unhandledType("scalar value", vt))
 		return nil
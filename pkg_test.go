@@ -2,6 +2,7 @@ package binq
 
 import (
 	"encoding/binary"
+	"math"
 	"github.com/pkg/errors"
 )
 
@@ -12,6 +13,17 @@ type u32be uint32
 type u16le uint16
 type u16be uint16
 type u8 uint8
+type i64le int64
+type i64be int64
+type i32le int32
+type i32be int32
+type i16le int16
+type i16be int16
+type i8 int8
+type f64le float64
+type f64be float64
+type f32le float32
+type f32be float32
 
 var uintTypes = []ReturnType{
 	ReturnType_RETURN_TYPE_BOOL,
@@ -21,6 +33,31 @@ var uintTypes = []ReturnType{
 	ReturnType_RETURN_TYPE_U64,
 }
 
+var floatTypes = []ReturnType{
+	ReturnType_RETURN_TYPE_BOOL,
+	ReturnType_RETURN_TYPE_U8,
+	ReturnType_RETURN_TYPE_U16,
+	ReturnType_RETURN_TYPE_U32,
+	ReturnType_RETURN_TYPE_U64,
+	ReturnType_RETURN_TYPE_I8,
+	ReturnType_RETURN_TYPE_I16,
+	ReturnType_RETURN_TYPE_I32,
+	ReturnType_RETURN_TYPE_I64,
+	ReturnType_RETURN_TYPE_F32,
+	ReturnType_RETURN_TYPE_F64,
+}
+
+var intTypes = []ReturnType{
+	ReturnType_RETURN_TYPE_BOOL,
+	ReturnType_RETURN_TYPE_U8,
+	ReturnType_RETURN_TYPE_U16,
+	ReturnType_RETURN_TYPE_U32,
+	ReturnType_RETURN_TYPE_I8,
+	ReturnType_RETURN_TYPE_I16,
+	ReturnType_RETURN_TYPE_I32,
+	ReturnType_RETURN_TYPE_I64,
+}
+
 // TestType is an interface for benchmarks are unit tests.
 type TestType interface {
 	Helper()
@@ -99,6 +136,48 @@ func makeBytes(t TestType, objs ...interface{}) []byte {
 			b = []byte{byte(val)}
 		case uint8:
 			b = []byte{byte(val)}
+		case i64le:
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, uint64(val))
+			b = buf
+		case i64be:
+			buf := make([]byte, 8)
+			binary.BigEndian.PutUint64(buf, uint64(val))
+			b = buf
+		case i32le:
+			buf := make([]byte, 4)
+			binary.LittleEndian.PutUint32(buf, uint32(val))
+			b = buf
+		case i32be:
+			buf := make([]byte, 4)
+			binary.BigEndian.PutUint32(buf, uint32(val))
+			b = buf
+		case i16le:
+			buf := make([]byte, 2)
+			binary.LittleEndian.PutUint16(buf, uint16(val))
+			b = buf
+		case i16be:
+			buf := make([]byte, 2)
+			binary.BigEndian.PutUint16(buf, uint16(val))
+			b = buf
+		case i8:
+			b = []byte{byte(val)}
+		case f64le:
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, math.Float64bits(float64(val)))
+			b = buf
+		case f64be:
+			buf := make([]byte, 8)
+			binary.BigEndian.PutUint64(buf, math.Float64bits(float64(val)))
+			b = buf
+		case f32le:
+			buf := make([]byte, 4)
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(val)))
+			b = buf
+		case f32be:
+			buf := make([]byte, 4)
+			binary.BigEndian.PutUint32(buf, math.Float32bits(float32(val)))
+			b = buf
 		default:
 			t.Fatal(errors.Errorf("cannot serialize bytes of %T", val))
 		}
@@ -10,6 +10,30 @@ var uintValueTypes = []ValueType{
 	ValueType_VALUE_TYPE_U64BE,
 }
 
+var floatValueTypes = []ValueType{
+	ValueType_VALUE_TYPE_U8,
+	ValueType_VALUE_TYPE_U32LE,
+	ValueType_VALUE_TYPE_I16LE,
+	ValueType_VALUE_TYPE_I64BE,
+	ValueType_VALUE_TYPE_F32LE,
+	ValueType_VALUE_TYPE_F32BE,
+	ValueType_VALUE_TYPE_F64LE,
+	ValueType_VALUE_TYPE_F64BE,
+}
+
+var intValueTypes = []ValueType{
+	ValueType_VALUE_TYPE_U8,
+	ValueType_VALUE_TYPE_U16LE,
+	ValueType_VALUE_TYPE_U32BE,
+	ValueType_VALUE_TYPE_I8,
+	ValueType_VALUE_TYPE_I16LE,
+	ValueType_VALUE_TYPE_I16BE,
+	ValueType_VALUE_TYPE_I32LE,
+	ValueType_VALUE_TYPE_I32BE,
+	ValueType_VALUE_TYPE_I64LE,
+	ValueType_VALUE_TYPE_I64BE,
+}
+
 func makeValueTypeValue(t TestType, valueType ValueType) interface{} {
 	t.Helper()
 	switch valueType {
@@ -30,6 +54,28 @@ func makeValueTypeValue(t TestType, valueType ValueType) interface{} {
 		return u64le(0)
 	case ValueType_VALUE_TYPE_U64BE:
 		return u64be(0)
+	case ValueType_VALUE_TYPE_I8:
+		return i8(0)
+	case ValueType_VALUE_TYPE_I16LE:
+		return i16le(0)
+	case ValueType_VALUE_TYPE_I16BE:
+		return i16be(0)
+	case ValueType_VALUE_TYPE_I32LE:
+		return i32le(0)
+	case ValueType_VALUE_TYPE_I32BE:
+		return i32be(0)
+	case ValueType_VALUE_TYPE_I64LE:
+		return i64le(0)
+	case ValueType_VALUE_TYPE_I64BE:
+		return i64be(0)
+	case ValueType_VALUE_TYPE_F32LE:
+		return f32le(0)
+	case ValueType_VALUE_TYPE_F32BE:
+		return f32be(0)
+	case ValueType_VALUE_TYPE_F64LE:
+		return f64le(0)
+	case ValueType_VALUE_TYPE_F64BE:
+		return f64be(0)
 	default:
 		t.Fatal(unhandledEnum("value type", valueType))
 		return nil
@@ -52,6 +98,18 @@ func makeReturnTypeValue(t TestType, returnType ReturnType) interface{} {
 		return uint32(0)
 	case ReturnType_RETURN_TYPE_U64:
 		return uint64(0)
+	case ReturnType_RETURN_TYPE_I8:
+		return int8(0)
+	case ReturnType_RETURN_TYPE_I16:
+		return int16(0)
+	case ReturnType_RETURN_TYPE_I32:
+		return int32(0)
+	case ReturnType_RETURN_TYPE_I64:
+		return int64(0)
+	case ReturnType_RETURN_TYPE_F32:
+		return float32(0)
+	case ReturnType_RETURN_TYPE_F64:
+		return float64(0)
 	default:
 		t.Fatal(unhandledEnum("return type", returnType))
 		return nil
@@ -1,6 +1,6 @@
 package binq
 
-import "errors"
+import "github.com/pkg/errors"
 
 var (
 	booleanOps = map[BinaryOpCode]struct{}{
@@ -11,205 +11,190 @@ var (
 		BinaryOpCode_BINARY_OP_CODE_GREATER:    {},
 		BinaryOpCode_BINARY_OP_CODE_GREATER_EQ: {},
 	}
+	logicalOps = map[BinaryOpCode]struct{}{
+		BinaryOpCode_BINARY_OP_CODE_AND: {},
+		BinaryOpCode_BINARY_OP_CODE_OR:  {},
+	}
 )
 
-func PredicateToMatcher(pred *Predicate) (Matcher, error) {
+// MatcherOption configures the conversion of a Predicate into a matcher.
+type MatcherOption func(*matcherOptions)
+
+type matcherOptions struct {
+	optimize      bool
+	adaptiveOrder bool
+}
+
+// WithOptimize simplifies predicates with Optimize before converting them.
+func WithOptimize() MatcherOption {
+	return func(options *matcherOptions) {
+		options.optimize = true
+	}
+}
+
+// WithAdaptiveOrder evaluates the expressions of an All or Any with an AdaptiveRecordMatcher, ordered
+// by ExpressionCost and then by their cost and selectivity while matching. Chains of ANDs within an
+// All and of ORs within an Any are ordered by each of their operands. The matcher is not safe
+// for concurrent use, and which record fails may differ from evaluating them in order.
+func WithAdaptiveOrder() MatcherOption {
+	return func(options *matcherOptions) {
+		options.adaptiveOrder = true
+	}
+}
+
+// PredicateToMatcher converts a Predicate into a Matcher over the value of records.
+// Predicates that read from record keys require PredicateToRecordMatcher.
+func PredicateToMatcher(pred *Predicate, opts ...MatcherOption) (Matcher, error) {
+	if predicateReadsKey(pred) {
+		return nil, errors.New("predicate reads record keys, use a record matcher")
+	}
+	matcher, err := PredicateToRecordMatcher(pred, opts...)
+	if err != nil {
+		// nowrap: same conversion
+		return nil, err
+	}
+	return MatcherFunc(func(b []byte) (bool, error) {
+		return matcher.MatchRecord(nil, b)
+	}), nil
+}
+
+// PredicateToRecordMatcher converts a Predicate into a RecordMatcher over the key and value of records.
+// Type errors are reported as CheckErrors.
+func PredicateToRecordMatcher(pred *Predicate, opts ...MatcherOption) (RecordMatcher, error) {
+	var options matcherOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if errs := Check(pred); len(errs) > 0 {
+		return nil, errs
+	}
+	if options.optimize {
+		optimized, _, err := Optimize(pred)
+		if err != nil {
+			return nil, wrap(err, "unable to optimize predicate")
+		}
+		pred = optimized
+	}
 	switch t := pred.GetPredicate().(type) {
 	case *Predicate_Expression:
-		matcher, err := expressionToMatcher(t.Expression)
+		matcher, err := compileMatcher(t.Expression)
 		if err != nil {
 			return nil, wrap(err, "unable to convert expression to matcher")
 		}
 		return matcher, nil
 	case *Predicate_Any:
-		matchers, err := expressionsToMatchers(t.Any.Expressions)
+		exs := t.Any.Expressions
+		if options.adaptiveOrder {
+			exs = chainedExpressions(exs, BinaryOpCode_BINARY_OP_CODE_OR)
+		}
+		matchers, err := expressionsToMatchers(exs)
 		if err != nil {
 			return nil, wrap(err, "unable to convert expressions to matchers")
 		}
-		return Any(matchers...), nil
+		if options.adaptiveOrder {
+			return AdaptiveAnyRecords(expressionCosts(exs), matchers...), nil
+		}
+		return AnyRecords(matchers...), nil
 	case *Predicate_All:
-		matchers, err := expressionsToMatchers(t.All.Expressions)
+		exs := t.All.Expressions
+		if options.adaptiveOrder {
+			exs = chainedExpressions(exs, BinaryOpCode_BINARY_OP_CODE_AND)
+		}
+		matchers, err := expressionsToMatchers(exs)
 		if err != nil {
 			return nil, wrap(err, "unable to convert expressions to matchers")
 		}
-		return All(matchers...), nil
+		if options.adaptiveOrder {
+			return AdaptiveAllRecords(expressionCosts(exs), matchers...), nil
+		}
+		return AllRecords(matchers...), nil
 	default:
 		return nil, unhandledType("predicate type", t)
 	}
 }
-func expressionsToMatchers(exs []*Expression) ([]Matcher, error) {
-	matchers := make([]Matcher, len(exs))
-	for index, ex := range exs {
-		matcher, err := expressionToMatcher(ex)
-		if err != nil {
-			return nil, wrap(err, "unable to sub-expression to matcher")
-		}
-		matchers[index] = matcher
+
+// predicateReadsKey determines if any Value in a Predicate targets record keys.
+func predicateReadsKey(pred *Predicate) bool {
+	switch t := pred.GetPredicate().(type) {
+	case *Predicate_Expression:
+		return expressionReadsKey(t.Expression)
+	case *Predicate_Any:
+		return expressionsReadKey(t.Any.GetExpressions())
+	case *Predicate_All:
+		return expressionsReadKey(t.All.GetExpressions())
+	default:
+		return false
 	}
-	return matchers, nil
 }
 
-func expressionToMatcher(ex *Expression) (Matcher, error) {
-	evaluator, returnType, err := expressionToEvaluator(ex)
-	if err != nil {
-		return nil, wrap(err, "invalid expression")
-	}
-	if returnType != ReturnType_RETURN_TYPE_BOOL {
-		return nil, errors.New("expression is not a boolean expression")
-	}
-	matcher := MatcherFunc(func(b []byte) (bool, error) {
-		value, _, err := evaluator.Evaluate(b)
-		if err != nil {
-			return false, wrap(err, "error evaluating expression")
+func expressionsReadKey(exs []*Expression) bool {
+	for _, ex := range exs {
+		if expressionReadsKey(ex) {
+			return true
 		}
-		return value.(bool), nil
-	})
-	return matcher, nil
+	}
+	return false
 }
 
-func expressionToEvaluator(ex *Expression) (Evaluator, ReturnType, error) {
+func expressionReadsKey(ex *Expression) bool {
 	switch t := ex.GetExpression().(type) {
-	case *Expression_Scalar:
-		evaluator, returnType, err := scalarToEvaluator(t.Scalar)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to convert scalar to evaluator")
-		}
-		return evaluator, returnType, nil
 	case *Expression_Value:
-		evaluator, returnType, err := valueToEvaluator(t.Value)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to convert value to evaluator")
-		}
-		return evaluator, returnType, nil
+		return t.Value.Target == Target_TARGET_KEY
 	case *Expression_BinaryOperation:
-		evaluator, returnType, err := binaryOperationEvaluator(t.BinaryOperation)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to convert value to evaluator")
-		}
-		return evaluator, returnType, nil
+		return expressionReadsKey(t.BinaryOperation.Left) || expressionReadsKey(t.BinaryOperation.Right)
+	case *Expression_UnaryOperation:
+		return expressionReadsKey(t.UnaryOperation.Operand)
 	default:
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, unhandledType("expression type", t)
-	}
-}
-
-func binaryOperationEvaluator(op *BinaryOperation) (EvaluatorFunc, ReturnType, error) {
-	leftEvaluator, leftType, err := expressionToEvaluator(op.Left)
-	if err != nil {
-		// nowrap: recursive call
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, err
-	}
-	rightEvaluator, rightType, err := expressionToEvaluator(op.Right)
-	if err != nil {
-		// nowrap: recursive call
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, err
-	}
-	upscaleLeft, upscaleRight, upscaledType, err := getUpscaler(leftType, rightType)
-	if err != nil {
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "invalid expression")
+		return false
 	}
-	opCode := op.BinaryOpCode
-	returnType := getReturnType(upscaledType, opCode)
-	evaluator := EvaluatorFunc(func(b []byte) (interface{}, ReturnType, error) {
-		leftValue, _, err := leftEvaluator.Evaluate(b)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to evaluate left hand expression")
-		}
-		rightValue, _, err := rightEvaluator.Evaluate(b)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to evaluate right hand expression")
-		}
-		leftValue = upscaleLeft(leftValue)
-		rightValue = upscaleRight(rightValue)
-		value, err := performBinaryOperation(upscaledType, leftValue, rightValue, opCode)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to evaluate binary expression")
-		}
-		return value, returnType, nil
-	})
-	return evaluator, returnType, nil
 }
 
-func getReturnType(returnType ReturnType, code BinaryOpCode) ReturnType {
-	if _, isBinaryOp := booleanOps[code]; isBinaryOp {
-		return ReturnType_RETURN_TYPE_BOOL
+// chainedExpressions returns the operands of the chains of one logical operation in expressions,
+// such as those of an AND within an All, so that each of them is ordered separately.
+func chainedExpressions(exs []*Expression, opCode BinaryOpCode) []*Expression {
+	var chained []*Expression
+	for _, ex := range exs {
+		chained = append(chained, chainExpressions(ex, opCode)...)
 	}
-	return returnType
-}
-
-type scalarEvaluatorImpl struct {
-	val        interface{}
-	returnType ReturnType
-}
-
-func (s scalarEvaluatorImpl) evaluate([]byte) (interface{}, ReturnType, error) {
-	return s.val, s.returnType, nil
+	return chained
 }
 
-func scalarToEvaluator(s *Scalar) (EvaluatorFunc, ReturnType, error) {
-	var eval scalarEvaluatorImpl
-	switch t := s.Value.(type) {
-	case *Scalar_Bool:
-		eval = scalarEvaluatorImpl{val: t.Bool, returnType: ReturnType_RETURN_TYPE_BOOL}
-	case *Scalar_U32:
-		eval = scalarEvaluatorImpl{val: t.U32, returnType: ReturnType_RETURN_TYPE_U32}
-	case *Scalar_U64:
-		eval = scalarEvaluatorImpl{val: t.U64, returnType: ReturnType_RETURN_TYPE_U64}
-	default:
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, unhandledType("scalar type", t)
+// chainExpressions returns the operands of a chain of one logical operation.
+func chainExpressions(ex *Expression, opCode BinaryOpCode) []*Expression {
+	operands := chainOperands("", ex, opCode)
+	exs := make([]*Expression, len(operands))
+	for index, op := range operands {
+		exs[index] = op.ex
 	}
-	return eval.evaluate, eval.returnType, nil
+	return exs
 }
 
-type getterFunc func([]byte) (interface{}, error)
-
-type valueEvaluatorImpl struct {
-	getter     getterFunc
-	returnType ReturnType
-}
-
-func valueToEvaluator(v *Value) (EvaluatorFunc, ReturnType, error) {
-	jumper, err := jumpToJumper(v.Jump)
-	if err != nil {
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "invalid value jump")
-	}
-	var eval valueEvaluatorImpl
-	switch v.Type {
-	case ValueType_VALUE_TYPE_U64LE:
-		eval = valueEvaluatorImpl{getter: GetU64le, returnType: ReturnType_RETURN_TYPE_U64}
-	case ValueType_VALUE_TYPE_U64BE:
-		eval = valueEvaluatorImpl{getter: GetU64be, returnType: ReturnType_RETURN_TYPE_U64}
-	case ValueType_VALUE_TYPE_U32LE:
-		eval = valueEvaluatorImpl{getter: GetU32le, returnType: ReturnType_RETURN_TYPE_U32}
-	case ValueType_VALUE_TYPE_U32BE:
-		eval = valueEvaluatorImpl{getter: GetU32be, returnType: ReturnType_RETURN_TYPE_U32}
-	case ValueType_VALUE_TYPE_U16LE:
-		eval = valueEvaluatorImpl{getter: GetU16le, returnType: ReturnType_RETURN_TYPE_U16}
-	case ValueType_VALUE_TYPE_U16BE:
-		eval = valueEvaluatorImpl{getter: GetU16be, returnType: ReturnType_RETURN_TYPE_U16}
-	case ValueType_VALUE_TYPE_U8:
-		eval = valueEvaluatorImpl{getter: GetU8, returnType: ReturnType_RETURN_TYPE_U8}
-	default:
-		return nil, ReturnType_RETURN_TYPE_UNKNOWN, unhandledEnum("value type", v.Type)
+func expressionCosts(exs []*Expression) []float64 {
+	costs := make([]float64, len(exs))
+	for index, ex := range exs {
+		costs[index] = ExpressionCost(ex)
 	}
-	evaluator := valueEvaluatorImplWithJump(jumper, eval)
-	return evaluator, eval.returnType, nil
+	return costs
 }
 
-// valueEvaluatorImplWithJump creates an EvaluatorFunc for data at position that is jumped to.
-func valueEvaluatorImplWithJump(jumper Jumper, value valueEvaluatorImpl) EvaluatorFunc {
-	return func(bytes []byte) (interface{}, ReturnType, error) {
-		jumped, err := jumper.Jump(bytes)
-		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to jump")
-		}
-		gotValue, err := value.getter(jumped)
+func expressionsToMatchers(exs []*Expression) ([]RecordMatcher, error) {
+	matchers := make([]RecordMatcher, len(exs))
+	for index, ex := range exs {
+		matcher, err := compileMatcher(ex)
 		if err != nil {
-			return nil, ReturnType_RETURN_TYPE_UNKNOWN, wrap(err, "unable to run matcher")
+			return nil, wrap(err, "unable to sub-expression to matcher")
 		}
-		return gotValue, value.returnType, nil
+		matchers[index] = matcher
 	}
+	return matchers, nil
 }
 
+// targetSelector selects the data of a record that a Value reads from.
+type targetSelector func(key, value []byte) []byte
+
+func selectKey(key, _ []byte) []byte     { return key }
+func selectValue(_, value []byte) []byte { return value }
+
 func jumpToJumper(j *Jump) (Jumper, error) {
 	var jumper Jumper
 	switch t := j.Jump.(type) {
@@ -114,6 +114,96 @@ func TestPredicateToMatch_BooleanBinaryOperationsOnUintTypes(t *testing.T) {
 	}
 }
 
+func TestPredicateToMatch_BooleanBinaryOperationsOnIntTypes(t *testing.T) {
+	t.Parallel()
+	for _, aType := range intValueTypes {
+		aType := aType
+		t.Run(aType.String(), func(t *testing.T) {
+			t.Parallel()
+			for _, bType := range intValueTypes {
+				bType := bType
+				t.Run(bType.String(), func(t *testing.T) {
+					t.Parallel()
+					for op := range booleanOps {
+						op := op
+						t.Run(op.String(), func(t *testing.T) {
+							t.Parallel()
+							aBytes := makeBytes(t, makeValueTypeValue(t, aType))
+							bBytes := makeBytes(t, makeValueTypeValue(t, bType))
+							bytes := makeBytes(t, aBytes, bBytes)
+							predicate := &Predicate{
+								Predicate: &Predicate_Expression{
+									Expression: &Expression{
+										Expression: &Expression_BinaryOperation{
+											BinaryOperation: &BinaryOperation{
+												Left:         makeValueExpression(aType, 0),
+												Right:        makeValueExpression(bType, uint64(len(aBytes))),
+												BinaryOpCode: op,
+											},
+										},
+									},
+								},
+							}
+
+							matcher, err := PredicateToMatcher(predicate)
+							if !assert.NoError(t, err) {
+								return
+							}
+							_, err = matcher.Match(bytes)
+							assert.NoError(t, err)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestPredicateToMatch_BooleanBinaryOperationsOnFloatTypes(t *testing.T) {
+	t.Parallel()
+	for _, aType := range floatValueTypes {
+		aType := aType
+		t.Run(aType.String(), func(t *testing.T) {
+			t.Parallel()
+			for _, bType := range floatValueTypes {
+				bType := bType
+				t.Run(bType.String(), func(t *testing.T) {
+					t.Parallel()
+					for op := range booleanOps {
+						op := op
+						t.Run(op.String(), func(t *testing.T) {
+							t.Parallel()
+							aBytes := makeBytes(t, makeValueTypeValue(t, aType))
+							bBytes := makeBytes(t, makeValueTypeValue(t, bType))
+							bytes := makeBytes(t, aBytes, bBytes)
+							predicate := &Predicate{
+								Predicate: &Predicate_Expression{
+									Expression: &Expression{
+										Expression: &Expression_BinaryOperation{
+											BinaryOperation: &BinaryOperation{
+												Left:         makeValueExpression(aType, 0),
+												Right:        makeValueExpression(bType, uint64(len(aBytes))),
+												BinaryOpCode: op,
+											},
+										},
+									},
+								},
+							}
+
+							matcher, err := PredicateToMatcher(predicate)
+							if !assert.NoError(t, err) {
+								return
+							}
+							_, err = matcher.Match(bytes)
+							assert.NoError(t, err)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
 func TestPredicateToMatch_BooleanBinaryOperationsOnScalarTypes(t *testing.T) {
 	scalars := []struct {
 		name       string
@@ -217,3 +307,68 @@ func TestPredicateToMatch_NonBoolean(t *testing.T) {
 	_, err := PredicateToMatcher(predicate)
 	assert.Error(t, err)
 }
+
+func TestPredicateToRecordMatcher_Targets(t *testing.T) {
+	t.Parallel()
+	keyU32 := makeValueExpression(ValueType_VALUE_TYPE_U32LE, 0)
+	keyU32.GetValue().Target = Target_TARGET_KEY
+	valueU32 := makeValueExpression(ValueType_VALUE_TYPE_U32LE, 0)
+
+	predicate := &Predicate{
+		Predicate: &Predicate_Expression{
+			Expression: makeBinaryOperationExpression(keyU32, BinaryOpCode_BINARY_OP_CODE_LESS, valueU32),
+		},
+	}
+
+	cases := []struct {
+		name          string
+		key           []byte
+		value         []byte
+		expectedMatch bool
+		expectedErr   bool
+	}{
+		{"less", makeBytes(t, u32le(1)), makeBytes(t, u32le(2)), true, false},
+		{"equal", makeBytes(t, u32le(2)), makeBytes(t, u32le(2)), false, false},
+		{"greater", makeBytes(t, u32le(3)), makeBytes(t, u32le(2)), false, false},
+		{"missing-key", nil, makeBytes(t, u32le(2)), false, true},
+		{"missing-value", makeBytes(t, u32le(1)), nil, false, true},
+	}
+
+	matcher, err := PredicateToRecordMatcher(predicate)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := matcher.MatchRecord(tc.key, tc.value)
+			assert.Equal(t, tc.expectedErr, err != nil, "(un)expected error: %s", err)
+			assert.Equal(t, tc.expectedMatch, matches, "(un)expected match")
+		})
+	}
+
+	// Value only matchers cannot read keys.
+	_, err = PredicateToMatcher(predicate)
+	assert.Error(t, err)
+}
+
+func TestPredicateToMatch_LogicalNonBoolean(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		ex   *Expression
+	}{
+		{"and", makeBinaryOperationExpression(makeScalarExpression(t, true), BinaryOpCode_BINARY_OP_CODE_AND, makeScalarExpression(t, uint64(1)))},
+		{"or", makeBinaryOperationExpression(makeScalarExpression(t, uint32(1)), BinaryOpCode_BINARY_OP_CODE_OR, makeScalarExpression(t, false))},
+		{"not", makeUnaryOperationExpression(makeScalarExpression(t, uint64(0)))},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := PredicateToMatcher(&Predicate{Predicate: &Predicate_Expression{Expression: tc.ex}})
+			assert.Error(t, err)
+		})
+	}
+}
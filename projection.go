@@ -0,0 +1,160 @@
+package binq
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// aliasKeyword separates a field of a projection from its alias in text.
+const aliasKeyword = "AS"
+
+// isIdentifier reports if s is a valid alias: a letter or underscore followed by letters,
+// digits or underscores.
+func isIdentifier(s string) bool {
+	for index, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (index == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// CheckProjection reports every type error of a projection like Check, each with the path
+// to the offending node, and aliases that are invalid or used more than once.
+// Returns nil if the projection is well typed.
+func CheckProjection(projection []*Projection) CheckErrors {
+	c := &checker{}
+	c.checkProjection("projection", projection, make(map[string]bool, len(projection)))
+	return c.errs
+}
+
+// checkProjection checks the fields of a projection at path, adding their aliases to aliases.
+func (c *checker) checkProjection(path string, projection []*Projection, aliases map[string]bool) {
+	for index, field := range projection {
+		fieldPath := fmt.Sprintf("%s[%d]", path, index)
+		if field.GetValue() == nil {
+			c.errorf(fieldPath, "missing value")
+		} else {
+			c.checkValue(joinPath(fieldPath, "value"), field.GetValue())
+		}
+		c.checkAlias(joinPath(fieldPath, "alias"), field.GetAlias(), aliases)
+	}
+}
+
+// checkAlias checks that an alias, if any, is valid and not in aliases, then adds it to aliases.
+func (c *checker) checkAlias(path string, alias string, aliases map[string]bool) {
+	switch {
+	case alias == "":
+	case !isIdentifier(alias):
+		c.errorf(path, "invalid alias %q", alias)
+	case aliases[alias]:
+		c.errorf(path, "duplicate alias %q", alias)
+	default:
+		aliases[alias] = true
+	}
+}
+
+// Projector extracts the fields selected by a projection from records.
+type Projector struct {
+	// names are the names of the fields.
+	names []string
+	// fields are the compiled values of the fields.
+	fields []compiled
+}
+
+// NewProjector creates a Projector for a projection, after checking it with CheckProjection.
+func NewProjector(projection []*Projection) (*Projector, error) {
+	if errs := CheckProjection(projection); len(errs) > 0 {
+		return nil, errs
+	}
+	return newProjector(projection)
+}
+
+// newProjector creates a Projector for a projection that is well typed.
+func newProjector(projection []*Projection) (*Projector, error) {
+	p := &Projector{
+		names:  make([]string, len(projection)),
+		fields: make([]compiled, len(projection)),
+	}
+	for index, field := range projection {
+		name := field.GetAlias()
+		if name == "" {
+			text, err := formatValue(field.GetValue())
+			if err != nil {
+				return nil, wrap(err, "unable to format field")
+			}
+			name = text
+		}
+		c, err := compileValue(field.GetValue())
+		if err != nil {
+			return nil, wrap(err, "unable to compile field")
+		}
+		p.names[index] = name
+		p.fields[index] = c
+	}
+	return p, nil
+}
+
+// Names returns the names of the fields, which are their aliases, or their filter text
+// if they have none.
+func (p *Projector) Names() []string {
+	return p.names
+}
+
+// Project extracts the fields of a record. Integers narrower than 32 bits are widened
+// to U32 or I32, and bytes are copied.
+func (p *Projector) Project(key, value []byte) ([]*Scalar, error) {
+	scalars := make([]*Scalar, len(p.fields))
+	for index := range p.fields {
+		scalar, err := fieldToScalar(&p.fields[index], key, value)
+		if err != nil {
+			return nil, wrap(err, fmt.Sprintf("unable to get field %s", p.names[index]))
+		}
+		scalars[index] = scalar
+	}
+	return scalars, nil
+}
+
+// fieldToScalar reads the value of a compiled field of a record into a Scalar.
+func fieldToScalar(field *compiled, key, value []byte) (*Scalar, error) {
+	switch {
+	case isUnsignedType(field.returnType):
+		v, err := field.uintFn(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if typeBits(field.returnType) <= 32 {
+			return &Scalar{Value: &Scalar_U32{U32: uint32(v)}}, nil
+		}
+		return &Scalar{Value: &Scalar_U64{U64: v}}, nil
+	case isSignedType(field.returnType):
+		v, err := field.intFn(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if typeBits(field.returnType) <= 32 {
+			return &Scalar{Value: &Scalar_I32{I32: int32(v)}}, nil
+		}
+		return &Scalar{Value: &Scalar_I64{I64: v}}, nil
+	case field.returnType == ReturnType_RETURN_TYPE_F32:
+		v, err := field.floatFn(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return &Scalar{Value: &Scalar_F32{F32: float32(v)}}, nil
+	case field.returnType == ReturnType_RETURN_TYPE_F64:
+		v, err := field.floatFn(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return &Scalar{Value: &Scalar_F64{F64: v}}, nil
+	case field.returnType == ReturnType_RETURN_TYPE_BYTES:
+		v, err := field.bytesFn(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return &Scalar{Value: &Scalar_Bytes{Bytes: append([]byte(nil), v...)}}, nil
+	default:
+		return nil, unhandledEnum("field type", field.returnType)
+	}
+}
@@ -0,0 +1,148 @@
+package binq
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_ReadProjection(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		names    []string
+	}{
+		{"single", "VALUE(0, U32LE)", "VALUE(0, U32LE)", []string{"VALUE(0, U32LE)"}},
+		{"alias", "VALUE(0, U32LE) AS id", "VALUE(0, U32LE) AS id", []string{"id"}},
+		{"lowercase-as", "value(0, u8) as flag_1", "VALUE(0, U8) AS flag_1", []string{"flag_1"}},
+		{
+			"many",
+			"KEY(0, U32LE) AS id,VALUE(JUMP(4, U8), BYTES(U8)) AS name, VALUE(8, F64LE)",
+			"KEY(0, U32LE) AS id, VALUE(JUMP(4, U8), BYTES(U8)) AS name, VALUE(8, F64LE)",
+			[]string{"id", "name", "VALUE(8, F64LE)"},
+		},
+		{"comment", "VALUE(0, I8) AS x # the x coordinate", "VALUE(0, I8) AS x", []string{"x"}},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			projection, err := NewParser(tc.input).ReadProjection()
+			if !assert.NoError(t, err) {
+				return
+			}
+			text, err := FormatProjection(projection)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, text)
+			projector, err := NewProjector(projection)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.names, projector.Names())
+		})
+	}
+}
+
+func TestParser_ReadProjection_errors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"empty-field", "VALUE(0, U8),, VALUE(1, U8)"},
+		{"trailing-comma", "VALUE(0, U8),"},
+		{"scalar", "U32(1)"},
+		{"expression", "VALUE(0, U8) + U32(1)"},
+		{"missing-comma", "VALUE(0, U8) VALUE(1, U8)"},
+		{"missing-alias", "VALUE(0, U8) AS"},
+		{"invalid-alias", "VALUE(0, U8) AS 1x"},
+		{"unknown", "VALUE(0, U8) AS x y"},
+		{"invalid-value", "VALUE(0, BOOL)"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewParser(tc.input).ReadProjection()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheckProjection(t *testing.T) {
+	t.Parallel()
+	value := &Value{Jump: &Jump{Jump: &Jump_Offset{}}, Type: ValueType_VALUE_TYPE_U8}
+	projection := []*Projection{
+		{Value: value, Alias: "a"},
+		{Value: value},
+		{Value: &Value{Jump: &Jump{Jump: &Jump_Offset{}}}},
+		{Alias: "b"},
+		{Value: value, Alias: "a"},
+		{Value: value, Alias: "a-b"},
+	}
+	var paths []string
+	for _, err := range CheckProjection(projection) {
+		paths = append(paths, err.Path)
+	}
+	assert.Equal(t, []string{"projection[2].value.type", "projection[3]", "projection[4].alias", "projection[5].alias"}, paths)
+	assert.Nil(t, CheckProjection(projection[:2]))
+
+	_, err := NewProjector(projection)
+	assert.Error(t, err)
+}
+
+func TestProjector_Project(t *testing.T) {
+	t.Parallel()
+	projection, err := NewParser("KEY(0, U16BE), VALUE(0, I8), VALUE(1, U64LE), VALUE(9, F32LE), VALUE(13, BYTES(U8))").ReadProjection()
+	if !assert.NoError(t, err) {
+		return
+	}
+	projector, err := NewProjector(projection)
+	if !assert.NoError(t, err) {
+		return
+	}
+	key := makeBytes(t, u16be(7))
+	value := makeBytes(t, i8(-2), u64le(1<<40), f32le(1.5), u8(2), "hi")
+	scalars, err := projector.Project(key, value)
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected := []*Scalar{
+		{Value: &Scalar_U32{U32: 7}},
+		{Value: &Scalar_I32{I32: -2}},
+		{Value: &Scalar_U64{U64: 1 << 40}},
+		{Value: &Scalar_F32{F32: 1.5}},
+		{Value: &Scalar_Bytes{Bytes: []byte("hi")}},
+	}
+	assert.Equal(t, expected, scalars)
+
+	value[14] = 'o'
+	assert.Equal(t, []byte("hi"), scalars[4].GetBytes(), "bytes are copied")
+
+	_, err = projector.Project(key, value[:4])
+	assert.Error(t, err)
+}
+
+func BenchmarkProjector_Project(b *testing.B) {
+	projection, err := NewParser("KEY(0, U16BE), VALUE(0, I8), VALUE(1, U64LE), VALUE(9, F32LE)").ReadProjection()
+	if err != nil {
+		b.Fatal(err)
+	}
+	projector, err := NewProjector(projection)
+	if err != nil {
+		b.Fatal(err)
+	}
+	key := makeBytes(b, u16be(7))
+	value := makeBytes(b, i8(-2), u64le(1<<40), f32le(1.5))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := projector.Project(key, value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
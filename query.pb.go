@@ -4,9 +4,11 @@
 package binq
 
 import (
+	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
 	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	grpc "google.golang.org/grpc"
 	math "math"
 )
 
@@ -31,15 +33,29 @@ const (
 	ReturnType_RETURN_TYPE_U32     ReturnType = 3
 	ReturnType_RETURN_TYPE_U16     ReturnType = 4
 	ReturnType_RETURN_TYPE_U8      ReturnType = 5
+	ReturnType_RETURN_TYPE_I64     ReturnType = 6
+	ReturnType_RETURN_TYPE_I32     ReturnType = 7
+	ReturnType_RETURN_TYPE_I16     ReturnType = 8
+	ReturnType_RETURN_TYPE_I8      ReturnType = 9
+	ReturnType_RETURN_TYPE_F32     ReturnType = 10
+	ReturnType_RETURN_TYPE_F64     ReturnType = 11
+	ReturnType_RETURN_TYPE_BYTES   ReturnType = 12
 )
 
 var ReturnType_name = map[int32]string{
-	0: "RETURN_TYPE_UNKNOWN",
-	1: "RETURN_TYPE_BOOL",
-	2: "RETURN_TYPE_U64",
-	3: "RETURN_TYPE_U32",
-	4: "RETURN_TYPE_U16",
-	5: "RETURN_TYPE_U8",
+	0:  "RETURN_TYPE_UNKNOWN",
+	1:  "RETURN_TYPE_BOOL",
+	2:  "RETURN_TYPE_U64",
+	3:  "RETURN_TYPE_U32",
+	4:  "RETURN_TYPE_U16",
+	5:  "RETURN_TYPE_U8",
+	6:  "RETURN_TYPE_I64",
+	7:  "RETURN_TYPE_I32",
+	8:  "RETURN_TYPE_I16",
+	9:  "RETURN_TYPE_I8",
+	10: "RETURN_TYPE_F32",
+	11: "RETURN_TYPE_F64",
+	12: "RETURN_TYPE_BYTES",
 }
 
 var ReturnType_value = map[string]int32{
@@ -49,6 +65,13 @@ var ReturnType_value = map[string]int32{
 	"RETURN_TYPE_U32":     3,
 	"RETURN_TYPE_U16":     4,
 	"RETURN_TYPE_U8":      5,
+	"RETURN_TYPE_I64":     6,
+	"RETURN_TYPE_I32":     7,
+	"RETURN_TYPE_I16":     8,
+	"RETURN_TYPE_I8":      9,
+	"RETURN_TYPE_F32":     10,
+	"RETURN_TYPE_F64":     11,
+	"RETURN_TYPE_BYTES":   12,
 }
 
 func (x ReturnType) String() string {
@@ -87,6 +110,49 @@ func (Endianness) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_5c6ac9b241082464, []int{1}
 }
 
+// AggregateFunction is a function computing a value from the records of a group.
+type AggregateFunction int32
+
+const (
+	AggregateFunction_AGGREGATE_FUNCTION_UNKNOWN AggregateFunction = 0
+	// COUNT counts the records, and returns a U64.
+	AggregateFunction_AGGREGATE_FUNCTION_COUNT AggregateFunction = 1
+	// SUM adds the values of a numeric expression, and returns a U64, I64 or F64.
+	AggregateFunction_AGGREGATE_FUNCTION_SUM AggregateFunction = 2
+	// MIN returns the least value of a numeric or bytes expression.
+	AggregateFunction_AGGREGATE_FUNCTION_MIN AggregateFunction = 3
+	// MAX returns the greatest value of a numeric or bytes expression.
+	AggregateFunction_AGGREGATE_FUNCTION_MAX AggregateFunction = 4
+	// AVG returns the mean value of a numeric expression as a F64.
+	AggregateFunction_AGGREGATE_FUNCTION_AVG AggregateFunction = 5
+)
+
+var AggregateFunction_name = map[int32]string{
+	0: "AGGREGATE_FUNCTION_UNKNOWN",
+	1: "AGGREGATE_FUNCTION_COUNT",
+	2: "AGGREGATE_FUNCTION_SUM",
+	3: "AGGREGATE_FUNCTION_MIN",
+	4: "AGGREGATE_FUNCTION_MAX",
+	5: "AGGREGATE_FUNCTION_AVG",
+}
+
+var AggregateFunction_value = map[string]int32{
+	"AGGREGATE_FUNCTION_UNKNOWN": 0,
+	"AGGREGATE_FUNCTION_COUNT":   1,
+	"AGGREGATE_FUNCTION_SUM":     2,
+	"AGGREGATE_FUNCTION_MIN":     3,
+	"AGGREGATE_FUNCTION_MAX":     4,
+	"AGGREGATE_FUNCTION_AVG":     5,
+}
+
+func (x AggregateFunction) String() string {
+	return proto.EnumName(AggregateFunction_name, int32(x))
+}
+
+func (AggregateFunction) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{2}
+}
+
 // Op is an operation to perform between two expressions.
 type BinaryOpCode int32
 
@@ -98,16 +164,43 @@ const (
 	BinaryOpCode_BINARY_OP_CODE_LESS_EQ    BinaryOpCode = 4
 	BinaryOpCode_BINARY_OP_CODE_GREATER    BinaryOpCode = 5
 	BinaryOpCode_BINARY_OP_CODE_GREATER_EQ BinaryOpCode = 6
+	// Arithmetic and bitwise operations return the type of their operands.
+	// Integer operations wrap around on overflow, division or modulo by zero is an error.
+	BinaryOpCode_BINARY_OP_CODE_ADD     BinaryOpCode = 7
+	BinaryOpCode_BINARY_OP_CODE_SUB     BinaryOpCode = 8
+	BinaryOpCode_BINARY_OP_CODE_MUL     BinaryOpCode = 9
+	BinaryOpCode_BINARY_OP_CODE_DIV     BinaryOpCode = 10
+	BinaryOpCode_BINARY_OP_CODE_MOD     BinaryOpCode = 11
+	BinaryOpCode_BINARY_OP_CODE_BIT_AND BinaryOpCode = 12
+	BinaryOpCode_BINARY_OP_CODE_BIT_OR  BinaryOpCode = 13
+	BinaryOpCode_BINARY_OP_CODE_BIT_XOR BinaryOpCode = 14
+	BinaryOpCode_BINARY_OP_CODE_SHL     BinaryOpCode = 15
+	BinaryOpCode_BINARY_OP_CODE_SHR     BinaryOpCode = 16
+	// Logical operations short-circuit: the right-hand side is only evaluated when it decides the result.
+	BinaryOpCode_BINARY_OP_CODE_AND BinaryOpCode = 17
+	BinaryOpCode_BINARY_OP_CODE_OR  BinaryOpCode = 18
 )
 
 var BinaryOpCode_name = map[int32]string{
-	0: "BINARY_OP_CODE_UNKNOWN",
-	1: "BINARY_OP_CODE_EQ",
-	2: "BINARY_OP_CODE_NEQ",
-	3: "BINARY_OP_CODE_LESS",
-	4: "BINARY_OP_CODE_LESS_EQ",
-	5: "BINARY_OP_CODE_GREATER",
-	6: "BINARY_OP_CODE_GREATER_EQ",
+	0:  "BINARY_OP_CODE_UNKNOWN",
+	1:  "BINARY_OP_CODE_EQ",
+	2:  "BINARY_OP_CODE_NEQ",
+	3:  "BINARY_OP_CODE_LESS",
+	4:  "BINARY_OP_CODE_LESS_EQ",
+	5:  "BINARY_OP_CODE_GREATER",
+	6:  "BINARY_OP_CODE_GREATER_EQ",
+	7:  "BINARY_OP_CODE_ADD",
+	8:  "BINARY_OP_CODE_SUB",
+	9:  "BINARY_OP_CODE_MUL",
+	10: "BINARY_OP_CODE_DIV",
+	11: "BINARY_OP_CODE_MOD",
+	12: "BINARY_OP_CODE_BIT_AND",
+	13: "BINARY_OP_CODE_BIT_OR",
+	14: "BINARY_OP_CODE_BIT_XOR",
+	15: "BINARY_OP_CODE_SHL",
+	16: "BINARY_OP_CODE_SHR",
+	17: "BINARY_OP_CODE_AND",
+	18: "BINARY_OP_CODE_OR",
 }
 
 var BinaryOpCode_value = map[string]int32{
@@ -118,6 +211,18 @@ var BinaryOpCode_value = map[string]int32{
 	"BINARY_OP_CODE_LESS_EQ":    4,
 	"BINARY_OP_CODE_GREATER":    5,
 	"BINARY_OP_CODE_GREATER_EQ": 6,
+	"BINARY_OP_CODE_ADD":        7,
+	"BINARY_OP_CODE_SUB":        8,
+	"BINARY_OP_CODE_MUL":        9,
+	"BINARY_OP_CODE_DIV":        10,
+	"BINARY_OP_CODE_MOD":        11,
+	"BINARY_OP_CODE_BIT_AND":    12,
+	"BINARY_OP_CODE_BIT_OR":     13,
+	"BINARY_OP_CODE_BIT_XOR":    14,
+	"BINARY_OP_CODE_SHL":        15,
+	"BINARY_OP_CODE_SHR":        16,
+	"BINARY_OP_CODE_AND":        17,
+	"BINARY_OP_CODE_OR":         18,
 }
 
 func (x BinaryOpCode) String() string {
@@ -125,7 +230,61 @@ func (x BinaryOpCode) String() string {
 }
 
 func (BinaryOpCode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{2}
+	return fileDescriptor_5c6ac9b241082464, []int{3}
+}
+
+// UnaryOpCode is an operation to perform on a single expression.
+type UnaryOpCode int32
+
+const (
+	UnaryOpCode_UNARY_OP_CODE_UNKNOWN UnaryOpCode = 0
+	UnaryOpCode_UNARY_OP_CODE_NOT     UnaryOpCode = 1
+)
+
+var UnaryOpCode_name = map[int32]string{
+	0: "UNARY_OP_CODE_UNKNOWN",
+	1: "UNARY_OP_CODE_NOT",
+}
+
+var UnaryOpCode_value = map[string]int32{
+	"UNARY_OP_CODE_UNKNOWN": 0,
+	"UNARY_OP_CODE_NOT":     1,
+}
+
+func (x UnaryOpCode) String() string {
+	return proto.EnumName(UnaryOpCode_name, int32(x))
+}
+
+func (UnaryOpCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{4}
+}
+
+// Target selects the part of a key-value record to read data from.
+type Target int32
+
+const (
+	// TARGET_VALUE reads from the value of a record and is the default.
+	Target_TARGET_VALUE Target = 0
+	// TARGET_KEY reads from the key of a record.
+	Target_TARGET_KEY Target = 1
+)
+
+var Target_name = map[int32]string{
+	0: "TARGET_VALUE",
+	1: "TARGET_KEY",
+}
+
+var Target_value = map[string]int32{
+	"TARGET_VALUE": 0,
+	"TARGET_KEY":   1,
+}
+
+func (x Target) String() string {
+	return proto.EnumName(Target_name, int32(x))
+}
+
+func (Target) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{5}
 }
 
 type ValueType int32
@@ -139,28 +298,81 @@ const (
 	ValueType_VALUE_TYPE_U16LE   ValueType = 5
 	ValueType_VALUE_TYPE_U16BE   ValueType = 6
 	ValueType_VALUE_TYPE_U8      ValueType = 7
+	ValueType_VALUE_TYPE_I64LE   ValueType = 8
+	ValueType_VALUE_TYPE_I64BE   ValueType = 9
+	ValueType_VALUE_TYPE_I32LE   ValueType = 10
+	ValueType_VALUE_TYPE_I32BE   ValueType = 11
+	ValueType_VALUE_TYPE_I16LE   ValueType = 12
+	ValueType_VALUE_TYPE_I16BE   ValueType = 13
+	ValueType_VALUE_TYPE_I8      ValueType = 14
+	ValueType_VALUE_TYPE_F32LE   ValueType = 15
+	ValueType_VALUE_TYPE_F32BE   ValueType = 16
+	ValueType_VALUE_TYPE_F64LE   ValueType = 17
+	ValueType_VALUE_TYPE_F64BE   ValueType = 18
+	// VALUE_TYPE_BYTES reads the number of bytes given by the length of the value.
+	ValueType_VALUE_TYPE_BYTES ValueType = 19
+	// VALUE_TYPE_BYTES_U* read a length prefix followed by that number of bytes.
+	ValueType_VALUE_TYPE_BYTES_U8    ValueType = 20
+	ValueType_VALUE_TYPE_BYTES_U16LE ValueType = 21
+	ValueType_VALUE_TYPE_BYTES_U16BE ValueType = 22
+	ValueType_VALUE_TYPE_BYTES_U32LE ValueType = 23
+	ValueType_VALUE_TYPE_BYTES_U32BE ValueType = 24
 )
 
 var ValueType_name = map[int32]string{
-	0: "VALUE_TYPE_UNKNOWN",
-	1: "VALUE_TYPE_U64LE",
-	2: "VALUE_TYPE_U64BE",
-	3: "VALUE_TYPE_U32LE",
-	4: "VALUE_TYPE_U32BE",
-	5: "VALUE_TYPE_U16LE",
-	6: "VALUE_TYPE_U16BE",
-	7: "VALUE_TYPE_U8",
+	0:  "VALUE_TYPE_UNKNOWN",
+	1:  "VALUE_TYPE_U64LE",
+	2:  "VALUE_TYPE_U64BE",
+	3:  "VALUE_TYPE_U32LE",
+	4:  "VALUE_TYPE_U32BE",
+	5:  "VALUE_TYPE_U16LE",
+	6:  "VALUE_TYPE_U16BE",
+	7:  "VALUE_TYPE_U8",
+	8:  "VALUE_TYPE_I64LE",
+	9:  "VALUE_TYPE_I64BE",
+	10: "VALUE_TYPE_I32LE",
+	11: "VALUE_TYPE_I32BE",
+	12: "VALUE_TYPE_I16LE",
+	13: "VALUE_TYPE_I16BE",
+	14: "VALUE_TYPE_I8",
+	15: "VALUE_TYPE_F32LE",
+	16: "VALUE_TYPE_F32BE",
+	17: "VALUE_TYPE_F64LE",
+	18: "VALUE_TYPE_F64BE",
+	19: "VALUE_TYPE_BYTES",
+	20: "VALUE_TYPE_BYTES_U8",
+	21: "VALUE_TYPE_BYTES_U16LE",
+	22: "VALUE_TYPE_BYTES_U16BE",
+	23: "VALUE_TYPE_BYTES_U32LE",
+	24: "VALUE_TYPE_BYTES_U32BE",
 }
 
 var ValueType_value = map[string]int32{
-	"VALUE_TYPE_UNKNOWN": 0,
-	"VALUE_TYPE_U64LE":   1,
-	"VALUE_TYPE_U64BE":   2,
-	"VALUE_TYPE_U32LE":   3,
-	"VALUE_TYPE_U32BE":   4,
-	"VALUE_TYPE_U16LE":   5,
-	"VALUE_TYPE_U16BE":   6,
-	"VALUE_TYPE_U8":      7,
+	"VALUE_TYPE_UNKNOWN":     0,
+	"VALUE_TYPE_U64LE":       1,
+	"VALUE_TYPE_U64BE":       2,
+	"VALUE_TYPE_U32LE":       3,
+	"VALUE_TYPE_U32BE":       4,
+	"VALUE_TYPE_U16LE":       5,
+	"VALUE_TYPE_U16BE":       6,
+	"VALUE_TYPE_U8":          7,
+	"VALUE_TYPE_I64LE":       8,
+	"VALUE_TYPE_I64BE":       9,
+	"VALUE_TYPE_I32LE":       10,
+	"VALUE_TYPE_I32BE":       11,
+	"VALUE_TYPE_I16LE":       12,
+	"VALUE_TYPE_I16BE":       13,
+	"VALUE_TYPE_I8":          14,
+	"VALUE_TYPE_F32LE":       15,
+	"VALUE_TYPE_F32BE":       16,
+	"VALUE_TYPE_F64LE":       17,
+	"VALUE_TYPE_F64BE":       18,
+	"VALUE_TYPE_BYTES":       19,
+	"VALUE_TYPE_BYTES_U8":    20,
+	"VALUE_TYPE_BYTES_U16LE": 21,
+	"VALUE_TYPE_BYTES_U16BE": 22,
+	"VALUE_TYPE_BYTES_U32LE": 23,
+	"VALUE_TYPE_BYTES_U32BE": 24,
 }
 
 func (x ValueType) String() string {
@@ -168,22 +380,29 @@ func (x ValueType) String() string {
 }
 
 func (ValueType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{3}
+	return fileDescriptor_5c6ac9b241082464, []int{6}
 }
 
 // Query is a query to execute over a range of binary key-value data.
 type Query struct {
 	// start is the minimum key to search, inclusive.
+	// Keys are ordered like byte strings.
 	Start []byte `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
-	// end is the maximum key to search, exclusive.
+	// end is the maximum key to search, exclusive, or empty for no maximum.
 	End []byte `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
 	// query_options are additional options for this query.
 	QueryOptions *Options `protobuf:"bytes,3,opt,name=query_options,json=queryOptions,proto3" json:"query_options,omitempty"`
 	// predicate is the filter to apply to each record.
-	Predicate            *Predicate `protobuf:"bytes,4,opt,name=predicate,proto3" json:"predicate,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	Predicate *Predicate `protobuf:"bytes,4,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	// projection selects the fields returned for each record.
+	// An empty projection returns whole records.
+	Projection []*Projection `protobuf:"bytes,5,rep,name=projection,proto3" json:"projection,omitempty"`
+	// aggregation summarises the records instead of returning them.
+	// It cannot be combined with a projection.
+	Aggregation          *Aggregation `protobuf:"bytes,6,opt,name=aggregation,proto3" json:"aggregation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *Query) Reset()         { *m = Query{} }
@@ -239,6 +458,70 @@ func (m *Query) GetPredicate() *Predicate {
 	return nil
 }
 
+func (m *Query) GetProjection() []*Projection {
+	if m != nil {
+		return m.Projection
+	}
+	return nil
+}
+
+func (m *Query) GetAggregation() *Aggregation {
+	if m != nil {
+		return m.Aggregation
+	}
+	return nil
+}
+
+// Projection selects a field of the records of a query.
+type Projection struct {
+	// value is the field to select.
+	Value *Value `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// alias names the field, such as in the output of the binq command.
+	Alias                string   `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Projection) Reset()         { *m = Projection{} }
+func (m *Projection) String() string { return proto.CompactTextString(m) }
+func (*Projection) ProtoMessage()    {}
+func (*Projection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{1}
+}
+
+func (m *Projection) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Projection.Unmarshal(m, b)
+}
+func (m *Projection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Projection.Marshal(b, m, deterministic)
+}
+func (m *Projection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Projection.Merge(m, src)
+}
+func (m *Projection) XXX_Size() int {
+	return xxx_messageInfo_Projection.Size(m)
+}
+func (m *Projection) XXX_DiscardUnknown() {
+	xxx_messageInfo_Projection.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Projection proto.InternalMessageInfo
+
+func (m *Projection) GetValue() *Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Projection) GetAlias() string {
+	if m != nil {
+		return m.Alias
+	}
+	return ""
+}
+
 // Options specifies additional options for a query.
 type Options struct {
 	// limit determines the maximum number of results.
@@ -253,7 +536,7 @@ func (m *Options) Reset()         { *m = Options{} }
 func (m *Options) String() string { return proto.CompactTextString(m) }
 func (*Options) ProtoMessage()    {}
 func (*Options) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{1}
+	return fileDescriptor_5c6ac9b241082464, []int{2}
 }
 
 func (m *Options) XXX_Unmarshal(b []byte) error {
@@ -281,6 +564,117 @@ func (m *Options) GetLimit() uint64 {
 	return 0
 }
 
+// Aggregation summarises the records of a query into groups, returning a record for each group
+// whose fields are the fields of group_by followed by the aggregates.
+type Aggregation struct {
+	// group_by are the fields whose values group the records.
+	// Without any, all records form a single group, which is returned even if it is empty.
+	GroupBy []*Projection `protobuf:"bytes,1,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	// aggregates are computed over the records of each group.
+	Aggregates           []*Aggregate `protobuf:"bytes,2,rep,name=aggregates,proto3" json:"aggregates,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Aggregation) Reset()         { *m = Aggregation{} }
+func (m *Aggregation) String() string { return proto.CompactTextString(m) }
+func (*Aggregation) ProtoMessage()    {}
+func (*Aggregation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{3}
+}
+
+func (m *Aggregation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Aggregation.Unmarshal(m, b)
+}
+func (m *Aggregation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Aggregation.Marshal(b, m, deterministic)
+}
+func (m *Aggregation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Aggregation.Merge(m, src)
+}
+func (m *Aggregation) XXX_Size() int {
+	return xxx_messageInfo_Aggregation.Size(m)
+}
+func (m *Aggregation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Aggregation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Aggregation proto.InternalMessageInfo
+
+func (m *Aggregation) GetGroupBy() []*Projection {
+	if m != nil {
+		return m.GroupBy
+	}
+	return nil
+}
+
+func (m *Aggregation) GetAggregates() []*Aggregate {
+	if m != nil {
+		return m.Aggregates
+	}
+	return nil
+}
+
+// Aggregate is an aggregate function over an expression.
+type Aggregate struct {
+	// function is the function to compute.
+	Function AggregateFunction `protobuf:"varint,1,opt,name=function,proto3,enum=AggregateFunction" json:"function,omitempty"`
+	// expression is evaluated for each record, and is empty for COUNT.
+	Expression *Expression `protobuf:"bytes,2,opt,name=expression,proto3" json:"expression,omitempty"`
+	// alias names the aggregate, such as in the output of the binq command.
+	Alias                string   `protobuf:"bytes,3,opt,name=alias,proto3" json:"alias,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Aggregate) Reset()         { *m = Aggregate{} }
+func (m *Aggregate) String() string { return proto.CompactTextString(m) }
+func (*Aggregate) ProtoMessage()    {}
+func (*Aggregate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{4}
+}
+
+func (m *Aggregate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Aggregate.Unmarshal(m, b)
+}
+func (m *Aggregate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Aggregate.Marshal(b, m, deterministic)
+}
+func (m *Aggregate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Aggregate.Merge(m, src)
+}
+func (m *Aggregate) XXX_Size() int {
+	return xxx_messageInfo_Aggregate.Size(m)
+}
+func (m *Aggregate) XXX_DiscardUnknown() {
+	xxx_messageInfo_Aggregate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Aggregate proto.InternalMessageInfo
+
+func (m *Aggregate) GetFunction() AggregateFunction {
+	if m != nil {
+		return m.Function
+	}
+	return AggregateFunction_AGGREGATE_FUNCTION_UNKNOWN
+}
+
+func (m *Aggregate) GetExpression() *Expression {
+	if m != nil {
+		return m.Expression
+	}
+	return nil
+}
+
+func (m *Aggregate) GetAlias() string {
+	if m != nil {
+		return m.Alias
+	}
+	return ""
+}
+
 // Predicate defines a set of expressions to apply to the values of key-value binary data.
 // A predicates root expression must represent a boolean value.
 type Predicate struct {
@@ -298,7 +692,7 @@ func (m *Predicate) Reset()         { *m = Predicate{} }
 func (m *Predicate) String() string { return proto.CompactTextString(m) }
 func (*Predicate) ProtoMessage()    {}
 func (*Predicate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{2}
+	return fileDescriptor_5c6ac9b241082464, []int{5}
 }
 
 func (m *Predicate) XXX_Unmarshal(b []byte) error {
@@ -468,6 +862,7 @@ type Expression struct {
 	//	*Expression_BinaryOperation
 	//	*Expression_Value
 	//	*Expression_Scalar
+	//	*Expression_UnaryOperation
 	Expression           isExpression_Expression `protobuf_oneof:"expression"`
 	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
 	XXX_unrecognized     []byte                  `json:"-"`
@@ -478,7 +873,7 @@ func (m *Expression) Reset()         { *m = Expression{} }
 func (m *Expression) String() string { return proto.CompactTextString(m) }
 func (*Expression) ProtoMessage()    {}
 func (*Expression) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{3}
+	return fileDescriptor_5c6ac9b241082464, []int{6}
 }
 
 func (m *Expression) XXX_Unmarshal(b []byte) error {
@@ -515,12 +910,18 @@ type Expression_Scalar struct {
 	Scalar *Scalar `protobuf:"bytes,3,opt,name=scalar,proto3,oneof"`
 }
 
+type Expression_UnaryOperation struct {
+	UnaryOperation *UnaryOperation `protobuf:"bytes,4,opt,name=unary_operation,json=unaryOperation,proto3,oneof"`
+}
+
 func (*Expression_BinaryOperation) isExpression_Expression() {}
 
 func (*Expression_Value) isExpression_Expression() {}
 
 func (*Expression_Scalar) isExpression_Expression() {}
 
+func (*Expression_UnaryOperation) isExpression_Expression() {}
+
 func (m *Expression) GetExpression() isExpression_Expression {
 	if m != nil {
 		return m.Expression
@@ -549,12 +950,20 @@ func (m *Expression) GetScalar() *Scalar {
 	return nil
 }
 
+func (m *Expression) GetUnaryOperation() *UnaryOperation {
+	if x, ok := m.GetExpression().(*Expression_UnaryOperation); ok {
+		return x.UnaryOperation
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Expression) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Expression_OneofMarshaler, _Expression_OneofUnmarshaler, _Expression_OneofSizer, []interface{}{
 		(*Expression_BinaryOperation)(nil),
 		(*Expression_Value)(nil),
 		(*Expression_Scalar)(nil),
+		(*Expression_UnaryOperation)(nil),
 	}
 }
 
@@ -577,6 +986,11 @@ func _Expression_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Scalar); err != nil {
 			return err
 		}
+	case *Expression_UnaryOperation:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.UnaryOperation); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Expression.Expression has unexpected type %T", x)
@@ -611,6 +1025,14 @@ func _Expression_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buf
 		err := b.DecodeMessage(msg)
 		m.Expression = &Expression_Scalar{msg}
 		return true, err
+	case 4: // expression.unary_operation
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(UnaryOperation)
+		err := b.DecodeMessage(msg)
+		m.Expression = &Expression_UnaryOperation{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -635,6 +1057,11 @@ func _Expression_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Expression_UnaryOperation:
+		s := proto.Size(x.UnaryOperation)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -654,7 +1081,7 @@ func (m *Expressions) Reset()         { *m = Expressions{} }
 func (m *Expressions) String() string { return proto.CompactTextString(m) }
 func (*Expressions) ProtoMessage()    {}
 func (*Expressions) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{4}
+	return fileDescriptor_5c6ac9b241082464, []int{7}
 }
 
 func (m *Expressions) XXX_Unmarshal(b []byte) error {
@@ -694,7 +1121,7 @@ func (m *Predicates) Reset()         { *m = Predicates{} }
 func (m *Predicates) String() string { return proto.CompactTextString(m) }
 func (*Predicates) ProtoMessage()    {}
 func (*Predicates) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{5}
+	return fileDescriptor_5c6ac9b241082464, []int{8}
 }
 
 func (m *Predicates) XXX_Unmarshal(b []byte) error {
@@ -728,6 +1155,11 @@ type Scalar struct {
 	//	*Scalar_Bool
 	//	*Scalar_U64
 	//	*Scalar_U32
+	//	*Scalar_I64
+	//	*Scalar_I32
+	//	*Scalar_F32
+	//	*Scalar_F64
+	//	*Scalar_Bytes
 	Value                isScalar_Value `protobuf_oneof:"value"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
@@ -738,7 +1170,7 @@ func (m *Scalar) Reset()         { *m = Scalar{} }
 func (m *Scalar) String() string { return proto.CompactTextString(m) }
 func (*Scalar) ProtoMessage()    {}
 func (*Scalar) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{6}
+	return fileDescriptor_5c6ac9b241082464, []int{9}
 }
 
 func (m *Scalar) XXX_Unmarshal(b []byte) error {
@@ -775,12 +1207,42 @@ type Scalar_U32 struct {
 	U32 uint32 `protobuf:"varint,4,opt,name=u32,proto3,oneof"`
 }
 
+type Scalar_I64 struct {
+	I64 int64 `protobuf:"zigzag64,5,opt,name=i64,proto3,oneof"`
+}
+
+type Scalar_I32 struct {
+	I32 int32 `protobuf:"zigzag32,6,opt,name=i32,proto3,oneof"`
+}
+
+type Scalar_F32 struct {
+	F32 float32 `protobuf:"fixed32,7,opt,name=f32,proto3,oneof"`
+}
+
+type Scalar_F64 struct {
+	F64 float64 `protobuf:"fixed64,8,opt,name=f64,proto3,oneof"`
+}
+
+type Scalar_Bytes struct {
+	Bytes []byte `protobuf:"bytes,9,opt,name=bytes,proto3,oneof"`
+}
+
 func (*Scalar_Bool) isScalar_Value() {}
 
 func (*Scalar_U64) isScalar_Value() {}
 
 func (*Scalar_U32) isScalar_Value() {}
 
+func (*Scalar_I64) isScalar_Value() {}
+
+func (*Scalar_I32) isScalar_Value() {}
+
+func (*Scalar_F32) isScalar_Value() {}
+
+func (*Scalar_F64) isScalar_Value() {}
+
+func (*Scalar_Bytes) isScalar_Value() {}
+
 func (m *Scalar) GetValue() isScalar_Value {
 	if m != nil {
 		return m.Value
@@ -809,12 +1271,52 @@ func (m *Scalar) GetU32() uint32 {
 	return 0
 }
 
+func (m *Scalar) GetI64() int64 {
+	if x, ok := m.GetValue().(*Scalar_I64); ok {
+		return x.I64
+	}
+	return 0
+}
+
+func (m *Scalar) GetI32() int32 {
+	if x, ok := m.GetValue().(*Scalar_I32); ok {
+		return x.I32
+	}
+	return 0
+}
+
+func (m *Scalar) GetF32() float32 {
+	if x, ok := m.GetValue().(*Scalar_F32); ok {
+		return x.F32
+	}
+	return 0
+}
+
+func (m *Scalar) GetF64() float64 {
+	if x, ok := m.GetValue().(*Scalar_F64); ok {
+		return x.F64
+	}
+	return 0
+}
+
+func (m *Scalar) GetBytes() []byte {
+	if x, ok := m.GetValue().(*Scalar_Bytes); ok {
+		return x.Bytes
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Scalar) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Scalar_OneofMarshaler, _Scalar_OneofUnmarshaler, _Scalar_OneofSizer, []interface{}{
 		(*Scalar_Bool)(nil),
 		(*Scalar_U64)(nil),
 		(*Scalar_U32)(nil),
+		(*Scalar_I64)(nil),
+		(*Scalar_I32)(nil),
+		(*Scalar_F32)(nil),
+		(*Scalar_F64)(nil),
+		(*Scalar_Bytes)(nil),
 	}
 }
 
@@ -835,6 +1337,21 @@ func _Scalar_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 	case *Scalar_U32:
 		b.EncodeVarint(4<<3 | proto.WireVarint)
 		b.EncodeVarint(uint64(x.U32))
+	case *Scalar_I64:
+		b.EncodeVarint(5<<3 | proto.WireVarint)
+		b.EncodeZigzag64(uint64(x.I64))
+	case *Scalar_I32:
+		b.EncodeVarint(6<<3 | proto.WireVarint)
+		b.EncodeZigzag32(uint64(x.I32))
+	case *Scalar_F32:
+		b.EncodeVarint(7<<3 | proto.WireFixed32)
+		b.EncodeFixed32(uint64(math.Float32bits(x.F32)))
+	case *Scalar_F64:
+		b.EncodeVarint(8<<3 | proto.WireFixed64)
+		b.EncodeFixed64(math.Float64bits(x.F64))
+	case *Scalar_Bytes:
+		b.EncodeVarint(9<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.Bytes)
 	case nil:
 	default:
 		return fmt.Errorf("Scalar.Value has unexpected type %T", x)
@@ -866,6 +1383,41 @@ func _Scalar_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer)
 		x, err := b.DecodeVarint()
 		m.Value = &Scalar_U32{uint32(x)}
 		return true, err
+	case 5: // value.i64
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeZigzag64()
+		m.Value = &Scalar_I64{int64(x)}
+		return true, err
+	case 6: // value.i32
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeZigzag32()
+		m.Value = &Scalar_I32{int32(x)}
+		return true, err
+	case 7: // value.f32
+		if wire != proto.WireFixed32 {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeFixed32()
+		m.Value = &Scalar_F32{math.Float32frombits(uint32(x))}
+		return true, err
+	case 8: // value.f64
+		if wire != proto.WireFixed64 {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeFixed64()
+		m.Value = &Scalar_F64{math.Float64frombits(x)}
+		return true, err
+	case 9: // value.bytes
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Value = &Scalar_Bytes{x}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -884,6 +1436,22 @@ func _Scalar_OneofSizer(msg proto.Message) (n int) {
 	case *Scalar_U32:
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(x.U32))
+	case *Scalar_I64:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(uint64(x.I64<<1) ^ uint64((int64(x.I64) >> 63))))
+	case *Scalar_I32:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64((uint32(x.I32) << 1) ^ uint32((int32(x.I32) >> 31))))
+	case *Scalar_F32:
+		n += 1 // tag and wire
+		n += 4
+	case *Scalar_F64:
+		n += 1 // tag and wire
+		n += 8
+	case *Scalar_Bytes:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.Bytes)))
+		n += len(x.Bytes)
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -908,7 +1476,7 @@ func (m *BinaryOperation) Reset()         { *m = BinaryOperation{} }
 func (m *BinaryOperation) String() string { return proto.CompactTextString(m) }
 func (*BinaryOperation) ProtoMessage()    {}
 func (*BinaryOperation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{7}
+	return fileDescriptor_5c6ac9b241082464, []int{10}
 }
 
 func (m *BinaryOperation) XXX_Unmarshal(b []byte) error {
@@ -950,22 +1518,76 @@ func (m *BinaryOperation) GetRight() *Expression {
 	return nil
 }
 
+// UnaryOperation is an operation performed on a single expression.
+type UnaryOperation struct {
+	// unary_op_code is the operation to perform on the operand.
+	UnaryOpCode UnaryOpCode `protobuf:"varint,1,opt,name=unary_op_code,json=unaryOpCode,proto3,enum=UnaryOpCode" json:"unary_op_code,omitempty"`
+	// operand is the expression the operation is performed on.
+	Operand              *Expression `protobuf:"bytes,2,opt,name=operand,proto3" json:"operand,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *UnaryOperation) Reset()         { *m = UnaryOperation{} }
+func (m *UnaryOperation) String() string { return proto.CompactTextString(m) }
+func (*UnaryOperation) ProtoMessage()    {}
+func (*UnaryOperation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{11}
+}
+
+func (m *UnaryOperation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnaryOperation.Unmarshal(m, b)
+}
+func (m *UnaryOperation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnaryOperation.Marshal(b, m, deterministic)
+}
+func (m *UnaryOperation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnaryOperation.Merge(m, src)
+}
+func (m *UnaryOperation) XXX_Size() int {
+	return xxx_messageInfo_UnaryOperation.Size(m)
+}
+func (m *UnaryOperation) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnaryOperation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnaryOperation proto.InternalMessageInfo
+
+func (m *UnaryOperation) GetUnaryOpCode() UnaryOpCode {
+	if m != nil {
+		return m.UnaryOpCode
+	}
+	return UnaryOpCode_UNARY_OP_CODE_UNKNOWN
+}
+
+func (m *UnaryOperation) GetOperand() *Expression {
+	if m != nil {
+		return m.Operand
+	}
+	return nil
+}
+
 // Value is a value contained with binary data.
 type Value struct {
 	// Jump indicates the position of the data for this expression.
 	Jump *Jump `protobuf:"bytes,1,opt,name=jump,proto3" json:"jump,omitempty"`
 	// type is the type of data pointed to.
-	Type                 ValueType `protobuf:"varint,2,opt,name=type,proto3,enum=ValueType" json:"type,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+	Type ValueType `protobuf:"varint,2,opt,name=type,proto3,enum=ValueType" json:"type,omitempty"`
+	// target is the part of the record the data is read from.
+	Target Target `protobuf:"varint,3,opt,name=target,proto3,enum=Target" json:"target,omitempty"`
+	// length is the number of bytes read by VALUE_TYPE_BYTES.
+	Length               uint64   `protobuf:"varint,4,opt,name=length,proto3" json:"length,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Value) Reset()         { *m = Value{} }
 func (m *Value) String() string { return proto.CompactTextString(m) }
 func (*Value) ProtoMessage()    {}
 func (*Value) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{8}
+	return fileDescriptor_5c6ac9b241082464, []int{12}
 }
 
 func (m *Value) XXX_Unmarshal(b []byte) error {
@@ -1000,6 +1622,20 @@ func (m *Value) GetType() ValueType {
 	return ValueType_VALUE_TYPE_UNKNOWN
 }
 
+func (m *Value) GetTarget() Target {
+	if m != nil {
+		return m.Target
+	}
+	return Target_TARGET_VALUE
+}
+
+func (m *Value) GetLength() uint64 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
 // Jump defines a tree of jump-style lookups on data.
 // ex:
 //  Jump{offset:8} means that the position of the data is at position 8.
@@ -1026,7 +1662,7 @@ func (m *Jump) Reset()         { *m = Jump{} }
 func (m *Jump) String() string { return proto.CompactTextString(m) }
 func (*Jump) ProtoMessage()    {}
 func (*Jump) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5c6ac9b241082464, []int{9}
+	return fileDescriptor_5c6ac9b241082464, []int{13}
 }
 
 func (m *Jump) XXX_Unmarshal(b []byte) error {
@@ -1310,6 +1946,297 @@ func _Jump_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// Record is a key-value record of binary data.
+type Record struct {
+	// key is the encoded primary key of the record.
+	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// value is the binary data of the record, empty if the query has a projection.
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// fields are the fields selected by the projection of the query, in its order.
+	Fields               []*Scalar `protobuf:"bytes,3,rep,name=fields,proto3" json:"fields,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Record) Reset()         { *m = Record{} }
+func (m *Record) String() string { return proto.CompactTextString(m) }
+func (*Record) ProtoMessage()    {}
+func (*Record) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{14}
+}
+
+func (m *Record) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Record.Unmarshal(m, b)
+}
+func (m *Record) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Record.Marshal(b, m, deterministic)
+}
+func (m *Record) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Record.Merge(m, src)
+}
+func (m *Record) XXX_Size() int {
+	return xxx_messageInfo_Record.Size(m)
+}
+func (m *Record) XXX_DiscardUnknown() {
+	xxx_messageInfo_Record.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Record proto.InternalMessageInfo
+
+func (m *Record) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *Record) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Record) GetFields() []*Scalar {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+// CountResponse is the result of counting the records that match a query.
+type CountResponse struct {
+	// count is the number of records that match the query, up to its limit.
+	Count                uint64   `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountResponse) Reset()         { *m = CountResponse{} }
+func (m *CountResponse) String() string { return proto.CompactTextString(m) }
+func (*CountResponse) ProtoMessage()    {}
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{15}
+}
+
+func (m *CountResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountResponse.Unmarshal(m, b)
+}
+func (m *CountResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountResponse.Marshal(b, m, deterministic)
+}
+func (m *CountResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountResponse.Merge(m, src)
+}
+func (m *CountResponse) XXX_Size() int {
+	return xxx_messageInfo_CountResponse.Size(m)
+}
+func (m *CountResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountResponse proto.InternalMessageInfo
+
+func (m *CountResponse) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+// Range is a range of keys, which are ordered like byte strings.
+type Range struct {
+	// start is the minimum key of the range, inclusive.
+	Start []byte `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	// end is the maximum key of the range, exclusive, or empty for no maximum.
+	End []byte `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	// min_key_length is the length below which keys within the range are skipped.
+	MinKeyLength         uint32   `protobuf:"varint,3,opt,name=min_key_length,json=minKeyLength,proto3" json:"min_key_length,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Range) Reset()         { *m = Range{} }
+func (m *Range) String() string { return proto.CompactTextString(m) }
+func (*Range) ProtoMessage()    {}
+func (*Range) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{16}
+}
+
+func (m *Range) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Range.Unmarshal(m, b)
+}
+func (m *Range) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Range.Marshal(b, m, deterministic)
+}
+func (m *Range) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Range.Merge(m, src)
+}
+func (m *Range) XXX_Size() int {
+	return xxx_messageInfo_Range.Size(m)
+}
+func (m *Range) XXX_DiscardUnknown() {
+	xxx_messageInfo_Range.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Range proto.InternalMessageInfo
+
+func (m *Range) GetStart() []byte {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *Range) GetEnd() []byte {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+func (m *Range) GetMinKeyLength() uint32 {
+	if m != nil {
+		return m.MinKeyLength
+	}
+	return 0
+}
+
+// ExplainResponse describes how a query is executed.
+type ExplainResponse struct {
+	// ranges are the ranges of keys that are visited, in order.
+	Ranges []*Range `protobuf:"bytes,1,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	// residual is the predicate applied to each record within the ranges.
+	Residual             *Predicate `protobuf:"bytes,2,opt,name=residual,proto3" json:"residual,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ExplainResponse) Reset()         { *m = ExplainResponse{} }
+func (m *ExplainResponse) String() string { return proto.CompactTextString(m) }
+func (*ExplainResponse) ProtoMessage()    {}
+func (*ExplainResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{17}
+}
+
+func (m *ExplainResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExplainResponse.Unmarshal(m, b)
+}
+func (m *ExplainResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExplainResponse.Marshal(b, m, deterministic)
+}
+func (m *ExplainResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExplainResponse.Merge(m, src)
+}
+func (m *ExplainResponse) XXX_Size() int {
+	return xxx_messageInfo_ExplainResponse.Size(m)
+}
+func (m *ExplainResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExplainResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExplainResponse proto.InternalMessageInfo
+
+func (m *ExplainResponse) GetRanges() []*Range {
+	if m != nil {
+		return m.Ranges
+	}
+	return nil
+}
+
+func (m *ExplainResponse) GetResidual() *Predicate {
+	if m != nil {
+		return m.Residual
+	}
+	return nil
+}
+
+// InsertRequest is a request to insert records.
+type InsertRequest struct {
+	// records are the records to insert, whose keys must not exist.
+	Records              []*Record `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *InsertRequest) Reset()         { *m = InsertRequest{} }
+func (m *InsertRequest) String() string { return proto.CompactTextString(m) }
+func (*InsertRequest) ProtoMessage()    {}
+func (*InsertRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{18}
+}
+
+func (m *InsertRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InsertRequest.Unmarshal(m, b)
+}
+func (m *InsertRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InsertRequest.Marshal(b, m, deterministic)
+}
+func (m *InsertRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InsertRequest.Merge(m, src)
+}
+func (m *InsertRequest) XXX_Size() int {
+	return xxx_messageInfo_InsertRequest.Size(m)
+}
+func (m *InsertRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InsertRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InsertRequest proto.InternalMessageInfo
+
+func (m *InsertRequest) GetRecords() []*Record {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+// InsertResponse is the result of inserting records.
+type InsertResponse struct {
+	// inserted is the number of records inserted.
+	Inserted             uint64   `protobuf:"varint,1,opt,name=inserted,proto3" json:"inserted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InsertResponse) Reset()         { *m = InsertResponse{} }
+func (m *InsertResponse) String() string { return proto.CompactTextString(m) }
+func (*InsertResponse) ProtoMessage()    {}
+func (*InsertResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5c6ac9b241082464, []int{19}
+}
+
+func (m *InsertResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InsertResponse.Unmarshal(m, b)
+}
+func (m *InsertResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InsertResponse.Marshal(b, m, deterministic)
+}
+func (m *InsertResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InsertResponse.Merge(m, src)
+}
+func (m *InsertResponse) XXX_Size() int {
+	return xxx_messageInfo_InsertResponse.Size(m)
+}
+func (m *InsertResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InsertResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InsertResponse proto.InternalMessageInfo
+
+func (m *InsertResponse) GetInserted() uint64 {
+	if m != nil {
+		return m.Inserted
+	}
+	return 0
+}
+
 var E_ReturnType = &proto.ExtensionDesc{
 	ExtendedType:  (*descriptor.FieldOptions)(nil),
 	ExtensionType: (*ReturnType)(nil),
@@ -1349,18 +2276,31 @@ var E_EnumEndianness = &proto.ExtensionDesc{
 func init() {
 	proto.RegisterEnum("ReturnType", ReturnType_name, ReturnType_value)
 	proto.RegisterEnum("Endianness", Endianness_name, Endianness_value)
+	proto.RegisterEnum("AggregateFunction", AggregateFunction_name, AggregateFunction_value)
 	proto.RegisterEnum("BinaryOpCode", BinaryOpCode_name, BinaryOpCode_value)
+	proto.RegisterEnum("UnaryOpCode", UnaryOpCode_name, UnaryOpCode_value)
+	proto.RegisterEnum("Target", Target_name, Target_value)
 	proto.RegisterEnum("ValueType", ValueType_name, ValueType_value)
 	proto.RegisterType((*Query)(nil), "Query")
+	proto.RegisterType((*Projection)(nil), "Projection")
 	proto.RegisterType((*Options)(nil), "Options")
+	proto.RegisterType((*Aggregation)(nil), "Aggregation")
+	proto.RegisterType((*Aggregate)(nil), "Aggregate")
 	proto.RegisterType((*Predicate)(nil), "Predicate")
 	proto.RegisterType((*Expression)(nil), "Expression")
 	proto.RegisterType((*Expressions)(nil), "Expressions")
 	proto.RegisterType((*Predicates)(nil), "Predicates")
 	proto.RegisterType((*Scalar)(nil), "Scalar")
 	proto.RegisterType((*BinaryOperation)(nil), "BinaryOperation")
+	proto.RegisterType((*UnaryOperation)(nil), "UnaryOperation")
 	proto.RegisterType((*Value)(nil), "Value")
 	proto.RegisterType((*Jump)(nil), "Jump")
+	proto.RegisterType((*Record)(nil), "Record")
+	proto.RegisterType((*CountResponse)(nil), "CountResponse")
+	proto.RegisterType((*Range)(nil), "Range")
+	proto.RegisterType((*ExplainResponse)(nil), "ExplainResponse")
+	proto.RegisterType((*InsertRequest)(nil), "InsertRequest")
+	proto.RegisterType((*InsertResponse)(nil), "InsertResponse")
 	proto.RegisterExtension(E_ReturnType)
 	proto.RegisterExtension(E_Endianness)
 	proto.RegisterExtension(E_EnumReturnType)
@@ -1370,68 +2310,333 @@ func init() {
 func init() { proto.RegisterFile("query.proto", fileDescriptor_5c6ac9b241082464) }
 
 var fileDescriptor_5c6ac9b241082464 = []byte{
-	// 1008 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x95, 0xdd, 0x4e, 0xe3, 0x46,
-	0x14, 0xc7, 0xe3, 0xcf, 0xc0, 0x49, 0x80, 0xd9, 0x81, 0xb2, 0x5e, 0xba, 0x5d, 0xc0, 0x52, 0x25,
-	0x84, 0xba, 0x46, 0x38, 0x28, 0x42, 0xa8, 0x45, 0xc2, 0xbb, 0xee, 0x42, 0x37, 0x4a, 0x60, 0x08,
-	0xdb, 0xd2, 0x8b, 0x46, 0x31, 0x19, 0x58, 0x57, 0xc6, 0xce, 0xfa, 0xa3, 0x2a, 0x6f, 0x50, 0xf5,
-	0xa2, 0x0f, 0x50, 0xf5, 0x81, 0x50, 0xaf, 0xfa, 0x1a, 0xad, 0x7a, 0xd9, 0x07, 0xa8, 0x66, 0xc6,
-	0x49, 0x86, 0xc0, 0xb6, 0x7b, 0xe7, 0xf3, 0xff, 0xfd, 0xe7, 0xf8, 0x9c, 0x33, 0xc7, 0x32, 0xd4,
-	0xde, 0x15, 0x34, 0xbd, 0x71, 0x86, 0x69, 0x92, 0x27, 0x2b, 0x9f, 0xe5, 0x6f, 0xc3, 0x74, 0xd0,
-	0x1b, 0xf6, 0xd3, 0xfc, 0x66, 0xeb, 0x2a, 0x49, 0xae, 0x22, 0xba, 0xc5, 0x49, 0x50, 0x5c, 0x6e,
-	0x0d, 0x68, 0x76, 0x91, 0x86, 0xc3, 0x3c, 0x49, 0x85, 0xdb, 0xfe, 0x49, 0x01, 0xe3, 0x84, 0x9d,
-	0xc6, 0x4b, 0x60, 0x64, 0x79, 0x3f, 0xcd, 0x2d, 0x65, 0x4d, 0xd9, 0xa8, 0x13, 0x11, 0x60, 0x04,
-	0x1a, 0x8d, 0x07, 0x96, 0xca, 0x35, 0xf6, 0x88, 0x9f, 0xc3, 0x1c, 0x7f, 0x5d, 0x2f, 0x19, 0xe6,
-	0x61, 0x12, 0x67, 0x96, 0xb6, 0xa6, 0x6c, 0xd4, 0xdc, 0x19, 0xa7, 0x23, 0x62, 0x52, 0xe7, 0xb8,
-	0x8c, 0xf0, 0x06, 0xcc, 0x0e, 0x53, 0x3a, 0x08, 0x2f, 0xfa, 0x39, 0xb5, 0x74, 0x6e, 0x05, 0xe7,
-	0x78, 0xa4, 0x90, 0x09, 0xb4, 0x57, 0xa1, 0x3a, 0x3a, 0xb4, 0x04, 0x46, 0x14, 0x5e, 0x87, 0xa2,
-	0x16, 0x9d, 0x88, 0xc0, 0xfe, 0x55, 0x81, 0xd9, 0xf1, 0x49, 0xec, 0x02, 0xd0, 0x1f, 0x87, 0x29,
-	0xcd, 0xb2, 0x30, 0x89, 0xb9, 0xb1, 0xe6, 0xd6, 0x1c, 0x7f, 0x2c, 0x79, 0xda, 0xed, 0xbe, 0x72,
-	0x58, 0x21, 0x92, 0x0b, 0x7f, 0x0a, 0x5a, 0x3f, 0xbe, 0xe1, 0xdd, 0xd4, 0xdc, 0xba, 0x64, 0xce,
-	0x46, 0x6e, 0xc6, 0xb9, 0x2d, 0x8a, 0xca, 0xc6, 0xde, 0x63, 0x8b, 0x22, 0xaf, 0x26, 0xb5, 0x66,
-	0xff, 0xa6, 0x00, 0x4c, 0x8c, 0xf8, 0x0b, 0x40, 0x41, 0x18, 0xf7, 0xf9, 0x98, 0x68, 0xda, 0xcf,
-	0x27, 0x35, 0x22, 0xc7, 0xe3, 0xa0, 0x33, 0xd2, 0x0f, 0x2b, 0x64, 0x21, 0xb8, 0x2b, 0xe1, 0x67,
-	0x60, 0xfc, 0xd0, 0x8f, 0x0a, 0x5a, 0x96, 0x6a, 0x3a, 0x6f, 0x58, 0x74, 0x58, 0x21, 0x42, 0xc6,
-	0xeb, 0x60, 0x66, 0x17, 0xfd, 0xa8, 0x9f, 0x96, 0x45, 0x56, 0x9d, 0x53, 0x1e, 0x1e, 0x56, 0x48,
-	0x09, 0xbc, 0xba, 0x3c, 0x1f, 0xfb, 0x73, 0xa8, 0x49, 0x6d, 0xe0, 0xe7, 0x50, 0x9b, 0xc0, 0xcc,
-	0x52, 0xd6, 0xb4, 0xa9, 0xe9, 0x11, 0x99, 0xdb, 0xbb, 0x00, 0xe3, 0xc1, 0x67, 0x78, 0x13, 0x60,
-	0xdc, 0xf7, 0xe8, 0xac, 0x7c, 0xa7, 0x12, 0xb5, 0xbf, 0x03, 0x53, 0x54, 0x86, 0x9f, 0x80, 0x1e,
-	0x24, 0x49, 0xc4, 0xa7, 0x30, 0x33, 0x9a, 0x23, 0x97, 0xf0, 0x63, 0xd0, 0x8a, 0xe6, 0x0e, 0x6f,
-	0x45, 0x67, 0x44, 0x65, 0x13, 0x2e, 0x9a, 0x3b, 0x1c, 0x34, 0x5c, 0xbe, 0x36, 0x73, 0x0c, 0x68,
-	0x1c, 0x34, 0x5c, 0xaf, 0x5a, 0xce, 0xc7, 0xfe, 0x59, 0x81, 0x85, 0xa9, 0x79, 0xe2, 0x55, 0xd0,
-	0x23, 0x7a, 0x99, 0x3f, 0xb0, 0x13, 0x84, 0x03, 0xdc, 0x80, 0xf9, 0xf1, 0xe5, 0xf4, 0x2e, 0x92,
-	0x81, 0x18, 0xf3, 0xbc, 0x3b, 0x37, 0xbe, 0x9a, 0x17, 0xc9, 0x80, 0x92, 0x7a, 0x20, 0x45, 0x78,
-	0x1d, 0x8c, 0x34, 0xbc, 0x7a, 0x9b, 0x97, 0x13, 0xbf, 0x93, 0x56, 0x10, 0xdb, 0x03, 0x83, 0xdf,
-	0x13, 0xeb, 0xf5, 0xfb, 0xe2, 0x7a, 0x58, 0x56, 0x60, 0x38, 0x5f, 0x15, 0xd7, 0x43, 0xc2, 0x25,
-	0xfc, 0x0c, 0xf4, 0xfc, 0x66, 0x38, 0x7a, 0x23, 0x88, 0x8b, 0xed, 0xde, 0x0c, 0x29, 0xe1, 0xba,
-	0xfd, 0xbb, 0x02, 0x3a, 0xb3, 0x63, 0x0b, 0xcc, 0xe4, 0xf2, 0x32, 0xa3, 0xe5, 0x47, 0xc0, 0x6e,
-	0x56, 0xc4, 0x78, 0x19, 0x8c, 0xa2, 0xb9, 0x13, 0x89, 0x1c, 0x0c, 0x88, 0xb0, 0xd4, 0x03, 0x2a,
-	0x06, 0x59, 0xea, 0x81, 0xd0, 0x1b, 0x6e, 0x24, 0x3e, 0x3f, 0xa1, 0xb3, 0xb0, 0xd4, 0x03, 0x6a,
-	0x19, 0x92, 0x5e, 0xfa, 0xb7, 0x9b, 0x11, 0xb5, 0xcc, 0xb1, 0xce, 0xc2, 0x52, 0x0f, 0xa8, 0x55,
-	0x95, 0xf4, 0x80, 0x62, 0x04, 0x6a, 0xb1, 0x6b, 0xcd, 0x94, 0xa2, 0x5a, 0xec, 0x7a, 0xa6, 0xe8,
-	0x7f, 0xf3, 0x17, 0x05, 0x80, 0xd0, 0xbc, 0x48, 0x63, 0xd6, 0x21, 0x7e, 0x0c, 0x8b, 0xc4, 0xef,
-	0x9e, 0x91, 0x76, 0xaf, 0x7b, 0x7e, 0xec, 0xf7, 0xce, 0xda, 0xaf, 0xdb, 0x9d, 0xaf, 0xdb, 0xa8,
-	0x82, 0x97, 0x00, 0xc9, 0xc0, 0xeb, 0x74, 0x5a, 0x48, 0xc1, 0x8b, 0xb0, 0x70, 0xc7, 0xde, 0xdc,
-	0x41, 0xea, 0x3d, 0xb1, 0xe1, 0x22, 0xed, 0x9e, 0xb8, 0xdd, 0x44, 0x3a, 0xc6, 0x30, 0x7f, 0x47,
-	0xdc, 0x45, 0xc6, 0x66, 0x07, 0xc0, 0x8f, 0x07, 0x61, 0x3f, 0x8e, 0x69, 0x96, 0xe1, 0x65, 0xc0,
-	0x7e, 0xfb, 0xe5, 0xd1, 0x41, 0xbb, 0xed, 0x9f, 0x9e, 0x4a, 0xe5, 0x7c, 0x04, 0x8f, 0x24, 0xbd,
-	0x75, 0xd4, 0xed, 0xb6, 0x7c, 0xa4, 0xb0, 0x84, 0x92, 0xec, 0x1d, 0xbd, 0x42, 0xea, 0xe6, 0xdf,
-	0x0a, 0xd4, 0xe5, 0xa5, 0xc1, 0xab, 0xb0, 0xec, 0x1d, 0xb5, 0x0f, 0xc8, 0x79, 0xaf, 0x73, 0xdc,
-	0x7b, 0xd1, 0x79, 0x29, 0xb5, 0xb9, 0xa2, 0xdd, 0xee, 0x57, 0xf0, 0x0a, 0x3c, 0x9a, 0x32, 0xf8,
-	0x27, 0x48, 0x61, 0x4c, 0xc1, 0x1f, 0x03, 0x9e, 0x62, 0x6d, 0xff, 0x04, 0xa9, 0x02, 0x3e, 0x85,
-	0xc5, 0x29, 0xd8, 0xf2, 0x4f, 0x4f, 0x91, 0x26, 0xe8, 0xfd, 0xf7, 0x32, 0xca, 0x72, 0xeb, 0xef,
-	0x33, 0xbc, 0x22, 0xfe, 0x41, 0xd7, 0x27, 0xc8, 0x10, 0x06, 0x1b, 0x9e, 0x3c, 0x6c, 0x60, 0x49,
-	0x4c, 0xee, 0xd9, 0xfc, 0x47, 0x81, 0xd9, 0xf1, 0xc6, 0xb2, 0x72, 0xdf, 0x1c, 0xb4, 0xce, 0xfc,
-	0xa9, 0xeb, 0x14, 0x7d, 0x3e, 0x05, 0x24, 0xc3, 0xe6, 0x0e, 0x9b, 0xe1, 0x8a, 0x79, 0xbb, 0xaf,
-	0xfe, 0xc1, 0x9b, 0x99, 0xa2, 0x9e, 0x8f, 0xd4, 0x92, 0xaa, 0xd3, 0xb4, 0xe1, 0xb6, 0x7c, 0xa4,
-	0x31, 0xaa, 0x3d, 0x70, 0xb6, 0xe1, 0x7a, 0x3e, 0xd2, 0x4b, 0x7a, 0xef, 0xec, 0x76, 0xb3, 0xe5,
-	0x23, 0x83, 0x51, 0xfd, 0x81, 0xb3, 0xdb, 0x4d, 0xcf, 0x47, 0x66, 0x49, 0x55, 0xbc, 0x0c, 0x73,
-	0x32, 0xdd, 0x45, 0x55, 0xd6, 0x8b, 0xb1, 0xd7, 0x82, 0x5a, 0xca, 0xd7, 0xb8, 0xc7, 0xbe, 0x51,
-	0xfc, 0x89, 0x23, 0xfe, 0xab, 0xce, 0xe8, 0xbf, 0xea, 0x7c, 0x19, 0xd2, 0x68, 0x50, 0xfe, 0xbd,
-	0xac, 0x3f, 0xab, 0xfc, 0xdb, 0xae, 0x39, 0x93, 0xd5, 0x27, 0x90, 0x8e, 0x9f, 0xf7, 0x5e, 0x03,
-	0xd0, 0xc9, 0x12, 0xfe, 0x4f, 0xb2, 0xbf, 0x46, 0xc9, 0x26, 0x7b, 0x4b, 0xa4, 0xe3, 0x7b, 0xe7,
-	0x80, 0x68, 0x5c, 0x5c, 0xf7, 0xe4, 0xfa, 0xd6, 0xef, 0xa5, 0xf4, 0xe3, 0xe2, 0x9a, 0xdf, 0xdb,
-	0x7f, 0xd5, 0x38, 0xcf, 0x12, 0x4d, 0xe2, 0xbd, 0x6f, 0x60, 0x81, 0xa7, 0x96, 0x8a, 0xfd, 0x80,
-	0xcc, 0x0f, 0x15, 0xcc, 0x33, 0x4f, 0x62, 0xcf, 0xfc, 0x56, 0x0f, 0xc2, 0xf8, 0x5d, 0x60, 0xf2,
-	0x34, 0x8d, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x4b, 0x1d, 0xbb, 0xeb, 0xc1, 0x08, 0x00, 0x00,
+	// 1927 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x58, 0x4b, 0x73, 0x1a, 0xd9,
+	0x15, 0x56, 0xf3, 0x68, 0xe0, 0x34, 0x8f, 0xab, 0x6b, 0x5b, 0x6e, 0x33, 0x8e, 0x2d, 0x77, 0xe2,
+	0x94, 0xa2, 0xcc, 0xb4, 0x47, 0x40, 0x51, 0x2a, 0x55, 0xa2, 0x0a, 0x2d, 0xb5, 0x24, 0x62, 0x0c,
+	0xf6, 0x15, 0x38, 0x56, 0x36, 0x14, 0x88, 0x2b, 0xdc, 0x33, 0xa8, 0xc1, 0x0d, 0x9d, 0x1a, 0x2a,
+	0x55, 0xa9, 0xca, 0x36, 0xdb, 0xec, 0xb2, 0x4a, 0xe5, 0x9f, 0x64, 0xa7, 0xca, 0x6a, 0x96, 0xd9,
+	0x65, 0x95, 0x45, 0xf2, 0x2b, 0xa6, 0xee, 0xa3, 0xa1, 0x69, 0xd0, 0xcc, 0xac, 0xe0, 0x7c, 0xdf,
+	0x79, 0xdf, 0x73, 0x1f, 0xd5, 0xa0, 0x7d, 0xf2, 0xa9, 0x37, 0x37, 0x27, 0xde, 0x78, 0x36, 0x2e,
+	0x7e, 0x3e, 0xfb, 0xe8, 0x78, 0x83, 0xee, 0xa4, 0xe7, 0xcd, 0xe6, 0xaf, 0x86, 0xe3, 0xf1, 0x70,
+	0x44, 0x5f, 0x71, 0xa6, 0xef, 0xdf, 0xbc, 0x1a, 0xd0, 0xe9, 0xb5, 0xe7, 0x4c, 0x66, 0x63, 0x4f,
+	0x68, 0x1b, 0xff, 0x55, 0x20, 0xf9, 0x8e, 0x59, 0xe3, 0x87, 0x90, 0x9c, 0xce, 0x7a, 0xde, 0x4c,
+	0x57, 0x76, 0x95, 0xbd, 0x2c, 0x11, 0x02, 0x46, 0x10, 0xa7, 0xee, 0x40, 0x8f, 0x71, 0x8c, 0xfd,
+	0xc5, 0x5f, 0x40, 0x8e, 0x87, 0xeb, 0x8e, 0x27, 0x33, 0x67, 0xec, 0x4e, 0xf5, 0xf8, 0xae, 0xb2,
+	0xa7, 0x95, 0xd2, 0x66, 0x4b, 0xc8, 0x24, 0xcb, 0x69, 0x29, 0xe1, 0x3d, 0xc8, 0x4c, 0x3c, 0x3a,
+	0x70, 0xae, 0x7b, 0x33, 0xaa, 0x27, 0xb8, 0x2a, 0x98, 0x6f, 0x03, 0x84, 0x2c, 0x49, 0xfc, 0x4b,
+	0x80, 0x89, 0x37, 0xfe, 0x8a, 0x5e, 0x33, 0x43, 0x3d, 0xb9, 0x1b, 0xdf, 0xd3, 0x4a, 0x9a, 0xf9,
+	0x76, 0x01, 0x91, 0x10, 0x8d, 0x4d, 0xd0, 0x7a, 0xc3, 0xa1, 0x47, 0x87, 0x3d, 0xae, 0xad, 0x72,
+	0xc7, 0x59, 0xb3, 0xb6, 0xc4, 0x48, 0x58, 0xc1, 0xf8, 0x0d, 0xc0, 0xd2, 0x13, 0x7e, 0x0a, 0xc9,
+	0x3f, 0xf4, 0x46, 0x3e, 0xe5, 0xb5, 0x6a, 0x25, 0xd5, 0x7c, 0xcf, 0x24, 0x22, 0x40, 0xd6, 0x89,
+	0xde, 0xc8, 0xe9, 0x4d, 0x79, 0xd5, 0x19, 0x22, 0x04, 0xe3, 0x39, 0xa4, 0x82, 0x9a, 0x1e, 0x42,
+	0x72, 0xe4, 0xdc, 0x3a, 0xa2, 0x55, 0x09, 0x22, 0x04, 0xa3, 0x07, 0x5a, 0x28, 0x3c, 0xfe, 0x39,
+	0xa4, 0x87, 0xde, 0xd8, 0x9f, 0x74, 0xfb, 0x73, 0x5d, 0x59, 0x2f, 0x26, 0xc5, 0x49, 0x6b, 0x8e,
+	0xf7, 0x01, 0x82, 0x44, 0x29, 0x0b, 0x19, 0xe7, 0x1d, 0x0a, 0x3c, 0x51, 0x12, 0x62, 0x8d, 0x3f,
+	0x41, 0x66, 0x41, 0x60, 0x13, 0xd2, 0x37, 0xbe, 0x2b, 0xba, 0xc5, 0x12, 0xc9, 0x97, 0xf0, 0xd2,
+	0xec, 0x4c, 0x32, 0x64, 0xa1, 0xc3, 0xfa, 0x4b, 0xbf, 0x99, 0x78, 0x74, 0x3a, 0x65, 0x16, 0x31,
+	0x5e, 0xb9, 0x66, 0xda, 0x0b, 0x88, 0x84, 0xe8, 0x65, 0x0f, 0xe2, 0xe1, 0x1e, 0xfc, 0x4d, 0x81,
+	0xcc, 0x62, 0xed, 0x70, 0x69, 0xc5, 0xa1, 0xb2, 0xe6, 0xd0, 0x8a, 0xdf, 0x1d, 0x2b, 0x17, 0x5b,
+	0x2b, 0x7e, 0x5f, 0x42, 0xbc, 0xe7, 0xce, 0x65, 0xf4, 0x6c, 0x48, 0x79, 0x1a, 0x68, 0x33, 0x9e,
+	0xab, 0x8d, 0x46, 0x7a, 0xfc, 0xfb, 0xd4, 0x46, 0x23, 0x4b, 0x0b, 0x0d, 0x97, 0xf1, 0x6f, 0x05,
+	0x60, 0xa9, 0x88, 0x7f, 0x0d, 0xa8, 0xef, 0xb8, 0x3d, 0x3e, 0xa8, 0xd4, 0xeb, 0xcd, 0x96, 0x39,
+	0x22, 0xd3, 0xe2, 0x44, 0x2b, 0xc0, 0x2f, 0xb6, 0x48, 0xa1, 0xbf, 0x0a, 0xe1, 0x67, 0xc1, 0x88,
+	0xc4, 0xc2, 0x23, 0x72, 0xb1, 0x15, 0x0c, 0xc9, 0x0b, 0x50, 0xa7, 0xd7, 0xbd, 0x51, 0xcf, 0x93,
+	0x49, 0xa6, 0xcc, 0x4b, 0x2e, 0x5e, 0x6c, 0x11, 0x49, 0xe0, 0x23, 0x28, 0xf8, 0x91, 0x04, 0xc4,
+	0x06, 0x28, 0x98, 0x9d, 0x68, 0xfc, 0xbc, 0xbf, 0x82, 0x58, 0xd9, 0x70, 0x6f, 0x8d, 0x5f, 0x81,
+	0x16, 0x6a, 0x01, 0xfe, 0x02, 0xb4, 0x25, 0x39, 0x5d, 0x4c, 0x57, 0x68, 0x29, 0xc3, 0xbc, 0x71,
+	0xc8, 0x66, 0x5f, 0x76, 0x69, 0xca, 0xe6, 0x6d, 0xd1, 0xb3, 0xc0, 0x36, 0xbc, 0x23, 0x43, 0xac,
+	0xf1, 0x1f, 0x05, 0x54, 0x51, 0x16, 0x7e, 0x02, 0x89, 0xfe, 0x78, 0x3c, 0xe2, 0x2d, 0x4c, 0x07,
+	0x8b, 0xc0, 0x21, 0xfc, 0x18, 0xe2, 0x7e, 0xb5, 0xc2, 0xfb, 0x90, 0x60, 0x4c, 0x8c, 0x2d, 0x8f,
+	0x5f, 0xad, 0x70, 0xa2, 0x5c, 0xe2, 0x45, 0xe7, 0x18, 0x11, 0xe7, 0x44, 0xb9, 0xc4, 0x08, 0xa7,
+	0x5a, 0xd1, 0x93, 0xbb, 0xca, 0x1e, 0x66, 0x84, 0xca, 0x08, 0x47, 0x58, 0x38, 0xe5, 0x12, 0xdf,
+	0xce, 0xdb, 0x8c, 0x48, 0x71, 0x42, 0x58, 0xdc, 0x94, 0x4b, 0x7a, 0x6a, 0x57, 0xd9, 0x8b, 0x31,
+	0x02, 0x18, 0x71, 0x23, 0x89, 0x6a, 0x45, 0x4f, 0xef, 0x2a, 0x7b, 0x0a, 0x23, 0x34, 0x4e, 0x54,
+	0x2b, 0xf8, 0x33, 0x48, 0xf6, 0xe7, 0xac, 0xc4, 0x0c, 0x3b, 0xbb, 0x18, 0x95, 0x65, 0xab, 0xc7,
+	0x31, 0x2b, 0x25, 0x57, 0xd7, 0xf8, 0x8b, 0x02, 0x85, 0xc8, 0x34, 0xe0, 0xe7, 0x90, 0x18, 0xd1,
+	0x9b, 0xd9, 0x86, 0x89, 0x26, 0x9c, 0xc0, 0x65, 0xc8, 0x2f, 0x46, 0xab, 0x7b, 0x3d, 0x1e, 0x88,
+	0x21, 0xc9, 0x97, 0x72, 0x8b, 0xc1, 0x3a, 0x19, 0x0f, 0x28, 0xc9, 0xf6, 0x43, 0x12, 0x7e, 0x01,
+	0x49, 0xcf, 0x19, 0x7e, 0x9c, 0xe9, 0xf1, 0x75, 0xb7, 0x82, 0x31, 0x1c, 0xc8, 0xaf, 0x0e, 0x06,
+	0xfe, 0x12, 0x72, 0xfe, 0x4a, 0x20, 0xb1, 0xd1, 0xb3, 0x66, 0x67, 0xe9, 0x99, 0x68, 0x7e, 0x28,
+	0xcc, 0x4b, 0x48, 0xf1, 0x71, 0x93, 0x87, 0x76, 0x24, 0x50, 0xc0, 0x19, 0x7f, 0x84, 0x24, 0x1f,
+	0x68, 0xb6, 0xae, 0x5f, 0xf9, 0xb7, 0x13, 0x59, 0x6c, 0xd2, 0xfc, 0xad, 0x7f, 0x3b, 0x21, 0x1c,
+	0xc2, 0xcf, 0x20, 0x31, 0x9b, 0x4f, 0x82, 0xe2, 0x40, 0xec, 0x80, 0xf6, 0x7c, 0x42, 0x09, 0xc7,
+	0xf1, 0x73, 0x50, 0x67, 0x3d, 0x6f, 0x48, 0x45, 0x49, 0xf9, 0x52, 0xca, 0x6c, 0x73, 0x91, 0x48,
+	0x18, 0xef, 0x80, 0x3a, 0xa2, 0xee, 0x70, 0xf6, 0x91, 0x8f, 0x40, 0x82, 0x48, 0xc9, 0xf8, 0x97,
+	0x02, 0x09, 0x16, 0x07, 0xeb, 0xa0, 0x8e, 0x6f, 0x6e, 0xa6, 0x54, 0x9e, 0xa4, 0x6c, 0xef, 0x08,
+	0x19, 0xef, 0x40, 0xd2, 0xaf, 0x56, 0x46, 0x22, 0x38, 0x23, 0x84, 0x28, 0xf1, 0x3e, 0xd5, 0xe3,
+	0x21, 0xbc, 0x2f, 0xf0, 0x72, 0x69, 0x24, 0xae, 0x18, 0x81, 0x33, 0x51, 0xe2, 0x7d, 0xaa, 0x27,
+	0x43, 0xb8, 0xd4, 0x3f, 0xa8, 0x8e, 0xa8, 0xae, 0x2e, 0x70, 0x26, 0x4a, 0xbc, 0x4f, 0xf5, 0x54,
+	0x08, 0xef, 0x53, 0x8c, 0x20, 0xe6, 0x1f, 0xea, 0x69, 0x09, 0xc6, 0xfc, 0x43, 0x4b, 0x15, 0x8d,
+	0x33, 0xde, 0x81, 0x4a, 0xe8, 0xf5, 0xd8, 0x1b, 0xb0, 0xbb, 0xf2, 0x6b, 0x3a, 0x97, 0xf7, 0x27,
+	0xfb, 0x8b, 0x1f, 0xca, 0x31, 0x93, 0xf7, 0xa7, 0x10, 0x58, 0xdf, 0x6e, 0x1c, 0x3a, 0x1a, 0xb0,
+	0xc3, 0x35, 0x1e, 0x3a, 0x3a, 0x88, 0x84, 0x8d, 0x97, 0x90, 0x3b, 0x19, 0xfb, 0xee, 0x8c, 0xd0,
+	0xe9, 0x64, 0xec, 0x4e, 0xf9, 0x8d, 0x74, 0xcd, 0x80, 0xe0, 0xc2, 0xe1, 0x82, 0xd1, 0x81, 0x24,
+	0xe9, 0xb9, 0x43, 0xfa, 0xa3, 0xaf, 0xee, 0x9f, 0x41, 0xfe, 0xd6, 0x71, 0xbb, 0x5f, 0xd3, 0x79,
+	0x57, 0xae, 0x0b, 0xeb, 0x62, 0x8e, 0x64, 0x6f, 0x1d, 0xf7, 0x35, 0x9d, 0x37, 0xc4, 0xea, 0x5c,
+	0x41, 0xc1, 0xfe, 0x66, 0x32, 0xea, 0x39, 0xee, 0x22, 0xfe, 0x33, 0x50, 0x3d, 0x16, 0x29, 0x38,
+	0x2f, 0x54, 0x93, 0x07, 0x26, 0x12, 0x65, 0x77, 0x9d, 0x47, 0xa7, 0xce, 0xc0, 0xef, 0x8d, 0xe4,
+	0xd4, 0x85, 0x4f, 0x94, 0x05, 0x67, 0x94, 0x20, 0x57, 0x77, 0xa7, 0xd4, 0x9b, 0x11, 0xfa, 0xc9,
+	0xa7, 0xd3, 0x19, 0x7e, 0x01, 0x29, 0x8f, 0x37, 0x2f, 0xf0, 0x9c, 0x32, 0x45, 0x33, 0x49, 0x80,
+	0x1b, 0x9f, 0x43, 0x3e, 0xb0, 0x91, 0xd9, 0x14, 0x21, 0xed, 0x70, 0x84, 0x0e, 0x64, 0x43, 0x16,
+	0xf2, 0xfe, 0x3f, 0x62, 0x00, 0x84, 0xce, 0x7c, 0xcf, 0x65, 0x83, 0x8a, 0x1f, 0xc3, 0x03, 0x62,
+	0xb7, 0x3b, 0xa4, 0xd9, 0x6d, 0x5f, 0xbd, 0xb5, 0xbb, 0x9d, 0xe6, 0xeb, 0x66, 0xeb, 0x77, 0x4d,
+	0xb4, 0x85, 0x1f, 0x02, 0x0a, 0x13, 0x56, 0xab, 0xd5, 0x40, 0x0a, 0x7e, 0x00, 0x85, 0x15, 0xf5,
+	0x6a, 0x05, 0xc5, 0xd6, 0xc0, 0x72, 0x09, 0xc5, 0xd7, 0xc0, 0x83, 0x2a, 0x4a, 0x60, 0x0c, 0xf9,
+	0x15, 0xf0, 0x10, 0x25, 0xa3, 0x8a, 0xf5, 0x6a, 0x05, 0xa9, 0x6b, 0x60, 0xb9, 0x84, 0x52, 0x6b,
+	0xe0, 0x41, 0x15, 0xa5, 0xa3, 0x2e, 0xeb, 0x87, 0x28, 0x13, 0x55, 0x3c, 0x2b, 0x97, 0x10, 0xac,
+	0x81, 0xd5, 0x0a, 0xd2, 0xf0, 0x23, 0xd8, 0x5e, 0xa9, 0xf2, 0xaa, 0x6d, 0x5f, 0xa2, 0xec, 0x7e,
+	0x0b, 0xc0, 0x76, 0x07, 0x4e, 0xcf, 0x75, 0xe9, 0x74, 0x8a, 0x77, 0x00, 0xdb, 0xcd, 0xd3, 0x7a,
+	0xad, 0xd9, 0xb4, 0x2f, 0x2f, 0x43, 0x2d, 0x7a, 0x04, 0xdb, 0x21, 0xbc, 0x51, 0x6f, 0xb7, 0x1b,
+	0x36, 0x52, 0x58, 0x46, 0x21, 0xd8, 0xaa, 0x9f, 0xa3, 0xd8, 0xfe, 0x3f, 0x15, 0xd8, 0x5e, 0x7b,
+	0x7a, 0xe0, 0x67, 0x50, 0xac, 0x9d, 0x9f, 0x13, 0xfb, 0xbc, 0xd6, 0xb6, 0xbb, 0x67, 0x9d, 0xe6,
+	0x49, 0xbb, 0xde, 0x6a, 0x86, 0x02, 0x3c, 0x05, 0x7d, 0x03, 0x7f, 0xd2, 0xea, 0x34, 0xdb, 0x48,
+	0xc1, 0x45, 0xd8, 0xd9, 0xc0, 0x5e, 0x76, 0xde, 0xa0, 0xd8, 0x3d, 0xdc, 0x9b, 0x7a, 0x13, 0xc5,
+	0xef, 0xe3, 0x6a, 0x1f, 0x50, 0xe2, 0x1e, 0xae, 0xf6, 0xfe, 0x1c, 0x25, 0xf7, 0xff, 0x9e, 0x80,
+	0x6c, 0xf8, 0xf8, 0xc6, 0xcf, 0x61, 0xc7, 0xaa, 0x37, 0x6b, 0xe4, 0xaa, 0xdb, 0x7a, 0xdb, 0x3d,
+	0x69, 0x9d, 0x86, 0xc6, 0xa7, 0x18, 0xbf, 0x3b, 0xde, 0xc2, 0x45, 0xd8, 0x8e, 0x28, 0xd8, 0xef,
+	0x90, 0xc2, 0x38, 0x05, 0x7f, 0x06, 0x38, 0xc2, 0x35, 0xed, 0x77, 0x28, 0x26, 0xc8, 0xa7, 0xf0,
+	0x20, 0x42, 0x36, 0xec, 0xcb, 0x4b, 0x14, 0x17, 0xec, 0x7a, 0x5c, 0xc6, 0x32, 0xdf, 0x89, 0xfb,
+	0x14, 0xce, 0x89, 0x5d, 0x6b, 0xdb, 0x04, 0x25, 0x85, 0x82, 0x01, 0x4f, 0x36, 0x2b, 0x30, 0x27,
+	0xaa, 0xd0, 0xd9, 0x59, 0x4b, 0xb0, 0x76, 0x7a, 0x8a, 0x52, 0x1b, 0xf0, 0xcb, 0x8e, 0x85, 0xd2,
+	0x1b, 0xf0, 0x37, 0x9d, 0x06, 0xca, 0x6c, 0xc0, 0x4f, 0xeb, 0xef, 0x11, 0x6c, 0xd2, 0x6f, 0x9d,
+	0x22, 0x8d, 0x2d, 0x41, 0x04, 0xb7, 0xea, 0xed, 0x6e, 0xad, 0x79, 0x8a, 0xb2, 0xf8, 0x09, 0x3c,
+	0xda, 0xc0, 0xb5, 0x08, 0xca, 0xdd, 0x63, 0xf6, 0xa1, 0x45, 0x50, 0x7e, 0x53, 0xca, 0x17, 0x0d,
+	0x54, 0xd8, 0x88, 0x13, 0x84, 0x36, 0xac, 0x0d, 0x0b, 0xbf, 0x2d, 0xfa, 0xb2, 0xbe, 0xa8, 0x2d,
+	0x82, 0x30, 0xe7, 0xf6, 0xeb, 0xa0, 0x85, 0xee, 0x5d, 0xfc, 0x0c, 0x1e, 0x75, 0x7e, 0x60, 0x3e,
+	0x56, 0xf9, 0x66, 0xab, 0x2d, 0xe7, 0x63, 0x7f, 0x1f, 0x54, 0x71, 0x59, 0x62, 0x04, 0xd9, 0x76,
+	0x8d, 0x9c, 0xdb, 0xed, 0xee, 0xfb, 0x5a, 0xa3, 0x63, 0xa3, 0x2d, 0x9c, 0x07, 0x90, 0xc8, 0x6b,
+	0xfb, 0x0a, 0x29, 0xfb, 0x7f, 0x56, 0x21, 0xb3, 0xb8, 0x7b, 0x59, 0xf6, 0x5c, 0x31, 0x72, 0xa2,
+	0x89, 0x90, 0x4f, 0x01, 0x85, 0xc9, 0x6a, 0x85, 0x6d, 0xd9, 0xa2, 0x7a, 0x77, 0x1c, 0xfb, 0xf6,
+	0x58, 0x59, 0x67, 0x2d, 0x1b, 0xc5, 0x24, 0x1b, 0x8b, 0xb2, 0xe5, 0x52, 0xc3, 0x46, 0x71, 0xc6,
+	0xc6, 0x37, 0xd8, 0x96, 0x4b, 0x96, 0x8d, 0x12, 0x92, 0x5d, 0xb3, 0x3d, 0xa8, 0x36, 0x6c, 0x94,
+	0x64, 0x6c, 0x62, 0x83, 0xed, 0x41, 0xd5, 0xb2, 0x91, 0x2a, 0xd9, 0x18, 0xde, 0x81, 0x5c, 0x98,
+	0x3d, 0x44, 0x29, 0x56, 0x4b, 0x32, 0x62, 0x55, 0xe7, 0xb5, 0xa4, 0x99, 0x95, 0xba, 0xee, 0xb3,
+	0xce, 0x6b, 0xc9, 0x14, 0xd5, 0x6f, 0x8f, 0x63, 0x77, 0xc7, 0x6a, 0x94, 0xe5, 0xb5, 0x00, 0xb3,
+	0x4d, 0x6d, 0xb0, 0xe5, 0xb5, 0x68, 0xd2, 0x36, 0x15, 0x65, 0x79, 0x2d, 0x59, 0x66, 0x9b, 0xde,
+	0x60, 0xcb, 0x6b, 0xc9, 0x49, 0xdb, 0x74, 0xa4, 0x96, 0xfa, 0x21, 0xca, 0xb3, 0x5a, 0x32, 0x11,
+	0xab, 0x33, 0x9e, 0x4f, 0x81, 0xf9, 0x84, 0x75, 0x9f, 0x67, 0x3c, 0x1f, 0x24, 0xd9, 0x68, 0x6f,
+	0xcf, 0x78, 0x1f, 0xb6, 0x59, 0x44, 0xe5, 0xee, 0x58, 0x5b, 0x67, 0x2d, 0x1b, 0x61, 0x99, 0x8f,
+	0x86, 0x9f, 0xac, 0xb0, 0xe2, 0xfc, 0x7f, 0xc0, 0x52, 0xca, 0xb2, 0x43, 0x28, 0x4a, 0xb1, 0xe6,
+	0x3f, 0x14, 0xac, 0x01, 0x3b, 0xeb, 0x2c, 0x6f, 0xc5, 0x23, 0x19, 0xfa, 0x5e, 0x1d, 0xcb, 0x46,
+	0x3b, 0x2c, 0xf9, 0x2c, 0x4b, 0x7e, 0xa3, 0x0e, 0x2f, 0xff, 0xb1, 0xd4, 0x51, 0xee, 0xd3, 0xb1,
+	0x6c, 0xa4, 0xcb, 0x42, 0xb2, 0xa5, 0xbf, 0x2a, 0x90, 0xb0, 0x1c, 0xf7, 0x13, 0x2e, 0x06, 0xdf,
+	0x2b, 0x54, 0x93, 0xff, 0x16, 0x83, 0x17, 0xc3, 0x97, 0xec, 0x60, 0x4c, 0xf2, 0x77, 0xd3, 0x82,
+	0xcb, 0x9b, 0xab, 0xef, 0xa8, 0x9f, 0x42, 0x4a, 0x3e, 0x6d, 0x16, 0x2a, 0xc8, 0x8c, 0x3e, 0x76,
+	0x7e, 0x01, 0xaa, 0x78, 0x70, 0xe0, 0xbc, 0xb9, 0xf2, 0x5a, 0x29, 0x16, 0xcc, 0xd5, 0x97, 0xc8,
+	0x51, 0x03, 0x34, 0x8f, 0x3f, 0x36, 0xba, 0xfc, 0x41, 0xfc, 0x13, 0x53, 0x7c, 0x6f, 0x31, 0x83,
+	0xef, 0x2d, 0xe6, 0x19, 0x7b, 0xd0, 0xc9, 0xcf, 0x06, 0xfa, 0xff, 0x52, 0xfc, 0x99, 0xac, 0x99,
+	0xcb, 0x07, 0x0a, 0x01, 0x6f, 0xf1, 0xff, 0xe8, 0x35, 0x00, 0x5d, 0x5e, 0xcb, 0x3f, 0xe0, 0xec,
+	0xff, 0x81, 0xb3, 0xe5, 0x4d, 0x4e, 0x42, 0xe6, 0x47, 0x57, 0x80, 0xa8, 0xeb, 0xdf, 0x76, 0xc3,
+	0xf9, 0xbd, 0x58, 0x73, 0x69, 0xbb, 0xfe, 0x2d, 0x3f, 0x5a, 0xbe, 0x2f, 0xc7, 0x3c, 0x73, 0xb4,
+	0x94, 0x8f, 0x3e, 0x40, 0x81, 0xbb, 0x0e, 0x25, 0xfb, 0x23, 0x3c, 0x6f, 0x4a, 0x98, 0x7b, 0x5e,
+	0xca, 0x96, 0xfa, 0xfb, 0x44, 0xdf, 0x71, 0x3f, 0xf5, 0x55, 0xee, 0xa6, 0xfc, 0xdd, 0x00, 0xe6,
+	0x58, 0x37, 0x5b, 0xd9, 0x12, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// BinqClient is the client API for Binq service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type BinqClient interface {
+	// Query streams the records that match a query, in order of their keys.
+	Query(ctx context.Context, in *Query, opts ...grpc.CallOption) (Binq_QueryClient, error)
+	// Count counts the records that match a query.
+	Count(ctx context.Context, in *Query, opts ...grpc.CallOption) (*CountResponse, error)
+	// Explain describes how a query is executed, without executing it.
+	Explain(ctx context.Context, in *Query, opts ...grpc.CallOption) (*ExplainResponse, error)
+	// Insert inserts records into the database.
+	Insert(ctx context.Context, in *InsertRequest, opts ...grpc.CallOption) (*InsertResponse, error)
+}
+
+type binqClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewBinqClient(cc *grpc.ClientConn) BinqClient {
+	return &binqClient{cc}
+}
+
+func (c *binqClient) Query(ctx context.Context, in *Query, opts ...grpc.CallOption) (Binq_QueryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Binq_serviceDesc.Streams[0], "/Binq/Query", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &binqQueryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Binq_QueryClient interface {
+	Recv() (*Record, error)
+	grpc.ClientStream
+}
+
+type binqQueryClient struct {
+	grpc.ClientStream
+}
+
+func (x *binqQueryClient) Recv() (*Record, error) {
+	m := new(Record)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *binqClient) Count(ctx context.Context, in *Query, opts ...grpc.CallOption) (*CountResponse, error) {
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, "/Binq/Count", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *binqClient) Explain(ctx context.Context, in *Query, opts ...grpc.CallOption) (*ExplainResponse, error) {
+	out := new(ExplainResponse)
+	err := c.cc.Invoke(ctx, "/Binq/Explain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *binqClient) Insert(ctx context.Context, in *InsertRequest, opts ...grpc.CallOption) (*InsertResponse, error) {
+	out := new(InsertResponse)
+	err := c.cc.Invoke(ctx, "/Binq/Insert", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BinqServer is the server API for Binq service.
+type BinqServer interface {
+	// Query streams the records that match a query, in order of their keys.
+	Query(*Query, Binq_QueryServer) error
+	// Count counts the records that match a query.
+	Count(context.Context, *Query) (*CountResponse, error)
+	// Explain describes how a query is executed, without executing it.
+	Explain(context.Context, *Query) (*ExplainResponse, error)
+	// Insert inserts records into the database.
+	Insert(context.Context, *InsertRequest) (*InsertResponse, error)
+}
+
+func RegisterBinqServer(s *grpc.Server, srv BinqServer) {
+	s.RegisterService(&_Binq_serviceDesc, srv)
+}
+
+func _Binq_Query_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Query)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BinqServer).Query(m, &binqQueryServer{stream})
+}
+
+type Binq_QueryServer interface {
+	Send(*Record) error
+	grpc.ServerStream
+}
+
+type binqQueryServer struct {
+	grpc.ServerStream
+}
+
+func (x *binqQueryServer) Send(m *Record) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Binq_Count_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Query)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinqServer).Count(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Binq/Count",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinqServer).Count(ctx, req.(*Query))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Binq_Explain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Query)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinqServer).Explain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Binq/Explain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinqServer).Explain(ctx, req.(*Query))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Binq_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BinqServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Binq/Insert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BinqServer).Insert(ctx, req.(*InsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Binq_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "Binq",
+	HandlerType: (*BinqServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Count",
+			Handler:    _Binq_Count_Handler,
+		},
+		{
+			MethodName: "Explain",
+			Handler:    _Binq_Explain_Handler,
+		},
+		{
+			MethodName: "Insert",
+			Handler:    _Binq_Insert_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Query",
+			Handler:       _Binq_Query_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "query.proto",
 }
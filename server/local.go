@@ -0,0 +1,59 @@
+package server
+
+import (
+	"explodes/github.com/binq"
+	"explodes/github.com/binq/db3"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"time"
+)
+
+const (
+	// localBufferSize is the size of the in-memory connection buffers of a local server.
+	localBufferSize = 1 << 20
+)
+
+// LocalClient is a binq.BinqClient connected to a server running in process,
+// without a network, such as for tests.
+type LocalClient struct {
+	binq.BinqClient
+	// conn is the client connection to the server.
+	conn *grpc.ClientConn
+	// server serves the table.
+	server *grpc.Server
+}
+
+// Local serves a table in process and returns a client connected to it.
+// Closing the client stops the server.
+func Local(table *db3.Table) (*LocalClient, error) {
+	listener := bufconn.Listen(localBufferSize)
+	server := grpc.NewServer()
+	binq.RegisterBinqServer(server, New(table))
+	go func() {
+		// Serve returns when the server is stopped.
+		_ = server.Serve(listener)
+	}()
+
+	dial := func(string, time.Duration) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.Dial("bufconn", grpc.WithDialer(dial), grpc.WithInsecure())
+	if err != nil {
+		server.Stop()
+		return nil, errors.Wrap(err, "unable to dial local server")
+	}
+	return &LocalClient{
+		BinqClient: binq.NewBinqClient(conn),
+		conn:       conn,
+		server:     server,
+	}, nil
+}
+
+// Close closes the connection and stops the server.
+func (c *LocalClient) Close() error {
+	err := c.conn.Close()
+	c.server.Stop()
+	return errors.Wrap(err, "unable to close connection")
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"explodes/github.com/binq"
+	"explodes/github.com/binq/db3"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+)
+
+var _ binq.BinqServer = (*Server)(nil)
+
+// Server is a binq.BinqServer backed by a db3.Table.
+// Tables are not safe for concurrent use, so requests are served one at a time.
+type Server struct {
+	// mu guards table.
+	mu sync.Mutex
+	// table is the table to serve.
+	table *db3.Table
+}
+
+// New creates a Server for a table.
+func New(table *db3.Table) *Server {
+	return &Server{table: table}
+}
+
+// Query streams the records that match a query, in order of their keys.
+// If the query has a projection, only the fields it selects are sent.
+// If the query has an aggregation, the fields of each group are sent instead.
+// The records are read before any is sent, so a slow client does not hold up other requests.
+func (s *Server) Query(query *binq.Query, stream binq.Binq_QueryServer) error {
+	records, err := s.query(stream.Context(), query)
+	for _, record := range records {
+		if err := stream.Send(record); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// query reads the records to send for a query, along with those read before any error.
+// Keys and values are copied, since the pages of the table may change once it is unlocked.
+func (s *Server) query(ctx context.Context, query *binq.Query) ([]*binq.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if query.GetAggregation() != nil {
+		return s.aggregate(ctx, query)
+	}
+	var projector *binq.Projector
+	if len(query.GetProjection()) > 0 {
+		var err error
+		projector, err = binq.NewProjector(query.GetProjection())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid projection: %v", err)
+		}
+	}
+	it, err := s.execute(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var records []*binq.Record
+	for ; !it.End(); it.Next() {
+		key, value, err := it.Value()
+		if err != nil {
+			return records, statusError(ctx, err, "unable to get record")
+		}
+		record := &binq.Record{Key: append([]byte(nil), key...)}
+		if projector == nil {
+			record.Value = append([]byte(nil), value...)
+		} else if record.Fields, err = projector.Project(key, value); err != nil {
+			return records, statusError(ctx, err, "unable to project record")
+		}
+		records = append(records, record)
+	}
+	if err := it.Err(); err != nil {
+		return records, statusError(ctx, err, "unable to execute query")
+	}
+	return records, nil
+}
+
+// aggregate reads a record with the fields of each group of the records that match a query
+// with an aggregation. The limit of the query applies to the groups.
+func (s *Server) aggregate(ctx context.Context, query *binq.Query) ([]*binq.Record, error) {
+	if len(query.GetProjection()) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "a query cannot have both a projection and an aggregation")
+	}
+	aggregator, err := binq.NewAggregator(query.GetAggregation())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid aggregation: %v", err)
+	}
+	it, err := s.execute(ctx, &binq.Query{Start: query.GetStart(), End: query.GetEnd(), Predicate: query.GetPredicate()})
+	if err != nil {
+		return nil, err
+	}
+	for ; !it.End(); it.Next() {
+		key, value, err := it.Value()
+		if err != nil {
+			return nil, statusError(ctx, err, "unable to get record")
+		}
+		err = aggregator.Add(key, value)
+		if errors.Cause(err) == binq.ErrTooManyGroups {
+			return nil, status.Errorf(codes.ResourceExhausted, "aggregation has more than %d groups", binq.MaxGroups)
+		}
+		if err != nil {
+			return nil, statusError(ctx, err, "unable to aggregate record")
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, statusError(ctx, err, "unable to execute query")
+	}
+	groups, err := aggregator.Results()
+	if err != nil {
+		return nil, statusError(ctx, err, "unable to aggregate records")
+	}
+	var records []*binq.Record
+	for index, fields := range groups {
+		if limit := query.GetQueryOptions().GetLimit(); limit > 0 && uint64(index) >= limit {
+			break
+		}
+		records = append(records, &binq.Record{Fields: fields})
+	}
+	return records, nil
+}
+
+// Count counts the records that match a query.
+func (s *Server) Count(ctx context.Context, query *binq.Query) (*binq.CountResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it, err := s.execute(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	response := &binq.CountResponse{}
+	for ; !it.End(); it.Next() {
+		response.Count++
+	}
+	if err := it.Err(); err != nil {
+		return nil, statusError(ctx, err, "unable to execute query")
+	}
+	return response, nil
+}
+
+// Explain describes the key ranges a query visits and the predicate it applies within them.
+func (s *Server) Explain(ctx context.Context, query *binq.Query) (*binq.ExplainResponse, error) {
+	ranges, residual, err := db3.QueryKeyRanges(query)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid query: %v", err)
+	}
+	response := &binq.ExplainResponse{Residual: residual}
+	for _, r := range ranges {
+		response.Ranges = append(response.Ranges, &binq.Range{Start: r.Start, End: r.End, MinKeyLength: uint32(r.MinLength)})
+	}
+	return response, nil
+}
+
+// Insert inserts records in order. Keys must be at most the maximum key size of the table,
+// and values must be as large as its data size unless it has db3.VariableDataSize. The records
+// of a request are validated before any is inserted, but records inserted before a failing one
+// remain inserted.
+func (s *Server) Insert(ctx context.Context, request *binq.InsertRequest) (*binq.InsertResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for index, record := range request.GetRecords() {
+		if maxKeySize := s.table.MaxKeySize(); len(record.GetKey()) > int(maxKeySize) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid record %d: key length %d, want at most %d", index, len(record.GetKey()), maxKeySize)
+		}
+		if dataSize := s.table.DataSize(); dataSize != db3.VariableDataSize && len(record.GetValue()) != int(dataSize) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid record %d: value length %d, want %d", index, len(record.GetValue()), dataSize)
+		}
+	}
+
+	response := &binq.InsertResponse{}
+	for index, record := range request.GetRecords() {
+		if err := ctx.Err(); err != nil {
+			return nil, statusError(ctx, err, "insert cancelled")
+		}
+		err := s.table.Insert(record.GetKey(), record.GetValue())
+		if errors.Cause(err) == db3.ErrDuplicateKey {
+			return nil, status.Errorf(codes.AlreadyExists, "record %d: key %x already exists", index, record.GetKey())
+		}
+		if err != nil {
+			return nil, statusError(ctx, err, "unable to insert record")
+		}
+		response.Inserted++
+	}
+	return response, nil
+}
+
+// execute executes a query.
+func (s *Server) execute(ctx context.Context, query *binq.Query) (db3.ResultIterator, error) {
+	it, err := db3.Execute(ctx, s.table, query)
+	if invalid, ok := errors.Cause(err).(*db3.InvalidQueryError); ok {
+		return nil, status.Error(codes.InvalidArgument, invalid.Error())
+	}
+	if err != nil {
+		return nil, statusError(ctx, err, "unable to execute query")
+	}
+	return it, nil
+}
+
+// statusError converts an error to a status error, whose code reflects the context
+// if it has ended.
+func statusError(ctx context.Context, err error, msg string) error {
+	code := codes.Internal
+	switch ctx.Err() {
+	case context.Canceled:
+		code = codes.Canceled
+	case context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	}
+	return status.Errorf(code, "%s: %v", msg, err)
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"explodes/github.com/binq"
+	"explodes/github.com/binq/db3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	// testKeySize is the maximum size of the keys of test records.
+	testKeySize = 4
+	// testDataSize is the size of the values of test records.
+	testDataSize = 8
+)
+
+// testWithClient runs f with a client of a local server of a new table.
+func testWithClient(t *testing.T, f func(t *testing.T, client binq.BinqClient)) {
+	t.Helper()
+	testWithDataSize(t, testDataSize, f)
+}
+
+// testWithDataSize runs f with a client of a local server of a new table with a data size.
+func testWithDataSize(t *testing.T, dataSize uint16, f func(t *testing.T, client binq.BinqClient)) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "binq_server_test")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	pager, err := db3.OpenPager(filepath.Join(dir, "table.db"), os.O_RDWR|os.O_CREATE, 0600)
+	must(t, err)
+	defer func() {
+		must(t, pager.Close())
+	}()
+	table, err := db3.Open(pager, testKeySize, dataSize)
+	must(t, err)
+
+	client, err := Local(table)
+	must(t, err)
+	defer func() {
+		must(t, client.Close())
+	}()
+
+	f(t, client)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKey encodes the key of a test record as big-endian, so that keys are ordered like numbers.
+func testKey(key uint32) []byte {
+	b := make([]byte, testKeySize)
+	binary.BigEndian.PutUint32(b, key)
+	return b
+}
+
+// testRecord creates a record whose value holds its key and the key doubled.
+func testRecord(key uint32) *binq.Record {
+	value := make([]byte, testDataSize)
+	binary.LittleEndian.PutUint32(value, key)
+	binary.LittleEndian.PutUint32(value[4:], 2*key)
+	return &binq.Record{Key: testKey(key), Value: value}
+}
+
+// insertTestRecords inserts records with keys from 1 to numKeys.
+func insertTestRecords(t *testing.T, client binq.BinqClient, numKeys int) {
+	t.Helper()
+	request := &binq.InsertRequest{}
+	for key := 1; key <= numKeys; key++ {
+		request.Records = append(request.Records, testRecord(uint32(key)))
+	}
+	response, err := client.Insert(context.Background(), request)
+	must(t, err)
+	assert.Equal(t, uint64(numKeys), response.GetInserted())
+}
+
+func parseQuery(t *testing.T, limit uint64, predicate string) *binq.Query {
+	t.Helper()
+	query := &binq.Query{QueryOptions: &binq.Options{Limit: limit}}
+	if predicate != "" {
+		pred, err := binq.NewParser(predicate).ReadPredicate()
+		must(t, err)
+		query.Predicate = pred
+	}
+	return query
+}
+
+// queryKeys streams the keys of the records of a query.
+func queryKeys(t *testing.T, client binq.BinqClient, query *binq.Query) ([]uint32, error) {
+	t.Helper()
+	stream, err := client.Query(context.Background(), query)
+	must(t, err)
+	var keys []uint32
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			return keys, nil
+		}
+		if err != nil {
+			return keys, err
+		}
+		if !assert.Len(t, record.GetKey(), testKeySize) {
+			return keys, nil
+		}
+		key := binary.BigEndian.Uint32(record.GetKey())
+		assert.Equal(t, testRecord(key).GetValue(), record.GetValue())
+		keys = append(keys, key)
+	}
+}
+
+func TestServer_Query(t *testing.T) {
+	cases := []struct {
+		name      string
+		limit     uint64
+		predicate string
+		expected  []uint32
+	}{
+		{"all", 0, "", []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{"limit", 3, "", []uint32{1, 2, 3}},
+		{"key-range", 0, "KEY(0, U32BE) >= U32(4) AND KEY(0, U32BE) < U32(7)", []uint32{4, 5, 6}},
+		{"value", 2, "VALUE(4, U32LE) > U32(10)", []uint32{6, 7}},
+		{"none", 0, "KEY(0, U32BE) > U32(10)", nil},
+	}
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		insertTestRecords(t, client, 10)
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				query := parseQuery(t, tc.limit, tc.predicate)
+				keys, err := queryKeys(t, client, query)
+				must(t, err)
+				assert.Equal(t, tc.expected, keys)
+
+				count, err := client.Count(context.Background(), query)
+				must(t, err)
+				assert.Equal(t, uint64(len(tc.expected)), count.GetCount())
+			})
+		}
+	})
+}
+
+func TestServer_Query_errors(t *testing.T) {
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		insertTestRecords(t, client, 10)
+
+		_, err := queryKeys(t, client, &binq.Query{Predicate: &binq.Predicate{}})
+		assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+		_, err = client.Count(context.Background(), &binq.Query{Predicate: &binq.Predicate{}})
+		assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+
+		keys, err := queryKeys(t, client, parseQuery(t, 0, "U32(1) / (VALUE(0, U32LE) - U32(3)) = U32(0)"))
+		assert.Equal(t, codes.Internal, grpc.Code(err))
+		assert.Equal(t, []uint32{1, 2}, keys)
+	})
+}
+
+// TestServer_Query_slowClient asserts that a query that has not been received
+// does not hold up inserts.
+func TestServer_Query_slowClient(t *testing.T) {
+	const numKeys = 10000
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		insertTestRecords(t, client, numKeys)
+
+		stream, err := client.Query(context.Background(), &binq.Query{})
+		must(t, err)
+		_, err = stream.Recv()
+		must(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err = client.Insert(ctx, &binq.InsertRequest{Records: []*binq.Record{testRecord(numKeys + 1)}})
+		must(t, err)
+
+		received := 1
+		for {
+			record, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			must(t, err)
+			assert.Equal(t, testRecord(binary.BigEndian.Uint32(record.GetKey())), record)
+			received++
+		}
+		assert.Equal(t, numKeys, received)
+	})
+}
+
+func TestServer_Explain(t *testing.T) {
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		query := parseQuery(t, 0, "KEY(0, U32BE) >= U32(4) AND KEY(0, U32BE) < U32(7) AND VALUE(0, U8) = U32(1)")
+		query.End = testKey(6)
+		response, err := client.Explain(context.Background(), query)
+		must(t, err)
+		assert.Equal(t, []*binq.Range{{Start: testKey(4), End: testKey(6), MinKeyLength: testKeySize}}, response.GetRanges())
+		text, err := binq.FormatPredicate(response.GetResidual(), binq.FormatOptions{})
+		must(t, err)
+		assert.Equal(t, "VALUE(0, U8) = U32(1)", text)
+
+		_, err = client.Explain(context.Background(), &binq.Query{Predicate: &binq.Predicate{}})
+		assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+	})
+}
+
+func TestServer_Insert(t *testing.T) {
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		insertTestRecords(t, client, 3)
+
+		cases := []struct {
+			name    string
+			records []*binq.Record
+			code    codes.Code
+		}{
+			{"invalid-key", []*binq.Record{testRecord(4), {Key: append(testKey(5), 0), Value: testRecord(5).GetValue()}}, codes.InvalidArgument},
+			{"invalid-value", []*binq.Record{testRecord(4), {Key: testKey(5), Value: []byte{1}}}, codes.InvalidArgument},
+			{"duplicate", []*binq.Record{testRecord(2)}, codes.AlreadyExists},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := client.Insert(context.Background(), &binq.InsertRequest{Records: tc.records})
+				assert.Equal(t, tc.code, grpc.Code(err))
+			})
+		}
+
+		keys, err := queryKeys(t, client, &binq.Query{})
+		must(t, err)
+		assert.Equal(t, []uint32{1, 2, 3}, keys, "invalid requests insert nothing")
+	})
+}
+
+func TestServer_Insert_variableDataSize(t *testing.T) {
+	testWithDataSize(t, db3.VariableDataSize, func(t *testing.T, client binq.BinqClient) {
+		records := []*binq.Record{
+			{Key: []byte{0}, Value: []byte("short key")},
+			{Key: testKey(1)},
+			{Key: testKey(2), Value: []byte("two")},
+			{Key: testKey(3), Value: bytes.Repeat([]byte("three"), db3.PageSize)},
+		}
+		response, err := client.Insert(context.Background(), &binq.InsertRequest{Records: records})
+		must(t, err)
+		assert.Equal(t, uint64(len(records)), response.GetInserted())
+
+		stream, err := client.Query(context.Background(), &binq.Query{})
+		must(t, err)
+		for _, expected := range records {
+			record, err := stream.Recv()
+			must(t, err)
+			assert.Equal(t, expected.GetKey(), record.GetKey())
+			assert.Equal(t, expected.GetValue(), record.GetValue())
+		}
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+func TestServer_Query_projection(t *testing.T) {
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		insertTestRecords(t, client, 5)
+
+		query := parseQuery(t, 2, "KEY(0, U32BE) >= U32(3)")
+		projection, err := binq.NewParser("VALUE(4, U32LE) AS double, KEY(3, U8)").ReadProjection()
+		must(t, err)
+		query.Projection = projection
+		stream, err := client.Query(context.Background(), query)
+		must(t, err)
+		for _, key := range []uint32{3, 4} {
+			record, err := stream.Recv()
+			must(t, err)
+			assert.Equal(t, testKey(key), record.GetKey())
+			assert.Empty(t, record.GetValue())
+			assert.Equal(t, []*binq.Scalar{{Value: &binq.Scalar_U32{U32: 2 * key}}, {Value: &binq.Scalar_U32{U32: key}}}, record.GetFields())
+		}
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+
+		query.Projection = []*binq.Projection{{Alias: "missing"}}
+		_, err = queryKeys(t, client, query)
+		assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+	})
+}
+
+func TestServer_Query_aggregation(t *testing.T) {
+	testWithClient(t, func(t *testing.T, client binq.BinqClient) {
+		insertTestRecords(t, client, 5)
+
+		aggregates, err := binq.NewParser("COUNT() AS n, SUM(VALUE(4, U32LE)), MAX(KEY(0, U32BE))").ReadAggregates()
+		must(t, err)
+		groupBy, err := binq.NewParser("KEY(1, U8)").ReadProjection()
+		must(t, err)
+		cases := []struct {
+			name        string
+			limit       uint64
+			predicate   string
+			aggregation *binq.Aggregation
+			expected    [][]*binq.Scalar
+		}{
+			{
+				"all",
+				0,
+				"KEY(0, U32BE) >= U32(2)",
+				&binq.Aggregation{Aggregates: aggregates},
+				[][]*binq.Scalar{{{Value: &binq.Scalar_U64{U64: 4}}, {Value: &binq.Scalar_U64{U64: 28}}, {Value: &binq.Scalar_U32{U32: 5}}}},
+			},
+			{
+				"empty",
+				0,
+				"KEY(0, U32BE) > U32(5)",
+				&binq.Aggregation{Aggregates: aggregates},
+				[][]*binq.Scalar{{{Value: &binq.Scalar_U64{U64: 0}}, {Value: &binq.Scalar_U64{U64: 0}}, {}}},
+			},
+			{
+				"group-by",
+				2,
+				"",
+				&binq.Aggregation{GroupBy: groupBy, Aggregates: aggregates},
+				[][]*binq.Scalar{{{Value: &binq.Scalar_U32{U32: 0}}, {Value: &binq.Scalar_U64{U64: 5}}, {Value: &binq.Scalar_U64{U64: 30}}, {Value: &binq.Scalar_U32{U32: 5}}}},
+			},
+			{
+				"limit",
+				1,
+				"KEY(0, U32BE) < U32(4)",
+				&binq.Aggregation{GroupBy: []*binq.Projection{{Value: aggregates[2].GetExpression().GetValue()}}, Aggregates: aggregates[:1]},
+				[][]*binq.Scalar{{{Value: &binq.Scalar_U32{U32: 1}}, {Value: &binq.Scalar_U64{U64: 1}}}},
+			},
+		}
+		for _, tc := range cases {
+			query := parseQuery(t, tc.limit, tc.predicate)
+			query.Aggregation = tc.aggregation
+			stream, err := client.Query(context.Background(), query)
+			must(t, err)
+			var groups [][]*binq.Scalar
+			for {
+				record, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				must(t, err)
+				assert.Empty(t, record.GetKey(), tc.name)
+				groups = append(groups, record.GetFields())
+			}
+			assert.Equal(t, tc.expected, groups, tc.name)
+		}
+
+		query := parseQuery(t, 0, "")
+		query.Aggregation = &binq.Aggregation{Aggregates: []*binq.Aggregate{{Function: binq.AggregateFunction_AGGREGATE_FUNCTION_SUM}}}
+		_, err = queryKeys(t, client, query)
+		assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+
+		query.Aggregation = &binq.Aggregation{Aggregates: aggregates}
+		query.Projection = groupBy
+		_, err = queryKeys(t, client, query)
+		assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+	})
+}
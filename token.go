@@ -16,12 +16,19 @@ const (
 	TokenKey    // KEY(offset OR jump, type)
 	TokenValue  // VALUE(offset OR jump, type)
 	TokenJump   // JUMP(offset OR jump, type)
+	TokenBytes  // BYTES(length OR length type)
 
 	/* Scalar functions */
 	TokenScalarU64   // U64(0)
 	TokenScalarU32   // U32(0)
 	TokenScalarU16   // U16(0)
 	TokenScalarU8    // U8(0)
+	TokenScalarI64   // I64(0)
+	TokenScalarI32   // I32(0)
+	TokenScalarI16   // I16(0)
+	TokenScalarI8    // I8(0)
+	TokenScalarF64   // F64(0.0)
+	TokenScalarF32   // F32(0.0)
 	TokenScalarBool  // BOOL([true|false]) OR true OR false
 
 	/* Type identifiers */
@@ -32,6 +39,17 @@ const (
 	TokenTypeU16LE  // U16, U16LE
 	TokenTypeU16BE  // U16BE
 	TokenTypeU8     // U8
+	TokenTypeI64LE  // I64, I64LE
+	TokenTypeI64BE  // I64BE
+	TokenTypeI32LE  // I32, I32LE
+	TokenTypeI32BE  // I32BE
+	TokenTypeI16LE  // I16, I16LE
+	TokenTypeI16BE  // I16BE
+	TokenTypeI8     // I8
+	TokenTypeF64LE  // F64, F64LE
+	TokenTypeF64BE  // F64BE
+	TokenTypeF32LE  // F32, F32LE
+	TokenTypeF32BE  // F32BE
 	TokenTypeBool   // BOOL
 
 	/* Literal values */
@@ -39,17 +57,29 @@ const (
 	TokenSignedIntegerLiteral    // -1000
 	TokenFloatLiteral            // -100e-4
 	TokenStringLiteral           // "abc123"
+	TokenHexStringLiteral        // x"deadbeef"
 	TokenBoolLiteral             // false | true
 
 	/* Operators */
 	TokenAnd        // AND
 	TokenOr         // OR
+	TokenNot        // NOT
 	TokenLess       // <
 	TokenLessEq     // <=
 	TokenGreater    // >
 	TokenGreaterEq  // >=
 	TokenEq         // =
 	TokenNeq        // !=
+	TokenAdd        // +
+	TokenSub        // -
+	TokenMul        // *
+	TokenDiv        // /
+	TokenMod        // %
+	TokenBitAnd     // &
+	TokenBitOr      // |
+	TokenBitXor     // ^
+	TokenShl        // <<
+	TokenShr        // >>
 
 	tokenMax
 )
@@ -74,6 +104,8 @@ func (t Token) String() string {
 		return "VALUE"
 	case TokenJump:
 		return "JUMP"
+	case TokenBytes:
+		return "BYTES"
 	case TokenScalarU64:
 		return "SCALAR_U64"
 	case TokenScalarU32:
@@ -82,6 +114,18 @@ func (t Token) String() string {
 		return "SCALAR_U16"
 	case TokenScalarU8:
 		return "SCALAR_U8"
+	case TokenScalarI64:
+		return "SCALAR_I64"
+	case TokenScalarI32:
+		return "SCALAR_I32"
+	case TokenScalarI16:
+		return "SCALAR_I16"
+	case TokenScalarI8:
+		return "SCALAR_I8"
+	case TokenScalarF64:
+		return "SCALAR_F64"
+	case TokenScalarF32:
+		return "SCALAR_F32"
 	case TokenScalarBool:
 		return "SCALAR_BOOL"
 	case TokenTypeU64LE:
@@ -89,7 +133,7 @@ func (t Token) String() string {
 	case TokenTypeU64BE:
 		return "TYPE_U64BE"
 	case TokenTypeU32LE:
-		return "TYPE_U64LE"
+		return "TYPE_U32LE"
 	case TokenTypeU32BE:
 		return "TYPE_U32BE"
 	case TokenTypeU16LE:
@@ -98,6 +142,28 @@ func (t Token) String() string {
 		return "TYPE_U16BE"
 	case TokenTypeU8:
 		return "U8"
+	case TokenTypeI64LE:
+		return "TYPE_I64LE"
+	case TokenTypeI64BE:
+		return "TYPE_I64BE"
+	case TokenTypeI32LE:
+		return "TYPE_I32LE"
+	case TokenTypeI32BE:
+		return "TYPE_I32BE"
+	case TokenTypeI16LE:
+		return "TYPE_I16LE"
+	case TokenTypeI16BE:
+		return "TYPE_I16BE"
+	case TokenTypeI8:
+		return "TYPE_I8"
+	case TokenTypeF64LE:
+		return "TYPE_F64LE"
+	case TokenTypeF64BE:
+		return "TYPE_F64BE"
+	case TokenTypeF32LE:
+		return "TYPE_F32LE"
+	case TokenTypeF32BE:
+		return "TYPE_F32BE"
 	case TokenTypeBool:
 		return "BOOL"
 	case TokenUnsignedIntegerLiteral:
@@ -108,12 +174,16 @@ func (t Token) String() string {
 		return "FLOAT"
 	case TokenStringLiteral:
 		return "STRING"
+	case TokenHexStringLiteral:
+		return "HEX_STRING"
 	case TokenBoolLiteral:
 		return "BOOL_LITERAL"
 	case TokenAnd:
 		return "AND"
 	case TokenOr:
 		return "OR"
+	case TokenNot:
+		return "NOT"
 	case TokenLess:
 		return "LESS"
 	case TokenLessEq:
@@ -126,6 +196,26 @@ func (t Token) String() string {
 		return "EQUAL"
 	case TokenNeq:
 		return "NOT_EQUAL"
+	case TokenAdd:
+		return "ADD"
+	case TokenSub:
+		return "SUB"
+	case TokenMul:
+		return "MUL"
+	case TokenDiv:
+		return "DIV"
+	case TokenMod:
+		return "MOD"
+	case TokenBitAnd:
+		return "BIT_AND"
+	case TokenBitOr:
+		return "BIT_OR"
+	case TokenBitXor:
+		return "BIT_XOR"
+	case TokenShl:
+		return "SHL"
+	case TokenShr:
+		return "SHR"
 	case TokenUnknown:
 		return "UNKNOWN"
 	default:
@@ -135,8 +225,11 @@ func (t Token) String() string {
 
 func (t Token) IsFunction() bool {
 	switch t {
-	case TokenKey, TokenValue, TokenJump,
-		TokenScalarU64, TokenScalarU32, TokenScalarU16, TokenScalarU8, TokenScalarBool:
+	case TokenKey, TokenValue, TokenJump, TokenBytes,
+		TokenScalarU64, TokenScalarU32, TokenScalarU16, TokenScalarU8,
+		TokenScalarI64, TokenScalarI32, TokenScalarI16, TokenScalarI8,
+		TokenScalarF64, TokenScalarF32,
+		TokenScalarBool:
 		return true
 	default:
 		return false
@@ -150,7 +243,10 @@ func (t Token) NumArgs() int {
 	switch t {
 	case TokenKey, TokenValue, TokenJump:
 		return 2
-	case TokenScalarU64, TokenScalarU32, TokenScalarU16, TokenScalarU8, TokenScalarBool:
+	case TokenScalarU64, TokenScalarU32, TokenScalarU16, TokenScalarU8,
+		TokenScalarI64, TokenScalarI32, TokenScalarI16, TokenScalarI8,
+		TokenScalarF64, TokenScalarF32,
+		TokenScalarBool, TokenBytes:
 		return 1
 	default:
 		panic("unhandled function token")
@@ -163,6 +259,12 @@ func (t Token) IsTypeIdentifier() bool {
 		TokenTypeU32LE, TokenTypeU32BE,
 		TokenTypeU16LE, TokenTypeU16BE,
 		TokenTypeU8,
+		TokenTypeI64LE, TokenTypeI64BE,
+		TokenTypeI32LE, TokenTypeI32BE,
+		TokenTypeI16LE, TokenTypeI16BE,
+		TokenTypeI8,
+		TokenTypeF64LE, TokenTypeF64BE,
+		TokenTypeF32LE, TokenTypeF32BE,
 		TokenTypeBool:
 		return true
 	default:
@@ -174,18 +276,27 @@ func (t Token) IsOperator() bool {
 	switch t {
 	case TokenLess, TokenLessEq,
 		TokenGreater, TokenGreaterEq,
-		TokenEq, TokenNeq, TokenAnd, TokenOr:
+		TokenEq, TokenNeq, TokenAnd, TokenOr, TokenNot,
+		TokenAdd, TokenSub, TokenMul, TokenDiv, TokenMod,
+		TokenBitAnd, TokenBitOr, TokenBitXor, TokenShl, TokenShr:
 		return true
 	default:
 		return false
 	}
 }
 
+// IsUnaryOperator determines if a token is a prefix operator with a single operand.
+func (t Token) IsUnaryOperator() bool {
+	return t == TokenNot
+}
+
 func (t Token) IsBinaryOperator() bool {
 	switch t {
 	case TokenLess, TokenLessEq,
 		TokenGreater, TokenGreaterEq,
-		TokenEq, TokenNeq, TokenAnd, TokenOr:
+		TokenEq, TokenNeq, TokenAnd, TokenOr,
+		TokenAdd, TokenSub, TokenMul, TokenDiv, TokenMod,
+		TokenBitAnd, TokenBitOr, TokenBitXor, TokenShl, TokenShr:
 		return true
 	default:
 		return false
@@ -194,7 +305,7 @@ func (t Token) IsBinaryOperator() bool {
 
 func (t Token) IsLiteral() bool {
 	switch t {
-	case TokenUnsignedIntegerLiteral, TokenSignedIntegerLiteral, TokenStringLiteral, TokenBoolLiteral:
+	case TokenUnsignedIntegerLiteral, TokenSignedIntegerLiteral, TokenFloatLiteral, TokenStringLiteral, TokenHexStringLiteral, TokenBoolLiteral:
 		return true
 	default:
 		return false
@@ -219,16 +330,34 @@ func (t Token) IsParenthesis() bool {
 	}
 }
 
+// Precedence ranks binary and prefix operators, higher binds tighter:
+// multiplicative, additive, comparison, NOT, AND, then OR.
 func (t Token) Precedence() int {
 	switch t {
-	case TokenAnd,
-		TokenOr,
-		TokenLess,
+	case TokenMul,
+		TokenDiv,
+		TokenMod,
+		TokenBitAnd,
+		TokenShl,
+		TokenShr:
+		return 60
+	case TokenAdd,
+		TokenSub,
+		TokenBitOr,
+		TokenBitXor:
+		return 50
+	case TokenLess,
 		TokenLessEq,
 		TokenGreater,
 		TokenGreaterEq,
 		TokenEq,
 		TokenNeq:
+		return 40
+	case TokenNot:
+		return 30
+	case TokenAnd:
+		return 20
+	case TokenOr:
 		return 10
 	default:
 		panic(errors.Errorf("unhandled precedence for %s", t.String()))
@@ -237,7 +366,9 @@ func (t Token) Precedence() int {
 
 func (t Token) IsLeftAssociative() bool {
 	switch t {
-	case TokenAnd,
+	case TokenMul, TokenDiv, TokenMod, TokenBitAnd, TokenShl, TokenShr,
+		TokenAdd, TokenSub, TokenBitOr, TokenBitXor,
+		TokenAnd,
 		TokenOr,
 		TokenLess,
 		TokenLessEq,
@@ -246,6 +377,8 @@ func (t Token) IsLeftAssociative() bool {
 		TokenEq,
 		TokenNeq:
 		return true
+	case TokenNot:
+		return false
 	default:
 		panic(errors.Errorf("unhandled associativity for %s", t.String()))
 	}
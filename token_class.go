@@ -1,7 +1,9 @@
 package binq
 
 import (
+	"encoding/hex"
 	"github.com/pkg/errors"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -16,11 +18,9 @@ var isUnsupportedToken [tokenMax]bool
 func init() {
 	isUnsupportedToken[TokenScalarU16] = true
 	isUnsupportedToken[TokenScalarU8] = true
-	isUnsupportedToken[TokenScalarBool] = true
+	isUnsupportedToken[TokenScalarI16] = true
+	isUnsupportedToken[TokenScalarI8] = true
 	isUnsupportedToken[TokenTypeBool] = true
-	isUnsupportedToken[TokenStringLiteral] = true
-	isUnsupportedToken[TokenSignedIntegerLiteral] = true
-	isUnsupportedToken[TokenFloatLiteral] = true
 }
 
 var functionOrKeywordTokens = []struct {
@@ -32,11 +32,18 @@ var functionOrKeywordTokens = []struct {
 	{"KEY", TokenKey, TokenUnknown},
 	{"VALUE", TokenValue, TokenUnknown},
 	{"JUMP", TokenJump, TokenUnknown},
+	{"BYTES", TokenBytes, TokenUnknown},
 	// Function or ident/operator
 	{"U64", TokenScalarU64, TokenTypeU64LE},
 	{"U32", TokenScalarU32, TokenTypeU32LE},
-	{"U16", TokenScalarU32, TokenTypeU16LE},
+	{"U16", TokenScalarU16, TokenTypeU16LE},
 	{"U8", TokenScalarU8, TokenTypeU8},
+	{"I64", TokenScalarI64, TokenTypeI64LE},
+	{"I32", TokenScalarI32, TokenTypeI32LE},
+	{"I16", TokenScalarI16, TokenTypeI16LE},
+	{"I8", TokenScalarI8, TokenTypeI8},
+	{"F64", TokenScalarF64, TokenTypeF64LE},
+	{"F32", TokenScalarF32, TokenTypeF32LE},
 	{"BOOL", TokenScalarBool, TokenTypeBool},
 	// Keyword only
 	{"U64LE", TokenUnknown, TokenTypeU64LE},
@@ -45,15 +52,46 @@ var functionOrKeywordTokens = []struct {
 	{"U32BE", TokenUnknown, TokenTypeU32BE},
 	{"U16LE", TokenUnknown, TokenTypeU16LE},
 	{"U16BE", TokenUnknown, TokenTypeU16BE},
+	{"I64LE", TokenUnknown, TokenTypeI64LE},
+	{"I64BE", TokenUnknown, TokenTypeI64BE},
+	{"I32LE", TokenUnknown, TokenTypeI32LE},
+	{"I32BE", TokenUnknown, TokenTypeI32BE},
+	{"I16LE", TokenUnknown, TokenTypeI16LE},
+	{"I16BE", TokenUnknown, TokenTypeI16BE},
+	{"F64LE", TokenUnknown, TokenTypeF64LE},
+	{"F64BE", TokenUnknown, TokenTypeF64BE},
+	{"F32LE", TokenUnknown, TokenTypeF32LE},
+	{"F32BE", TokenUnknown, TokenTypeF32BE},
 	// Operator "keywords" only
 	{"AND", TokenUnknown, TokenAnd},
 	{"OR", TokenUnknown, TokenOr},
+	{"NOT", TokenUnknown, TokenNot},
 	{"!=", TokenUnknown, TokenNeq},
 	{"=", TokenUnknown, TokenEq},
 	{"<", TokenUnknown, TokenLess},
 	{"<=", TokenUnknown, TokenLessEq},
 	{">", TokenUnknown, TokenGreater},
 	{">=", TokenUnknown, TokenGreaterEq},
+	{"+", TokenUnknown, TokenAdd},
+	{"-", TokenUnknown, TokenSub},
+	{"*", TokenUnknown, TokenMul},
+	{"/", TokenUnknown, TokenDiv},
+	{"%", TokenUnknown, TokenMod},
+	{"&", TokenUnknown, TokenBitAnd},
+	{"|", TokenUnknown, TokenBitOr},
+	{"^", TokenUnknown, TokenBitXor},
+	{"<<", TokenUnknown, TokenShl},
+	{">>", TokenUnknown, TokenShr},
+}
+
+// keyword returns the text that reads as this operator token.
+func (t Token) keyword() string {
+	for _, ident := range functionOrKeywordTokens {
+		if ident.funcToken == TokenUnknown && ident.keywordToken == t {
+			return ident.value
+		}
+	}
+	panic(errors.Errorf("unhandled keyword for %s", t.String()))
 }
 
 func unexpectedToken(value string) (Token, error) {
@@ -133,13 +171,84 @@ func classifyBoolLiteral(value string) (Token, error) {
 }
 
 func classifyStringToken(value string) (Token, error) {
-	if len(value) < 2 || value[0] != '"' {
+	token := TokenStringLiteral
+	if len(value) > 1 && (value[0] == 'x' || value[0] == 'X') && value[1] == '"' {
+		token = TokenHexStringLiteral
+	} else if len(value) == 0 || value[0] != '"' {
 		return TokenUnknown, nil
 	}
-	if value[len(value)-1] != '"' {
-		return TokenUnknown, errors.New("unterminated string literal")
+	if _, err := stringLiteralBytes(token, value); err != nil {
+		return TokenUnknown, err
+	}
+	return token, nil
+}
+
+// integerLiteralDigits returns the signed digits of an integer literal and their base,
+// which is 16 for literals prefixed by 0x and 10 otherwise.
+func integerLiteralDigits(value string) (string, int) {
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+	if len(value) >= 2 && value[0] == '0' && (value[1] == 'x' || value[1] == 'X') {
+		return sign + value[2:], 16
+	}
+	return sign + value, 10
+}
+
+// parseUintLiteral parses an unsigned decimal or hex integer literal.
+func parseUintLiteral(value string, bitSize int) (uint64, error) {
+	digits, base := integerLiteralDigits(value)
+	return strconv.ParseUint(digits, base, bitSize)
+}
+
+// parseIntLiteral parses a signed or unsigned decimal or hex integer literal.
+func parseIntLiteral(value string, bitSize int) (int64, error) {
+	digits, base := integerLiteralDigits(value)
+	return strconv.ParseInt(digits, base, bitSize)
+}
+
+// parseFloatLiteral parses a float literal, or an integer literal as a float.
+func parseFloatLiteral(value string, bitSize int) (float64, error) {
+	digits, base := integerLiteralDigits(value)
+	switch {
+	case base == 10:
+		return strconv.ParseFloat(value, bitSize)
+	case strings.HasPrefix(digits, "-"):
+		i, err := strconv.ParseInt(digits, base, 64)
+		return float64(i), err
+	default:
+		u, err := strconv.ParseUint(digits, base, 64)
+		return float64(u), err
+	}
+}
+
+// stringLiteralBytes decodes the bytes of a string or hex string literal.
+// String literals may contain Go escape sequences such as \x00 and \".
+func stringLiteralBytes(token Token, value string) ([]byte, error) {
+	quoted := value
+	if token == TokenHexStringLiteral {
+		quoted = value[1:]
+	}
+	if len(quoted) < 2 || quoted[len(quoted)-1] != '"' {
+		return nil, errors.New("unterminated string literal")
+	}
+	switch token {
+	case TokenStringLiteral:
+		s, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, errors.Errorf("invalid string literal %s", value)
+		}
+		return []byte(s), nil
+	case TokenHexStringLiteral:
+		b, err := hex.DecodeString(quoted[1 : len(quoted)-1])
+		if err != nil {
+			return nil, errors.Errorf("invalid hex literal %s", value)
+		}
+		return b, nil
+	default:
+		return nil, unhandledEnum("string literal token", token)
 	}
-	return TokenStringLiteral, nil
 }
 
 func invalidNumericLiteral(value string) (Token, error) {
@@ -152,12 +261,28 @@ func classifyNumericToken(value string) (Token, error) {
 	if signed {
 		index++
 	}
+	if digits, base := integerLiteralDigits(value); base == 16 {
+		if len(digits) == index {
+			return invalidNumericLiteral(value)
+		}
+		for _, r := range digits[index:] {
+			if !unicode.Is(unicode.ASCII_Hex_Digit, r) {
+				return invalidNumericLiteral(value)
+			}
+		}
+		if signed {
+			return TokenSignedIntegerLiteral, nil
+		}
+		return TokenUnsignedIntegerLiteral, nil
+	}
 	hasDecimal := false
 	hasExponent := false
 	hasDigit := false
 
-	for _, r := range value[index:] {
+	for i, r := range value[index:] {
 		switch {
+		case (r == '-' || r == '+') && i > 0 && (value[index+i-1] == 'e' || value[index+i-1] == 'E'):
+			// The exponent may be signed.
 		case r == 'e' || r == 'E':
 			if hasExponent {
 				return invalidNumericLiteral(value)
@@ -12,3 +12,46 @@ func TestTokenString(t *testing.T) {
 		assert.NotEqual(t, "<unknown>", s)
 	}
 }
+
+func TestClassifyNumericToken(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		value       string
+		expected    Token
+		expectedErr bool
+	}{
+		{"100", TokenUnsignedIntegerLiteral, false},
+		{"-100", TokenSignedIntegerLiteral, false},
+		{"1.5", TokenFloatLiteral, false},
+		{"-100e-4", TokenFloatLiteral, false},
+		{"1.5E+06", TokenFloatLiteral, false},
+		{"1-5", TokenUnknown, true},
+		{"1e5e5", TokenUnknown, true},
+		{"1.5.2", TokenUnknown, true},
+		{"0x8000", TokenUnsignedIntegerLiteral, false},
+		{"-0XfF", TokenSignedIntegerLiteral, false},
+		{"0x", TokenUnknown, true},
+		{"0x1g", TokenUnknown, true},
+	}
+	for _, tc := range cases {
+		token, err := classifyNumericToken(tc.value)
+		assert.Equal(t, tc.expectedErr, err != nil, "%s: %v", tc.value, err)
+		assert.Equal(t, tc.expected, token, tc.value)
+	}
+}
+
+func TestTokenPrecedence(t *testing.T) {
+	t.Parallel()
+	for i := TokenUnknown; i < tokenMax; i++ {
+		if !i.IsOperator() {
+			continue
+		}
+		assert.NotPanics(t, func() { i.Precedence() }, i.String())
+		assert.NotPanics(t, func() { i.IsLeftAssociative() }, i.String())
+	}
+	assert.Greater(t, TokenMul.Precedence(), TokenAdd.Precedence())
+	assert.Greater(t, TokenAdd.Precedence(), TokenEq.Precedence())
+	assert.Greater(t, TokenEq.Precedence(), TokenNot.Precedence())
+	assert.Greater(t, TokenNot.Precedence(), TokenAnd.Precedence())
+	assert.Greater(t, TokenAnd.Precedence(), TokenOr.Precedence())
+}
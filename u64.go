@@ -4,8 +4,13 @@ import "encoding/binary"
 
 // GetU64le gets the little-endian uint64 value in the byte slice.
 func GetU64le(bytes []byte) (interface{}, error) {
+	return readU64le(bytes)
+}
+
+// readU64le is GetU64le without boxing the value.
+func readU64le(bytes []byte) (uint64, error) {
 	if len(bytes) < 8 {
-		return uint64(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := binary.LittleEndian.Uint64(bytes)
 	return bytesValue, nil
@@ -13,8 +18,13 @@ func GetU64le(bytes []byte) (interface{}, error) {
 
 // GetU64be gets the little-endian uint64 value in the byte slice.
 func GetU64be(bytes []byte) (interface{}, error) {
+	return readU64be(bytes)
+}
+
+// readU64be is GetU64be without boxing the value.
+func readU64be(bytes []byte) (uint64, error) {
 	if len(bytes) < 8 {
-		return uint64(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := binary.BigEndian.Uint64(bytes)
 	return bytesValue, nil
@@ -22,8 +32,13 @@ func GetU64be(bytes []byte) (interface{}, error) {
 
 // GetU32le gets the little-endian uint32 value in the byte slice.
 func GetU32le(bytes []byte) (interface{}, error) {
+	return readU32le(bytes)
+}
+
+// readU32le is GetU32le without boxing the value.
+func readU32le(bytes []byte) (uint32, error) {
 	if len(bytes) < 4 {
-		return uint32(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := binary.LittleEndian.Uint32(bytes)
 	return bytesValue, nil
@@ -31,8 +46,13 @@ func GetU32le(bytes []byte) (interface{}, error) {
 
 // GetU32be gets the little-endian uint32 value in the byte slice.
 func GetU32be(bytes []byte) (interface{}, error) {
+	return readU32be(bytes)
+}
+
+// readU32be is GetU32be without boxing the value.
+func readU32be(bytes []byte) (uint32, error) {
 	if len(bytes) < 4 {
-		return uint32(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := binary.BigEndian.Uint32(bytes)
 	return bytesValue, nil
@@ -40,8 +60,13 @@ func GetU32be(bytes []byte) (interface{}, error) {
 
 // GetU16le gets the little-endian uint16 value in the byte slice.
 func GetU16le(bytes []byte) (interface{}, error) {
+	return readU16le(bytes)
+}
+
+// readU16le is GetU16le without boxing the value.
+func readU16le(bytes []byte) (uint16, error) {
 	if len(bytes) < 2 {
-		return uint16(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := binary.LittleEndian.Uint16(bytes)
 	return bytesValue, nil
@@ -49,8 +74,13 @@ func GetU16le(bytes []byte) (interface{}, error) {
 
 // GetU16be gets the little-endian uint16 value in the byte slice.
 func GetU16be(bytes []byte) (interface{}, error) {
+	return readU16be(bytes)
+}
+
+// readU16be is GetU16be without boxing the value.
+func readU16be(bytes []byte) (uint16, error) {
 	if len(bytes) < 2 {
-		return uint16(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := binary.BigEndian.Uint16(bytes)
 	return bytesValue, nil
@@ -58,8 +88,13 @@ func GetU16be(bytes []byte) (interface{}, error) {
 
 // GetU8 gets the little-endian uint8 value in the byte slice.
 func GetU8(bytes []byte) (interface{}, error) {
+	return readU8(bytes)
+}
+
+// readU8 is GetU8 without boxing the value.
+func readU8(bytes []byte) (uint8, error) {
 	if len(bytes) < 1 {
-		return uint8(0), ErrBytesTooSmall
+		return 0, ErrBytesTooSmall
 	}
 	bytesValue := bytes[0]
 	return bytesValue, nil
@@ -2,96 +2,153 @@ package binq
 
 import "github.com/pkg/errors"
 
-type upscaleFunc func(val interface{}) interface{}
-
 var (
-	upscaleFunctionMap = make(map[int64]upscaleFunc)
+	// upscales are the pairs of types whose first type is held by the second type.
+	upscales = make(map[int64]struct{})
 )
 
 func init() {
 	// Upscale bool types
-	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U8, func(val interface{}) interface{} {
-		if val.(bool) {
-			return uint8(1)
-		} else {
-			return uint8(0)
-		}
-	})
-	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U16, func(val interface{}) interface{} {
-		if val.(bool) {
-			return uint16(1)
-		} else {
-			return uint16(0)
-		}
-	})
-	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U32, func(val interface{}) interface{} {
-		if val.(bool) {
-			return uint32(1)
-		} else {
-			return uint32(0)
-		}
-	})
-	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U64, func(val interface{}) interface{} {
-		if val.(bool) {
-			return uint64(1)
-		} else {
-			return uint64(0)
-		}
-	})
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U8)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U16)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U32)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_U64)
 
 	// Upscale u8 types
-	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_U16, func(val interface{}) interface{} {
-		return uint16(val.(uint8))
-	})
-	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_U32, func(val interface{}) interface{} {
-		return uint32(val.(uint8))
-	})
-	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_U64, func(val interface{}) interface{} {
-		return uint64(val.(uint8))
-	})
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_U16)
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_U32)
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_U64)
 
 	// Upscale u16 types
-	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_U32, func(val interface{}) interface{} {
-		return uint32(val.(uint16))
-	})
-	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_U64, func(val interface{}) interface{} {
-		return uint64(val.(uint16))
-	})
+	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_U32)
+	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_U64)
 
 	// Upscale u32 types
-	registerUpscale(ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_U64, func(val interface{}) interface{} {
-		return uint64(val.(uint32))
-	})
+	registerUpscale(ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_U64)
+
+	// Upscale bool types to signed types
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_I8)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_I16)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_I32)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_I64)
+
+	// Upscale i8 types
+	registerUpscale(ReturnType_RETURN_TYPE_I8, ReturnType_RETURN_TYPE_I16)
+	registerUpscale(ReturnType_RETURN_TYPE_I8, ReturnType_RETURN_TYPE_I32)
+	registerUpscale(ReturnType_RETURN_TYPE_I8, ReturnType_RETURN_TYPE_I64)
+
+	// Upscale i16 types
+	registerUpscale(ReturnType_RETURN_TYPE_I16, ReturnType_RETURN_TYPE_I32)
+	registerUpscale(ReturnType_RETURN_TYPE_I16, ReturnType_RETURN_TYPE_I64)
+
+	// Upscale i32 types
+	registerUpscale(ReturnType_RETURN_TYPE_I32, ReturnType_RETURN_TYPE_I64)
+
+	// Upscale unsigned types to wider signed types, which represent every unsigned value exactly.
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_I16)
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_I32)
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_I64)
+	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_I32)
+	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_I64)
+	registerUpscale(ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_I64)
+
+	// Upscale bool types to float types
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_F32)
+	registerUpscale(ReturnType_RETURN_TYPE_BOOL, ReturnType_RETURN_TYPE_F64)
+
+	// Upscale integer types to float types. Types up to 16 bits fit exactly in a float.
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_F32)
+	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_F32)
+	registerUpscale(ReturnType_RETURN_TYPE_I8, ReturnType_RETURN_TYPE_F32)
+	registerUpscale(ReturnType_RETURN_TYPE_I16, ReturnType_RETURN_TYPE_F32)
+
+	// Types up to 32 bits fit exactly in a double.
+	registerUpscale(ReturnType_RETURN_TYPE_U8, ReturnType_RETURN_TYPE_F64)
+	registerUpscale(ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_F64)
+	registerUpscale(ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_F64)
+	registerUpscale(ReturnType_RETURN_TYPE_I8, ReturnType_RETURN_TYPE_F64)
+	registerUpscale(ReturnType_RETURN_TYPE_I16, ReturnType_RETURN_TYPE_F64)
+	registerUpscale(ReturnType_RETURN_TYPE_I32, ReturnType_RETURN_TYPE_F64)
+
+	// 64 bit types are rounded to the nearest double when their magnitude exceeds 2^53.
+	registerUpscale(ReturnType_RETURN_TYPE_U64, ReturnType_RETURN_TYPE_F64)
+	registerUpscale(ReturnType_RETURN_TYPE_I64, ReturnType_RETURN_TYPE_F64)
+
+	// Upscale f32 types
+	registerUpscale(ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_F64)
+}
+
+// promotionTypes are the types, narrowest first, that two types are promoted to
+// when neither type can be upscaled to the other.
+//
+// Mixed signed and unsigned types are promoted to the narrowest signed type
+// that holds every value of both types:
+//
+//	      U8   U16  U32  U64
+//	I8    I16  I32  I64  -
+//	I16   I16  I32  I64  -
+//	I32   I32  I32  I64  -
+//	I64   I64  I64  I64  -
+//
+// No type holds every U64 and I64 value, so U64 cannot be used with signed types.
+var promotionTypes = []ReturnType{
+	ReturnType_RETURN_TYPE_I16,
+	ReturnType_RETURN_TYPE_I32,
+	ReturnType_RETURN_TYPE_I64,
+}
+
+// floatPromotionTypes are the types that an integer type and a float type are promoted to
+// when neither type can be upscaled to the other. Integers are only promoted to floats
+// when one side is already a float, so U64 and signed types remain incompatible.
+//
+//	      U8   U16  U32  U64  I8   I16  I32  I64
+//	F32   F32  F32  F64  F64  F32  F32  F64  F64
+//	F64   F64  F64  F64  F64  F64  F64  F64  F64
+var floatPromotionTypes = []ReturnType{
+	ReturnType_RETURN_TYPE_F64,
 }
 
-func identityUpscale(val interface{}) interface{} {
-	return val
+func isFloatType(returnType ReturnType) bool {
+	return returnType == ReturnType_RETURN_TYPE_F32 || returnType == ReturnType_RETURN_TYPE_F64
 }
 
 func upscaleKey(typeA, typeB ReturnType) int64 {
 	return (int64(typeA) << 32) | int64(typeB)
 }
 
-func registerUpscale(typeA, typeB ReturnType, upscaleFunc upscaleFunc) {
-	key := upscaleKey(typeA, typeB)
-	upscaleFunctionMap[key] = upscaleFunc
+func registerUpscale(typeA, typeB ReturnType) {
+	upscales[upscaleKey(typeA, typeB)] = struct{}{}
+}
+
+func canUpscale(typeA, typeB ReturnType) bool {
+	_, ok := upscales[upscaleKey(typeA, typeB)]
+	return ok
 }
 
-func getUpscaler(typeA, typeB ReturnType) (upscaledA, upscaledB upscaleFunc, valueTypes ReturnType, err error) {
+// getUpscaledType returns the type that values of both types are converted to before an operation on them.
+func getUpscaledType(typeA, typeB ReturnType) (ReturnType, error) {
 	// Same types, no conversion required.
 	if typeA == typeB {
-		return identityUpscale, identityUpscale, typeA, nil
+		return typeA, nil
 	}
 	// Upscale A to B?
-	leftToRightKey := upscaleKey(typeA, typeB)
-	if upscaler, ok := upscaleFunctionMap[leftToRightKey]; ok {
-		return upscaler, identityUpscale, typeB, nil
+	if canUpscale(typeA, typeB) {
+		return typeB, nil
 	}
 	// Upscale B to A?
-	rightToLeftKey := upscaleKey(typeB, typeA)
-	if upscaler, ok := upscaleFunctionMap[rightToLeftKey]; ok {
-		return identityUpscale, upscaler, typeA, nil
+	if canUpscale(typeB, typeA) {
+		return typeA, nil
+	}
+	// Upscale both A and B to a common type?
+	candidates := promotionTypes
+	if isFloatType(typeA) || isFloatType(typeB) {
+		candidates = floatPromotionTypes
+	}
+	for _, promotedType := range candidates {
+		if canUpscale(typeA, promotedType) && canUpscale(typeB, promotedType) {
+			return promotedType, nil
+		}
 	}
 	// Cannot upscale.
-	return nil, nil, ReturnType_RETURN_TYPE_UNKNOWN, errors.Errorf("cannot upscale %s to %s", typeA, typeB)
+	return ReturnType_RETURN_TYPE_UNKNOWN, errors.Errorf("cannot upscale %s to %s", typeA, typeB)
 }
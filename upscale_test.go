@@ -5,6 +5,15 @@ import (
 	"testing"
 )
 
+// assertConverts asserts that a constant of a type converts to an upscaled type.
+func assertConverts(t *testing.T, fromType, toType ReturnType) {
+	t.Helper()
+	c, err := convert(makeConstant(fromType, makeReturnTypeValue(t, fromType)), toType)
+	if assert.NoError(t, err) {
+		assert.Equal(t, toType, c.returnType)
+	}
+}
+
 // TestUpscaleUintTypes asserts that uint types can be upscaled between each other.
 func TestUpscaleUintTypes(t *testing.T) {
 	t.Parallel()
@@ -16,20 +25,98 @@ func TestUpscaleUintTypes(t *testing.T) {
 				bType := bType
 				t.Run(bType.String(), func(t *testing.T) {
 					t.Parallel()
-					aFunc, bFunc, resultType, err := getUpscaler(aType, bType)
+					resultType, err := getUpscaledType(aType, bType)
 					assert.NoError(t, err)
 					assert.NotEqual(t, ReturnType_RETURN_TYPE_UNKNOWN, resultType)
-					assert.NotNil(t, aFunc)
-					assert.NotNil(t, bFunc)
+					assertConverts(t, aType, resultType)
+					assertConverts(t, bType, resultType)
+				})
+			}
+		})
+	}
+}
 
-					aValue := makeReturnTypeValue(t, aType)
-					bValue := makeReturnTypeValue(t, bType)
-					aUpscaled := aFunc(aValue)
-					bUpscaled := bFunc(bValue)
-					assert.IsType(t, makeReturnTypeValue(t, resultType), aUpscaled)
-					assert.IsType(t, makeReturnTypeValue(t, resultType), bUpscaled)
+// TestUpscaleIntTypes asserts that signed types can be upscaled with each other and with narrower uint types.
+func TestUpscaleIntTypes(t *testing.T) {
+	t.Parallel()
+	for _, aType := range intTypes {
+		aType := aType
+		t.Run(aType.String(), func(t *testing.T) {
+			t.Parallel()
+			for _, bType := range intTypes {
+				bType := bType
+				t.Run(bType.String(), func(t *testing.T) {
+					t.Parallel()
+					resultType, err := getUpscaledType(aType, bType)
+					if !assert.NoError(t, err) {
+						return
+					}
+					assertConverts(t, aType, resultType)
+					assertConverts(t, bType, resultType)
 				})
 			}
 		})
 	}
 }
+
+// TestUpscaleU64AndSigned asserts that U64 cannot be mixed with signed types,
+// since no type holds both ranges.
+func TestUpscaleU64AndSigned(t *testing.T) {
+	t.Parallel()
+	for _, signedType := range []ReturnType{
+		ReturnType_RETURN_TYPE_I8,
+		ReturnType_RETURN_TYPE_I16,
+		ReturnType_RETURN_TYPE_I32,
+		ReturnType_RETURN_TYPE_I64,
+	} {
+		_, err := getUpscaledType(ReturnType_RETURN_TYPE_U64, signedType)
+		assert.Error(t, err, signedType.String())
+		_, err = getUpscaledType(signedType, ReturnType_RETURN_TYPE_U64)
+		assert.Error(t, err, signedType.String())
+	}
+}
+
+// TestUpscaleFloatTypes asserts that every numeric type can be upscaled with float types.
+func TestUpscaleFloatTypes(t *testing.T) {
+	t.Parallel()
+	for _, aType := range floatTypes {
+		aType := aType
+		t.Run(aType.String(), func(t *testing.T) {
+			t.Parallel()
+			for _, bType := range []ReturnType{ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_F64} {
+				bType := bType
+				t.Run(bType.String(), func(t *testing.T) {
+					t.Parallel()
+					resultType, err := getUpscaledType(aType, bType)
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.True(t, isFloatType(resultType))
+					assertConverts(t, aType, resultType)
+					assertConverts(t, bType, resultType)
+				})
+			}
+		})
+	}
+}
+
+// TestUpscaleFloatPromotion asserts that wide integers and floats are compared as doubles.
+func TestUpscaleFloatPromotion(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		a, b     ReturnType
+		expected ReturnType
+	}{
+		{ReturnType_RETURN_TYPE_U16, ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_F32},
+		{ReturnType_RETURN_TYPE_U32, ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_F64},
+		{ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_I32, ReturnType_RETURN_TYPE_F64},
+		{ReturnType_RETURN_TYPE_I64, ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_F64},
+		{ReturnType_RETURN_TYPE_F32, ReturnType_RETURN_TYPE_F64, ReturnType_RETURN_TYPE_F64},
+	}
+	for _, tc := range cases {
+		resultType, err := getUpscaledType(tc.a, tc.b)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.expected, resultType, "%s, %s", tc.a, tc.b)
+		}
+	}
+}